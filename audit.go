@@ -0,0 +1,23 @@
+package cruder
+
+import (
+	"net/http"
+
+	"github.com/pechorka/cruder/pkg/audit"
+)
+
+// WithAudit records every request to a mutating route (POST, PUT, PATCH, DELETE)
+// with its principal, bound request and resulting status into log.
+// Log is owned by the caller, close it after the http server is stopped to flush pending entries.
+func WithAudit(log *audit.Log) Option {
+	return func(mux *Mux) {
+		mux.auditLog = log
+	}
+}
+
+func (mux *Mux) recordAudit(r *http.Request, req any, status int) {
+	if mux.auditLog == nil || !audit.Mutating(r.Method) {
+		return
+	}
+	mux.auditLog.Record(r.Context(), r.Pattern, req, status)
+}
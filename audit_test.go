@@ -0,0 +1,48 @@
+package cruder_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/audit"
+	"github.com/stretchr/testify/require"
+)
+
+type memAuditStore struct {
+	entries []audit.Entry
+}
+
+func (s *memAuditStore) Insert(_ context.Context, e audit.Entry) error {
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memAuditStore) Query(context.Context, audit.Filter) ([]audit.Entry, error) {
+	return s.entries, nil
+}
+
+type created struct {
+	Status int `status:"" json:"-"`
+	ID     int `json:"id"`
+}
+
+func TestAuditRecordsWrittenStatus(t *testing.T) {
+	store := &memAuditStore{}
+	log := audit.NewLog(store)
+	mux := cruder.NewMux(cruder.WithAudit(log))
+	err := cruder.RegisterHandler(mux, "POST /orders", func(context.Context, struct{}) (created, error) {
+		return created{Status: http.StatusCreated, ID: 1}, nil
+	})
+	require.NoError(t, err)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("POST", "/orders", nil))
+	require.Equal(t, http.StatusCreated, w.Code)
+
+	require.NoError(t, log.Close(context.Background()))
+	require.Len(t, store.entries, 1)
+	require.Equal(t, http.StatusCreated, store.entries[0].Status)
+}
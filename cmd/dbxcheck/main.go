@@ -0,0 +1,84 @@
+// Command dbxcheck verifies dbx builders of a package against the database schema,
+// read from migrations or a dev database, and exits with 1 if they don't match.
+//
+//	//go:generate go run github.com/pechorka/cruder/cmd/dbxcheck -migrations ./migrations
+//	//go:generate go run github.com/pechorka/cruder/cmd/dbxcheck -driver pgx -dsn postgres://localhost/dev
+package main
+
+import (
+	"context"
+	"database/sql"
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+	"time"
+
+	_ "github.com/jackc/pgx/v5/stdlib"
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxcheck"
+)
+
+func main() {
+	problems, err := run()
+	if err != nil {
+		slog.Error("failed to check queries", "error", err)
+		os.Exit(1)
+	}
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+	}
+	if len(problems) > 0 {
+		os.Exit(1)
+	}
+}
+
+func run() ([]dbxcheck.Problem, error) {
+	dir := flag.String("dir", ".", "package directory to check")
+	migrations := flag.String("migrations", "", "directory of migrations to build the schema from")
+	driver := flag.String("driver", "", "database/sql driver of the dev database, pgx or sqlite3")
+	dsn := flag.String("dsn", "", "data source name of the dev database")
+	dialect := flag.String("dialect", "", "dialect of the dev database: postgres, mysql, sqlite or oracle, derived from -driver if empty")
+	flag.Parse()
+
+	usages, err := dbxcheck.Find(*dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var schema dbxcheck.Schema
+	switch {
+	case *migrations != "":
+		schema, err = dbxcheck.LoadMigrations(os.DirFS(*migrations))
+	case *driver != "":
+		schema, err = readSchema(*driver, *dsn, *dialect, dbxcheck.Tables(usages))
+	default:
+		return nil, fmt.Errorf("-migrations or -driver is required")
+	}
+	if err != nil {
+		return nil, err
+	}
+	return dbxcheck.Verify(usages, schema), nil
+}
+
+func readSchema(driver, dsn, dialect string, tables []string) (dbxcheck.Schema, error) {
+	if dialect == "" {
+		dialect = map[string]string{"pgx": "postgres", "postgres": "postgres", "sqlite3": "sqlite", "mysql": "mysql"}[driver]
+	}
+	d, ok := map[string]dbx.Dialect{"postgres": dbx.Postgres, "mysql": dbx.MySQL, "sqlite": dbx.SQLite, "oracle": dbx.Oracle}[dialect]
+	if !ok {
+		return nil, fmt.Errorf("unknown dialect %q, set it with -dialect", dialect)
+	}
+
+	db, err := sql.Open(driver, dsn)
+	if err != nil {
+		return nil, err
+	}
+	defer db.Close()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	return dbxcheck.ReadSchema(ctx, db, d, tables...)
+}
@@ -0,0 +1,53 @@
+// Command openapi2go generates cruder request/response structs and handler stubs
+// from an existing OpenAPI document.
+//
+//	openapi2go -in openapi.yaml -out api_gen.go -pkg api
+package main
+
+import (
+	"flag"
+	"fmt"
+	"log/slog"
+	"os"
+
+	"github.com/pechorka/cruder/pkg/openapi2go"
+)
+
+func main() {
+	if err := run(); err != nil {
+		slog.Error("failed to generate code", "error", err)
+		os.Exit(1)
+	}
+}
+
+func run() error {
+	in := flag.String("in", "", "path to OpenAPI document (json or yaml)")
+	out := flag.String("out", "", "path to generated file, stdout if empty")
+	pkg := flag.String("pkg", "api", "package name of generated file")
+	flag.Parse()
+
+	if *in == "" {
+		return fmt.Errorf("-in is required")
+	}
+
+	data, err := os.ReadFile(*in)
+	if err != nil {
+		return err
+	}
+
+	spec, err := openapi2go.Parse(data)
+	if err != nil {
+		return err
+	}
+
+	code, err := openapi2go.Generate(spec, *pkg)
+	if err != nil {
+		return err
+	}
+
+	if *out == "" {
+		_, err = os.Stdout.Write(code)
+		return err
+	}
+	return os.WriteFile(*out, code, 0o644)
+}
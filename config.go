@@ -0,0 +1,271 @@
+package cruder
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/limit"
+)
+
+// RuntimeConfig holds middleware settings that can be changed without restarting the server.
+// Zero values disable the corresponding limit.
+type RuntimeConfig struct {
+	MaxConcurrency int       `json:"max_concurrency"`
+	QueueTimeout   Duration  `json:"queue_timeout"`
+	RateLimit      RateLimit `json:"rate_limit"`
+	// RouteRateLimits replace RateLimit for route patterns, e.g. "POST /checkout",
+	// a zero limit exempts the route
+	RouteRateLimits map[string]RateLimit `json:"route_rate_limits"`
+	MaxBodyBytes    int64                `json:"max_body_bytes"`
+	CORSOrigins     []string             `json:"cors_origins"`
+	FeatureFlags    map[string]bool      `json:"feature_flags"`
+}
+
+// RateLimit allows Requests per Interval, requests above it are rejected with 429
+type RateLimit struct {
+	Requests int      `json:"requests"`
+	Interval Duration `json:"interval"`
+}
+
+func (rl RateLimit) enabled() bool {
+	return rl.Requests > 0 && rl.Interval > 0
+}
+
+// rateLimited reports whether requests of the route pattern are rate limited
+func (cfg RuntimeConfig) rateLimited(pattern string) bool {
+	if rl, ok := cfg.RouteRateLimits[pattern]; ok {
+		return rl.enabled()
+	}
+	return cfg.RateLimit.enabled()
+}
+
+func (rl RateLimit) limiter() *limit.RateLimiter {
+	if !rl.enabled() {
+		return nil
+	}
+	return limit.NewRateLimiter(rl.Requests, time.Duration(rl.Interval))
+}
+
+// Duration is time.Duration encoded as a string like "1.5s" in json
+type Duration time.Duration
+
+// UnmarshalJSON implements json.Unmarshaler
+func (d *Duration) UnmarshalJSON(b []byte) error {
+	var s string
+	if err := json.Unmarshal(b, &s); err != nil {
+		return fmt.Errorf("duration must be a string like \"1s\": %w", err)
+	}
+	v, err := time.ParseDuration(s)
+	if err != nil {
+		return err
+	}
+	*d = Duration(v)
+	return nil
+}
+
+// MarshalJSON implements json.Marshaler
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+// ConfigWatcher delivers runtime config updates to apply until ctx is done
+type ConfigWatcher interface {
+	Watch(ctx context.Context, apply func(RuntimeConfig)) error
+}
+
+// ConfigWatcherFunc adapts a function to ConfigWatcher, e.g. a subscription to a config service
+type ConfigWatcherFunc func(ctx context.Context, apply func(RuntimeConfig)) error
+
+// Watch implements ConfigWatcher
+func (f ConfigWatcherFunc) Watch(ctx context.Context, apply func(RuntimeConfig)) error {
+	return f(ctx, apply)
+}
+
+// FileWatcher reloads runtime config from a json file whenever its modification time changes
+type FileWatcher struct {
+	Path     string
+	Interval time.Duration
+}
+
+// Watch implements ConfigWatcher
+func (fw FileWatcher) Watch(ctx context.Context, apply func(RuntimeConfig)) error {
+	interval := fw.Interval
+	if interval <= 0 {
+		interval = 5 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	var lastMod time.Time
+	for {
+		info, err := os.Stat(fw.Path)
+		if err != nil {
+			slog.ErrorContext(ctx, "failed to stat runtime config", "path", fw.Path, "error", err)
+		} else if !info.ModTime().Equal(lastMod) {
+			cfg, err := readRuntimeConfig(fw.Path)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to read runtime config", "path", fw.Path, "error", err)
+			} else {
+				lastMod = info.ModTime()
+				apply(cfg)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		case <-ticker.C:
+		}
+	}
+}
+
+func readRuntimeConfig(path string) (RuntimeConfig, error) {
+	var cfg RuntimeConfig
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return cfg, err
+	}
+	err = json.Unmarshal(data, &cfg)
+	return cfg, err
+}
+
+// runtimeState is an immutable snapshot derived from RuntimeConfig, swapped atomically on update
+type runtimeState struct {
+	cfg     RuntimeConfig
+	limiter *limit.Limiter
+	rate    *limit.RateLimiter
+	// routeRates are limiters of RouteRateLimits, nil for exempt routes
+	routeRates map[string]*limit.RateLimiter
+	origins    map[string]struct{}
+	anyOrigin  bool
+}
+
+func newRuntimeState(cfg RuntimeConfig) *runtimeState {
+	st := &runtimeState{
+		cfg:     cfg,
+		origins: make(map[string]struct{}, len(cfg.CORSOrigins)),
+	}
+	if cfg.MaxConcurrency > 0 {
+		st.limiter = limit.NewLimiter(cfg.MaxConcurrency, time.Duration(cfg.QueueTimeout))
+	}
+	st.rate = cfg.RateLimit.limiter()
+	if len(cfg.RouteRateLimits) > 0 {
+		st.routeRates = make(map[string]*limit.RateLimiter, len(cfg.RouteRateLimits))
+		for pattern, rl := range cfg.RouteRateLimits {
+			st.routeRates[pattern] = rl.limiter()
+		}
+	}
+	for _, origin := range cfg.CORSOrigins {
+		if origin == "*" {
+			st.anyOrigin = true
+		}
+		st.origins[origin] = struct{}{}
+	}
+	return st
+}
+
+// WithRuntimeConfig enables concurrency and rate limits, body size limit, CORS and feature flags
+// driven by cfg, which can later be replaced with UpdateConfig or WatchConfig.
+// Feature flags from the config are used unless WithFlagProvider is set.
+func WithRuntimeConfig(cfg RuntimeConfig) Option {
+	return func(mux *Mux) {
+		mux.runtime = &atomic.Pointer[runtimeState]{}
+		mux.runtime.Store(newRuntimeState(cfg))
+		if mux.flags == nil {
+			mux.flags = runtimeFlags{runtime: mux.runtime}
+		}
+		mux.Use(mux.runtimeMiddleware)
+	}
+}
+
+// UpdateConfig atomically replaces the runtime config, requests in flight keep the old one
+func (mux *Mux) UpdateConfig(cfg RuntimeConfig) error {
+	if mux.runtime == nil {
+		return fmt.Errorf("mux is created without WithRuntimeConfig")
+	}
+	mux.runtime.Store(newRuntimeState(cfg))
+	return nil
+}
+
+// WatchConfig applies updates delivered by w until ctx is done, it blocks
+func (mux *Mux) WatchConfig(ctx context.Context, w ConfigWatcher) error {
+	if mux.runtime == nil {
+		return fmt.Errorf("mux is created without WithRuntimeConfig")
+	}
+	return w.Watch(ctx, func(cfg RuntimeConfig) {
+		mux.runtime.Store(newRuntimeState(cfg))
+	})
+}
+
+func (mux *Mux) runtimeMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		st := mux.runtime.Load()
+
+		if origin := r.Header.Get("Origin"); origin != "" && st.allowOrigin(origin) {
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			w.Header().Add("Vary", "Origin")
+			if r.Method == http.MethodOptions && r.Header.Get("Access-Control-Request-Method") != "" {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, POST, PUT, PATCH, DELETE")
+				if headers := r.Header.Get("Access-Control-Request-Headers"); headers != "" {
+					w.Header().Set("Access-Control-Allow-Headers", headers)
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+		}
+
+		if st.cfg.MaxBodyBytes > 0 {
+			r.Body = http.MaxBytesReader(w, r.Body, st.cfg.MaxBodyBytes)
+		}
+
+		if rate := mux.rateLimiter(st, r); rate != nil && !rate.Allow() {
+			rate.Reject(w)
+			return
+		}
+
+		if st.limiter != nil {
+			if !st.limiter.Acquire(r.Context()) {
+				st.limiter.Reject(w)
+				return
+			}
+			defer st.limiter.Release()
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// rateLimiter returns the limiter of the route r matches or the global one, nil if r isn't limited
+func (mux *Mux) rateLimiter(st *runtimeState, r *http.Request) *limit.RateLimiter {
+	if len(st.routeRates) > 0 {
+		// the pattern isn't set on r before the ServeMux routes it
+		if _, pattern := mux.mux.Handler(r); pattern != "" {
+			if rate, ok := st.routeRates[pattern]; ok {
+				return rate
+			}
+		}
+	}
+	return st.rate
+}
+
+func (st *runtimeState) allowOrigin(origin string) bool {
+	if st.anyOrigin {
+		return true
+	}
+	_, ok := st.origins[strings.TrimSuffix(origin, "/")]
+	return ok
+}
+
+type runtimeFlags struct {
+	runtime *atomic.Pointer[runtimeState]
+}
+
+func (f runtimeFlags) Enabled(_ context.Context, flag string) bool {
+	return f.runtime.Load().cfg.FeatureFlags[flag]
+}
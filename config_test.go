@@ -0,0 +1,96 @@
+package cruder_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRuntimeConfig(t *testing.T) {
+	mux := cruder.NewMux(cruder.WithRuntimeConfig(cruder.RuntimeConfig{
+		CORSOrigins: []string{"https://example.com"},
+	}))
+	err := cruder.RegisterHandler(mux, "POST /checkout", checkout, cruder.WithFeatureFlag("new-checkout"))
+	require.NoError(t, err)
+
+	do := func(body string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("POST", "/checkout", strings.NewReader(body))
+		r.Header.Set("Origin", "https://example.com")
+		r.Header.Set("Content-Type", "application/json")
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do(`{}`)
+	require.Equal(t, http.StatusNotFound, w.Code)
+	require.Equal(t, "https://example.com", w.Header().Get("Access-Control-Allow-Origin"))
+
+	err = mux.UpdateConfig(cruder.RuntimeConfig{
+		MaxBodyBytes: 10,
+		FeatureFlags: map[string]bool{"new-checkout": true},
+	})
+	require.NoError(t, err)
+
+	w = do(`{}`)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.Empty(t, w.Header().Get("Access-Control-Allow-Origin"))
+
+	w = do(`{"cart":"way too long for the limit"}`)
+	require.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+}
+
+func TestRuntimeRateLimit(t *testing.T) {
+	mux := cruder.NewMux(cruder.WithRuntimeConfig(cruder.RuntimeConfig{
+		RateLimit: cruder.RateLimit{Requests: 1, Interval: cruder.Duration(time.Hour)},
+		RouteRateLimits: map[string]cruder.RateLimit{
+			"GET /orders/{id}": {Requests: 2, Interval: cruder.Duration(time.Hour)},
+			// a zero limit exempts the route from the global one
+			"POST /checkout": {},
+		},
+	}))
+	require.NoError(t, cruder.RegisterHandler(mux, "GET /orders/{id}", getOrder))
+	require.NoError(t, cruder.RegisterHandler(mux, "POST /checkout", checkout))
+	require.NoError(t, cruder.RegisterHandler(mux, "GET /items", func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	}))
+
+	do := func(method, path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest(method, path, nil))
+		return w
+	}
+	codes := func(method, path string, n int) []int {
+		var codes []int
+		for range n {
+			codes = append(codes, do(method, path).Code)
+		}
+		return codes
+	}
+
+	require.Equal(t, []int{200, 200, 429}, codes("GET", "/orders/1", 3))
+	require.Equal(t, []int{200, 200, 200}, codes("POST", "/checkout", 3))
+	require.Equal(t, []int{200, 429}, codes("GET", "/items", 2))
+	w := do("GET", "/items")
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	require.NotEmpty(t, w.Header().Get("Retry-After"))
+
+	paths := mux.Swagger().Schema().Paths
+	require.Contains(t, paths["/orders/{id}"].GET.Responses, "429")
+	require.Contains(t, paths["/items"].GET.Responses, "429")
+	require.NotContains(t, paths["/checkout"].POST.Responses, "429")
+
+	// updated limits apply right away
+	require.NoError(t, mux.UpdateConfig(cruder.RuntimeConfig{
+		RateLimit: cruder.RateLimit{Requests: 3, Interval: cruder.Duration(time.Hour)},
+	}))
+	require.Equal(t, []int{200, 200, 200, 429}, codes("POST", "/checkout", 4))
+	require.NoError(t, mux.UpdateConfig(cruder.RuntimeConfig{}))
+	require.Equal(t, []int{200, 200}, codes("GET", "/items", 2))
+}
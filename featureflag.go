@@ -0,0 +1,150 @@
+package cruder
+
+import (
+	"context"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+// FlagProvider decides whether a feature flag is enabled for the request
+type FlagProvider interface {
+	Enabled(ctx context.Context, flag string) bool
+}
+
+// StaticFlags is a FlagProvider backed by a fixed set of flags
+type StaticFlags map[string]bool
+
+// Enabled implements FlagProvider
+func (f StaticFlags) Enabled(_ context.Context, flag string) bool {
+	return f[flag]
+}
+
+// WithFlagProvider sets the provider consulted by routes registered with WithFeatureFlag.
+// Without a provider every flagged route is disabled.
+func WithFlagProvider(p FlagProvider) Option {
+	return func(mux *Mux) {
+		mux.flags = p
+	}
+}
+
+// WithFeatureFlag hides the route behind a feature flag: while the flag is disabled
+// the route responds with 404 and is left out of the served spec
+func WithFeatureFlag(flag string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.featureFlag = flag
+	}
+}
+
+type flaggedRoute struct {
+	path   string
+	method string
+	flag   string
+}
+
+func (mux *Mux) flagEnabled(ctx context.Context, flag string) bool {
+	return mux.flags != nil && mux.flags.Enabled(ctx, flag)
+}
+
+func (mux *Mux) gate(flag string, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !mux.flagEnabled(r.Context(), flag) {
+			http.NotFound(w, r)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// publicSchema returns the spec without operations of disabled routes
+// and without component schemas referenced only by those operations
+func (mux *Mux) publicSchema(ctx context.Context) *swaggergen.OpenAPI {
+	schema := mux.sg.Schema()
+	if len(mux.flagged) == 0 {
+		return schema
+	}
+
+	var components map[string]*swaggergen.Schema
+	if schema.Components != nil {
+		components = schema.Components.Schemas
+	}
+	hidden := make(map[string]bool)
+
+	filtered := *schema
+	filtered.Paths = maps.Clone(schema.Paths)
+	for _, route := range mux.flagged {
+		if mux.flagEnabled(ctx, route.flag) {
+			continue
+		}
+		item, ok := filtered.Paths[route.path]
+		if !ok {
+			continue
+		}
+		var op **swaggergen.Operation
+		switch strings.ToUpper(route.method) {
+		case http.MethodGet:
+			op = &item.GET
+		case http.MethodPost:
+			op = &item.POST
+		case http.MethodPut:
+			op = &item.PUT
+		case http.MethodDelete:
+			op = &item.DELETE
+		case http.MethodPatch:
+			op = &item.PATCH
+		}
+		if op == nil || *op == nil {
+			continue
+		}
+		operationRefs(*op, components, hidden)
+		*op = nil
+		if item == (swaggergen.PathItem{}) {
+			delete(filtered.Paths, route.path)
+			continue
+		}
+		filtered.Paths[route.path] = item
+	}
+	if len(hidden) == 0 || schema.Components == nil {
+		return &filtered
+	}
+
+	visible := make(map[string]bool)
+	for _, items := range []map[string]swaggergen.PathItem{filtered.Paths, filtered.Webhooks} {
+		for _, item := range items {
+			for _, op := range []*swaggergen.Operation{item.GET, item.POST, item.PUT, item.DELETE, item.PATCH} {
+				operationRefs(op, components, visible)
+			}
+		}
+	}
+	prunedComponents := *schema.Components
+	prunedComponents.Schemas = maps.Clone(components)
+	for name := range hidden {
+		if !visible[name] {
+			delete(prunedComponents.Schemas, name)
+		}
+	}
+	filtered.Components = &prunedComponents
+	return &filtered
+}
+
+// operationRefs marks names of component schemas referenced by parameters, request body and responses of op
+func operationRefs(op *swaggergen.Operation, components map[string]*swaggergen.Schema, used map[string]bool) {
+	if op == nil {
+		return
+	}
+	for _, param := range op.Parameters {
+		collectRefs(param.Schema, components, used)
+	}
+	if op.RequestBody != nil {
+		for _, media := range op.RequestBody.Content {
+			collectRefs(media.Schema, components, used)
+		}
+	}
+	for _, resp := range op.Responses {
+		for _, media := range resp.Content {
+			collectRefs(media.Schema, components, used)
+		}
+	}
+}
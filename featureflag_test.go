@@ -0,0 +1,81 @@
+package cruder_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/stretchr/testify/require"
+)
+
+type checkoutRequest struct {
+	Cart string `json:"cart"`
+}
+
+type checkoutResponse struct {
+	OrderID int `json:"order_id"`
+}
+
+type betaOptions struct {
+	Express bool `json:"express"`
+}
+
+type betaCheckout struct {
+	Cart    string      `json:"cart"`
+	Options betaOptions `json:"options"`
+}
+
+func checkout(context.Context, checkoutRequest) (checkoutResponse, error) {
+	return checkoutResponse{OrderID: 1}, nil
+}
+
+func TestFeatureFlag(t *testing.T) {
+	flags := cruder.StaticFlags{}
+	mux := cruder.NewMux(cruder.WithFlagProvider(flags))
+	err := cruder.RegisterHandler(mux, "POST /checkout", checkout, cruder.WithFeatureFlag("new-checkout"))
+	require.NoError(t, err)
+	// the response type is shared with a route that is always visible
+	err = cruder.RegisterHandler(mux, "POST /checkout/legacy", checkout)
+	require.NoError(t, err)
+	err = cruder.RegisterHandler(mux, "POST /checkout/beta", func(context.Context, betaCheckout) (checkoutResponse, error) {
+		return checkoutResponse{}, nil
+	}, cruder.WithFeatureFlag("new-checkout"))
+	require.NoError(t, err)
+
+	var spec struct {
+		Paths      map[string]any `json:"paths"`
+		Components struct {
+			Schemas map[string]any `json:"schemas"`
+		} `json:"components"`
+	}
+	paths := func() map[string]any {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/swagger.json", nil))
+		spec.Paths, spec.Components.Schemas = nil, nil
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&spec))
+		return spec.Paths
+	}
+	status := func() int {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("POST", "/checkout", nil))
+		return w.Code
+	}
+
+	require.Equal(t, http.StatusNotFound, status())
+	require.NotContains(t, paths(), "/checkout")
+	require.NotContains(t, spec.Paths, "/checkout/beta")
+	require.Contains(t, spec.Components.Schemas, "checkoutRequest")
+	require.Contains(t, spec.Components.Schemas, "checkoutResponse")
+	// schemas referenced only by hidden operations are left out
+	require.NotContains(t, spec.Components.Schemas, "betaCheckout")
+	require.NotContains(t, spec.Components.Schemas, "betaOptions")
+
+	flags["new-checkout"] = true
+	require.Equal(t, http.StatusOK, status())
+	require.Contains(t, paths(), "/checkout")
+	require.Contains(t, spec.Components.Schemas, "betaCheckout")
+	require.Contains(t, spec.Components.Schemas, "betaOptions")
+}
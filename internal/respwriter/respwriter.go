@@ -0,0 +1,62 @@
+// Package respwriter captures the status and body handlers write to http.ResponseWriter
+package respwriter
+
+import (
+	"bytes"
+	"net/http"
+)
+
+// Writer records the status of the response written through it
+type Writer struct {
+	http.ResponseWriter
+	status      int
+	wroteHeader bool
+	// body copies the written body, nil unless created with Recording
+	body *bytes.Buffer
+}
+
+// New wraps w, the status is 200 until the handler writes another one
+func New(w http.ResponseWriter) *Writer {
+	return &Writer{ResponseWriter: w, status: http.StatusOK}
+}
+
+// Recording wraps w like New and also keeps a copy of the body
+func Recording(w http.ResponseWriter) *Writer {
+	rw := New(w)
+	rw.body = &bytes.Buffer{}
+	return rw
+}
+
+// Status returns the status sent to the client
+func (w *Writer) Status() int {
+	return w.status
+}
+
+// Body returns the copy of the body, nil for writers created with New
+func (w *Writer) Body() []byte {
+	if w.body == nil {
+		return nil
+	}
+	return w.body.Bytes()
+}
+
+func (w *Writer) WriteHeader(status int) {
+	if !w.wroteHeader {
+		w.status = status
+		w.wroteHeader = true
+	}
+	w.ResponseWriter.WriteHeader(status)
+}
+
+func (w *Writer) Write(b []byte) (int, error) {
+	w.wroteHeader = true
+	if w.body != nil {
+		w.body.Write(b)
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+// Unwrap returns the wrapped writer, so http.ResponseController reaches Flush and deadlines
+func (w *Writer) Unwrap() http.ResponseWriter {
+	return w.ResponseWriter
+}
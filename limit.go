@@ -0,0 +1,24 @@
+package cruder
+
+import (
+	"time"
+
+	"github.com/pechorka/cruder/pkg/limit"
+)
+
+// WithConcurrencyLimit caps the number of requests handled by the mux at once.
+// Requests above the limit wait for a free slot for at most queueTimeout
+// and are rejected with 503 and Retry-After afterwards.
+func WithConcurrencyLimit(max int, queueTimeout time.Duration) Option {
+	return func(mux *Mux) {
+		mux.limited = true
+		mux.Use(limit.NewLimiter(max, queueTimeout).Middleware)
+	}
+}
+
+// WithRouteConcurrencyLimit is WithConcurrencyLimit for a single route
+func WithRouteConcurrencyLimit(max int, queueTimeout time.Duration) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.limiter = limit.NewLimiter(max, queueTimeout)
+	}
+}
@@ -0,0 +1,44 @@
+package cruder
+
+import (
+	"log/slog"
+	"net/http"
+	"time"
+
+	"github.com/pechorka/cruder/internal/respwriter"
+	"github.com/pechorka/cruder/pkg/redact"
+)
+
+// WithLogger logs every handled request with its status and duration.
+// Bound request payloads are logged at debug level and attached to error logs,
+// fields tagged `redact:"true"` are replaced with a placeholder in both.
+func WithLogger(logger *slog.Logger) Option {
+	return func(mux *Mux) {
+		mux.logger = logger
+		mux.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				start := time.Now()
+				sw := respwriter.New(w)
+				next.ServeHTTP(sw, r)
+
+				logger.InfoContext(r.Context(), "request handled",
+					"method", r.Method,
+					"path", r.URL.Path,
+					"status", sw.Status(),
+					"duration", time.Since(start),
+				)
+			})
+		})
+	}
+}
+
+func (mux *Mux) logPayload(r *http.Request, req any) {
+	if mux.logger == nil || !mux.logger.Enabled(r.Context(), slog.LevelDebug) {
+		return
+	}
+	mux.logger.DebugContext(r.Context(), "request decoded",
+		"method", r.Method,
+		"path", r.URL.Path,
+		"request", redact.Value(req),
+	)
+}
@@ -0,0 +1,122 @@
+package cruder
+
+import (
+	"context"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"reflect"
+
+	"github.com/pechorka/cruder/pkg/httpio"
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+// Manifest is a machine-readable description of every registered route,
+// meant for client code generators that need more than the OpenAPI spec carries
+type Manifest struct {
+	Routes  []ManifestRoute               `json:"routes"`
+	Schemas map[string]*swaggergen.Schema `json:"schemas"`
+}
+
+// ManifestRoute describes a single route
+type ManifestRoute struct {
+	Pattern     string `json:"pattern"`
+	Method      string `json:"method"`
+	Path        string `json:"path"`
+	FeatureFlag string `json:"feature_flag,omitempty"`
+	// RequestType and ResponseType are Go type names
+	RequestType  string `json:"request_type"`
+	ResponseType string `json:"response_type"`
+	// Bindings are request fields bound from query, path, header and cookie values
+	Bindings []httpio.Binding `json:"bindings"`
+	// RequestSchema and ResponseSchema describe json bodies, named types reference Schemas
+	RequestSchema  *swaggergen.Schema `json:"request_schema,omitempty"`
+	ResponseSchema *swaggergen.Schema `json:"response_schema,omitempty"`
+}
+
+// WithManifest serves the route manifest as json at path,
+// routes behind feature flags disabled for the request and types only they use are left out
+func WithManifest(path string) Option {
+	return func(mux *Mux) {
+		mux.mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "application/json")
+			if err := json.NewEncoder(w).Encode(mux.publicManifest(r.Context())); err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+		})
+	}
+}
+
+// Manifest describes routes registered so far, schemas are the ones generated at registration.
+// Like Swagger it includes routes behind disabled feature flags, e.g. for tests.
+func (mux *Mux) Manifest() Manifest {
+	routes := make([]ManifestRoute, 0, len(mux.routes))
+	for _, rt := range mux.routes {
+		mr := ManifestRoute{
+			Pattern:        rt.pattern,
+			Method:         rt.method,
+			Path:           rt.path,
+			FeatureFlag:    rt.cfg.featureFlag,
+			RequestType:    typeName(rt.requestType),
+			ResponseType:   typeName(rt.responseType),
+			Bindings:       []httpio.Binding{},
+			RequestSchema:  rt.requestSchema,
+			ResponseSchema: rt.responseSchema,
+		}
+		if rt.requestType != nil {
+			// request types are checked by RegisterHandler
+			if bindings, _ := httpio.Describe(rt.requestType); bindings != nil {
+				mr.Bindings = bindings
+			}
+		}
+		routes = append(routes, mr)
+	}
+
+	return Manifest{
+		Routes:  routes,
+		Schemas: mux.defs,
+	}
+}
+
+// publicManifest is the manifest without routes behind feature flags disabled for ctx
+// and schemas only they reference, see publicSchema
+func (mux *Mux) publicManifest(ctx context.Context) Manifest {
+	manifest := mux.Manifest()
+	if len(mux.flagged) == 0 {
+		return manifest
+	}
+
+	hidden := make(map[string]bool)
+	visible := make(map[string]bool)
+	routes := make([]ManifestRoute, 0, len(manifest.Routes))
+	for _, route := range manifest.Routes {
+		used := visible
+		if route.FeatureFlag != "" && !mux.flagEnabled(ctx, route.FeatureFlag) {
+			used = hidden
+		} else {
+			routes = append(routes, route)
+		}
+		collectRefs(route.RequestSchema, manifest.Schemas, used)
+		collectRefs(route.ResponseSchema, manifest.Schemas, used)
+	}
+	manifest.Routes = routes
+	if len(hidden) == 0 {
+		return manifest
+	}
+
+	manifest.Schemas = maps.Clone(manifest.Schemas)
+	for name := range hidden {
+		if !visible[name] {
+			delete(manifest.Schemas, name)
+		}
+	}
+	return manifest
+}
+
+func typeName(t reflect.Type) string {
+	if t == nil {
+		return ""
+	}
+	return t.String()
+}
@@ -0,0 +1,125 @@
+package cruder_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/stretchr/testify/require"
+)
+
+type orderQuery struct {
+	ID int `path:"id"`
+}
+
+type order struct {
+	ID int `json:"id"`
+}
+
+func getOrder(_ context.Context, q orderQuery) (order, error) {
+	return order{ID: q.ID}, nil
+}
+
+func TestManifestAndSchemaBundle(t *testing.T) {
+	mux := cruder.NewMux(
+		cruder.WithManifest("/manifest.json"),
+		cruder.WithSchemaRegistry("/schemas.json", "v1"),
+	)
+	require.NoError(t, cruder.RegisterHandler(mux, "GET /orders/{id}", getOrder))
+	require.NoError(t, cruder.RegisterHandler(mux, "POST /checkout", checkout, cruder.WithFeatureFlag("new-checkout")))
+
+	components := func() map[string]any {
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, httptest.NewRequest("GET", "/swagger.json", nil))
+		var spec struct {
+			Components struct {
+				Schemas map[string]any `json:"schemas"`
+			} `json:"components"`
+		}
+		require.NoError(t, json.NewDecoder(w.Body).Decode(&spec))
+		return spec.Components.Schemas
+	}
+	before := components()
+
+	var wg sync.WaitGroup
+	for range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for _, path := range []string{"/manifest.json", "/schemas.json", "/swagger.json"} {
+				w := httptest.NewRecorder()
+				mux.ServeHTTP(w, httptest.NewRequest("GET", path, nil))
+				require.Equal(t, http.StatusOK, w.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	// the GET request type is documented as params only, fetching the manifest doesn't add it
+	require.Equal(t, before, components())
+	require.NotContains(t, before, "orderQuery")
+
+	// the served manifest leaves out the route behind the disabled flag and types only it uses
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/manifest.json", nil))
+	var served cruder.Manifest
+	require.NoError(t, json.NewDecoder(w.Body).Decode(&served))
+	require.Len(t, served.Routes, 1)
+	require.Equal(t, "GET /orders/{id}", served.Routes[0].Pattern)
+	require.Contains(t, served.Schemas, "order")
+	require.NotContains(t, served.Schemas, "checkoutRequest")
+	require.NotContains(t, served.Schemas, "checkoutResponse")
+
+	manifest := mux.Manifest()
+	require.Len(t, manifest.Routes, 2)
+	require.Contains(t, manifest.Schemas, "checkoutRequest")
+	require.Equal(t, "#/components/schemas/orderQuery", manifest.Routes[0].RequestSchema.Ref)
+	require.Contains(t, manifest.Schemas, "orderQuery")
+
+	bundle := mux.SchemaBundle(context.Background(), "v1")
+	require.Contains(t, bundle.Routes, "GET /orders/{id}")
+	require.NotContains(t, bundle.Routes, "POST /checkout")
+	require.Contains(t, bundle.Defs, "order")
+	require.NotContains(t, bundle.Defs, "checkoutRequest")
+	require.NotContains(t, bundle.Defs, "checkoutResponse")
+}
+
+func TestSchemaRegistryETag(t *testing.T) {
+	mux := cruder.NewMux(
+		cruder.WithSchemaRegistry("/schemas.json", "v1"),
+		cruder.WithRuntimeConfig(cruder.RuntimeConfig{}),
+	)
+	require.NoError(t, cruder.RegisterHandler(mux, "GET /orders/{id}", getOrder))
+	require.NoError(t, cruder.RegisterHandler(mux, "POST /checkout", checkout, cruder.WithFeatureFlag("new-checkout")))
+
+	get := func(etag string) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", "/schemas.json", nil)
+		if etag != "" {
+			r.Header.Set("If-None-Match", etag)
+		}
+		w := httptest.NewRecorder()
+		mux.ServeHTTP(w, r)
+		return w
+	}
+
+	w := get("")
+	require.Equal(t, http.StatusOK, w.Code)
+	etag := w.Header().Get("ETag")
+	require.NotEmpty(t, etag)
+
+	w = get(etag)
+	require.Equal(t, http.StatusNotModified, w.Code)
+	require.Equal(t, etag, w.Header().Get("ETag"))
+	require.Empty(t, w.Body.String())
+
+	// enabling the flag adds the checkout route to the bundle, so a cached one is stale
+	require.NoError(t, mux.UpdateConfig(cruder.RuntimeConfig{FeatureFlags: map[string]bool{"new-checkout": true}}))
+	w = get(etag)
+	require.Equal(t, http.StatusOK, w.Code)
+	require.NotEqual(t, etag, w.Header().Get("ETag"))
+	require.Contains(t, w.Body.String(), "POST /checkout")
+}
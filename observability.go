@@ -0,0 +1,15 @@
+package cruder
+
+import "github.com/pechorka/cruder/pkg/observability"
+
+// WithObservability wires tracing and metrics for the whole mux: every request gets
+// a server span named after the matched route plus a duration measurement,
+// and request decoding gets its own child span.
+// Use observability.New(cfg).DB to get dbx query spans and observability.LogHandler
+// to get trace-correlated logs with the same attribute naming.
+func WithObservability(cfg observability.Config) Option {
+	return func(mux *Mux) {
+		mux.obs = observability.New(cfg)
+		mux.Use(mux.obs.Middleware)
+	}
+}
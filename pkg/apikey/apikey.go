@@ -0,0 +1,281 @@
+package apikey
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+// DefaultHeader is the header api keys are read from
+const DefaultHeader = "X-API-Key"
+
+const (
+	tokenPrefix   = "ck_"
+	schemeName    = "ApiKeyAuth"
+	visiblePrefix = 8
+)
+
+var (
+	// ErrInvalidKey is returned when a key is unknown or revoked
+	ErrInvalidKey = errors.New("invalid api key")
+	// ErrNotFound is returned by stores when a key doesn't exist
+	ErrNotFound = errors.New("api key not found")
+)
+
+// Key is a stored api key, the plain token is never stored
+type Key struct {
+	ID        string     `db:"id"`
+	Name      string     `db:"name"`
+	Prefix    string     `db:"prefix"`
+	Hash      string     `db:"hash"`
+	CreatedAt time.Time  `db:"created_at"`
+	RevokedAt *time.Time `db:"revoked_at"`
+}
+
+// Revoked reports whether the key was revoked
+func (k Key) Revoked() bool {
+	return k.RevokedAt != nil
+}
+
+// Store persists api keys
+type Store interface {
+	Create(ctx context.Context, key Key) error
+	FindByID(ctx context.Context, id string) (Key, error)
+	FindByHash(ctx context.Context, hash string) (Key, error)
+	Revoke(ctx context.Context, id string, at time.Time) error
+}
+
+// revokedKey is the input of the revoke query
+type revokedKey struct {
+	ID        string    `db:"id"`
+	RevokedAt time.Time `db:"revoked_at"`
+}
+
+// DBStore stores keys in the api_keys table
+type DBStore struct {
+	db      dbx.DB
+	dialect dbx.Dialect
+	insert  *dbx.CompiledInsertQuery[Key, struct{}]
+	byID    *dbx.CompiledSelectQuery[Key]
+	byHash  *dbx.CompiledSelectQuery[Key]
+	revoke  *dbx.CompiledUpdateQuery[revokedKey]
+}
+
+// StoreOption configures DBStore
+type StoreOption func(*DBStore)
+
+// WithDialect sets placeholder syntax of the store queries, dbx.Postgres by default
+func WithDialect(d dbx.Dialect) StoreOption {
+	return func(s *DBStore) {
+		s.dialect = d
+	}
+}
+
+// NewDBStore creates a new dbx backed store
+func NewDBStore(db dbx.DB, opts ...StoreOption) *DBStore {
+	s := &DBStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.insert = dbx.Insert[Key]("api_keys").Dialect(s.dialect).Compile()
+	s.byID = dbx.Select[Key]("api_keys").Dialect(s.dialect).Where(dbx.Eq("id")).Compile()
+	s.byHash = dbx.Select[Key]("api_keys").Dialect(s.dialect).Where(dbx.Eq("hash")).Compile()
+	s.revoke = dbx.Update[revokedKey]("api_keys").Dialect(s.dialect).
+		Where(dbx.Eq("id"), dbx.IsNull("revoked_at")).
+		Compile()
+	return s
+}
+
+// Create implements Store
+func (s *DBStore) Create(ctx context.Context, key Key) error {
+	_, err := s.insert.New(key).ExecContext(ctx, s.db)
+	return err
+}
+
+// FindByID implements Store
+func (s *DBStore) FindByID(ctx context.Context, id string) (Key, error) {
+	return s.find(ctx, s.byID, id)
+}
+
+// FindByHash implements Store
+func (s *DBStore) FindByHash(ctx context.Context, hash string) (Key, error) {
+	return s.find(ctx, s.byHash, hash)
+}
+
+// Revoke implements Store
+func (s *DBStore) Revoke(ctx context.Context, id string, at time.Time) error {
+	n, err := s.revoke.New(revokedKey{ID: id, RevokedAt: at}).ExecContext(ctx, s.db)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *DBStore) find(ctx context.Context, q *dbx.CompiledSelectQuery[Key], arg any) (Key, error) {
+	k, err := q.New(arg).GetContext(ctx, s.db)
+	if errors.Is(err, sql.ErrNoRows) {
+		return k, ErrNotFound
+	}
+	return k, err
+}
+
+// Manager issues, rotates, revokes and verifies api keys
+type Manager struct {
+	store  Store
+	header string
+}
+
+// Option configures Manager
+type Option func(*Manager)
+
+// WithHeader sets the header api keys are read from
+func WithHeader(header string) Option {
+	return func(m *Manager) {
+		m.header = header
+	}
+}
+
+// NewManager creates a new api key manager
+func NewManager(store Store, opts ...Option) *Manager {
+	m := &Manager{
+		store:  store,
+		header: DefaultHeader,
+	}
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Issue creates a new key. Returned token is shown to the caller once and can't be recovered later.
+func (m *Manager) Issue(ctx context.Context, name string) (string, Key, error) {
+	id, err := randomString(16)
+	if err != nil {
+		return "", Key{}, err
+	}
+	secret, err := randomString(32)
+	if err != nil {
+		return "", Key{}, err
+	}
+	token := tokenPrefix + secret
+
+	key := Key{
+		ID:        id,
+		Name:      name,
+		Prefix:    token[:len(tokenPrefix)+visiblePrefix],
+		Hash:      hashToken(token),
+		CreatedAt: time.Now().UTC(),
+	}
+	if err := m.store.Create(ctx, key); err != nil {
+		return "", Key{}, fmt.Errorf("failed to store api key: %w", err)
+	}
+	return token, key, nil
+}
+
+// Rotate revokes the key and issues a new one with the same name
+func (m *Manager) Rotate(ctx context.Context, id string) (string, Key, error) {
+	old, err := m.store.FindByID(ctx, id)
+	if err != nil {
+		return "", Key{}, err
+	}
+	if old.Revoked() {
+		return "", Key{}, ErrInvalidKey
+	}
+
+	token, key, err := m.Issue(ctx, old.Name)
+	if err != nil {
+		return "", Key{}, err
+	}
+	if err := m.Revoke(ctx, id); err != nil {
+		return "", Key{}, err
+	}
+	return token, key, nil
+}
+
+// Revoke revokes the key, so it can't be used anymore
+func (m *Manager) Revoke(ctx context.Context, id string) error {
+	return m.store.Revoke(ctx, id, time.Now().UTC())
+}
+
+// Verify returns the key matching the token
+func (m *Manager) Verify(ctx context.Context, token string) (Key, error) {
+	if token == "" {
+		return Key{}, ErrInvalidKey
+	}
+	hash := hashToken(token)
+	key, err := m.store.FindByHash(ctx, hash)
+	if errors.Is(err, ErrNotFound) {
+		return Key{}, ErrInvalidKey
+	}
+	if err != nil {
+		return Key{}, err
+	}
+	// stores may match hashes loosely, e.g. with case-insensitive collations
+	if subtle.ConstantTimeCompare([]byte(key.Hash), []byte(hash)) != 1 || key.Revoked() {
+		return Key{}, ErrInvalidKey
+	}
+	return key, nil
+}
+
+// Middleware rejects requests without a valid key and puts the verified key into the request context
+func (m *Manager) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, err := m.Verify(r.Context(), r.Header.Get(m.header))
+		if errors.Is(err, ErrInvalidKey) {
+			http.Error(w, err.Error(), http.StatusUnauthorized)
+			return
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(context.WithValue(r.Context(), keyCtxKey{}, key)))
+	})
+}
+
+// Document adds the api key security scheme to the spec and requires it for every operation
+func (m *Manager) Document(g *swaggergen.Generator) {
+	g.AddSecurityScheme(schemeName, swaggergen.APIKeyAuth("header", m.header), true)
+}
+
+// Install protects every route of mux with the middleware and documents the security scheme
+func (m *Manager) Install(mux *cruder.Mux) {
+	mux.Use(m.Middleware)
+	m.Document(mux.Swagger())
+}
+
+type keyCtxKey struct{}
+
+// FromContext returns the key verified by the middleware
+func FromContext(ctx context.Context) (Key, bool) {
+	key, ok := ctx.Value(keyCtxKey{}).(Key)
+	return key, ok
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate random bytes: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
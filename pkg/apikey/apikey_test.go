@@ -0,0 +1,156 @@
+package apikey_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/apikey"
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+func newStore(t *testing.T) *apikey.DBStore {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE api_keys (
+		id TEXT PRIMARY KEY,
+		name TEXT NOT NULL,
+		prefix TEXT NOT NULL,
+		hash TEXT NOT NULL UNIQUE,
+		created_at TIMESTAMP NOT NULL,
+		revoked_at TIMESTAMP
+	)`)
+	require.NoError(t, err)
+	return apikey.NewDBStore(db, apikey.WithDialect(dbx.SQLite))
+}
+
+func TestManager(t *testing.T) {
+	ctx := context.Background()
+	m := apikey.NewManager(newStore(t))
+
+	token, key, err := m.Issue(ctx, "ci")
+	require.NoError(t, err)
+	require.True(t, strings.HasPrefix(token, "ck_"))
+	require.True(t, strings.HasPrefix(token, key.Prefix))
+	// only the hash of the token is stored
+	sum := sha256.Sum256([]byte(token))
+	require.Equal(t, hex.EncodeToString(sum[:]), key.Hash)
+	require.NotContains(t, key.Hash, token)
+
+	verified, err := m.Verify(ctx, token)
+	require.NoError(t, err)
+	require.Equal(t, key.ID, verified.ID)
+	require.Equal(t, "ci", verified.Name)
+
+	for _, bad := range []string{"", token + "x", strings.ToUpper(token)} {
+		_, err := m.Verify(ctx, bad)
+		require.ErrorIs(t, err, apikey.ErrInvalidKey)
+	}
+
+	rotated, rotatedKey, err := m.Rotate(ctx, key.ID)
+	require.NoError(t, err)
+	require.NotEqual(t, token, rotated)
+	require.Equal(t, "ci", rotatedKey.Name)
+	_, err = m.Verify(ctx, token)
+	require.ErrorIs(t, err, apikey.ErrInvalidKey)
+	_, err = m.Verify(ctx, rotated)
+	require.NoError(t, err)
+	// revoked keys can't be rotated again
+	_, _, err = m.Rotate(ctx, key.ID)
+	require.ErrorIs(t, err, apikey.ErrInvalidKey)
+
+	require.NoError(t, m.Revoke(ctx, rotatedKey.ID))
+	_, err = m.Verify(ctx, rotated)
+	require.ErrorIs(t, err, apikey.ErrInvalidKey)
+	require.ErrorIs(t, m.Revoke(ctx, rotatedKey.ID), apikey.ErrNotFound)
+	require.ErrorIs(t, m.Revoke(ctx, "missing"), apikey.ErrNotFound)
+}
+
+// stubStore returns key for any hash, like a store matching hashes loosely
+type stubStore struct {
+	apikey.Store
+	key apikey.Key
+}
+
+func (s stubStore) FindByHash(context.Context, string) (apikey.Key, error) {
+	return s.key, nil
+}
+
+func TestVerifyComparesHashes(t *testing.T) {
+	ctx := context.Background()
+	token := "ck_secret"
+	sum := sha256.Sum256([]byte(token))
+	hash := hex.EncodeToString(sum[:])
+
+	m := apikey.NewManager(stubStore{key: apikey.Key{ID: "1", Hash: hash}})
+	_, err := m.Verify(ctx, token)
+	require.NoError(t, err)
+
+	m = apikey.NewManager(stubStore{key: apikey.Key{ID: "1", Hash: strings.ToUpper(hash)}})
+	_, err = m.Verify(ctx, token)
+	require.ErrorIs(t, err, apikey.ErrInvalidKey)
+}
+
+func TestMiddleware(t *testing.T) {
+	ctx := context.Background()
+	m := apikey.NewManager(newStore(t), apikey.WithHeader("X-Token"))
+	token, key, err := m.Issue(ctx, "ci")
+	require.NoError(t, err)
+	revoked, revokedKey, err := m.Issue(ctx, "old")
+	require.NoError(t, err)
+	require.NoError(t, m.Revoke(ctx, revokedKey.ID))
+
+	h := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		got, ok := apikey.FromContext(r.Context())
+		require.True(t, ok)
+		require.Equal(t, key.ID, got.ID)
+	}))
+
+	for _, tt := range []struct {
+		name   string
+		token  string
+		status int
+	}{
+		{"valid", token, http.StatusOK},
+		{"missing", "", http.StatusUnauthorized},
+		{"unknown", "ck_unknown", http.StatusUnauthorized},
+		{"revoked", revoked, http.StatusUnauthorized},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			r := httptest.NewRequest("GET", "/", nil)
+			if tt.token != "" {
+				r.Header.Set("X-Token", tt.token)
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+			require.Equal(t, tt.status, w.Code)
+		})
+	}
+}
+
+func TestInstallDocumentsUnauthorized(t *testing.T) {
+	mux := cruder.NewMux()
+	err := cruder.RegisterHandler(mux, "GET /ping", func(context.Context, struct{}) (struct{}, error) {
+		return struct{}{}, nil
+	})
+	require.NoError(t, err)
+	apikey.NewManager(newStore(t)).Install(mux)
+
+	w := httptest.NewRecorder()
+	mux.ServeHTTP(w, httptest.NewRequest("GET", "/ping", nil))
+	require.Equal(t, http.StatusUnauthorized, w.Code)
+	require.Contains(t, mux.Swagger().Schema().Paths["/ping"].GET.Responses, "401")
+}
@@ -0,0 +1,219 @@
+package audit
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/redact"
+)
+
+// Entry is a single record of the audit trail
+type Entry struct {
+	ID        int64     `db:"id,auto" json:"id"`
+	Principal string    `db:"principal" json:"principal"`
+	Route     string    `db:"route" json:"route"`
+	Request   string    `db:"request" json:"request"`
+	Status    int       `db:"status" json:"status"`
+	CreatedAt time.Time `db:"created_at" json:"created_at"`
+}
+
+// Filter narrows down entries returned by Query, zero fields are ignored
+type Filter struct {
+	Principal string
+	Route     string
+	Since     time.Time
+	Until     time.Time
+	Limit     int
+}
+
+// Store persists audit entries
+type Store interface {
+	Insert(ctx context.Context, e Entry) error
+	Query(ctx context.Context, f Filter) ([]Entry, error)
+}
+
+var insertEntryQuery = dbx.Insert[Entry]("audit_log").Compile()
+
+// DBStore keeps entries in the audit_log table
+type DBStore struct {
+	db dbx.DB
+}
+
+// NewDBStore creates a new dbx backed store
+func NewDBStore(db dbx.DB) *DBStore {
+	return &DBStore{db: db}
+}
+
+// Insert implements Store
+func (s *DBStore) Insert(ctx context.Context, e Entry) error {
+	_, err := insertEntryQuery.New(e).ExecContext(ctx, s.db)
+	return err
+}
+
+// Query implements Store, entries are returned newest first
+func (s *DBStore) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	var conds []string
+	var args []any
+	add := func(cond string, arg any) {
+		args = append(args, arg)
+		conds = append(conds, fmt.Sprintf(cond, len(args)))
+	}
+	if f.Principal != "" {
+		add("principal = $%d", f.Principal)
+	}
+	if f.Route != "" {
+		add("route = $%d", f.Route)
+	}
+	if !f.Since.IsZero() {
+		add("created_at >= $%d", f.Since)
+	}
+	if !f.Until.IsZero() {
+		add("created_at < $%d", f.Until)
+	}
+
+	query := "SELECT id, principal, route, request, status, created_at FROM audit_log"
+	if len(conds) > 0 {
+		query += " WHERE " + strings.Join(conds, " AND ")
+	}
+	query += " ORDER BY created_at DESC, id DESC"
+	if f.Limit > 0 {
+		query += fmt.Sprintf(" LIMIT %d", f.Limit)
+	}
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []Entry
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Principal, &e.Route, &e.Request, &e.Status, &e.CreatedAt); err != nil {
+			return nil, err
+		}
+		entries = append(entries, e)
+	}
+	return entries, rows.Err()
+}
+
+// Log writes entries to the store asynchronously, so auditing never slows down handlers
+type Log struct {
+	store     Store
+	principal func(ctx context.Context) string
+	entries   chan Entry
+	done      chan struct{}
+	// mu guards closed, so Record never sends on the closed entries channel
+	mu     sync.RWMutex
+	closed bool
+}
+
+// Option configures Log
+type Option func(*Log)
+
+// WithPrincipal sets the function extracting the acting principal from the request context,
+// e.g. the api key name verified by the auth middleware
+func WithPrincipal(fn func(ctx context.Context) string) Option {
+	return func(l *Log) {
+		l.principal = fn
+	}
+}
+
+// WithBufferSize sets how many entries can wait to be written, entries above it are dropped
+func WithBufferSize(n int) Option {
+	return func(l *Log) {
+		l.entries = make(chan Entry, n)
+	}
+}
+
+// NewLog creates a log and starts its writer
+func NewLog(store Store, opts ...Option) *Log {
+	l := &Log{
+		store:     store,
+		principal: func(context.Context) string { return "" },
+		entries:   make(chan Entry, 1000),
+		done:      make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(l)
+	}
+
+	go l.write()
+	return l
+}
+
+// Record enqueues an entry for the request handled by route.
+// Request is stored as json with fields tagged `redact:"true"` replaced.
+// Entries recorded after Close, e.g. by requests in flight during shutdown, are dropped.
+func (l *Log) Record(ctx context.Context, route string, req any, status int) {
+	var summary []byte
+	if req != nil {
+		summary, _ = json.Marshal(redact.Value(req))
+	}
+
+	e := Entry{
+		Principal: l.principal(ctx),
+		Route:     route,
+		Request:   string(summary),
+		Status:    status,
+		CreatedAt: time.Now().UTC(),
+	}
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	if l.closed {
+		slog.WarnContext(ctx, "audit log is closed, entry dropped", "route", route, "principal", e.Principal)
+		return
+	}
+	select {
+	case l.entries <- e:
+	default:
+		slog.WarnContext(ctx, "audit buffer is full, entry dropped", "route", route, "principal", e.Principal)
+	}
+}
+
+// Query returns entries matching the filter
+func (l *Log) Query(ctx context.Context, f Filter) ([]Entry, error) {
+	return l.store.Query(ctx, f)
+}
+
+// Close flushes buffered entries and stops the writer
+func (l *Log) Close(ctx context.Context) error {
+	l.mu.Lock()
+	if !l.closed {
+		l.closed = true
+		close(l.entries)
+	}
+	l.mu.Unlock()
+	select {
+	case <-l.done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (l *Log) write() {
+	defer close(l.done)
+	for e := range l.entries {
+		if err := l.store.Insert(context.Background(), e); err != nil {
+			slog.Error("failed to write audit entry", "route", e.Route, "error", err)
+		}
+	}
+}
+
+// Mutating reports whether requests with the given method change state and should be audited
+func Mutating(method string) bool {
+	switch strings.ToUpper(method) {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	default:
+		return false
+	}
+}
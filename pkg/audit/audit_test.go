@@ -0,0 +1,99 @@
+package audit_test
+
+import (
+	"context"
+	"sync"
+	"testing"
+
+	"github.com/pechorka/cruder/pkg/audit"
+	"github.com/stretchr/testify/require"
+)
+
+type memStore struct {
+	mu      sync.Mutex
+	entries []audit.Entry
+	// started and release block Insert when set, so tests can fill the buffer
+	started chan struct{}
+	release chan struct{}
+}
+
+func (s *memStore) Insert(_ context.Context, e audit.Entry) error {
+	if s.started != nil {
+		s.started <- struct{}{}
+		<-s.release
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.entries = append(s.entries, e)
+	return nil
+}
+
+func (s *memStore) Query(_ context.Context, f audit.Filter) ([]audit.Entry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var res []audit.Entry
+	for _, e := range s.entries {
+		if f.Route == "" || e.Route == f.Route {
+			res = append(res, e)
+		}
+	}
+	return res, nil
+}
+
+type transfer struct {
+	To  string `json:"to"`
+	PIN string `json:"pin" redact:"true"`
+}
+
+func TestLog(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("record and query", func(t *testing.T) {
+		store := &memStore{}
+		log := audit.NewLog(store, audit.WithPrincipal(func(context.Context) string { return "alice" }))
+		log.Record(ctx, "POST /transfers", transfer{To: "bob", PIN: "1234"}, 201)
+		log.Record(ctx, "DELETE /transfers/{id}", nil, 204)
+		require.NoError(t, log.Close(ctx))
+
+		entries, err := log.Query(ctx, audit.Filter{Route: "POST /transfers"})
+		require.NoError(t, err)
+		require.Len(t, entries, 1)
+		require.Equal(t, "alice", entries[0].Principal)
+		require.Equal(t, 201, entries[0].Status)
+		require.NotContains(t, entries[0].Request, "1234")
+		require.Contains(t, entries[0].Request, "bob")
+	})
+
+	t.Run("full buffer drops entries", func(t *testing.T) {
+		store := &memStore{started: make(chan struct{}), release: make(chan struct{})}
+		log := audit.NewLog(store, audit.WithBufferSize(1))
+
+		log.Record(ctx, "first", nil, 200)
+		<-store.started // the writer holds the first entry
+		log.Record(ctx, "second", nil, 200)
+		log.Record(ctx, "dropped", nil, 200)
+		close(store.release)
+		go func() {
+			for range store.started {
+			}
+		}()
+		require.NoError(t, log.Close(ctx))
+		close(store.started)
+
+		routes := make([]string, len(store.entries))
+		for i, e := range store.entries {
+			routes[i] = e.Route
+		}
+		require.Equal(t, []string{"first", "second"}, routes)
+	})
+
+	t.Run("record after close", func(t *testing.T) {
+		store := &memStore{}
+		log := audit.NewLog(store)
+		require.NoError(t, log.Close(ctx))
+		require.NoError(t, log.Close(ctx))
+
+		require.NotPanics(t, func() { log.Record(ctx, "late", nil, 200) })
+		require.Empty(t, store.entries)
+	})
+}
@@ -0,0 +1,335 @@
+package crudertest
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"maps"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/httpio"
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+// Fuzz fuzzes the route registered on mux with pattern, e.g. "GET /users/{id}".
+// Seed corpus is generated from the binding plan and the json schema of the request type:
+// structurally valid values plus boundary and malformed ones for every bound field and body property.
+// Every input must be handled without panics and with one of the status codes documented in the spec,
+// which includes the statuses of framework options active on the route, e.g. 413, 503 or 401.
+// Call it from a FuzzXxx function, `go test` runs the seeds and `go test -fuzz` explores further.
+func Fuzz(f *testing.F, mux *cruder.Mux, pattern string) {
+	f.Helper()
+
+	manifest := mux.Manifest()
+	route, ok := findRoute(manifest, pattern)
+	if !ok {
+		f.Fatalf("route %q is not registered", pattern)
+	}
+	documented := documentedStatuses(mux.Swagger().Schema(), route)
+
+	// every params seed goes with the valid body and every body seed with the valid params
+	params := seedParams(route.Bindings)
+	bodies := seedBodies(route.RequestSchema, manifest.Schemas)
+	for _, p := range params {
+		f.Add(p, []byte(bodies[0]))
+	}
+	for _, body := range bodies[1:] {
+		f.Add(params[0], []byte(body))
+	}
+
+	f.Fuzz(func(t *testing.T, params string, body []byte) {
+		r, err := buildRequest(route, params, body)
+		if err != nil {
+			t.Skip(err)
+		}
+
+		w := httptest.NewRecorder()
+		func() {
+			defer func() {
+				if p := recover(); p != nil {
+					t.Fatalf("handler panicked on params %q and body %q: %v", params, body, p)
+				}
+			}()
+			mux.ServeHTTP(w, r)
+		}()
+
+		if _, ok := documented[w.Code]; !ok {
+			t.Fatalf("undocumented status %d on params %q and body %q: %s", w.Code, params, body, w.Body.String())
+		}
+	})
+}
+
+func findRoute(manifest cruder.Manifest, pattern string) (cruder.ManifestRoute, bool) {
+	for _, route := range manifest.Routes {
+		if route.Pattern == pattern {
+			return route, true
+		}
+	}
+	return cruder.ManifestRoute{}, false
+}
+
+func documentedStatuses(spec *swaggergen.OpenAPI, route cruder.ManifestRoute) map[int]struct{} {
+	res := make(map[int]struct{})
+	item := spec.Paths[route.Path]
+	var op *swaggergen.Operation
+	switch strings.ToUpper(route.Method) {
+	case http.MethodGet:
+		op = item.GET
+	case http.MethodPost:
+		op = item.POST
+	case http.MethodPut:
+		op = item.PUT
+	case http.MethodPatch:
+		op = item.PATCH
+	case http.MethodDelete:
+		op = item.DELETE
+	}
+	if op == nil {
+		return res
+	}
+	for code := range op.Responses {
+		if n, err := strconv.Atoi(code); err == nil {
+			res[n] = struct{}{}
+		}
+	}
+	return res
+}
+
+// seedParams returns url-encoded binding values: one valid set, then one set per
+// binding where only that binding gets each of its invalid values
+func seedParams(bindings []httpio.Binding) []string {
+	valid := url.Values{}
+	for _, b := range bindings {
+		valid.Set(b.Name, validValue(b.Type))
+	}
+	seeds := []string{valid.Encode(), ""}
+
+	for _, b := range bindings {
+		for _, invalid := range invalidValues(b.Type) {
+			vals := url.Values{}
+			for k, v := range valid {
+				vals[k] = v
+			}
+			vals.Set(b.Name, invalid)
+			seeds = append(seeds, vals.Encode())
+		}
+	}
+	return seeds
+}
+
+func validValue(typ string) string {
+	switch strings.TrimPrefix(typ, "*") {
+	case "int", "int8", "int16", "int32", "int64", "uint", "uint8", "uint16", "uint32", "uint64":
+		return "1"
+	case "float32", "float64":
+		return "1.5"
+	case "bool":
+		return "true"
+	default:
+		return "value"
+	}
+}
+
+func invalidValues(typ string) []string {
+	common := []string{"", " ", strings.Repeat("a", 4096), "\x00", "😀"}
+	switch strings.TrimPrefix(typ, "*") {
+	case "int", "int8", "int16", "int32", "int64":
+		return append(common, "-1", "0", "9223372036854775808", "-9223372036854775809", "1.5", "abc")
+	case "uint", "uint8", "uint16", "uint32", "uint64":
+		return append(common, "-1", "0", "18446744073709551616", "abc")
+	case "float32", "float64":
+		return append(common, "NaN", "Inf", "-0", "1e400", "abc")
+	case "bool":
+		return append(common, "yes", "2", "TRUE")
+	default:
+		return common
+	}
+}
+
+// malformedBodies are not valid json or not a json object
+var malformedBodies = []string{"", "{", "null", "[]", `"value"`}
+
+// maxSeedDepth limits nesting of generated bodies, so recursive types terminate
+const maxSeedDepth = 3
+
+// seedBodies returns json bodies built from the request schema: one valid body first, then one body
+// per property where only that property gets each of its invalid values, then malformed bodies
+func seedBodies(schema *swaggergen.Schema, defs map[string]*swaggergen.Schema) []string {
+	schema = resolveSchema(schema, defs)
+	if schema == nil || len(schema.Properties) == 0 {
+		return append([]string{"{}"}, malformedBodies...)
+	}
+
+	valid, _ := validJSON(schema, defs, maxSeedDepth).(map[string]any)
+	seeds := []string{mustMarshal(valid)}
+	for _, name := range slices.Sorted(maps.Keys(schema.Properties)) {
+		for _, invalid := range invalidJSON(resolveSchema(schema.Properties[name], defs)) {
+			body := maps.Clone(valid)
+			body[name] = invalid
+			seeds = append(seeds, mustMarshal(body))
+		}
+	}
+	unknown := maps.Clone(valid)
+	unknown["unknown_property"] = 1
+	seeds = append(seeds, mustMarshal(unknown))
+	return append(seeds, malformedBodies...)
+}
+
+// resolveSchema follows the component reference of s, if it has one
+func resolveSchema(s *swaggergen.Schema, defs map[string]*swaggergen.Schema) *swaggergen.Schema {
+	if s == nil {
+		return nil
+	}
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok {
+		return defs[name]
+	}
+	return s
+}
+
+func validJSON(s *swaggergen.Schema, defs map[string]*swaggergen.Schema, depth int) any {
+	s = resolveSchema(s, defs)
+	if s == nil {
+		return nil
+	}
+	if len(s.Enum) > 0 {
+		return s.Enum[0]
+	}
+	switch s.Type {
+	case "integer":
+		return 1
+	case "number":
+		return 1.5
+	case "boolean":
+		return true
+	case "string":
+		switch s.Format {
+		case "date-time":
+			return "2024-01-02T03:04:05Z"
+		case "uuid":
+			return "6ba7b810-9dad-11d1-80b4-00c04fd430c8"
+		case "byte":
+			return "dmFsdWU="
+		case "duration":
+			return "1s"
+		}
+		return "value"
+	case "array":
+		if depth == 0 {
+			return []any{}
+		}
+		return []any{validJSON(s.Items, defs, depth-1)}
+	}
+	if s.Type == "object" || s.Properties != nil {
+		obj := make(map[string]any, len(s.Properties))
+		if depth == 0 {
+			return obj
+		}
+		for name, prop := range s.Properties {
+			obj[name] = validJSON(prop, defs, depth-1)
+		}
+		return obj
+	}
+	return "value"
+}
+
+// invalidJSON returns values of the wrong type and boundary values for s
+func invalidJSON(s *swaggergen.Schema) []any {
+	common := []any{nil}
+	if s == nil {
+		return common
+	}
+	switch s.Type {
+	case "integer":
+		return append(common, "1", 1.5, -1, 0, json.Number("9223372036854775808"), json.Number("-9223372036854775809"))
+	case "number":
+		return append(common, "1.5", json.Number("1e400"), json.Number("-0"))
+	case "boolean":
+		return append(common, "true", 2)
+	case "string":
+		return append(common, 1, "", " ", strings.Repeat("a", 4096), "\x00", "😀")
+	case "array":
+		return append(common, map[string]any{}, "value", []any{nil})
+	default:
+		return append(common, []any{}, "value", 1)
+	}
+}
+
+func mustMarshal(v any) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		panic(fmt.Sprintf("crudertest: failed to marshal seed body: %v", err))
+	}
+	return string(b)
+}
+
+func buildRequest(route cruder.ManifestRoute, params string, body []byte) (*http.Request, error) {
+	vals, err := url.ParseQuery(params)
+	if err != nil {
+		return nil, fmt.Errorf("invalid params: %w", err)
+	}
+
+	path := route.Path
+	query := url.Values{}
+	header := http.Header{}
+	var cookies []*http.Cookie
+	for _, b := range route.Bindings {
+		v, ok := vals[b.Name]
+		if !ok || len(v) == 0 {
+			continue
+		}
+		switch b.Source {
+		case "path":
+			seg := url.PathEscape(v[0])
+			if seg == "" {
+				// empty segment would make the route unmatchable
+				seg = "_"
+			}
+			path = strings.ReplaceAll(path, "{"+b.Name+"}", seg)
+		case "query":
+			query[b.Name] = v
+		case "header":
+			header.Set(b.Name, v[0])
+		case "cookie":
+			cookies = append(cookies, &http.Cookie{Name: b.Name, Value: v[0]})
+		}
+	}
+	// path parameters without a value still need a segment
+	path = unfilledPathParam.ReplaceAllString(path, "_")
+
+	r := httptest.NewRequest(route.Method, path+"?"+query.Encode(), bytes.NewReader(body))
+	for k, v := range header {
+		if !validHeaderValue(v[0]) {
+			return nil, fmt.Errorf("invalid header value")
+		}
+		r.Header[k] = v
+	}
+	for _, c := range cookies {
+		if !validHeaderValue(c.Value) {
+			return nil, fmt.Errorf("invalid cookie value")
+		}
+		r.AddCookie(c)
+	}
+	if len(body) > 0 {
+		r.Header.Set("Content-Type", "application/json")
+	}
+	return r, nil
+}
+
+func validHeaderValue(v string) bool {
+	for i := 0; i < len(v); i++ {
+		if c := v[i]; c < ' ' && c != '\t' || c == 0x7f {
+			return false
+		}
+	}
+	return true
+}
+
+var unfilledPathParam = regexp.MustCompile(`\{[^}]+\}`)
@@ -0,0 +1,68 @@
+package crudertest_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/crudertest"
+)
+
+type getItemRequest struct {
+	ID     int    `path:"id"`
+	Locale string `header:"Accept-Language"`
+	Limit  *int   `query:"limit"`
+}
+
+type getItemResponse struct {
+	ID int `json:"id"`
+}
+
+func FuzzGetItem(f *testing.F) {
+	mux := cruder.NewMux()
+	err := cruder.RegisterHandler(mux, "GET /items/{id}", func(ctx context.Context, req getItemRequest) (getItemResponse, error) {
+		return getItemResponse{ID: req.ID}, nil
+	})
+	if err != nil {
+		f.Fatal(err)
+	}
+
+	crudertest.Fuzz(f, mux, "GET /items/{id}")
+}
+
+type itemOwner struct {
+	Name  string `json:"name"`
+	Admin bool   `json:"admin"`
+}
+
+type createItemRequest struct {
+	Name  string    `json:"name"`
+	Price float64   `json:"price"`
+	Stock int       `json:"stock"`
+	Tags  []string  `json:"tags"`
+	Owner itemOwner `json:"owner"`
+}
+
+func createItem(ctx context.Context, req createItemRequest) (getItemResponse, error) {
+	return getItemResponse{ID: len(req.Tags) + len(req.Owner.Name)}, nil
+}
+
+func FuzzCreateItem(f *testing.F) {
+	// oversized bodies are rejected with the documented 413
+	mux := cruder.NewMux(cruder.WithRuntimeConfig(cruder.RuntimeConfig{MaxBodyBytes: 1024}))
+	if err := cruder.RegisterHandler(mux, "POST /items", createItem); err != nil {
+		f.Fatal(err)
+	}
+
+	crudertest.Fuzz(f, mux, "POST /items")
+}
+
+func FuzzFlaggedItem(f *testing.F) {
+	// the route responds with the documented 404 while its flag is disabled
+	mux := cruder.NewMux()
+	if err := cruder.RegisterHandler(mux, "POST /items", createItem, cruder.WithFeatureFlag("items")); err != nil {
+		f.Fatal(err)
+	}
+
+	crudertest.Fuzz(f, mux, "POST /items")
+}
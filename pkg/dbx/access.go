@@ -0,0 +1,107 @@
+package dbx
+
+import (
+	"reflect"
+	"time"
+	"unsafe"
+)
+
+// access holds field accessors resolved at Compile time, so New and ExecContext
+// don't walk reflect metadata on every call
+type access struct {
+	// get returns the query arg of the field of the struct at p, nil if the field is behind a pointer
+	get func(p unsafe.Pointer) interface{}
+	// addr returns the scan destination of the field of the struct at p, nil if the field is behind a pointer
+	addr func(p unsafe.Pointer) interface{}
+}
+
+// resolveAccess sets accessors of fields reachable from root without pointer hops
+func resolveAccess(root reflect.Type, fields []fieldInfo) {
+	for i := range fields {
+		offset, ok := fieldOffset(root, fields[i].Index)
+		if !ok {
+			continue
+		}
+		fields[i].access = newAccess(fields[i].Type, offset)
+		if fields[i].Array {
+			fields[i].access = arrayAccess(fields[i].access)
+		}
+	}
+}
+
+func fieldOffset(t reflect.Type, index []int) (uintptr, bool) {
+	var offset uintptr
+	for _, x := range index {
+		if t.Kind() != reflect.Struct {
+			return 0, false
+		}
+		f := t.Field(x)
+		offset += f.Offset
+		t = f.Type
+	}
+	return offset, true
+}
+
+func newAccess(t reflect.Type, offset uintptr) access {
+	// common column types are read without reflection, named types fall through
+	// to the generic accessor since they may implement driver.Valuer
+	switch t {
+	case reflect.TypeOf(""):
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*string)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*string)(unsafe.Add(p, offset)) },
+		}
+	case reflect.TypeOf(0):
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*int)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*int)(unsafe.Add(p, offset)) },
+		}
+	case reflect.TypeOf(int64(0)):
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*int64)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*int64)(unsafe.Add(p, offset)) },
+		}
+	case reflect.TypeOf(false):
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*bool)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*bool)(unsafe.Add(p, offset)) },
+		}
+	case reflect.TypeOf(float64(0)):
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*float64)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*float64)(unsafe.Add(p, offset)) },
+		}
+	case timeType:
+		return access{
+			get:  func(p unsafe.Pointer) interface{} { return *(*time.Time)(unsafe.Add(p, offset)) },
+			addr: func(p unsafe.Pointer) interface{} { return (*time.Time)(unsafe.Add(p, offset)) },
+		}
+	}
+
+	ptrValuer := !t.Implements(driverValuerType) && reflect.PointerTo(t).Implements(driverValuerType)
+	isPointer := t.Kind() == reflect.Pointer
+	return access{
+		get: func(p unsafe.Pointer) interface{} {
+			v := reflect.NewAt(t, unsafe.Add(p, offset))
+			if ptrValuer {
+				// Value is declared on the pointer receiver, database/sql won't find it on a copy
+				return v.Interface()
+			}
+			if isPointer && v.Elem().IsNil() {
+				return nil
+			}
+			return v.Elem().Interface()
+		},
+		addr: func(p unsafe.Pointer) interface{} {
+			return reflect.NewAt(t, unsafe.Add(p, offset)).Interface()
+		},
+	}
+}
+
+// arrayAccess wraps values and scan destinations of a with the Array adapter
+func arrayAccess(a access) access {
+	return access{
+		get:  func(p unsafe.Pointer) interface{} { return Array(a.get(p)) },
+		addr: func(p unsafe.Pointer) interface{} { return Array(a.addr(p)) },
+	}
+}
@@ -0,0 +1,274 @@
+package dbx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// ArrayValue binds and scans a Go slice as a Postgres array column, see Array
+type ArrayValue interface {
+	driver.Valuer
+	sql.Scanner
+}
+
+// Array adapts a slice to a Postgres array in the text format, e.g. {"a","b"}.
+// Bind it with a slice, e.g. Array([]string{"a", "b"}), and scan it with a slice pointer, e.g. Array(&tags).
+// Elements are strings, integers, floats, bools, pointers to them for NULL elements and slices of them
+// for multidimensional arrays. A nil slice is bound as NULL and NULL is scanned as a nil slice.
+// Fields tagged with the array option, e.g. `db:"tags,array"`, are wrapped with Array automatically.
+// pgx binds and scans slices natively, so queries run with dbxpgx don't need the option, but it works there as well.
+func Array(v interface{}) ArrayValue {
+	return arrayValue{v: v}
+}
+
+type arrayValue struct {
+	v interface{}
+}
+
+func (a arrayValue) Value() (driver.Value, error) {
+	if a.v == nil {
+		return nil, nil
+	}
+	v := reflect.ValueOf(a.v)
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil, nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Slice {
+		return nil, fmt.Errorf("dbx: can't bind %T as an array", a.v)
+	}
+	if v.IsNil() {
+		return nil, nil
+	}
+	var b strings.Builder
+	if err := appendArray(&b, v); err != nil {
+		return nil, err
+	}
+	return b.String(), nil
+}
+
+func appendArray(b *strings.Builder, v reflect.Value) error {
+	b.WriteByte('{')
+	for i := 0; i < v.Len(); i++ {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		if err := appendElem(b, v.Index(i)); err != nil {
+			return err
+		}
+	}
+	b.WriteByte('}')
+	return nil
+}
+
+func appendElem(b *strings.Builder, v reflect.Value) error {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			b.WriteString("NULL")
+			return nil
+		}
+		v = v.Elem()
+	}
+	switch {
+	case v.Kind() == reflect.String:
+		b.WriteByte('"')
+		for _, r := range v.String() {
+			if r == '"' || r == '\\' {
+				b.WriteByte('\\')
+			}
+			b.WriteRune(r)
+		}
+		b.WriteByte('"')
+	case isIntegerKind(v.Kind()) && v.CanInt():
+		b.WriteString(strconv.FormatInt(v.Int(), 10))
+	case isIntegerKind(v.Kind()):
+		b.WriteString(strconv.FormatUint(v.Uint(), 10))
+	case v.Kind() == reflect.Float32 || v.Kind() == reflect.Float64:
+		b.WriteString(strconv.FormatFloat(v.Float(), 'g', -1, v.Type().Bits()))
+	case v.Kind() == reflect.Bool:
+		if v.Bool() {
+			b.WriteByte('t')
+		} else {
+			b.WriteByte('f')
+		}
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() != reflect.Uint8:
+		return appendArray(b, v)
+	default:
+		return fmt.Errorf("dbx: unsupported array element type %s", v.Type())
+	}
+	return nil
+}
+
+func (a arrayValue) Scan(src interface{}) error {
+	dest := reflect.ValueOf(a.v)
+	if dest.Kind() != reflect.Pointer || dest.IsNil() {
+		return fmt.Errorf("dbx: can't scan an array into %T, it must be a slice pointer", a.v)
+	}
+	dest = dest.Elem()
+
+	var text string
+	switch src := src.(type) {
+	case nil:
+		dest.SetZero()
+		return nil
+	case string:
+		text = src
+	case []byte:
+		text = string(src)
+	default:
+		return fmt.Errorf("dbx: can't scan %T into an array", src)
+	}
+
+	// pointer to slice fields are allocated for non NULL arrays
+	for dest.Kind() == reflect.Pointer {
+		if dest.IsNil() {
+			dest.Set(reflect.New(dest.Type().Elem()))
+		}
+		dest = dest.Elem()
+	}
+	if dest.Kind() != reflect.Slice {
+		return fmt.Errorf("dbx: can't scan an array into %T, it must be a slice pointer", a.v)
+	}
+
+	if i := strings.Index(text, "="); i >= 0 && strings.HasPrefix(text, "[") {
+		// dimension decoration of arrays with non default bounds, e.g. [0:1]={1,2}
+		text = text[i+1:]
+	}
+	p := arrayParser{text: text}
+	if err := p.parseArray(dest); err != nil {
+		return err
+	}
+	if p.pos != len(p.text) {
+		return fmt.Errorf("dbx: malformed array %q", text)
+	}
+	return nil
+}
+
+// arrayParser parses the text format of Postgres arrays
+type arrayParser struct {
+	text string
+	pos  int
+}
+
+func (p *arrayParser) parseArray(dest reflect.Value) error {
+	if !p.consume('{') {
+		return fmt.Errorf("dbx: malformed array %q", p.text)
+	}
+	elems := reflect.MakeSlice(dest.Type(), 0, 0)
+	if p.consume('}') {
+		dest.Set(elems)
+		return nil
+	}
+	for {
+		elem := reflect.New(dest.Type().Elem()).Elem()
+		if err := p.parseElem(elem); err != nil {
+			return err
+		}
+		elems = reflect.Append(elems, elem)
+
+		if p.consume('}') {
+			dest.Set(elems)
+			return nil
+		}
+		if !p.consume(',') {
+			return fmt.Errorf("dbx: malformed array %q", p.text)
+		}
+	}
+}
+
+func (p *arrayParser) parseElem(dest reflect.Value) error {
+	if p.pos < len(p.text) && p.text[p.pos] == '{' {
+		if dest.Kind() != reflect.Slice {
+			return fmt.Errorf("dbx: can't scan a nested array into %s", dest.Type())
+		}
+		return p.parseArray(dest)
+	}
+
+	var s string
+	if p.consume('"') {
+		var b strings.Builder
+		for {
+			if p.pos >= len(p.text) {
+				return fmt.Errorf("dbx: malformed array %q", p.text)
+			}
+			c := p.text[p.pos]
+			p.pos++
+			if c == '"' {
+				break
+			}
+			if c == '\\' && p.pos < len(p.text) {
+				c = p.text[p.pos]
+				p.pos++
+			}
+			b.WriteByte(c)
+		}
+		s = b.String()
+	} else {
+		end := strings.IndexAny(p.text[p.pos:], ",}")
+		if end < 0 {
+			return fmt.Errorf("dbx: malformed array %q", p.text)
+		}
+		s = strings.TrimSpace(p.text[p.pos : p.pos+end])
+		p.pos += end
+		if strings.EqualFold(s, "NULL") {
+			if dest.Kind() != reflect.Pointer {
+				return fmt.Errorf("dbx: can't scan NULL array element into %s", dest.Type())
+			}
+			return nil
+		}
+	}
+
+	if dest.Kind() == reflect.Pointer {
+		dest.Set(reflect.New(dest.Type().Elem()))
+		dest = dest.Elem()
+	}
+	return setArrayElem(dest, s)
+}
+
+func (p *arrayParser) consume(c byte) bool {
+	if p.pos < len(p.text) && p.text[p.pos] == c {
+		p.pos++
+		return true
+	}
+	return false
+}
+
+func setArrayElem(dest reflect.Value, s string) error {
+	switch {
+	case dest.Kind() == reflect.String:
+		dest.SetString(s)
+	case isIntegerKind(dest.Kind()) && dest.CanInt():
+		n, err := strconv.ParseInt(s, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("dbx: array element: %w", err)
+		}
+		dest.SetInt(n)
+	case isIntegerKind(dest.Kind()):
+		n, err := strconv.ParseUint(s, 10, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("dbx: array element: %w", err)
+		}
+		dest.SetUint(n)
+	case dest.Kind() == reflect.Float32 || dest.Kind() == reflect.Float64:
+		f, err := strconv.ParseFloat(s, dest.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("dbx: array element: %w", err)
+		}
+		dest.SetFloat(f)
+	case dest.Kind() == reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return fmt.Errorf("dbx: array element: %w", err)
+		}
+		dest.SetBool(b)
+	default:
+		return fmt.Errorf("dbx: unsupported array element type %s", dest.Type())
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ExecutableBatchQuery represents a multi-row insert query ready for execution
+type ExecutableBatchQuery[T, R any] struct {
+	compiled *CompiledInsertQuery[T, R]
+	query    string
+	args     []interface{}
+	rows     int
+}
+
+// NewBatch creates a new executable query inserting all inputs with a single multi-VALUES statement.
+// Statement text depends on the number of inputs, so it's built here and not at Compile time.
+func (cq *CompiledInsertQuery[T, R]) NewBatch(inputs []T) *ExecutableBatchQuery[T, R] {
+	var args []interface{}
+	for _, input := range inputs {
+		args = append(args, extractArgs(&input, cq.inputFields)...)
+	}
+
+	return &ExecutableBatchQuery[T, R]{
+		compiled: cq,
+		query:    cq.dialect.Rebind(buildBatchInsertQuery(cq.dialect, cq.table, cq.inputFields, cq.clauses, len(inputs))),
+		args:     args,
+		rows:     len(inputs),
+	}
+}
+
+func (cq *CompiledInsertQuery[T, R]) PreviewBatchQuery(inputs []T) (string, []any) {
+	eq := cq.NewBatch(inputs)
+	return eq.query, eq.args
+}
+
+// ExecContext executes the query. Inserted rows are returned in insertion order
+// if the query has a returning clause, otherwise the result is nil.
+func (eq *ExecutableBatchQuery[T, R]) ExecContext(ctx context.Context, db DB) ([]R, error) {
+	if eq.rows == 0 {
+		return nil, errors.New("batch insert requires at least one input")
+	}
+
+	if !eq.compiled.hasReturning {
+		_, err := db.ExecContext(ctx, eq.query, eq.args...)
+		return nil, err
+	}
+
+	return queryRows[R](ctx, db, eq.query, eq.args, eq.compiled.returningFields)
+}
+
+// buildBatchInsertQuery builds INSERT of n rows
+func buildBatchInsertQuery(d Dialect, table string, inputFields []fieldInfo, clauses insertClauses, n int) string {
+	var insertFields []fieldInfo
+	for _, field := range inputFields {
+		if !field.IsAuto {
+			insertFields = append(insertFields, field)
+		}
+	}
+
+	values := make([]string, 0, n)
+	placeholderCount := 0
+	for i := 0; i < n; i++ {
+		placeholders := make([]string, 0, len(insertFields))
+		for range insertFields {
+			placeholderCount++
+			placeholders = append(placeholders, fmt.Sprintf("$%d", placeholderCount))
+		}
+		values = append(values, "("+strings.Join(placeholders, ", ")+")")
+	}
+
+	insert := "INSERT INTO"
+	if clauses.doNothing && d == MySQL {
+		insert = "INSERT IGNORE INTO"
+	}
+	query := fmt.Sprintf("%s %s (%s) VALUES %s",
+		insert,
+		d.mustTable(table),
+		d.columnList(insertFields),
+		strings.Join(values, ", "))
+
+	if clauses.doNothing && d != MySQL {
+		query += " ON CONFLICT"
+		if len(clauses.conflictCols) > 0 {
+			cols := make([]string, len(clauses.conflictCols))
+			for i, col := range clauses.conflictCols {
+				cols[i] = d.mustIdent(col)
+			}
+			query += " (" + strings.Join(cols, ", ") + ")"
+		}
+		query += " DO NOTHING"
+	}
+	if clauses.returning != "" {
+		query += " RETURNING " + clauses.returning
+	}
+
+	return query
+}
+
+// Copier loads rows with the COPY protocol, e.g. an adapter of pgx.Conn:
+//
+//	func (c pgxCopier) CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error) {
+//		return c.conn.CopyFrom(ctx, pgx.Identifier{table}, columns, pgx.CopyFromRows(rows))
+//	}
+//
+// or of lib/pq CopyIn statements prepared in a transaction.
+type Copier interface {
+	CopyFrom(ctx context.Context, table string, columns []string, rows [][]any) (int64, error)
+}
+
+// CopyContext inserts inputs with the COPY protocol if db implements Copier and falls back
+// to the multi-VALUES statement otherwise. It returns the number of inserted rows.
+// COPY can't return rows, so queries with a returning clause are rejected.
+func (eq *ExecutableBatchQuery[T, R]) CopyContext(ctx context.Context, db DB) (int64, error) {
+	if eq.rows == 0 {
+		return 0, errors.New("batch insert requires at least one input")
+	}
+	if eq.compiled.hasReturning {
+		return 0, fmt.Errorf("query with returning clause can't be copied: %s", eq.compiled.query)
+	}
+
+	copier, ok := db.(Copier)
+	if !ok {
+		res, err := db.ExecContext(ctx, eq.query, eq.args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	var columns []string
+	for _, field := range eq.compiled.inputFields {
+		if !field.IsAuto {
+			columns = append(columns, field.DbName)
+		}
+	}
+	rows := make([][]any, eq.rows)
+	for i := range rows {
+		rows[i] = eq.args[i*len(columns) : (i+1)*len(columns)]
+	}
+	return copier.CopyFrom(ctx, eq.compiled.table, columns, rows)
+}
@@ -0,0 +1,77 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type benchRow struct {
+	ID        int64     `db:"id,auto"`
+	Name      string    `db:"name"`
+	Email     string    `db:"email"`
+	Age       int       `db:"age"`
+	Active    bool      `db:"active"`
+	CreatedAt time.Time `db:"created_at"`
+}
+
+// fakeRow assigns values to scan destinations without a driver, so benchmarks measure field mapping only
+type fakeRow []interface{}
+
+func (r fakeRow) Scan(dest ...interface{}) error {
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *int64:
+			*d = r[i].(int64)
+		case *int:
+			*d = r[i].(int)
+		case *string:
+			*d = r[i].(string)
+		case *bool:
+			*d = r[i].(bool)
+		case *time.Time:
+			*d = r[i].(time.Time)
+		default:
+			return fmt.Errorf("unexpected dest %T", d)
+		}
+	}
+	return nil
+}
+
+var benchInput = benchRow{ID: 1, Name: "John", Email: "john@example.com", Age: 30, Active: true, CreatedAt: time.Now()}
+
+func BenchmarkExtractArgs(b *testing.B) {
+	q := Insert[benchRow]("users").Compile()
+	b.ReportAllocs()
+	for b.Loop() {
+		q.PreviewQuery(benchInput)
+	}
+}
+
+func BenchmarkScanRow(b *testing.B) {
+	fields := extractFields(reflect.TypeOf(benchRow{}))
+	row := fakeRow{benchInput.ID, benchInput.Name, benchInput.Email, benchInput.Age, benchInput.Active, benchInput.CreatedAt}
+	b.ReportAllocs()
+	for b.Loop() {
+		var r benchRow
+		if err := scanRow(row, &r, fields); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+// BenchmarkExtractArgsByName is the per-call reflection baseline the compiled accessors replace
+func BenchmarkExtractArgsByName(b *testing.B) {
+	fields := extractFields(reflect.TypeOf(benchRow{}))
+	b.ReportAllocs()
+	for b.Loop() {
+		v := reflect.ValueOf(benchInput)
+		args := make([]interface{}, 0, len(fields))
+		for _, field := range fields {
+			if !field.IsAuto {
+				args = append(args, v.FieldByName(field.Name).Interface())
+			}
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// CountBuilder represents a SELECT COUNT(*) query builder
+type CountBuilder[T any] struct {
+	filter filter[T]
+}
+
+// ExistsBuilder represents a SELECT EXISTS query builder
+type ExistsBuilder[T any] struct {
+	filter filter[T]
+}
+
+// CompiledCountQuery represents a compiled count query
+type CompiledCountQuery[T any] struct {
+	compiledFilter[T]
+}
+
+// CompiledExistsQuery represents a compiled exists query
+type CompiledExistsQuery[T any] struct {
+	compiledFilter[T]
+}
+
+// ExecutableCountQuery represents a count query ready for execution
+type ExecutableCountQuery[T any] struct {
+	query string
+	args  []interface{}
+}
+
+// ExecutableExistsQuery represents an exists query ready for execution
+type ExecutableExistsQuery[T any] struct {
+	query string
+	args  []interface{}
+}
+
+// Count creates a new count query builder, WHERE placeholders are bound from the db tagged fields of T
+func Count[T any](table string) *CountBuilder[T] {
+	return &CountBuilder[T]{filter: newFilter[T](table)}
+}
+
+// Where adds conditions joined with AND, placeholders are bound from the fields of T with the same column name
+func (cb *CountBuilder[T]) Where(conds ...Condition) *CountBuilder[T] {
+	cb.filter.where = append(cb.filter.where, conds...)
+	return cb
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (cb *CountBuilder[T]) Dialect(d Dialect) *CountBuilder[T] {
+	cb.filter.dialect = d
+	return cb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (cb *CountBuilder[T]) Naming(naming NamingStrategy) *CountBuilder[T] {
+	cb.filter.inputFields = extractNamedFields(cb.filter.inputType, naming)
+	return cb
+}
+
+// Compile compiles the count query into a reusable form.
+// It panics if a WHERE column is not a field of T.
+func (cb *CountBuilder[T]) Compile() *CompiledCountQuery[T] {
+	return &CompiledCountQuery[T]{cb.filter.compile("SELECT COUNT(*) FROM %s", "")}
+}
+
+// New creates a new executable query with the given input
+func (cq *CompiledCountQuery[T]) New(input T) *ExecutableCountQuery[T] {
+	query, args := cq.PreviewQuery(input)
+	return &ExecutableCountQuery[T]{query: query, args: args}
+}
+
+// ExecContext executes the query and returns the number of matching rows
+func (eq *ExecutableCountQuery[T]) ExecContext(ctx context.Context, db DB) (int64, error) {
+	var n int64
+	err := db.QueryRowContext(ctx, eq.query, eq.args...).Scan(&n)
+	return n, err
+}
+
+// Exists creates a new exists query builder, WHERE placeholders are bound from the db tagged fields of T
+func Exists[T any](table string) *ExistsBuilder[T] {
+	return &ExistsBuilder[T]{filter: newFilter[T](table)}
+}
+
+// Where adds conditions joined with AND, placeholders are bound from the fields of T with the same column name
+func (eb *ExistsBuilder[T]) Where(conds ...Condition) *ExistsBuilder[T] {
+	eb.filter.where = append(eb.filter.where, conds...)
+	return eb
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (eb *ExistsBuilder[T]) Dialect(d Dialect) *ExistsBuilder[T] {
+	eb.filter.dialect = d
+	return eb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (eb *ExistsBuilder[T]) Naming(naming NamingStrategy) *ExistsBuilder[T] {
+	eb.filter.inputFields = extractNamedFields(eb.filter.inputType, naming)
+	return eb
+}
+
+// Compile compiles the exists query into a reusable form.
+// It panics if a WHERE column is not a field of T.
+func (eb *ExistsBuilder[T]) Compile() *CompiledExistsQuery[T] {
+	return &CompiledExistsQuery[T]{eb.filter.compile("SELECT EXISTS (SELECT 1 FROM %s", ")")}
+}
+
+// New creates a new executable query with the given input
+func (cq *CompiledExistsQuery[T]) New(input T) *ExecutableExistsQuery[T] {
+	query, args := cq.PreviewQuery(input)
+	return &ExecutableExistsQuery[T]{query: query, args: args}
+}
+
+// ExecContext executes the query and reports whether any row matches
+func (eq *ExecutableExistsQuery[T]) ExecContext(ctx context.Context, db DB) (bool, error) {
+	var exists bool
+	err := db.QueryRowContext(ctx, eq.query, eq.args...).Scan(&exists)
+	return exists, err
+}
+
+// filter is a table with WHERE conditions bound from the fields of T
+type filter[T any] struct {
+	table       string
+	inputType   reflect.Type
+	inputFields []fieldInfo
+	where       []Condition
+	dialect     Dialect
+}
+
+func newFilter[T any](table string) filter[T] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+	return filter[T]{
+		table:       table,
+		inputType:   inputType,
+		inputFields: extractFields(inputType),
+	}
+}
+
+// compile writes head formatted with the table, WHERE clause and tail
+func (f *filter[T]) compile(head, tail string) compiledFilter[T] {
+	b := &queryBuilder{dialect: f.dialect}
+	b.writeString(fmt.Sprintf(head, f.dialect.mustTable(f.table)))
+	appendWhere(b, f.where)
+	b.writeString(tail)
+
+	return compiledFilter[T]{
+		query:     f.dialect.Rebind(b.String()),
+		argFields: b.resolve(f.inputType, f.inputFields),
+		ph:        newPlaceholders(b, f.dialect),
+	}
+}
+
+type compiledFilter[T any] struct {
+	query     string
+	argFields []fieldInfo
+	ph        placeholders
+}
+
+func (cf *compiledFilter[T]) PreviewQuery(input T) (string, []any) {
+	return cf.ph.bind(cf.query, extractFieldArgs(&input, cf.argFields))
+}
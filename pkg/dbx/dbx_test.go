@@ -0,0 +1,1445 @@
+package dbx_test
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxtest"
+	"github.com/pechorka/cruder/pkg/pagination"
+)
+
+type user struct {
+	ID   int    `db:"id,auto"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+type insertUserInput struct {
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func openDB(t *testing.T) *sql.DB {
+	t.Helper()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	// every connection gets its own in-memory database
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, name TEXT NOT NULL, age INTEGER NOT NULL)")
+	require.NoError(t, err)
+	return db
+}
+
+func seedUsers(t *testing.T, db dbx.DB, users ...insertUserInput) {
+	t.Helper()
+	q := dbx.Insert[insertUserInput]("users").Compile()
+	for _, u := range users {
+		_, err := q.New(u).ExecContext(context.Background(), db)
+		require.NoError(t, err)
+	}
+}
+
+func TestSelect(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	q := dbx.Select[user]("users").
+		Where(dbx.Gte("age"), dbx.Or(dbx.Like("name"), dbx.Eq("name"))).
+		OrderBy("age DESC").
+		Limit(10).
+		Compile()
+
+	query, _ := q.PreviewQuery(26, "J%", "Bob")
+	require.Equal(t, "SELECT id, name, age FROM users WHERE age >= $1 AND (name LIKE $2 OR name = $3) ORDER BY age DESC LIMIT 10", query)
+
+	users, err := q.New(26, "J%", "Bob").ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 40}, {ID: 1, Name: "John", Age: 30}}, users)
+
+	u, err := dbx.Select[user]("users").Where(dbx.Eq("id")).Compile().New(2).GetContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, "Jane", u.Name)
+
+	_, err = q.New(1).ExecContext(ctx, db)
+	require.Error(t, err)
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+
+	q := dbx.Update[user]("users").Where(dbx.Eq("id")).Compile()
+
+	query, args := q.PreviewQuery(user{ID: 1, Name: "Johnny", Age: 31})
+	require.Equal(t, "UPDATE users SET name = $1, age = $2 WHERE id = $3", query)
+	require.Equal(t, []any{"Johnny", 31, 1}, args)
+
+	n, err := q.New(user{ID: 1, Name: "Johnny", Age: 31}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	users, err := dbx.Select[user]("users").OrderBy("id").Compile().New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "Johnny", Age: 31}, {ID: 2, Name: "Jane", Age: 25}}, users)
+
+	require.Panics(t, func() {
+		dbx.Update[user]("users").Where(dbx.Eq("email")).Compile()
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	type byID struct {
+		ID int `db:"id"`
+	}
+	q := dbx.Delete[byID]("users").Where(dbx.Eq("id")).Compile()
+
+	query, args := q.PreviewQuery(byID{ID: 1})
+	require.Equal(t, "DELETE FROM users WHERE id = $1", query)
+	require.Equal(t, []any{1}, args)
+
+	n, err := q.New(byID{ID: 1}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	type byAge struct {
+		Age int `db:"age"`
+	}
+	rq := dbx.DeleteReturning[byAge, user](dbx.Delete[byAge]("users").Where(dbx.Gt("age"))).Compile()
+	query, _ = rq.PreviewQuery(byAge{})
+	require.Equal(t, "DELETE FROM users WHERE age > $1 RETURNING id, name, age", query)
+
+	deleted, err := rq.New(byAge{Age: 35}).QueryContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 40}}, deleted)
+
+	_, err = q.New(byID{ID: 2}).QueryContext(ctx, db)
+	require.Error(t, err)
+
+	require.Panics(t, func() {
+		dbx.Delete[byID]("users").Where(dbx.Eq("name")).Compile()
+	})
+}
+
+func TestInsertBatch(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	q := dbx.Returning[insertUserInput, user](dbx.Insert[insertUserInput]("users")).Compile()
+	inputs := []insertUserInput{{Name: "John", Age: 30}, {Name: "Jane", Age: 25}}
+
+	query, args := q.PreviewBatchQuery(inputs)
+	require.Equal(t, "INSERT INTO users (name, age) VALUES ($1, $2), ($3, $4) RETURNING id, name, age", query)
+	require.Equal(t, []any{"John", 30, "Jane", 25}, args)
+
+	inserted, err := q.NewBatch(inputs).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}, {ID: 2, Name: "Jane", Age: 25}}, inserted)
+
+	res, err := dbx.Insert[insertUserInput]("users").Compile().NewBatch(inputs).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Nil(t, res)
+
+	_, err = q.NewBatch(nil).ExecContext(ctx, db)
+	require.Error(t, err)
+}
+
+func TestDialect(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	ins := dbx.Insert[insertUserInput]("users").Dialect(dbx.SQLite).Compile()
+	query, _ := ins.PreviewQuery(insertUserInput{})
+	require.Equal(t, "INSERT INTO users (name, age) VALUES (?, ?)", query)
+	_, err := ins.New(insertUserInput{Name: "John", Age: 30}).ExecContext(ctx, db)
+	require.NoError(t, err)
+
+	sel := dbx.Select[user]("users").Where(dbx.Eq("name"), dbx.Gte("age")).Dialect(dbx.SQLite).Compile()
+	query, _ = sel.PreviewQuery()
+	require.Equal(t, "SELECT id, name, age FROM users WHERE name = ? AND age >= ?", query)
+	users, err := sel.New("John", 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, users)
+
+	require.Equal(t, "UPDATE users SET name = :1 WHERE id = :2 AND note = '$3'",
+		dbx.Oracle.Rebind("UPDATE users SET name = $1 WHERE id = $2 AND note = '$3'"))
+	require.Equal(t, "SELECT * FROM t WHERE a = ? AND b = ?", dbx.MySQL.Rebind("SELECT * FROM t WHERE a = $1 AND b = $12"))
+}
+
+func TestInsertExecMany(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	q := dbx.Returning[insertUserInput, user](dbx.Insert[insertUserInput]("users")).Compile()
+	users, err := q.New(insertUserInput{Name: "John", Age: 30}).ExecManyContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, users)
+
+	_, err = dbx.Insert[insertUserInput]("users").Compile().New(insertUserInput{}).ExecManyContext(ctx, db)
+	require.Error(t, err)
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	q := dbx.Insert[insertUserInput]("users").Compile()
+	count := func() int {
+		var n int
+		require.NoError(t, db.QueryRow("SELECT COUNT(*) FROM users").Scan(&n))
+		return n
+	}
+
+	err := dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+		_, err := q.New(insertUserInput{Name: "John", Age: 30}).ExecContext(ctx, tx)
+		return err
+	})
+	require.NoError(t, err)
+	require.Equal(t, 1, count())
+
+	errBoom := errors.New("boom")
+	err = dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+		_, err := q.New(insertUserInput{Name: "Jane", Age: 25}).ExecContext(ctx, tx)
+		require.NoError(t, err)
+		return errBoom
+	}, dbx.WithIsolation(sql.LevelSerializable))
+	require.ErrorIs(t, err, errBoom)
+	require.Equal(t, 1, count())
+
+	require.Panics(t, func() {
+		_ = dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+			_, err := q.New(insertUserInput{Name: "Bob", Age: 40}).ExecContext(ctx, tx)
+			require.NoError(t, err)
+			panic("boom")
+		})
+	})
+	require.Equal(t, 1, count())
+
+	// nested calls roll back only their own savepoint
+	err = dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+		err := dbx.WithTx(ctx, tx, func(tx dbx.DB) error {
+			_, err := q.New(insertUserInput{Name: "Alice", Age: 35}).ExecContext(ctx, tx)
+			return err
+		})
+		require.NoError(t, err)
+
+		err = dbx.WithTx(ctx, tx, func(tx dbx.DB) error {
+			_, err := q.New(insertUserInput{Name: "Eve", Age: 20}).ExecContext(ctx, tx)
+			require.NoError(t, err)
+			return dbx.WithTx(ctx, tx, func(tx dbx.DB) error { return errBoom })
+		})
+		require.ErrorIs(t, err, errBoom)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, count())
+
+	err = dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+		require.NoError(t, dbx.WithTx(ctx, tx, func(tx dbx.DB) error {
+			_, err := q.New(insertUserInput{Name: "Mallory", Age: 50}).ExecContext(ctx, tx)
+			return err
+		}))
+		return errBoom
+	})
+	require.ErrorIs(t, err, errBoom)
+	require.Equal(t, 2, count())
+}
+
+func TestNamedQuery(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+
+	q := dbx.NamedQuery[user, user]("SELECT id, name, age FROM users WHERE age >= :age AND name <> ':name' AND name = :name").Compile()
+	query, args := q.PreviewQuery(user{Name: "John", Age: 18})
+	require.Equal(t, "SELECT id, name, age FROM users WHERE age >= $1 AND name <> ':name' AND name = $2", query)
+	require.Equal(t, []any{18, "John"}, args)
+
+	users, err := q.New(user{Name: "John", Age: 18}).QueryContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, users)
+
+	n, err := dbx.NamedQuery[user, struct{}]("UPDATE users SET age = age + 1 WHERE name = :name").Compile().
+		New(user{Name: "Jane"}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	upd := dbx.Update[user]("users").Where(dbx.Raw("lower(name) = lower(:name)")).Compile()
+	query, _ = upd.PreviewQuery(user{})
+	require.Equal(t, "UPDATE users SET age = $1 WHERE lower(name) = lower($2)", query)
+
+	require.Panics(t, func() {
+		dbx.NamedQuery[user, struct{}]("DELETE FROM users WHERE email = :email").Compile()
+	})
+}
+
+func TestIn(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	q := dbx.Select[user]("users").Where(dbx.In("name"), dbx.Gt("age")).OrderBy("id").Compile()
+
+	query, args := q.PreviewQuery([]string{"John", "Bob"}, 18)
+	require.Equal(t, "SELECT id, name, age FROM users WHERE name IN ($1, $2) AND age > $3 ORDER BY id", query)
+	require.Equal(t, []any{"John", "Bob", 18}, args)
+
+	users, err := q.New([]string{"John", "Bob"}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}, {ID: 3, Name: "Bob", Age: 40}}, users)
+
+	users, err = q.New([]string{}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Empty(t, users)
+
+	type byIDs struct {
+		IDs []int `db:"id"`
+	}
+	del := dbx.Delete[byIDs]("users").Where(dbx.NotIn("id")).Dialect(dbx.SQLite).Compile()
+	query, _ = del.PreviewQuery(byIDs{IDs: []int{1, 2}})
+	require.Equal(t, "DELETE FROM users WHERE id NOT IN (?, ?)", query)
+
+	n, err := del.New(byIDs{IDs: []int{1, 2}}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+}
+
+func TestSelectJoin(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+	_, err := db.Exec("CREATE TABLE orders (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, total INTEGER NOT NULL)")
+	require.NoError(t, err)
+	_, err = db.Exec("INSERT INTO orders (user_id, total) VALUES (2, 100), (1, 50)")
+	require.NoError(t, err)
+
+	type order struct {
+		ID    int  `db:"id"`
+		Total int  `db:"total"`
+		User  user `db:"u,nested"`
+	}
+	q := dbx.Select[order]("orders").
+		Join("users u", dbx.On("u.id", "orders.user_id")).
+		Where(dbx.Gt("orders.total")).
+		OrderBy("orders.id").
+		Compile()
+
+	query, _ := q.PreviewQuery(0)
+	require.Equal(t, "SELECT orders.id, orders.total, u.id, u.name, u.age FROM orders JOIN users u ON u.id = orders.user_id WHERE orders.total > $1 ORDER BY orders.id", query)
+
+	orders, err := q.New(10).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []order{
+		{ID: 1, Total: 100, User: user{ID: 2, Name: "Jane", Age: 25}},
+		{ID: 2, Total: 50, User: user{ID: 1, Name: "John", Age: 30}},
+	}, orders)
+	// Expr args of join conditions are bound before WHERE args
+	q = dbx.Select[order]("orders").
+		Join("users u", dbx.On("u.id", "orders.user_id"), dbx.Expr("u.age > $?", 26)).
+		Where(dbx.Gt("orders.total")).
+		Compile()
+	query, _ = q.PreviewQuery(10)
+	require.Equal(t, "SELECT orders.id, orders.total, u.id, u.name, u.age FROM orders JOIN users u ON u.id = orders.user_id AND u.age > $1 WHERE orders.total > $2", query)
+	orders, err = q.New(10).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []order{{ID: 2, Total: 50, User: user{ID: 1, Name: "John", Age: 30}}}, orders)
+
+	require.Panics(t, func() {
+		dbx.Select[order]("orders").Join("users u; DROP TABLE users", dbx.On("u.id", "orders.user_id")).Compile()
+	})
+	require.Panics(t, func() { dbx.Select[order]("orders").Join("users u", dbx.On("u.id", "1 OR 1=1")).Compile() })
+	require.Panics(t, func() { dbx.Select[order]("orders").Join("users u").Compile() })
+}
+
+func TestSelectOrderLimitOffset(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	q := dbx.Select[user]("users").OrderBy("age desc", "id").Limit(2).Offset(1).Compile()
+	query, _ := q.PreviewQuery()
+	require.Equal(t, "SELECT id, name, age FROM users ORDER BY age desc, id LIMIT 2 OFFSET 1", query)
+
+	users, err := q.New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}, {ID: 2, Name: "Jane", Age: 25}}, users)
+
+	require.Panics(t, func() {
+		dbx.Select[user]("users").OrderBy("agee").Compile()
+	})
+	require.Panics(t, func() {
+		dbx.Select[user]("users").OrderBy("age; DROP TABLE users").Compile()
+	})
+	require.Panics(t, func() {
+		dbx.Select[user]("users").Limit(-1).Compile()
+	})
+}
+
+func TestPaginate(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 30},
+		insertUserInput{Name: "Alice", Age: 17},
+	)
+
+	codec := pagination.NewCodec([]byte("secret"))
+	q := dbx.Paginate[user]("users", codec, "age", "id").Where(dbx.Gte("age")).Compile()
+
+	limit := 2
+	page, err := q.New(pagination.Params{Limit: &limit}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 2, Name: "Jane", Age: 25}, {ID: 1, Name: "John", Age: 30}}, page.Items)
+	require.NotEmpty(t, page.NextCursor)
+
+	require.Empty(t, page.PrevCursor)
+
+	page, err = q.New(pagination.Params{Cursor: page.NextCursor, Limit: &limit}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 30}}, page.Items)
+	require.Empty(t, page.NextCursor)
+	require.NotEmpty(t, page.PrevCursor)
+
+	page, err = q.New(pagination.Params{Cursor: page.PrevCursor, Limit: &limit}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 2, Name: "Jane", Age: 25}, {ID: 1, Name: "John", Age: 30}}, page.Items)
+	require.NotEmpty(t, page.NextCursor)
+	require.Empty(t, page.PrevCursor)
+
+	limit = 1
+	page, err = q.New(pagination.Params{Cursor: page.NextCursor, Limit: &limit}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 30}}, page.Items)
+	page, err = q.New(pagination.Params{Cursor: page.PrevCursor, Limit: &limit}, 18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, page.Items)
+	require.NotEmpty(t, page.PrevCursor)
+
+	_, err = q.New(pagination.Params{Cursor: "garbage"}, 18).ExecContext(ctx, db)
+	require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+	require.Panics(t, func() {
+		dbx.Paginate[user]("users", codec, "email").Compile()
+	})
+}
+
+func TestCountExists(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+
+	type byAge struct {
+		Age int `db:"age"`
+	}
+	count := dbx.Count[byAge]("users").Where(dbx.Gte("age")).Compile()
+	query, _ := count.PreviewQuery(byAge{})
+	require.Equal(t, "SELECT COUNT(*) FROM users WHERE age >= $1", query)
+
+	n, err := count.New(byAge{Age: 26}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	exists := dbx.Exists[byAge]("users").Where(dbx.Gt("age")).Compile()
+	query, _ = exists.PreviewQuery(byAge{})
+	require.Equal(t, "SELECT EXISTS (SELECT 1 FROM users WHERE age > $1)", query)
+
+	ok, err := exists.New(byAge{Age: 29}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.True(t, ok)
+
+	ok, err = exists.New(byAge{Age: 30}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.False(t, ok)
+}
+
+func TestNullable(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE profiles (id INTEGER PRIMARY KEY AUTOINCREMENT, user_id INTEGER NOT NULL, bio TEXT, nickname TEXT)")
+	require.NoError(t, err)
+
+	type profile struct {
+		ID       int            `db:"id,auto"`
+		UserID   int            `db:"user_id"`
+		Bio      *string        `db:"bio"`
+		Nickname sql.NullString `db:"nickname"`
+	}
+	bio := "hello"
+	ins := dbx.Insert[profile]("profiles").Compile()
+	_, err = ins.New(profile{UserID: 1, Bio: &bio, Nickname: sql.NullString{String: "jj", Valid: true}}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	_, err = ins.New(profile{UserID: 2}).ExecContext(ctx, db)
+	require.NoError(t, err)
+
+	profiles, err := dbx.Select[profile]("profiles").OrderBy("id").Compile().New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []profile{
+		{ID: 1, UserID: 1, Bio: &bio, Nickname: sql.NullString{String: "jj", Valid: true}},
+		{ID: 2, UserID: 2},
+	}, profiles)
+
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+	_, err = db.Exec("DELETE FROM profiles WHERE user_id = 2")
+	require.NoError(t, err)
+
+	type userWithProfile struct {
+		ID      int      `db:"id"`
+		Profile *profile `db:"p,nested"`
+	}
+	rows, err := dbx.Select[userWithProfile]("users").
+		LeftJoin("profiles p", dbx.On("p.user_id", "users.id")).
+		OrderBy("users.id").
+		Compile().New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, rows, 2)
+	require.Equal(t, &profile{ID: 1, UserID: 1, Bio: &bio, Nickname: sql.NullString{String: "jj", Valid: true}}, rows[0].Profile)
+	require.Nil(t, rows[1].Profile)
+}
+
+// tags is stored as a comma separated string, Value is declared on the pointer receiver on purpose
+type tags []string
+
+func (t *tags) Value() (driver.Value, error) {
+	return strings.Join(*t, ","), nil
+}
+
+func (t *tags) Scan(src any) error {
+	s, ok := src.(string)
+	if !ok {
+		return fmt.Errorf("unexpected tags type %T", src)
+	}
+	*t = strings.Split(s, ",")
+	return nil
+}
+
+func TestValuerScanner(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, tags TEXT NOT NULL, created_at TIMESTAMP NOT NULL)")
+	require.NoError(t, err)
+
+	type post struct {
+		ID        int       `db:"id,auto"`
+		Tags      tags      `db:"tags"`
+		CreatedAt time.Time `db:"created_at,nested"`
+	}
+	createdAt := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	inserted, err := dbx.Returning[post, post](dbx.Insert[post]("posts")).Compile().
+		New(post{Tags: tags{"go", "sql"}, CreatedAt: createdAt}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, post{ID: 1, Tags: tags{"go", "sql"}, CreatedAt: createdAt}, inserted)
+
+	var raw string
+	require.NoError(t, db.QueryRow("SELECT tags FROM posts").Scan(&raw))
+	require.Equal(t, "go,sql", raw)
+}
+
+type timestamps struct {
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt time.Time `db:"updated_at"`
+}
+
+func TestEmbeddedFields(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE notes (id INTEGER PRIMARY KEY AUTOINCREMENT, body TEXT NOT NULL, created_at TIMESTAMP NOT NULL, updated_at TIMESTAMP NOT NULL)")
+	require.NoError(t, err)
+
+	type note struct {
+		ID   int    `db:"id,auto"`
+		Body string `db:"body"`
+		timestamps
+	}
+	now := time.Date(2024, 5, 1, 12, 30, 0, 0, time.UTC)
+
+	ins := dbx.Returning[note, note](dbx.Insert[note]("notes")).Compile()
+	query, args := ins.PreviewQuery(note{Body: "hi", timestamps: timestamps{CreatedAt: now, UpdatedAt: now}})
+	require.Equal(t, "INSERT INTO notes (body, created_at, updated_at) VALUES ($1, $2, $3) RETURNING id, body, created_at, updated_at", query)
+	require.Equal(t, []any{"hi", now, now}, args)
+
+	inserted, err := ins.New(note{Body: "hi", timestamps: timestamps{CreatedAt: now, UpdatedAt: now}}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, note{ID: 1, Body: "hi", timestamps: timestamps{CreatedAt: now, UpdatedAt: now}}, inserted)
+}
+
+func TestNaming(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE accounts (id INTEGER PRIMARY KEY AUTOINCREMENT, display_name TEXT NOT NULL, http_status INTEGER NOT NULL, ignored TEXT)")
+	require.NoError(t, err)
+
+	type account struct {
+		ID          int `db:",auto"`
+		DisplayName string
+		HTTPStatus  int
+		Ignored     string `db:"-"`
+		internal    string
+	}
+
+	ins := dbx.Returning[account, account](dbx.Insert[account]("accounts").Naming(dbx.SnakeCase)).Compile()
+	query, _ := ins.PreviewQuery(account{})
+	require.Equal(t, "INSERT INTO accounts (display_name, http_status) VALUES ($1, $2) RETURNING id, display_name, http_status", query)
+
+	inserted, err := ins.New(account{DisplayName: "John", HTTPStatus: 200, internal: "x"}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, account{ID: 1, DisplayName: "John", HTTPStatus: 200}, inserted)
+
+	found, err := dbx.Select[account]("accounts").Naming(dbx.SnakeCase).Where(dbx.Eq("display_name")).Compile().
+		New("John").GetContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, inserted, found)
+
+	require.Equal(t, "user_id", dbx.SnakeCase("UserID"))
+	require.Equal(t, "created_at", dbx.SnakeCase("CreatedAt"))
+}
+
+type recordingHook struct {
+	queries []dbx.QueryInfo
+	errs    []error
+}
+
+func (h *recordingHook) BeforeQuery(ctx context.Context, q dbx.QueryInfo) context.Context {
+	return ctx
+}
+
+func (h *recordingHook) AfterQuery(ctx context.Context, q dbx.QueryInfo, duration time.Duration, err error) {
+	h.queries = append(h.queries, q)
+	h.errs = append(h.errs, err)
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	hook := &recordingHook{}
+	var logs bytes.Buffer
+	logHook := &dbx.LogHook{
+		Logger: slog.New(slog.NewTextHandler(&logs, nil)),
+		RedactArgs: func(q dbx.QueryInfo) []any {
+			return []any{"[REDACTED]"}
+		},
+	}
+	db := dbx.WithHooks(openDB(t), hook, logHook)
+
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30})
+	_, err := dbx.Select[user]("missing").Compile().New().ExecContext(ctx, db)
+	require.Error(t, err)
+
+	require.Len(t, hook.queries, 2)
+	require.Equal(t, dbx.QueryInfo{Operation: "Exec", Query: "INSERT INTO users (name, age) VALUES ($1, $2)", Args: []any{"John", 30}, RowsAffected: 1}, hook.queries[0])
+	require.NoError(t, hook.errs[0])
+	require.Equal(t, "Query", hook.queries[1].Operation)
+	require.Equal(t, int64(-1), hook.queries[1].RowsAffected)
+	require.Error(t, hook.errs[1])
+
+	require.Contains(t, logs.String(), "query failed")
+	require.Contains(t, logs.String(), "[REDACTED]")
+	require.NotContains(t, logs.String(), "John")
+}
+
+func TestStmtCache(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	cache := dbx.NewStmtCache(db, dbx.WithStmtCacheSize(2))
+
+	seedUsers(t, cache, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+	require.Equal(t, 1, cache.Len())
+
+	q := dbx.Select[user]("users").Where(dbx.Eq("id")).Compile()
+	for _, id := range []int{1, 2} {
+		u, err := q.New(id).GetContext(ctx, cache)
+		require.NoError(t, err)
+		require.Equal(t, id, u.ID)
+	}
+	require.Equal(t, 2, cache.Len())
+
+	n, err := dbx.Count[struct{}]("users").Compile().New(struct{}{}).ExecContext(ctx, cache)
+	require.NoError(t, err)
+	require.Equal(t, int64(2), n)
+	require.Equal(t, 2, cache.Len(), "least recently used statement is evicted")
+
+	require.NoError(t, cache.Close())
+	_, err = cache.ExecContext(ctx, "DELETE FROM users")
+	require.ErrorIs(t, err, dbx.ErrStmtCacheClosed)
+}
+
+func TestIdentifiers(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec(`CREATE TABLE items (id INTEGER PRIMARY KEY AUTOINCREMENT, "order" INTEGER NOT NULL)`)
+	require.NoError(t, err)
+
+	type item struct {
+		ID    int `db:"id,auto"`
+		Order int `db:"order"`
+	}
+	ins := dbx.Insert[item]("items").Compile()
+	query, _ := ins.PreviewQuery(item{})
+	require.Equal(t, `INSERT INTO items ("order") VALUES ($1)`, query)
+	_, err = ins.New(item{Order: 2}).ExecContext(ctx, db)
+	require.NoError(t, err)
+
+	sel := dbx.Select[item]("items").Where(dbx.Gt("order")).OrderBy("order DESC").Dialect(dbx.MySQL).Compile()
+	query, _ = sel.PreviewQuery(0)
+	require.Equal(t, "SELECT id, `order` FROM items WHERE `order` > ? ORDER BY `order` DESC", query)
+
+	items, err := dbx.Select[item]("items").Where(dbx.Gt("order")).Compile().New(1).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []item{{ID: 1, Order: 2}}, items)
+
+	require.Panics(t, func() {
+		dbx.Select[item]("items; DROP TABLE items").Compile()
+	})
+	require.Panics(t, func() {
+		dbx.Select[item]("items").Where(dbx.Eq("id = 1 OR 1")).Compile()
+	})
+
+	_, err = dbx.Postgres.QuoteIdent("users.name")
+	require.NoError(t, err)
+	_, err = dbx.Postgres.QuoteIdent("name--")
+	require.Error(t, err)
+}
+
+func TestRawQuery(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30}, insertUserInput{Name: "Jane", Age: 25})
+
+	users, err := dbx.Query[user](ctx, db, "SELECT age, name, id FROM users WHERE age > $1 ORDER BY id", 18)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}, {ID: 2, Name: "Jane", Age: 25}}, users)
+
+	u, err := dbx.Get[user](ctx, db, "SELECT id, name, age FROM users WHERE name = $1", "Jane")
+	require.NoError(t, err)
+	require.Equal(t, "Jane", u.Name)
+
+	_, err = dbx.Get[user](ctx, db, "SELECT id, name, age FROM users WHERE name = $1", "Bob")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	_, err = dbx.Query[user](ctx, db, "SELECT id, name AS nickname FROM users")
+	require.Error(t, err)
+}
+
+func TestSoftDelete(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE docs (id INTEGER PRIMARY KEY AUTOINCREMENT, title TEXT NOT NULL, deleted_at TIMESTAMP)")
+	require.NoError(t, err)
+
+	type doc struct {
+		ID        int        `db:"id,auto"`
+		Title     string     `db:"title"`
+		DeletedAt *time.Time `db:"deleted_at,softdelete"`
+	}
+	type docID struct {
+		ID int `db:"id"`
+	}
+	_, err = dbx.Insert[doc]("docs").Compile().NewBatch([]doc{{Title: "a"}, {Title: "b"}}).ExecContext(ctx, db)
+	require.NoError(t, err)
+
+	del := dbx.Delete[docID]("docs").SoftDelete("deleted_at").Where(dbx.Eq("id")).Compile()
+	query, _ := del.PreviewQuery(docID{})
+	require.Equal(t, "UPDATE docs SET deleted_at = CURRENT_TIMESTAMP WHERE id = $1 AND deleted_at IS NULL", query)
+	n, err := del.New(docID{ID: 1}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+
+	sel := dbx.Select[doc]("docs").OrderBy("id").Compile()
+	query, _ = sel.PreviewQuery()
+	require.Equal(t, "SELECT id, title, deleted_at FROM docs WHERE deleted_at IS NULL ORDER BY id", query)
+	docs, err := sel.New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, docs, 1)
+	require.Equal(t, "b", docs[0].Title)
+
+	docs, err = dbx.Select[doc]("docs").Unscoped().OrderBy("id").Compile().New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, docs, 2)
+	require.NotNil(t, docs[0].DeletedAt)
+
+	hard := dbx.Delete[doc]("docs").Unscoped().Where(dbx.Eq("id")).Compile()
+	query, _ = hard.PreviewQuery(doc{})
+	require.Equal(t, "DELETE FROM docs WHERE id = $1", query)
+}
+
+func TestPartialUpdate(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db, insertUserInput{Name: "John", Age: 30})
+	q := dbx.Update[user]("users").Where(dbx.Eq("id")).Compile()
+	get := func() user {
+		u, err := dbx.Get[user](ctx, db, "SELECT id, name, age FROM users WHERE id = 1")
+		require.NoError(t, err)
+		return u
+	}
+
+	n, err := q.NewPartial(user{ID: 1, Name: "Johnny"}, "name").ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	require.Equal(t, user{ID: 1, Name: "Johnny", Age: 30}, get())
+
+	n, err = q.NewNonZero(user{ID: 1, Age: 31}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, int64(1), n)
+	require.Equal(t, user{ID: 1, Name: "Johnny", Age: 31}, get())
+
+	_, err = q.NewPartial(user{ID: 1}, "id").ExecContext(ctx, db)
+	require.Error(t, err)
+	_, err = q.NewNonZero(user{ID: 1}).ExecContext(ctx, db)
+	require.ErrorIs(t, err, dbx.ErrNothingToUpdate)
+}
+
+func TestParseFilter(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	type listUsersRequest struct {
+		Name   *string `query:"name" filter:"name"`
+		MinAge *int    `query:"min_age" filter:"age,gte"`
+		IDs    []int   `query:"id" filter:"id,in"`
+		Sort   string  `query:"sort" sort:"name,age"`
+	}
+	minAge := 26
+	f, err := dbx.ParseFilter(listUsersRequest{MinAge: &minAge, IDs: []int{1, 2, 3}, Sort: "-age"})
+	require.NoError(t, err)
+	require.Equal(t, []string{"age DESC"}, f.OrderBy)
+
+	q := dbx.Select[user]("users").Where(f.Where...).OrderBy(f.OrderBy...).Compile()
+	query, _ := q.PreviewQuery(f.Args...)
+	require.Equal(t, "SELECT id, name, age FROM users WHERE age >= $1 AND id IN ($2, $3, $4) ORDER BY age DESC", query)
+
+	users, err := q.New(f.Args...).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 40}, {ID: 1, Name: "John", Age: 30}}, users)
+
+	_, err = dbx.ParseFilter(listUsersRequest{Sort: "password"})
+	require.Error(t, err)
+}
+
+func TestRepository(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	users := dbx.NewRepository[user, int]("users")
+
+	john, err := users.Insert(ctx, db, user{Name: "John", Age: 30})
+	require.NoError(t, err)
+	require.Equal(t, user{ID: 1, Name: "John", Age: 30}, john)
+	_, err = users.Insert(ctx, db, user{Name: "Jane", Age: 25})
+	require.NoError(t, err)
+
+	got, err := users.GetByID(ctx, db, john.ID)
+	require.NoError(t, err)
+	require.Equal(t, john, got)
+
+	john.Age = 31
+	n, err := users.Update(ctx, db, john)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	n, err = users.Delete(ctx, db, 2)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	all, err := users.List(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 31}}, all)
+
+	_, err = users.GetByID(ctx, db, 2)
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	require.Panics(t, func() { dbx.NewRepository[user, string]("users") })
+}
+
+func TestCompositePrimaryKey(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE members (org_id INTEGER NOT NULL, user_id INTEGER NOT NULL, role TEXT NOT NULL, PRIMARY KEY (org_id, user_id))")
+	require.NoError(t, err)
+
+	type member struct {
+		OrgID  int    `db:"org_id,pk"`
+		UserID int    `db:"user_id,pk"`
+		Role   string `db:"role"`
+	}
+	type memberKey struct {
+		OrgID  int `db:"org_id"`
+		UserID int `db:"user_id"`
+	}
+
+	update := dbx.Update[member]("members").WherePK().Compile()
+	query, _ := update.PreviewQuery(member{})
+	require.Equal(t, "UPDATE members SET role = $1 WHERE org_id = $2 AND user_id = $3", query)
+
+	members := dbx.NewRepository[member, memberKey]("members")
+	for _, m := range []member{{1, 1, "owner"}, {1, 2, "viewer"}, {2, 1, "viewer"}} {
+		_, err := members.Insert(ctx, db, m)
+		require.NoError(t, err)
+	}
+
+	n, err := members.Update(ctx, db, member{OrgID: 1, UserID: 2, Role: "editor"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	got, err := members.GetByID(ctx, db, memberKey{OrgID: 1, UserID: 2})
+	require.NoError(t, err)
+	require.Equal(t, member{OrgID: 1, UserID: 2, Role: "editor"}, got)
+
+	n, err = members.Delete(ctx, db, memberKey{OrgID: 2, UserID: 1})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	byOrg, err := dbx.Select[member]("members").WherePK().Compile().New(1, 1).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []member{{1, 1, "owner"}}, byOrg)
+
+	all, err := members.List(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, all, 2)
+
+	require.Panics(t, func() { dbx.NewRepository[member, int]("members") })
+	require.Panics(t, func() { dbx.Delete[user]("users").WherePK() })
+}
+
+func TestUpdateMany(t *testing.T) {
+	ctx := context.Background()
+	inputs := []user{{ID: 1, Name: "John", Age: 31}, {ID: 3, Name: "Bob", Age: 41}}
+
+	query, args := dbx.UpdateMany[user]("users").Compile().PreviewQuery(inputs)
+	require.Equal(t, "UPDATE users SET name = v.name, age = v.age FROM (SELECT name, age, id FROM users WHERE 1 = 0 UNION ALL VALUES ($1, $2, $3), ($4, $5, $6)) AS v WHERE users.id = v.id", query)
+	require.Equal(t, []any{"John", 31, 1, "Bob", 41, 3}, args)
+
+	for _, d := range []dbx.Dialect{dbx.SQLite, dbx.MySQL} {
+		db := openDB(t)
+		seedUsers(t, db,
+			insertUserInput{Name: "John", Age: 30},
+			insertUserInput{Name: "Jane", Age: 25},
+			insertUserInput{Name: "Bob", Age: 40},
+		)
+
+		n, err := dbx.UpdateMany[user]("users").Dialect(d).Compile().New(inputs).ExecContext(ctx, db)
+		require.NoError(t, err)
+		require.EqualValues(t, 2, n)
+
+		users, err := dbx.Select[user]("users").OrderBy("id").Compile().New().ExecContext(ctx, db)
+		require.NoError(t, err)
+		require.Equal(t, []user{{ID: 1, Name: "John", Age: 31}, {ID: 2, Name: "Jane", Age: 25}, {ID: 3, Name: "Bob", Age: 41}}, users)
+	}
+}
+
+type fakeCopier struct {
+	dbx.DB
+	table   string
+	columns []string
+	rows    [][]any
+}
+
+func (c *fakeCopier) CopyFrom(_ context.Context, table string, columns []string, rows [][]any) (int64, error) {
+	c.table, c.columns, c.rows = table, columns, rows
+	return int64(len(rows)), nil
+}
+
+func TestBatchCopy(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	inputs := []insertUserInput{{Name: "John", Age: 30}, {Name: "Jane", Age: 25}}
+	q := dbx.Insert[insertUserInput]("users").Compile()
+
+	copier := &fakeCopier{DB: db}
+	n, err := q.NewBatch(inputs).CopyContext(ctx, copier)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+	require.Equal(t, "users", copier.table)
+	require.Equal(t, []string{"name", "age"}, copier.columns)
+	require.Equal(t, [][]any{{"John", 30}, {"Jane", 25}}, copier.rows)
+
+	// drivers without COPY fall back to multi-VALUES insert
+	n, err = q.NewBatch(inputs).CopyContext(ctx, db)
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+
+	_, err = dbx.Returning[insertUserInput, user](dbx.Insert[insertUserInput]("users")).Compile().NewBatch(inputs).CopyContext(ctx, copier)
+	require.Error(t, err)
+}
+
+func TestExplain(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	plan, err := dbx.Select[user]("users").Dialect(dbx.SQLite).Where(dbx.Eq("id")).Compile().Explain(ctx, db, []any{1})
+	require.NoError(t, err)
+	require.Contains(t, plan, "USING INTEGER PRIMARY KEY")
+
+	plan, err = dbx.Update[user]("users").Dialect(dbx.SQLite).Where(dbx.Eq("age")).Compile().Explain(ctx, db, user{Age: 30})
+	require.NoError(t, err)
+	require.Contains(t, plan, "SCAN users")
+
+	_, err = dbx.Insert[insertUserInput]("users").Dialect(dbx.SQLite).Compile().Explain(ctx, db, insertUserInput{}, dbx.Analyze())
+	require.Error(t, err)
+}
+
+func TestCreateTable(t *testing.T) {
+	type account struct {
+		ID        int64          `db:"id,auto"`
+		Email     string         `db:"email"`
+		Nickname  *string        `db:"nickname"`
+		Bio       sql.NullString `db:"bio"`
+		Balance   float64        `db:"balance" ddl:"NUMERIC(10, 2)"`
+		CreatedAt time.Time      `db:"created_at,auto"`
+	}
+
+	require.Equal(t,
+		"CREATE TABLE accounts (id BIGSERIAL, email TEXT NOT NULL, nickname TEXT, bio TEXT, balance NUMERIC(10, 2) NOT NULL, created_at TIMESTAMPTZ NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id))",
+		dbx.CreateTable[account]("accounts").SQL())
+	require.Equal(t,
+		"CREATE TABLE IF NOT EXISTS accounts (id BIGINT NOT NULL AUTO_INCREMENT, email VARCHAR(255) NOT NULL, nickname VARCHAR(255), bio VARCHAR(255), balance NUMERIC(10, 2) NOT NULL, created_at DATETIME NOT NULL DEFAULT CURRENT_TIMESTAMP, PRIMARY KEY (id))",
+		dbx.CreateTable[account]("accounts").Dialect(dbx.MySQL).IfNotExists().SQL())
+
+	type member struct {
+		OrgID  int    `db:"org_id,pk"`
+		UserID int    `db:"user_id,pk"`
+		Role   string `db:"role"`
+	}
+	require.Equal(t,
+		"CREATE TABLE members (org_id INTEGER NOT NULL, user_id INTEGER NOT NULL, role TEXT NOT NULL, PRIMARY KEY (org_id, user_id))",
+		dbx.CreateTable[member]("members").Dialect(dbx.SQLite).SQL())
+
+	// generated DDL is usable with the rest of dbx
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec(dbx.CreateTable[account]("accounts").Dialect(dbx.SQLite).SQL())
+	require.NoError(t, err)
+
+	a, err := dbx.NewRepository[account, int64]("accounts").Insert(ctx, db, account{Email: "john@example.com"})
+	require.NoError(t, err)
+	require.EqualValues(t, 1, a.ID)
+	require.False(t, a.CreatedAt.IsZero())
+
+	type unsupported struct {
+		Tags map[string]string `db:"tags"`
+	}
+	require.Panics(t, func() { dbx.CreateTable[unsupported]("things").SQL() })
+}
+
+func TestValidateSchema(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	require.NoError(t, dbx.ValidateSchema(ctx, db, dbx.ModelOf[user]("users").Dialect(dbx.SQLite)))
+
+	type drifted struct {
+		ID       string  `db:"id"`
+		Name     *string `db:"name"`
+		Email    string  `db:"email"`
+		Nickname string  `db:"nickname"`
+	}
+	_, err := db.Exec("ALTER TABLE users ADD COLUMN nickname TEXT")
+	require.NoError(t, err)
+
+	err = dbx.ValidateSchema(ctx, db,
+		dbx.ModelOf[drifted]("users").Dialect(dbx.SQLite),
+		dbx.ModelOf[user]("accounts").Dialect(dbx.SQLite),
+	)
+	var schemaErr *dbx.SchemaError
+	require.ErrorAs(t, err, &schemaErr)
+	require.Equal(t, []string{
+		"column users.id is INTEGER, field ID is string",
+		"column users.email is missing",
+		"column users.nickname is nullable, field Nickname of type string can't hold NULL",
+		"table accounts is missing",
+	}, schemaErr.Problems)
+}
+
+func TestWithTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+
+	slow := func(ctx context.Context, db dbx.DB) (int64, error) {
+		var n int64
+		err := db.QueryRowContext(ctx, "WITH RECURSIVE n(i) AS (SELECT 1 UNION ALL SELECT i + 1 FROM n) SELECT count(*) FROM n").Scan(&n)
+		return n, err
+	}
+	start := time.Now()
+	_, err := dbx.WithTimeout(ctx, db, 50*time.Millisecond, slow)
+	require.ErrorIs(t, err, context.DeadlineExceeded)
+	require.Less(t, time.Since(start), 5*time.Second)
+
+	n, err := dbx.WithTimeout(ctx, db, time.Second, dbx.Count[struct{}]("users").Compile().New(struct{}{}).ExecContext)
+	require.NoError(t, err)
+	require.EqualValues(t, 0, n)
+}
+
+func TestWithPostgresTimeout(t *testing.T) {
+	ctx := context.Background()
+	db := dbxtest.New(t)
+
+	db.AddResult(0)
+	db.AddResult(1)
+	n, err := dbx.WithPostgresTimeout(ctx, db.DB, 2*time.Second, dbx.Update[user]("users").Where(dbx.Eq("id")).Compile().New(user{ID: 1}).ExecContext)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	var queries []string
+	for _, q := range db.Queries() {
+		queries = append(queries, q.SQL)
+	}
+	require.Equal(t, []string{
+		"SET statement_timeout = 2000",
+		"UPDATE users SET name = $1, age = $2 WHERE id = $3",
+		"RESET statement_timeout",
+	}, queries)
+}
+
+type sqlStateError string
+
+func (e sqlStateError) Error() string    { return "sqlstate " + string(e) }
+func (e sqlStateError) SQLState() string { return string(e) }
+
+func TestRetry(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	policy := dbx.RetryPolicy{Backoff: func(int) time.Duration { return 0 }}
+
+	attempts := 0
+	n, err := dbx.Retry(ctx, db, policy, func(ctx context.Context, db dbx.DB) (int, error) {
+		attempts++
+		if attempts < 3 {
+			return 0, fmt.Errorf("update: %w", sqlStateError("40001"))
+		}
+		return 42, nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 42, n)
+	require.Equal(t, 3, attempts)
+
+	attempts = 0
+	_, err = dbx.Retry(ctx, db, policy, func(ctx context.Context, db dbx.DB) (int, error) {
+		attempts++
+		return 0, sqlStateError("23505")
+	})
+	require.ErrorIs(t, err, sqlStateError("23505"))
+	require.Equal(t, 1, attempts)
+
+	attempts = 0
+	err = dbx.WithTxRetry(ctx, db, policy, func(tx dbx.DB) error {
+		attempts++
+		seedUsers(t, tx, insertUserInput{Name: "John", Age: 30})
+		if attempts == 1 {
+			return driver.ErrBadConn
+		}
+		return nil
+	})
+	require.NoError(t, err)
+	require.Equal(t, 2, attempts)
+	// the first attempt was rolled back
+	count, err := dbx.Count[struct{}]("users").Compile().New(struct{}{}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, count)
+
+	require.True(t, dbx.IsRetryable(sqlStateError("08006")))
+	require.False(t, dbx.IsRetryable(context.Canceled))
+}
+
+type unhealthyDB struct {
+	*dbxtest.Fake
+}
+
+func (db unhealthyDB) PingContext(context.Context) error {
+	return errors.New("replica is down")
+}
+
+func TestRouter(t *testing.T) {
+	ctx := context.Background()
+	primary, replica := dbxtest.New(t), dbxtest.New(t)
+	router := dbx.NewRouter(primary, []dbx.DB{replica})
+	defer router.Close()
+
+	get := dbx.Select[user]("users").Where(dbx.Eq("id")).Compile()
+	_, err := get.New(1).ExecContext(ctx, router)
+	require.NoError(t, err)
+	_, err = dbx.Update[user]("users").Where(dbx.Eq("id")).Compile().New(user{ID: 1}).ExecContext(ctx, router)
+	require.NoError(t, err)
+	_, err = dbx.Returning[insertUserInput, user](dbx.Insert[insertUserInput]("users")).Compile().New(insertUserInput{}).ExecContext(ctx, router)
+	require.Error(t, err) // no canned rows
+	require.Len(t, replica.Queries(), 1)
+	require.Len(t, primary.Queries(), 2)
+
+	// reads after writes of a sticky context go to the primary
+	sticky := dbx.Sticky(ctx)
+	_, err = get.New(1).ExecContext(sticky, router)
+	require.NoError(t, err)
+	require.Len(t, replica.Queries(), 2)
+	_, err = dbx.Delete[user]("users").Where(dbx.Eq("id")).Compile().New(user{ID: 1}).ExecContext(sticky, router)
+	require.NoError(t, err)
+	_, err = get.New(1).ExecContext(sticky, router)
+	require.NoError(t, err)
+	require.Len(t, replica.Queries(), 2)
+	require.Len(t, primary.Queries(), 4)
+
+	// unhealthy replicas are skipped
+	down := dbxtest.New(t)
+	router = dbx.NewRouter(primary, []dbx.DB{unhealthyDB{down}})
+	defer router.Close()
+	router.CheckHealth(ctx)
+	_, err = get.New(1).ExecContext(ctx, router)
+	require.NoError(t, err)
+	require.Empty(t, down.Queries())
+	require.Len(t, primary.Queries(), 5)
+}
+
+func TestSelectIter(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+	q := dbx.Select[user]("users").OrderBy("id").Compile()
+
+	var names []string
+	for u, err := range q.New().Iter(ctx, db) {
+		require.NoError(t, err)
+		names = append(names, u.Name)
+		if len(names) == 2 {
+			break
+		}
+	}
+	require.Equal(t, []string{"John", "Jane"}, names)
+
+	// the connection is released after break, so the next query doesn't block
+	users, err := q.New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Len(t, users, 3)
+
+	for _, err := range q.New(1).Iter(ctx, db) {
+		require.Error(t, err)
+	}
+}
+
+func TestSubquery(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	type userID struct {
+		ID int `db:"id"`
+	}
+	adults := dbx.Select[userID]("users").Where(dbx.Gte("age")).Compile()
+	q := dbx.Select[user]("users").Where(dbx.Ne("name"), dbx.InSubquery("id", adults)).OrderBy("id").Compile()
+	query, args := q.PreviewQuery("Bob", 26)
+	require.Equal(t, "SELECT id, name, age FROM users WHERE name <> $1 AND id IN (SELECT id FROM users WHERE age >= $2) ORDER BY id", query)
+	require.Equal(t, []any{"Bob", 26}, args)
+
+	users, err := q.New("Bob", 26).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, users)
+
+	// slice placeholders of subqueries stay expandable
+	named := dbx.Select[userID]("users").Where(dbx.In("name")).Compile()
+	q = dbx.Select[user]("users").Where(dbx.NotInSubquery("id", named)).Compile()
+	users, err = q.New([]string{"John", "Jane"}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 40}}, users)
+
+	older := dbx.Select[userID]("users o").Where(dbx.Raw("o.age > u.age")).Compile()
+	q = dbx.Select[user]("users u").Where(dbx.NotExistsSubquery(older)).Compile()
+	users, err = q.New().ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 40}}, users)
+
+	young := dbx.Select[user]("users").Where(dbx.Lt("age")).Compile()
+	from := dbx.SelectFrom[user](young, "young").Where(dbx.Like("name")).Compile()
+	query, _ = from.PreviewQuery(35, "J%")
+	require.Equal(t, "SELECT id, name, age FROM (SELECT id, name, age FROM users WHERE age < $1) young WHERE name LIKE $2", query)
+	users, err = from.New(35, "Ja%").ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 2, Name: "Jane", Age: 25}}, users)
+}
+
+func TestExpr(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+	)
+
+	get := dbx.Select[user]("users").Where(dbx.Expr("lower(name) = lower($?)", "JOHN"), dbx.Gte("age")).Compile()
+	query, args := get.PreviewQuery(18)
+	require.Equal(t, "SELECT id, name, age FROM users WHERE lower(name) = lower($1) AND age >= $2", query)
+	require.Equal(t, []any{"JOHN", 18}, args)
+	users, err := get.New(18).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John", Age: 30}}, users)
+
+	type birthday struct {
+		ID   int    `db:"id"`
+		Name string `db:"name"`
+	}
+	update := dbx.Update[birthday]("users").Set("age", dbx.Expr("age + $?", 1)).Where(dbx.Eq("id")).Compile()
+	query, args = update.PreviewQuery(birthday{ID: 2, Name: "Janet"})
+	require.Equal(t, "UPDATE users SET name = $1, age = age + $2 WHERE id = $3", query)
+	require.Equal(t, []any{"Janet", 1, 2}, args)
+	_, err = update.NewPartial(birthday{ID: 2}).ExecContext(ctx, db)
+	require.NoError(t, err)
+
+	deleted, err := dbx.DeleteReturning[user, user](dbx.Delete[user]("users").Where(dbx.Eq("id"))).
+		Expr("name", dbx.Expr("upper(name)")).Compile().New(user{ID: 2}).QueryContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 2, Name: "JANE", Age: 26}}, deleted)
+
+	require.Panics(t, func() { dbx.Select[user]("users").Where(dbx.Expr("age > $?")).Compile() })
+}
+
+func TestInsertOnConflictDoNothing(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE UNIQUE INDEX users_name ON users (name)")
+	require.NoError(t, err)
+
+	insert := dbx.Insert[insertUserInput]("users").OnConflictDoNothing("name").Compile()
+	query, _ := insert.PreviewQuery(insertUserInput{})
+	require.Equal(t, "INSERT INTO users (name, age) VALUES ($1, $2) ON CONFLICT (name) DO NOTHING", query)
+
+	_, inserted, err := insert.New(insertUserInput{Name: "John", Age: 30}).TryExecContext(ctx, db)
+	require.NoError(t, err)
+	require.True(t, inserted)
+	_, inserted, err = insert.New(insertUserInput{Name: "John", Age: 31}).TryExecContext(ctx, db)
+	require.NoError(t, err)
+	require.False(t, inserted)
+
+	returning := dbx.Returning[insertUserInput, user](dbx.Insert[insertUserInput]("users").OnConflictDoNothing()).Compile()
+	u, inserted, err := returning.New(insertUserInput{Name: "Jane", Age: 25}).TryExecContext(ctx, db)
+	require.NoError(t, err)
+	require.True(t, inserted)
+	require.Equal(t, "Jane", u.Name)
+	u, inserted, err = returning.New(insertUserInput{Name: "Jane", Age: 26}).TryExecContext(ctx, db)
+	require.NoError(t, err)
+	require.False(t, inserted)
+	require.Equal(t, user{}, u)
+
+	query, _ = dbx.Insert[insertUserInput]("users").Dialect(dbx.MySQL).OnConflictDoNothing().Compile().PreviewQuery(insertUserInput{})
+	require.Equal(t, "INSERT IGNORE INTO users (name, age) VALUES (?, ?)", query)
+	require.Panics(t, func() { dbx.Insert[insertUserInput]("users").Dialect(dbx.Oracle).OnConflictDoNothing().Compile() })
+}
+
+func TestUpdateManyReturning(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	seedUsers(t, db,
+		insertUserInput{Name: "John", Age: 30},
+		insertUserInput{Name: "Jane", Age: 25},
+		insertUserInput{Name: "Bob", Age: 40},
+	)
+
+	q := dbx.UpdateManyReturning[user, user](dbx.UpdateMany[user]("users").Dialect(dbx.SQLite)).Compile()
+	inputs := []user{{ID: 3, Name: "Bob", Age: 41}, {ID: 4, Name: "Alice", Age: 20}, {ID: 1, Name: "John", Age: 31}}
+	query, _ := q.PreviewQuery(inputs[:1])
+	require.Equal(t, "WITH v AS (SELECT name, age, id, 0 AS dbx_index FROM users WHERE 1 = 0 UNION ALL VALUES (?, ?, ?, 0)) UPDATE users SET name = v.name, age = v.age FROM v WHERE users.id = v.id RETURNING users.id, users.name, users.age, (SELECT v.dbx_index FROM v WHERE users.id = v.id)", query)
+	query, _ = dbx.UpdateManyReturning[user, user](dbx.UpdateMany[user]("users")).Compile().PreviewQuery(inputs[:1])
+	require.Equal(t, "WITH v AS (SELECT name, age, id, 0 AS dbx_index FROM users WHERE 1 = 0 UNION ALL VALUES ($1, $2, $3, 0)) UPDATE users SET name = v.name, age = v.age FROM v WHERE users.id = v.id RETURNING users.id, users.name, users.age, v.dbx_index", query)
+
+	updated, err := q.New(inputs).QueryContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 3, Name: "Bob", Age: 41}, {ID: 1, Name: "John", Age: 31}}, updated)
+
+	_, err = dbx.UpdateMany[user]("users").Compile().New(inputs).QueryContext(ctx, db)
+	require.Error(t, err)
+	require.Panics(t, func() {
+		dbx.UpdateManyReturning[user, user](dbx.UpdateMany[user]("users").Dialect(dbx.MySQL)).Compile()
+	})
+}
+
+func TestArray(t *testing.T) {
+	ctx := context.Background()
+	db := openDB(t)
+	_, err := db.Exec("CREATE TABLE posts (id INTEGER PRIMARY KEY AUTOINCREMENT, tags TEXT, scores TEXT)")
+	require.NoError(t, err)
+
+	type post struct {
+		ID     int      `db:"id,auto"`
+		Tags   []string `db:"tags,array"`
+		Scores []*int   `db:"scores,array"`
+	}
+
+	// SQLite stores the text format of the array as is
+	three := 3
+	q := dbx.Returning[post, post](dbx.Insert[post]("posts")).Compile()
+	inserted, err := q.New(post{Tags: []string{"go", `say "hi"`, `a,b\c`}, Scores: []*int{&three, nil}}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	require.Equal(t, []string{"go", `say "hi"`, `a,b\c`}, inserted.Tags)
+	require.Equal(t, []*int{&three, nil}, inserted.Scores)
+
+	var raw string
+	require.NoError(t, db.QueryRow("SELECT tags FROM posts").Scan(&raw))
+	require.Equal(t, `{"go","say \"hi\"","a,b\\c"}`, raw)
+
+	_, err = dbx.Insert[post]("posts").Compile().New(post{}).ExecContext(ctx, db)
+	require.NoError(t, err)
+	posts, err := dbx.Query[post](ctx, db, "SELECT id, tags FROM posts ORDER BY id")
+	require.NoError(t, err)
+	require.Len(t, posts, 2)
+	require.Nil(t, posts[1].Tags)
+
+	var matrix [][]int
+	require.NoError(t, dbx.Array(&matrix).Scan("{{1,2},{3,4}}"))
+	require.Equal(t, [][]int{{1, 2}, {3, 4}}, matrix)
+	v, err := dbx.Array([]bool{true, false}).Value()
+	require.NoError(t, err)
+	require.Equal(t, "{t,f}", v)
+
+	type tagged struct {
+		ID   int64    `db:"id,auto"`
+		Tags []string `db:"tags,array"`
+	}
+	require.Equal(t,
+		"CREATE TABLE tagged (id BIGSERIAL, tags TEXT[], PRIMARY KEY (id))",
+		dbx.CreateTable[tagged]("tagged").SQL())
+}
@@ -0,0 +1,520 @@
+// Package dbxcheck verifies dbx builders against the database schema before the code runs.
+// It finds builder definitions in Go source, e.g. dbx.Select[User]("users").Where(dbx.Eq("email")),
+// and reports tables and columns missing from the schema and fields of incompatible types.
+// It's meant to run with go generate, failing the build on drift:
+//
+//	//go:generate go run github.com/pechorka/cruder/cmd/dbxcheck -migrations ./migrations
+//
+// The schema is read from a live database with ReadSchema or replayed from migrations with LoadMigrations.
+// Builders are found syntactically: table names must be string literals and models must be structs
+// declared in the checked package, other builders are skipped.
+package dbxcheck
+
+import (
+	"database/sql"
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+const dbxPath = "github.com/pechorka/cruder/pkg/dbx"
+
+// Problem is a mismatch between a builder and the schema
+type Problem struct {
+	Pos     token.Position
+	Message string
+}
+
+func (p Problem) String() string {
+	return p.Pos.String() + ": " + p.Message
+}
+
+// Usage is a dbx builder found in the source
+type Usage struct {
+	Pos     token.Position
+	Builder string
+	Table   string
+	// Fields are the columns read or written by the builder, empty for builders binding
+	// only WHERE args from their model, e.g. Delete
+	Fields []Field
+	// Conditions are the unqualified columns of WHERE conditions, e.g. "email" of dbx.Eq("email")
+	Conditions []string
+	// Joined is true if the builder joins other tables, conditions may refer to their columns then
+	Joined bool
+}
+
+// Field is a db tagged field of a model
+type Field struct {
+	Name   string
+	Column string
+	// Type is the field type if it's known to dbxcheck, e.g. string, *int64 or time.Time, nil otherwise
+	Type  reflect.Type
+	Array bool
+}
+
+// fieldBuilders read or write the fields of their model, the rest bind only WHERE args from it
+var fieldBuilders = map[string]bool{
+	"Insert":        true,
+	"Select":        true,
+	"Update":        true,
+	"UpdateMany":    true,
+	"Paginate":      true,
+	"NewRepository": true,
+	"ModelOf":       true,
+	"Delete":        false,
+	"Count":         false,
+	"Exists":        false,
+}
+
+// returningBuilders add a RETURNING model, the second type argument, to the builder passed to them
+var returningBuilders = map[string]bool{
+	"Returning":           true,
+	"DeleteReturning":     true,
+	"UpdateManyReturning": true,
+}
+
+// conditions are the dbx functions creating a condition on the column passed first
+var conditions = map[string]bool{
+	"Eq": true, "Ne": true, "Gt": true, "Gte": true, "Lt": true, "Lte": true, "Like": true,
+	"In": true, "NotIn": true, "IsNull": true, "IsNotNull": true, "InSubquery": true, "NotInSubquery": true,
+}
+
+// Find parses non-test Go files of the package in dir and returns dbx builders defined there
+func Find(dir string) ([]Usage, error) {
+	fset := token.NewFileSet()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(fset, filepath.Join(dir, name), nil, parser.ParseComments)
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, f)
+	}
+
+	f := finder{fset: fset, structs: make(map[string]*ast.StructType)}
+	for _, file := range files {
+		ast.Inspect(file, func(n ast.Node) bool {
+			if spec, ok := n.(*ast.TypeSpec); ok {
+				if st, ok := spec.Type.(*ast.StructType); ok {
+					f.structs[spec.Name.Name] = st
+				}
+			}
+			return true
+		})
+	}
+	for _, file := range files {
+		f.findBuilders(file)
+	}
+	return f.usages(), nil
+}
+
+type finder struct {
+	fset    *token.FileSet
+	structs map[string]*ast.StructType
+	found   []*builder
+}
+
+// builder is a builder call and the parts of its method chain relevant to the schema
+type builder struct {
+	call       *ast.CallExpr
+	name       string
+	table      string
+	model      ast.Expr
+	returning  []ast.Expr
+	conditions []string
+	exprs      []string
+	joined     bool
+	naming     bool
+}
+
+func (f *finder) findBuilders(file *ast.File) {
+	pkg := dbxName(file)
+	if pkg == "" {
+		return
+	}
+
+	builders := make(map[*ast.CallExpr]*builder)
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		name, typeArgs := dbxCall(pkg, call)
+		if _, ok := fieldBuilders[name]; !ok || len(typeArgs) == 0 || len(call.Args) == 0 {
+			return true
+		}
+		table, ok := stringLit(call.Args[0])
+		if !ok {
+			return true
+		}
+		b := &builder{call: call, name: name, table: table, model: typeArgs[0]}
+		builders[call] = b
+		f.found = append(f.found, b)
+		return true
+	})
+
+	ast.Inspect(file, func(n ast.Node) bool {
+		call, ok := n.(*ast.CallExpr)
+		if !ok {
+			return true
+		}
+		if name, typeArgs := dbxCall(pkg, call); returningBuilders[name] && len(typeArgs) == 2 && len(call.Args) == 1 {
+			if b := chainRoot(pkg, call.Args[0], builders); b != nil {
+				b.returning = append(b.returning, typeArgs[1])
+			}
+			return true
+		}
+
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return true
+		}
+		b := chainRoot(pkg, sel.X, builders)
+		if b == nil {
+			return true
+		}
+		switch sel.Sel.Name {
+		case "Where":
+			for _, arg := range call.Args {
+				b.conditions = append(b.conditions, conditionColumns(pkg, arg)...)
+			}
+		case "Join", "LeftJoin":
+			b.joined = true
+		case "Naming":
+			b.naming = true
+		case "Expr":
+			// computed columns of returning and select builders
+			if len(call.Args) > 0 {
+				if col, ok := stringLit(call.Args[0]); ok {
+					b.exprs = append(b.exprs, col)
+				}
+			}
+		}
+		return true
+	})
+}
+
+func (f *finder) usages() []Usage {
+	usages := make([]Usage, 0, len(f.found))
+	for _, b := range f.found {
+		u := Usage{
+			Pos:     f.fset.Position(b.call.Pos()),
+			Builder: b.name,
+			Table:   strings.Fields(b.table)[0],
+			Joined:  b.joined,
+		}
+		models := b.returning
+		if fieldBuilders[b.name] {
+			models = append([]ast.Expr{b.model}, models...)
+		}
+		for _, model := range models {
+			st := f.structType(model)
+			if st == nil {
+				continue
+			}
+			for _, field := range f.fields(st, b.naming) {
+				if !slices.Contains(b.exprs, field.Column) && !slices.ContainsFunc(u.Fields, func(other Field) bool { return other.Column == field.Column }) {
+					u.Fields = append(u.Fields, field)
+				}
+			}
+		}
+		for _, col := range b.conditions {
+			if !strings.Contains(col, ".") && !slices.Contains(u.Conditions, col) {
+				u.Conditions = append(u.Conditions, col)
+			}
+		}
+		usages = append(usages, u)
+	}
+	return usages
+}
+
+func (f *finder) structType(expr ast.Expr) *ast.StructType {
+	switch expr := expr.(type) {
+	case *ast.StructType:
+		return expr
+	case *ast.Ident:
+		return f.structs[expr.Name]
+	}
+	return nil
+}
+
+// fields returns columns of st like dbx does: embedded structs are flattened,
+// nested structs belong to joined tables and are skipped
+func (f *finder) fields(st *ast.StructType, naming bool) []Field {
+	var fields []Field
+	for _, field := range st.Fields.List {
+		var tag string
+		if field.Tag != nil {
+			unquoted, _ := strconv.Unquote(field.Tag.Value)
+			tag = reflect.StructTag(unquoted).Get("db")
+		}
+		if tag == "-" {
+			continue
+		}
+		if tag == "" && len(field.Names) == 0 {
+			typ := field.Type
+			if star, ok := typ.(*ast.StarExpr); ok {
+				typ = star.X
+			}
+			if embedded := f.structType(typ); embedded != nil {
+				fields = append(fields, f.fields(embedded, naming)...)
+			}
+			continue
+		}
+
+		opts := strings.Split(tag, ",")
+		if slices.Contains(opts[1:], "nested") {
+			continue
+		}
+		for _, name := range field.Names {
+			col := opts[0]
+			if col == "" {
+				if !naming || !name.IsExported() {
+					continue
+				}
+				col = dbx.SnakeCase(name.Name)
+			}
+			fields = append(fields, Field{
+				Name:   name.Name,
+				Column: col,
+				Type:   goType(field.Type),
+				Array:  slices.Contains(opts[1:], "array"),
+			})
+		}
+	}
+	return fields
+}
+
+// dbxName returns the name the file imports dbx with, empty if it doesn't
+func dbxName(file *ast.File) string {
+	for _, spec := range file.Imports {
+		path, _ := strconv.Unquote(spec.Path.Value)
+		if path != dbxPath {
+			continue
+		}
+		if spec.Name != nil {
+			return spec.Name.Name
+		}
+		return "dbx"
+	}
+	return ""
+}
+
+// dbxCall returns the name and type arguments of a call of a dbx function, e.g. dbx.Insert[User]
+func dbxCall(pkg string, call *ast.CallExpr) (string, []ast.Expr) {
+	fun := call.Fun
+	var typeArgs []ast.Expr
+	switch index := fun.(type) {
+	case *ast.IndexExpr:
+		fun, typeArgs = index.X, []ast.Expr{index.Index}
+	case *ast.IndexListExpr:
+		fun, typeArgs = index.X, index.Indices
+	}
+	sel, ok := fun.(*ast.SelectorExpr)
+	if !ok {
+		return "", nil
+	}
+	if id, ok := sel.X.(*ast.Ident); !ok || id.Name != pkg {
+		return "", nil
+	}
+	return sel.Sel.Name, typeArgs
+}
+
+// chainRoot returns the builder a method chain starts with, e.g. the Select of dbx.Select[User]("users").Where(...)
+func chainRoot(pkg string, expr ast.Expr, builders map[*ast.CallExpr]*builder) *builder {
+	for {
+		call, ok := expr.(*ast.CallExpr)
+		if !ok {
+			return nil
+		}
+		if b, ok := builders[call]; ok {
+			return b
+		}
+		if name, _ := dbxCall(pkg, call); returningBuilders[name] && len(call.Args) == 1 {
+			expr = call.Args[0]
+			continue
+		}
+		sel, ok := call.Fun.(*ast.SelectorExpr)
+		if !ok {
+			return nil
+		}
+		expr = sel.X
+	}
+}
+
+// conditionColumns returns columns of condition expr, And and Or groups included
+func conditionColumns(pkg string, expr ast.Expr) []string {
+	call, ok := expr.(*ast.CallExpr)
+	if !ok {
+		return nil
+	}
+	name, _ := dbxCall(pkg, call)
+	switch {
+	case name == "And" || name == "Or":
+		var cols []string
+		for _, arg := range call.Args {
+			cols = append(cols, conditionColumns(pkg, arg)...)
+		}
+		return cols
+	case conditions[name] && len(call.Args) > 0:
+		if col, ok := stringLit(call.Args[0]); ok {
+			return []string{col}
+		}
+	}
+	return nil
+}
+
+func stringLit(expr ast.Expr) (string, bool) {
+	lit, ok := expr.(*ast.BasicLit)
+	if !ok || lit.Kind != token.STRING {
+		return "", false
+	}
+	s, err := strconv.Unquote(lit.Value)
+	return s, err == nil && strings.TrimSpace(s) != ""
+}
+
+// knownTypes are the named types goType resolves
+var knownTypes = map[string]reflect.Type{
+	"string":          reflect.TypeOf(""),
+	"bool":            reflect.TypeOf(false),
+	"int":             reflect.TypeOf(int(0)),
+	"int8":            reflect.TypeOf(int8(0)),
+	"int16":           reflect.TypeOf(int16(0)),
+	"int32":           reflect.TypeOf(int32(0)),
+	"int64":           reflect.TypeOf(int64(0)),
+	"uint":            reflect.TypeOf(uint(0)),
+	"uint8":           reflect.TypeOf(uint8(0)),
+	"uint16":          reflect.TypeOf(uint16(0)),
+	"uint32":          reflect.TypeOf(uint32(0)),
+	"uint64":          reflect.TypeOf(uint64(0)),
+	"float32":         reflect.TypeOf(float32(0)),
+	"float64":         reflect.TypeOf(float64(0)),
+	"byte":            reflect.TypeOf(byte(0)),
+	"rune":            reflect.TypeOf(rune(0)),
+	"time.Time":       reflect.TypeOf(time.Time{}),
+	"sql.NullString":  reflect.TypeOf(sql.NullString{}),
+	"sql.NullBool":    reflect.TypeOf(sql.NullBool{}),
+	"sql.NullInt16":   reflect.TypeOf(sql.NullInt16{}),
+	"sql.NullInt32":   reflect.TypeOf(sql.NullInt32{}),
+	"sql.NullInt64":   reflect.TypeOf(sql.NullInt64{}),
+	"sql.NullFloat64": reflect.TypeOf(sql.NullFloat64{}),
+	"sql.NullTime":    reflect.TypeOf(sql.NullTime{}),
+	"sql.NullByte":    reflect.TypeOf(sql.NullByte{}),
+}
+
+// goType resolves the type of a field declared as expr, nil if it's unknown, e.g. uuid.UUID
+func goType(expr ast.Expr) reflect.Type {
+	switch expr := expr.(type) {
+	case *ast.Ident:
+		return knownTypes[expr.Name]
+	case *ast.SelectorExpr:
+		if pkg, ok := expr.X.(*ast.Ident); ok {
+			return knownTypes[pkg.Name+"."+expr.Sel.Name]
+		}
+	case *ast.StarExpr:
+		if t := goType(expr.X); t != nil {
+			return reflect.PointerTo(t)
+		}
+	case *ast.ArrayType:
+		if expr.Len != nil {
+			return nil
+		}
+		if t := goType(expr.Elt); t != nil {
+			return reflect.SliceOf(t)
+		}
+	}
+	return nil
+}
+
+// Verify compares usages with the schema
+func Verify(usages []Usage, schema Schema) []Problem {
+	var problems []Problem
+	for _, u := range usages {
+		report := func(format string, args ...any) {
+			problems = append(problems, Problem{Pos: u.Pos, Message: fmt.Sprintf(format, args...)})
+		}
+
+		columns, ok := schema[tableName(u.Table)]
+		if !ok {
+			report("%s: table %s doesn't exist", u.Builder, u.Table)
+			continue
+		}
+		for _, field := range u.Fields {
+			if strings.Contains(field.Column, ".") {
+				continue
+			}
+			col, ok := columns[strings.ToLower(field.Column)]
+			if !ok {
+				report("%s: column %s.%s of field %s doesn't exist", u.Builder, u.Table, field.Column, field.Name)
+				continue
+			}
+			if field.Type == nil || field.Array {
+				continue
+			}
+			if !dbx.ColumnTypeCompatible(field.Type, col.DataType) {
+				report("%s: column %s.%s is %s, field %s is %s", u.Builder, u.Table, field.Column, col.DataType, field.Name, field.Type)
+			}
+			if col.Nullable() && !nullable(field.Type) {
+				report("%s: column %s.%s is nullable, field %s of type %s can't hold NULL", u.Builder, u.Table, field.Column, field.Name, field.Type)
+			}
+		}
+		if u.Joined {
+			continue
+		}
+		for _, cond := range u.Conditions {
+			if _, ok := columns[strings.ToLower(cond)]; !ok {
+				report("%s: WHERE column %s.%s doesn't exist", u.Builder, u.Table, cond)
+			}
+		}
+	}
+	return problems
+}
+
+var sqlScannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+
+func nullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer || reflect.PointerTo(t).Implements(sqlScannerType)
+}
+
+// Tables returns the distinct tables of usages, e.g. to read them with ReadSchema
+func Tables(usages []Usage) []string {
+	var tables []string
+	for _, u := range usages {
+		if !slices.Contains(tables, u.Table) {
+			tables = append(tables, u.Table)
+		}
+	}
+	return tables
+}
+
+// Check finds builders in dir and verifies them against the schema of migrations in fsys
+func Check(dir string, migrations fs.FS) ([]Problem, error) {
+	usages, err := Find(dir)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := LoadMigrations(migrations)
+	if err != nil {
+		return nil, err
+	}
+	return Verify(usages, schema), nil
+}
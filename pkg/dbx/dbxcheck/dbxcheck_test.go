@@ -0,0 +1,131 @@
+package dbxcheck_test
+
+import (
+	"context"
+	"database/sql"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxcheck"
+)
+
+const source = `package store
+
+import (
+	"time"
+
+	db "github.com/pechorka/cruder/pkg/dbx"
+)
+
+type Timestamps struct {
+	CreatedAt time.Time ` + "`db:\"created_at\"`" + `
+}
+
+type User struct {
+	ID       int64   ` + "`db:\"id,auto\"`" + `
+	Email    string  ` + "`db:\"email\"`" + `
+	Nickname string  ` + "`db:\"nickname\"`" + `
+	Age      string  ` + "`db:\"age\"`" + `
+	Tags     []string ` + "`db:\"tags,array\"`" + `
+	Timestamps
+}
+
+type byEmail struct {
+	Email string ` + "`db:\"email\"`" + `
+}
+
+var (
+	insertUser = db.Returning[User, User](db.Insert[User]("users")).Compile()
+	deleteUser = db.Delete[byEmail]("users").Where(db.Eq("email")).Compile()
+	countUsers = db.Count[byEmail]("users").Where(db.Or(db.Eq("email"), db.Eq("mail"))).Compile()
+	orders     = db.Select[User]("orders").Compile()
+)
+`
+
+var migrations = fstest.MapFS{
+	"0001_users.up.sql": {Data: []byte(`
+		-- users of the app
+		CREATE TABLE users (
+			id BIGSERIAL PRIMARY KEY,
+			email TEXT NOT NULL,
+			nick TEXT,
+			age INTEGER NOT NULL,
+			created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+		);
+		CREATE INDEX users_email ON users (email);`)},
+	"0001_users.down.sql": {Data: []byte(`DROP TABLE users;`)},
+	"0002_tags.up.sql": {Data: []byte(`
+		ALTER TABLE users ADD COLUMN tags TEXT[];
+		ALTER TABLE users RENAME COLUMN nick TO nickname;`)},
+}
+
+func writeSource(t *testing.T) string {
+	t.Helper()
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "store.go"), []byte(source), 0o644))
+	return dir
+}
+
+func messages(problems []dbxcheck.Problem) []string {
+	msgs := make([]string, len(problems))
+	for i, p := range problems {
+		msgs[i] = p.Message
+	}
+	return msgs
+}
+
+func TestCheck(t *testing.T) {
+	problems, err := dbxcheck.Check(writeSource(t), migrations)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Insert: column users.nickname is nullable, field Nickname of type string can't hold NULL",
+		"Insert: column users.age is INTEGER, field Age is string",
+		"Count: WHERE column users.mail doesn't exist",
+		"Select: table orders doesn't exist",
+	}, messages(problems))
+	require.Equal(t, 27, problems[0].Pos.Line)
+}
+
+func TestLoadMigrations(t *testing.T) {
+	schema, err := dbxcheck.LoadMigrations(migrations)
+	require.NoError(t, err)
+	require.Equal(t, map[string]dbx.Column{
+		"id":         {Name: "id", DataType: "BIGSERIAL", IsNullable: "NO"},
+		"email":      {Name: "email", DataType: "TEXT", IsNullable: "NO"},
+		"nickname":   {Name: "nickname", DataType: "TEXT", IsNullable: "YES"},
+		"age":        {Name: "age", DataType: "INTEGER", IsNullable: "NO"},
+		"created_at": {Name: "created_at", DataType: "TIMESTAMPTZ", IsNullable: "NO"},
+		"tags":       {Name: "tags", DataType: "TEXT[]", IsNullable: "YES"},
+	}, schema["users"])
+
+	require.NoError(t, schema.ApplyDDL(`ALTER TABLE users ALTER COLUMN age TYPE TEXT USING age::text, ALTER COLUMN nickname SET NOT NULL; DROP TABLE users`))
+	require.Empty(t, schema)
+}
+
+func TestReadSchema(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY AUTOINCREMENT, email TEXT NOT NULL, nickname TEXT NOT NULL, age INTEGER NOT NULL, tags TEXT, created_at TIMESTAMP NOT NULL)")
+	require.NoError(t, err)
+
+	usages, err := dbxcheck.Find(writeSource(t))
+	require.NoError(t, err)
+	require.Equal(t, []string{"users", "orders"}, dbxcheck.Tables(usages))
+
+	schema, err := dbxcheck.ReadSchema(ctx, db, dbx.SQLite, dbxcheck.Tables(usages)...)
+	require.NoError(t, err)
+	require.Equal(t, []string{
+		"Insert: column users.age is INTEGER, field Age is string",
+		"Count: WHERE column users.mail doesn't exist",
+		"Select: table orders doesn't exist",
+	}, messages(dbxcheck.Verify(usages, schema)))
+}
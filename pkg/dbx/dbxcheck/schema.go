@@ -0,0 +1,304 @@
+package dbxcheck
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+// Schema maps lower case table names to their columns keyed by lower case name
+type Schema map[string]map[string]dbx.Column
+
+// ReadSchema reads columns of tables from the live database, missing tables are left out
+func ReadSchema(ctx context.Context, db dbx.DB, d dbx.Dialect, tables ...string) (Schema, error) {
+	schema := make(Schema)
+	for _, table := range tables {
+		cols, err := dbx.TableColumns(ctx, db, d, table)
+		if err != nil {
+			return nil, fmt.Errorf("read columns of %s: %w", table, err)
+		}
+		if len(cols) == 0 {
+			continue
+		}
+		columns := make(map[string]dbx.Column, len(cols))
+		for _, col := range cols {
+			columns[strings.ToLower(col.Name)] = col
+		}
+		schema[strings.ToLower(table)] = columns
+	}
+	return schema, nil
+}
+
+var migrationFile = regexp.MustCompile(`^(\d+)_.+\.sql$`)
+
+// LoadMigrations builds the schema by replaying up migrations of the migrate package layout,
+// files named <version>_<name>.up.sql, in version order. See ApplyDDL for the statements understood.
+func LoadMigrations(fsys fs.FS) (Schema, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	type file struct {
+		version int64
+		name    string
+	}
+	var files []file
+	for _, entry := range entries {
+		m := migrationFile.FindStringSubmatch(entry.Name())
+		if entry.IsDir() || m == nil || strings.HasSuffix(entry.Name(), ".down.sql") {
+			continue
+		}
+		version, err := strconv.ParseInt(m[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration %s: %w", entry.Name(), err)
+		}
+		files = append(files, file{version: version, name: entry.Name()})
+	}
+	slices.SortFunc(files, func(a, b file) int { return cmp.Compare(a.version, b.version) })
+
+	schema := make(Schema)
+	for _, f := range files {
+		data, err := fs.ReadFile(fsys, f.name)
+		if err != nil {
+			return nil, err
+		}
+		if err := schema.ApplyDDL(string(data)); err != nil {
+			return nil, fmt.Errorf("migration %s: %w", f.name, err)
+		}
+	}
+	return schema, nil
+}
+
+var (
+	createTableStmt = regexp.MustCompile(`(?is)^CREATE\s+(?:(?:GLOBAL\s+|LOCAL\s+)?TEMP(?:ORARY)?\s+)?TABLE\s+(?:IF\s+NOT\s+EXISTS\s+)?(\S+?)\s*\((.*)\)[^)]*$`)
+	alterTableStmt  = regexp.MustCompile(`(?is)^ALTER\s+TABLE\s+(?:IF\s+EXISTS\s+)?(?:ONLY\s+)?(\S+)\s+(.*)$`)
+	dropTableStmt   = regexp.MustCompile(`(?is)^DROP\s+TABLE\s+(?:IF\s+EXISTS\s+)?(.*?)(?:\s+(?:CASCADE|RESTRICT))?$`)
+
+	addColumn    = regexp.MustCompile(`(?is)^ADD\s+(?:COLUMN\s+)?(?:IF\s+NOT\s+EXISTS\s+)?(.*)$`)
+	dropColumn   = regexp.MustCompile(`(?is)^DROP\s+(?:COLUMN\s+)?(?:IF\s+EXISTS\s+)?(\S+)`)
+	renameColumn = regexp.MustCompile(`(?is)^RENAME\s+(?:COLUMN\s+)?(\S+)\s+TO\s+(\S+)$`)
+	renameTable  = regexp.MustCompile(`(?is)^RENAME\s+TO\s+(\S+)$`)
+	alterColumn  = regexp.MustCompile(`(?is)^(?:ALTER|MODIFY)\s+(?:COLUMN\s+)?(\S+)\s+(.*)$`)
+	setType      = regexp.MustCompile(`(?is)^(?:SET\s+DATA\s+)?TYPE\s+(.*?)(?:\s+USING\s.*)?$`)
+
+	// columnConstraint starts the constraints following the type of a column definition
+	columnConstraint = regexp.MustCompile(`(?i)\s(?:NOT\s+NULL|NULL|DEFAULT|PRIMARY\s+KEY|REFERENCES|UNIQUE|CHECK|GENERATED|CONSTRAINT|COLLATE|AUTO_INCREMENT|AUTOINCREMENT|IDENTITY)\b`)
+	notNull          = regexp.MustCompile(`(?i)\bNOT\s+NULL\b|\bPRIMARY\s+KEY\b`)
+	primaryKey       = regexp.MustCompile(`(?is)^(?:CONSTRAINT\s+\S+\s+)?PRIMARY\s+KEY\s*\((.*)\)`)
+	tableConstraint  = regexp.MustCompile(`(?i)^(?:CONSTRAINT|PRIMARY|UNIQUE|FOREIGN|CHECK|INDEX|KEY|EXCLUDE)\b`)
+)
+
+// ApplyDDL applies schema changes of sql to the schema: CREATE TABLE, DROP TABLE and ALTER TABLE
+// adding, dropping, renaming and altering columns. Other statements, e.g. CREATE INDEX or data changes, are skipped.
+func (s Schema) ApplyDDL(sql string) error {
+	for _, stmt := range splitStatements(sql) {
+		switch {
+		case createTableStmt.MatchString(stmt):
+			m := createTableStmt.FindStringSubmatch(stmt)
+			columns := make(map[string]dbx.Column)
+			for _, def := range splitTopLevel(m[2]) {
+				if pk := primaryKey.FindStringSubmatch(def); pk != nil {
+					for _, name := range splitTopLevel(pk[1]) {
+						key := strings.ToLower(unquote(name))
+						if col, ok := columns[key]; ok {
+							col.IsNullable = "NO"
+							columns[key] = col
+						}
+					}
+					continue
+				}
+				if tableConstraint.MatchString(def) {
+					continue
+				}
+				col := parseColumn(def)
+				columns[strings.ToLower(col.Name)] = col
+			}
+			s[tableName(m[1])] = columns
+		case alterTableStmt.MatchString(stmt):
+			m := alterTableStmt.FindStringSubmatch(stmt)
+			if err := s.alterTable(tableName(m[1]), m[2]); err != nil {
+				return err
+			}
+		case dropTableStmt.MatchString(stmt):
+			m := dropTableStmt.FindStringSubmatch(stmt)
+			for _, name := range splitTopLevel(m[1]) {
+				delete(s, tableName(name))
+			}
+		}
+	}
+	return nil
+}
+
+func (s Schema) alterTable(table, actions string) error {
+	columns, ok := s[table]
+	if !ok {
+		return fmt.Errorf("alter of unknown table %s", table)
+	}
+	for _, action := range splitTopLevel(actions) {
+		switch {
+		case renameTable.MatchString(action):
+			delete(s, table)
+			table = tableName(renameTable.FindStringSubmatch(action)[1])
+			s[table] = columns
+		case renameColumn.MatchString(action):
+			m := renameColumn.FindStringSubmatch(action)
+			from := strings.ToLower(unquote(m[1]))
+			col := columns[from]
+			delete(columns, from)
+			col.Name = unquote(m[2])
+			columns[strings.ToLower(col.Name)] = col
+		case addColumn.MatchString(action):
+			def := addColumn.FindStringSubmatch(action)[1]
+			if tableConstraint.MatchString(def) {
+				continue
+			}
+			col := parseColumn(def)
+			columns[strings.ToLower(col.Name)] = col
+		case dropColumn.MatchString(action):
+			name := dropColumn.FindStringSubmatch(action)[1]
+			if tableConstraint.MatchString(name) {
+				continue
+			}
+			delete(columns, strings.ToLower(unquote(name)))
+		case alterColumn.MatchString(action):
+			m := alterColumn.FindStringSubmatch(action)
+			name := strings.ToLower(unquote(m[1]))
+			col, ok := columns[name]
+			if !ok {
+				return fmt.Errorf("alter of unknown column %s.%s", table, name)
+			}
+			change := strings.ToUpper(strings.Join(strings.Fields(m[2]), " "))
+			switch {
+			case change == "SET NOT NULL":
+				col.IsNullable = "NO"
+			case change == "DROP NOT NULL":
+				col.IsNullable = "YES"
+			case setType.MatchString(m[2]):
+				col.DataType = setType.FindStringSubmatch(m[2])[1]
+			case !strings.HasPrefix(change, "SET ") && !strings.HasPrefix(change, "DROP "):
+				// MySQL MODIFY redefines the whole column
+				redefined := parseColumn(m[1] + " " + m[2])
+				col.DataType, col.IsNullable = redefined.DataType, redefined.IsNullable
+			}
+			columns[name] = col
+		}
+	}
+	return nil
+}
+
+// parseColumn parses a column definition, e.g. email TEXT NOT NULL
+func parseColumn(def string) dbx.Column {
+	fields := strings.Fields(def)
+	name := unquote(fields[0])
+	rest := " " + strings.TrimSpace(strings.TrimPrefix(def, fields[0]))
+
+	typ := rest
+	if loc := columnConstraint.FindStringIndex(rest); loc != nil {
+		typ = rest[:loc[0]]
+	}
+	nullable := "YES"
+	if notNull.MatchString(rest) {
+		nullable = "NO"
+	}
+	return dbx.Column{Name: name, DataType: strings.TrimSpace(typ), IsNullable: nullable}
+}
+
+// tableName strips quotes and the schema of a table name
+func tableName(name string) string {
+	name = unquote(name)
+	if i := strings.LastIndexByte(name, '.'); i >= 0 {
+		name = unquote(name[i+1:])
+	}
+	return strings.ToLower(name)
+}
+
+func unquote(name string) string {
+	name = strings.TrimSpace(name)
+	if len(name) >= 2 && strings.ContainsRune("\"`[", rune(name[0])) {
+		return name[1 : len(name)-1]
+	}
+	return name
+}
+
+// splitStatements splits sql on semicolons outside of quotes and drops comments
+func splitStatements(sql string) []string {
+	var stmts []string
+	var sb strings.Builder
+	flush := func() {
+		if stmt := strings.TrimSpace(sb.String()); stmt != "" {
+			stmts = append(stmts, stmt)
+		}
+		sb.Reset()
+	}
+
+	var quote byte
+	for i := 0; i < len(sql); i++ {
+		c := sql[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '-' && strings.HasPrefix(sql[i:], "--"):
+			end := strings.IndexByte(sql[i:], '\n')
+			if end < 0 {
+				end = len(sql) - i
+			}
+			i += end - 1
+			continue
+		case c == '/' && strings.HasPrefix(sql[i:], "/*"):
+			end := strings.Index(sql[i:], "*/")
+			if end < 0 {
+				end = len(sql) - i - 2
+			}
+			i += end + 1
+			sb.WriteByte(' ')
+			continue
+		case c == ';':
+			flush()
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	flush()
+	return stmts
+}
+
+// splitTopLevel splits s on commas outside of parentheses and quotes, parts are trimmed
+func splitTopLevel(s string) []string {
+	var parts []string
+	depth, start := 0, 0
+	var quote byte
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '(':
+			depth++
+		case c == ')':
+			depth--
+		case c == ',' && depth == 0:
+			parts = append(parts, strings.TrimSpace(s[start:i]))
+			start = i + 1
+		}
+	}
+	if last := strings.TrimSpace(s[start:]); last != "" {
+		parts = append(parts, last)
+	}
+	return parts
+}
@@ -0,0 +1,75 @@
+// Package dbxpgx runs dbx queries on pgx connections and pools without database/sql, e.g.
+//
+//	query, args := dbx.Select[User]("users").Where(dbx.Eq("id")).Compile().PreviewQuery(id)
+//	u, err := dbxpgx.Get[User](ctx, pool, query, args...)
+package dbxpgx
+
+import (
+	"context"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+// Querier is implemented by *pgx.Conn, *pgxpool.Pool and pgx.Tx
+type Querier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// Exec executes query and returns the number of affected rows
+func Exec(ctx context.Context, q Querier, query string, args ...any) (int64, error) {
+	tag, err := q.Exec(ctx, query, args...)
+	if err != nil {
+		return 0, err
+	}
+	return tag.RowsAffected(), nil
+}
+
+// Query executes query and scans all rows into R, columns are matched to the db tagged fields of R by name
+func Query[R any](ctx context.Context, q Querier, query string, args ...any) ([]R, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return dbx.ScanAll[R](Rows(rows))
+}
+
+// Get executes query and scans the first row into R, pgx.ErrNoRows is returned if there are none.
+// pgx.ErrNoRows matches sql.ErrNoRows with errors.Is.
+func Get[R any](ctx context.Context, q Querier, query string, args ...any) (R, error) {
+	var result R
+	rows, err := Query[R](ctx, q, query, args...)
+	if err != nil {
+		return result, err
+	}
+	if len(rows) == 0 {
+		return result, pgx.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// Rows adapts pgx.Rows to dbx.Rows
+func Rows(rows pgx.Rows) dbx.Rows {
+	return pgxRows{rows}
+}
+
+type pgxRows struct {
+	pgx.Rows
+}
+
+func (r pgxRows) Columns() ([]string, error) {
+	fds := r.FieldDescriptions()
+	cols := make([]string, len(fds))
+	for i, fd := range fds {
+		cols[i] = fd.Name
+	}
+	return cols, nil
+}
+
+func (r pgxRows) Scan(dest ...interface{}) error {
+	return r.Rows.Scan(dest...)
+}
@@ -0,0 +1,88 @@
+package dbxpgx_test
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"testing"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx/dbxpgx"
+)
+
+type user struct {
+	ID   int    `db:"id"`
+	Name string `db:"name"`
+}
+
+// fakeRows is pgx.Rows over in-memory values
+type fakeRows struct {
+	pgx.Rows
+	cols   []string
+	values [][]any
+	i      int
+}
+
+func (r *fakeRows) FieldDescriptions() []pgconn.FieldDescription {
+	fds := make([]pgconn.FieldDescription, len(r.cols))
+	for i, col := range r.cols {
+		fds[i].Name = col
+	}
+	return fds
+}
+
+func (r *fakeRows) Next() bool {
+	r.i++
+	return r.i <= len(r.values)
+}
+
+func (r *fakeRows) Scan(dest ...any) error {
+	for i, d := range dest {
+		switch d := d.(type) {
+		case *int:
+			*d = r.values[r.i-1][i].(int)
+		case *string:
+			*d = r.values[r.i-1][i].(string)
+		default:
+			return errors.New("unexpected scan destination")
+		}
+	}
+	return nil
+}
+
+func (r *fakeRows) Err() error { return nil }
+func (r *fakeRows) Close()     {}
+
+type fakeQuerier struct {
+	rows *fakeRows
+}
+
+func (q fakeQuerier) Exec(context.Context, string, ...any) (pgconn.CommandTag, error) {
+	return pgconn.NewCommandTag("UPDATE 2"), nil
+}
+
+func (q fakeQuerier) Query(context.Context, string, ...any) (pgx.Rows, error) {
+	return q.rows, nil
+}
+
+func TestQuery(t *testing.T) {
+	ctx := context.Background()
+	q := fakeQuerier{rows: &fakeRows{
+		cols:   []string{"name", "id"},
+		values: [][]any{{"John", 1}, {"Jane", 2}},
+	}}
+
+	users, err := dbxpgx.Query[user](ctx, q, "SELECT name, id FROM users")
+	require.NoError(t, err)
+	require.Equal(t, []user{{ID: 1, Name: "John"}, {ID: 2, Name: "Jane"}}, users)
+
+	_, err = dbxpgx.Get[user](ctx, fakeQuerier{rows: &fakeRows{cols: []string{"id"}}}, "SELECT id FROM users")
+	require.ErrorIs(t, err, sql.ErrNoRows)
+
+	n, err := dbxpgx.Exec(ctx, q, "UPDATE users SET name = $1", "x")
+	require.NoError(t, err)
+	require.EqualValues(t, 2, n)
+}
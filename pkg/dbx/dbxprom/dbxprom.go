@@ -0,0 +1,76 @@
+// Package dbxprom records dbx query metrics with Prometheus, e.g.
+//
+//	db = dbx.WithHooks(db, dbx.MetricsHook(dbxprom.New(prometheus.DefaultRegisterer)))
+package dbxprom
+
+import (
+	"context"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+// Metrics implements dbx.Metrics with dbx_queries_total and dbx_query_duration_seconds
+// labeled by query name, table, operation and, for the counter, status ok or error
+type Metrics struct {
+	queries  *prometheus.CounterVec
+	duration *prometheus.HistogramVec
+}
+
+// Option configures Metrics
+type Option func(*options)
+
+type options struct {
+	namespace string
+	buckets   []float64
+}
+
+// WithNamespace prefixes metric names, e.g. myapp_dbx_queries_total
+func WithNamespace(namespace string) Option {
+	return func(o *options) {
+		o.namespace = namespace
+	}
+}
+
+// WithBuckets sets buckets of the duration histogram in seconds, prometheus.DefBuckets by default
+func WithBuckets(buckets []float64) Option {
+	return func(o *options) {
+		o.buckets = buckets
+	}
+}
+
+// New creates metrics registered with reg, it panics if they are already registered
+func New(reg prometheus.Registerer, opts ...Option) *Metrics {
+	o := options{buckets: prometheus.DefBuckets}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	m := &Metrics{
+		queries: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: o.namespace,
+			Name:      "dbx_queries_total",
+			Help:      "Number of executed queries.",
+		}, []string{"name", "table", "operation", "status"}),
+		duration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: o.namespace,
+			Name:      "dbx_query_duration_seconds",
+			Help:      "Duration of executed queries.",
+			Buckets:   o.buckets,
+		}, []string{"name", "table", "operation"}),
+	}
+	reg.MustRegister(m.queries, m.duration)
+	return m
+}
+
+// ObserveQuery implements dbx.Metrics
+func (m *Metrics) ObserveQuery(_ context.Context, labels dbx.QueryLabels, duration time.Duration, err error) {
+	status := "ok"
+	if err != nil {
+		status = "error"
+	}
+	m.queries.WithLabelValues(labels.Name, labels.Table, labels.Operation, status).Inc()
+	m.duration.WithLabelValues(labels.Name, labels.Table, labels.Operation).Observe(duration.Seconds())
+}
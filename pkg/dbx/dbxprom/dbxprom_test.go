@@ -0,0 +1,43 @@
+package dbxprom_test
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"testing"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxprom"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { sqlDB.Close() })
+	sqlDB.SetMaxOpenConns(1)
+	_, err = sqlDB.Exec("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")
+	require.NoError(t, err)
+
+	reg := prometheus.NewRegistry()
+	db := dbx.WithHooks(sqlDB, dbx.MetricsHook(dbxprom.New(reg)))
+
+	_, err = db.ExecContext(dbx.WithQueryName(ctx, "users.create"), "INSERT INTO users (name) VALUES ($1)", "John")
+	require.NoError(t, err)
+	_, err = db.QueryContext(ctx, "SELECT missing FROM users")
+	require.Error(t, err)
+
+	expected := `
+# HELP dbx_queries_total Number of executed queries.
+# TYPE dbx_queries_total counter
+dbx_queries_total{name="",operation="Query",status="error",table="users"} 1
+dbx_queries_total{name="users.create",operation="Exec",status="ok",table="users"} 1
+`
+	require.NoError(t, testutil.GatherAndCompare(reg, strings.NewReader(expected), "dbx_queries_total"))
+	require.Equal(t, 2, testutil.CollectAndCount(reg, "dbx_query_duration_seconds"))
+}
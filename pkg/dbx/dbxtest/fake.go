@@ -0,0 +1,188 @@
+// Package dbxtest provides a fake dbx.DB for unit tests of code running dbx queries
+package dbxtest
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"sync"
+	"testing"
+)
+
+// Query is a query executed on Fake
+type Query struct {
+	SQL  string
+	Args []any
+}
+
+// Fake is a dbx.DB recording executed queries. Results are returned in the order
+// they were added with AddRows, AddResult and AddError, queries without them
+// get no rows and zero affected rows. Transactions are accepted and do nothing.
+type Fake struct {
+	*sql.DB
+
+	mu      sync.Mutex
+	queries []Query
+	results []fakeResult
+}
+
+type fakeResult struct {
+	cols         []string
+	rows         [][]any
+	rowsAffected int64
+	err          error
+}
+
+// New creates a new fake, it's closed when the test finishes
+func New(tb testing.TB) *Fake {
+	f := &Fake{}
+	f.DB = sql.OpenDB(connector{f})
+	tb.Cleanup(func() { f.DB.Close() })
+	return f
+}
+
+// AddRows adds rows returned by the next query, e.g. a RETURNING or SELECT one
+func (f *Fake) AddRows(cols []string, rows ...[]any) {
+	f.add(fakeResult{cols: cols, rows: rows})
+}
+
+// AddResult adds the number of rows affected by the next exec
+func (f *Fake) AddResult(rowsAffected int64) {
+	f.add(fakeResult{rowsAffected: rowsAffected})
+}
+
+// AddError adds an error returned by the next query or exec
+func (f *Fake) AddError(err error) {
+	f.add(fakeResult{err: err})
+}
+
+// Queries returns queries executed so far with their args as they were passed
+func (f *Fake) Queries() []Query {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]Query(nil), f.queries...)
+}
+
+// Reset forgets executed queries and results that were not used yet
+func (f *Fake) Reset() {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.queries = nil
+	f.results = nil
+}
+
+func (f *Fake) add(r fakeResult) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.results = append(f.results, r)
+}
+
+// record records the query and returns its result
+func (f *Fake) record(query string, args []driver.NamedValue) fakeResult {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	values := make([]any, len(args))
+	for i, arg := range args {
+		values[i] = arg.Value
+	}
+	f.queries = append(f.queries, Query{SQL: query, Args: values})
+
+	if len(f.results) == 0 {
+		return fakeResult{}
+	}
+	r := f.results[0]
+	f.results = f.results[1:]
+	return r
+}
+
+type connector struct {
+	fake *Fake
+}
+
+func (c connector) Connect(context.Context) (driver.Conn, error) {
+	return conn(c), nil
+}
+
+func (c connector) Driver() driver.Driver {
+	return fakeDriver{}
+}
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("dbxtest: use dbxtest.New")
+}
+
+type conn struct {
+	fake *Fake
+}
+
+func (c conn) Prepare(string) (driver.Stmt, error) {
+	return nil, errors.New("dbxtest: prepared statements are not supported")
+}
+
+func (c conn) Close() error {
+	return nil
+}
+
+func (c conn) Begin() (driver.Tx, error) {
+	return tx{}, nil
+}
+
+func (c conn) BeginTx(context.Context, driver.TxOptions) (driver.Tx, error) {
+	return tx{}, nil
+}
+
+// CheckNamedValue keeps args as they were passed, so they are recorded without driver conversions
+func (c conn) CheckNamedValue(*driver.NamedValue) error {
+	return nil
+}
+
+func (c conn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	r := c.fake.record(query, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return &rows{cols: r.cols, rows: r.rows}, nil
+}
+
+func (c conn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	r := c.fake.record(query, args)
+	if r.err != nil {
+		return nil, r.err
+	}
+	return driver.RowsAffected(r.rowsAffected), nil
+}
+
+type tx struct{}
+
+func (tx) Commit() error   { return nil }
+func (tx) Rollback() error { return nil }
+
+type rows struct {
+	cols []string
+	rows [][]any
+	next int
+}
+
+func (r *rows) Columns() []string {
+	return r.cols
+}
+
+func (r *rows) Close() error {
+	return nil
+}
+
+func (r *rows) Next(dest []driver.Value) error {
+	if r.next >= len(r.rows) {
+		return io.EOF
+	}
+	for i, v := range r.rows[r.next] {
+		dest[i] = v
+	}
+	r.next++
+	return nil
+}
@@ -0,0 +1,48 @@
+package dbxtest_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxtest"
+)
+
+type user struct {
+	ID   int    `db:"id,auto"`
+	Name string `db:"name"`
+	Age  int    `db:"age"`
+}
+
+func TestFake(t *testing.T) {
+	ctx := context.Background()
+	db := dbxtest.New(t)
+	users := dbx.NewRepository[user, int]("users")
+
+	db.AddRows([]string{"id", "name", "age"}, []any{1, "John", 30})
+	u, err := users.Insert(ctx, db, user{Name: "John", Age: 30})
+	require.NoError(t, err)
+	require.Equal(t, user{ID: 1, Name: "John", Age: 30}, u)
+
+	db.AddResult(1)
+	n, err := users.Delete(ctx, db, 1)
+	require.NoError(t, err)
+	require.EqualValues(t, 1, n)
+
+	db.AddError(errors.New("boom"))
+	_, err = users.List(ctx, db)
+	require.EqualError(t, err, "boom")
+
+	_, err = users.GetByID(ctx, db, 2)
+	require.Error(t, err)
+
+	require.Equal(t, []dbxtest.Query{
+		{SQL: "INSERT INTO users (name, age) VALUES ($1, $2) RETURNING id, name, age", Args: []any{"John", 30}},
+		{SQL: "DELETE FROM users WHERE id = $1", Args: []any{1}},
+		{SQL: "SELECT id, name, age FROM users ORDER BY id", Args: []any{}},
+		{SQL: "SELECT id, name, age FROM users WHERE id = $1", Args: []any{2}},
+	}, db.Queries())
+}
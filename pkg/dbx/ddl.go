@@ -0,0 +1,191 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// CreateTableBuilder generates CREATE TABLE statements from model structs
+type CreateTableBuilder[T any] struct {
+	table       string
+	inputType   reflect.Type
+	fields      []fieldInfo
+	dialect     Dialect
+	ifNotExists bool
+}
+
+// CreateTable creates a new CREATE TABLE builder with a column for every db tagged field of T.
+// Column types are derived from field types, pointers and sql.Null* types are nullable,
+// auto integer fields are generated by the database, e.g. BIGSERIAL on Postgres.
+// Primary key is the fields tagged with the pk option, or the auto "id" column if there are none.
+// Fields tagged with the array option are Postgres arrays of the element type, e.g. TEXT[].
+// Tag a field with ddl to set its column type explicitly, e.g. `ddl:"NUMERIC(10, 2)"`.
+func CreateTable[T any](table string) *CreateTableBuilder[T] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+	return &CreateTableBuilder[T]{
+		table:     table,
+		inputType: inputType,
+		fields:    extractFields(inputType),
+	}
+}
+
+// Dialect sets column types and syntax of the generated SQL
+func (cb *CreateTableBuilder[T]) Dialect(d Dialect) *CreateTableBuilder[T] {
+	cb.dialect = d
+	return cb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (cb *CreateTableBuilder[T]) Naming(naming NamingStrategy) *CreateTableBuilder[T] {
+	cb.fields = extractNamedFields(cb.inputType, naming)
+	return cb
+}
+
+// IfNotExists adds IF NOT EXISTS, Oracle doesn't support it
+func (cb *CreateTableBuilder[T]) IfNotExists() *CreateTableBuilder[T] {
+	cb.ifNotExists = true
+	return cb
+}
+
+// SQL returns the CREATE TABLE statement.
+// It panics if a column type can't be derived from the field type.
+func (cb *CreateTableBuilder[T]) SQL() string {
+	d := cb.dialect
+
+	var fields []fieldInfo
+	for _, field := range cb.fields {
+		// nested columns belong to joined tables
+		if !strings.Contains(field.DbName, ".") {
+			fields = append(fields, field)
+		}
+	}
+
+	pk := primaryKeyColumns(fields)
+	if pk == nil {
+		if field, ok := fieldByColumn(fields, "id"); ok && field.IsAuto {
+			pk = []string{"id"}
+		}
+	}
+
+	var defs []string
+	for _, field := range fields {
+		col := d.mustIdent(field.DbName)
+		structField := cb.inputType.FieldByIndex(field.Index)
+		colType, ok := structField.Tag.Lookup("ddl")
+		if !ok {
+			colType = d.columnType(cb.inputType, field)
+		}
+
+		if field.IsAuto && isIntegerKind(field.Type.Kind()) {
+			if d == SQLite && len(pk) == 1 && pk[0] == field.DbName {
+				// SQLite generates only INTEGER PRIMARY KEY columns
+				defs = append(defs, col+" INTEGER PRIMARY KEY AUTOINCREMENT")
+				pk = nil
+				continue
+			}
+			defs = append(defs, col+" "+d.autoIncrement(colType, field.Type))
+			continue
+		}
+
+		def := col + " " + colType
+		// nil slices of array fields are bound as NULL
+		if !ddlNullable(field.Type) && !field.Array {
+			def += " NOT NULL"
+		}
+		if field.IsAuto && field.Type == timeType {
+			def += " DEFAULT CURRENT_TIMESTAMP"
+		}
+		defs = append(defs, def)
+	}
+	if len(pk) > 0 {
+		cols := make([]string, len(pk))
+		for i, col := range pk {
+			cols[i] = d.mustIdent(col)
+		}
+		defs = append(defs, "PRIMARY KEY ("+strings.Join(cols, ", ")+")")
+	}
+
+	create := "CREATE TABLE "
+	if cb.ifNotExists {
+		create += "IF NOT EXISTS "
+	}
+	return create + d.mustTable(cb.table) + " (" + strings.Join(defs, ", ") + ")"
+}
+
+// columnType returns the column type of field
+func (d Dialect) columnType(t reflect.Type, field fieldInfo) string {
+	ft := field.Type
+	if ft.Kind() == reflect.Pointer {
+		ft = ft.Elem()
+	}
+	if isSQLNull(ft) {
+		// sql.NullString and friends wrap the value in the first field
+		ft = ft.Field(0).Type
+	}
+	if field.Array && d == Postgres && ft.Kind() == reflect.Slice {
+		elem := field
+		elem.Array = false
+		elem.Type = ft.Elem()
+		return d.columnType(t, elem) + "[]"
+	}
+
+	types := [...][4]string{
+		// Postgres, MySQL, SQLite, Oracle
+		reflect.Bool:    {"BOOLEAN", "BOOLEAN", "BOOLEAN", "NUMBER(1)"},
+		reflect.Int:     {"BIGINT", "BIGINT", "INTEGER", "NUMBER(19)"},
+		reflect.Int8:    {"SMALLINT", "TINYINT", "INTEGER", "NUMBER(3)"},
+		reflect.Int16:   {"SMALLINT", "SMALLINT", "INTEGER", "NUMBER(5)"},
+		reflect.Int32:   {"INTEGER", "INT", "INTEGER", "NUMBER(10)"},
+		reflect.Int64:   {"BIGINT", "BIGINT", "INTEGER", "NUMBER(19)"},
+		reflect.Uint:    {"BIGINT", "BIGINT UNSIGNED", "INTEGER", "NUMBER(20)"},
+		reflect.Uint8:   {"SMALLINT", "TINYINT UNSIGNED", "INTEGER", "NUMBER(3)"},
+		reflect.Uint16:  {"INTEGER", "SMALLINT UNSIGNED", "INTEGER", "NUMBER(5)"},
+		reflect.Uint32:  {"BIGINT", "INT UNSIGNED", "INTEGER", "NUMBER(10)"},
+		reflect.Uint64:  {"NUMERIC(20)", "BIGINT UNSIGNED", "INTEGER", "NUMBER(20)"},
+		reflect.Float32: {"REAL", "FLOAT", "REAL", "BINARY_FLOAT"},
+		reflect.Float64: {"DOUBLE PRECISION", "DOUBLE", "REAL", "BINARY_DOUBLE"},
+		reflect.String:  {"TEXT", "VARCHAR(255)", "TEXT", "VARCHAR2(4000)"},
+	}
+	switch {
+	case ft == timeType:
+		return [...]string{"TIMESTAMPTZ", "DATETIME", "TIMESTAMP", "TIMESTAMP WITH TIME ZONE"}[d]
+	case ft.Kind() == reflect.Slice && ft.Elem().Kind() == reflect.Uint8:
+		return [...]string{"BYTEA", "BLOB", "BLOB", "BLOB"}[d]
+	case int(ft.Kind()) < len(types) && types[ft.Kind()][d] != "":
+		return types[ft.Kind()][d]
+	}
+	panic(fmt.Sprintf("dbx: no column type for field %s.%s of type %s, set it with the ddl tag", t, field.Name, field.Type))
+}
+
+// autoIncrement returns the type of a generated integer column
+func (d Dialect) autoIncrement(colType string, t reflect.Type) string {
+	switch d {
+	case Postgres:
+		if t.Kind() == reflect.Int32 || t.Kind() == reflect.Int16 {
+			return "SERIAL"
+		}
+		return "BIGSERIAL"
+	case MySQL:
+		return colType + " NOT NULL AUTO_INCREMENT"
+	case SQLite:
+		return colType + " NOT NULL"
+	default:
+		return colType + " GENERATED BY DEFAULT AS IDENTITY"
+	}
+}
+
+// ddlNullable reports whether a column of type t is nullable, unlike isNullable
+// it doesn't consider every scanner nullable, e.g. uuid.UUID columns are NOT NULL
+func ddlNullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer || isSQLNull(t)
+}
+
+// isSQLNull reports whether t is one of sql.NullString, sql.Null[T] and the like
+func isSQLNull(t reflect.Type) bool {
+	return t.Kind() == reflect.Struct && t.PkgPath() == "database/sql" && strings.HasPrefix(t.Name(), "Null")
+}
+
+func isIntegerKind(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Uint64
+}
@@ -0,0 +1,186 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+)
+
+// DeleteBuilder represents a delete query builder
+type DeleteBuilder[T any] struct {
+	table       string
+	inputType   reflect.Type
+	inputFields []fieldInfo
+	where       []Condition
+	dialect     Dialect
+	naming      NamingStrategy
+	softDelete  string
+	unscoped    bool
+}
+
+// DeleteReturningBuilder represents a delete query builder with returning clause
+type DeleteReturningBuilder[T, R any] struct {
+	delete          *DeleteBuilder[T]
+	returningFields []fieldInfo
+	exprs           map[string]Expression
+}
+
+// CompiledDeleteQuery represents a compiled delete query
+type CompiledDeleteQuery[T, R any] struct {
+	dialect         Dialect
+	query           string
+	argFields       []fieldInfo
+	returningFields []fieldInfo
+	ph              placeholders
+}
+
+// ExecutableDeleteQuery represents a delete query ready for execution
+type ExecutableDeleteQuery[T, R any] struct {
+	compiled *CompiledDeleteQuery[T, R]
+	query    string
+	args     []interface{}
+}
+
+// Delete creates a new delete query builder, WHERE placeholders are bound from the db tagged fields of T
+func Delete[T any](table string) *DeleteBuilder[T] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+	return &DeleteBuilder[T]{
+		table:       table,
+		inputType:   inputType,
+		inputFields: extractFields(inputType),
+	}
+}
+
+// Where adds conditions joined with AND, placeholders are bound from the fields of T with the same column name
+func (del *DeleteBuilder[T]) Where(conds ...Condition) *DeleteBuilder[T] {
+	del.where = append(del.where, conds...)
+	return del
+}
+
+// WherePK adds conditions on the primary key columns of T, see Where.
+// Columns are the fields tagged with the pk option, e.g. `db:"user_id,pk"`, it panics if there are none.
+func (del *DeleteBuilder[T]) WherePK() *DeleteBuilder[T] {
+	return del.Where(primaryKeyConds(del.inputType, del.inputFields)...)
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (del *DeleteBuilder[T]) Dialect(d Dialect) *DeleteBuilder[T] {
+	del.dialect = d
+	return del
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase).
+// It applies to the returning type as well, so call it before DeleteReturning.
+func (del *DeleteBuilder[T]) Naming(naming NamingStrategy) *DeleteBuilder[T] {
+	del.naming = naming
+	del.inputFields = extractNamedFields(del.inputType, naming)
+	return del
+}
+
+// SoftDelete turns the delete into UPDATE setting col to the current timestamp.
+// Fields of T tagged with the softdelete option, e.g. `db:"deleted_at,softdelete"`, set it automatically.
+func (del *DeleteBuilder[T]) SoftDelete(col string) *DeleteBuilder[T] {
+	del.softDelete = col
+	return del
+}
+
+// Unscoped deletes rows for real even if a soft delete column is set
+func (del *DeleteBuilder[T]) Unscoped() *DeleteBuilder[T] {
+	del.unscoped = true
+	return del
+}
+
+// DeleteReturning adds a returning clause to the delete query
+func DeleteReturning[T, R any](del *DeleteBuilder[T]) *DeleteReturningBuilder[T, R] {
+	return &DeleteReturningBuilder[T, R]{
+		delete:          del,
+		returningFields: extractNamedFields(reflect.TypeOf((*R)(nil)).Elem(), del.naming),
+	}
+}
+
+// Expr returns expr as the column col of R, e.g. Expr("deleted_at", dbx.Expr("CURRENT_TIMESTAMP")).
+// The expression can't have args.
+func (drb *DeleteReturningBuilder[T, R]) Expr(col string, expr Expression) *DeleteReturningBuilder[T, R] {
+	if drb.exprs == nil {
+		drb.exprs = make(map[string]Expression)
+	}
+	drb.exprs[col] = expr
+	return drb
+}
+
+// Compile compiles the delete query into a reusable form.
+// It panics if a WHERE column is not a field of T.
+func (del *DeleteBuilder[T]) Compile() *CompiledDeleteQuery[T, struct{}] {
+	b, argFields := del.build("")
+	return &CompiledDeleteQuery[T, struct{}]{
+		dialect:   del.dialect,
+		query:     del.dialect.Rebind(b.String()),
+		argFields: argFields,
+		ph:        newPlaceholders(b, del.dialect),
+	}
+}
+
+// Compile compiles the delete with returning query into a reusable form.
+// It panics if a WHERE column is not a field of T.
+func (drb *DeleteReturningBuilder[T, R]) Compile() *CompiledDeleteQuery[T, R] {
+	b, argFields := drb.delete.build(returningList(drb.delete.dialect, drb.returningFields, drb.exprs))
+	return &CompiledDeleteQuery[T, R]{
+		dialect:         drb.delete.dialect,
+		query:           drb.delete.dialect.Rebind(b.String()),
+		argFields:       argFields,
+		returningFields: drb.returningFields,
+		ph:              newPlaceholders(b, drb.delete.dialect),
+	}
+}
+
+// build builds the query, returning is the RETURNING column list, empty if there is none
+func (del *DeleteBuilder[T]) build(returning string) (*queryBuilder, []fieldInfo) {
+	b := &queryBuilder{dialect: del.dialect}
+	if col := softDeleteColumn(del.softDelete, del.inputFields); col != "" && !del.unscoped {
+		b.writeString(fmt.Sprintf("UPDATE %s SET %s = CURRENT_TIMESTAMP", del.dialect.mustTable(del.table), del.dialect.mustIdent(col)))
+		// already deleted rows keep their original timestamp
+		appendWhere(b, append(slices.Clip(del.where), IsNull(col)))
+	} else {
+		b.writeString(fmt.Sprintf("DELETE FROM %s", del.dialect.mustTable(del.table)))
+		appendWhere(b, del.where)
+	}
+
+	if returning != "" {
+		b.writeString(" RETURNING " + returning)
+	}
+
+	return b, b.resolve(del.inputType, del.inputFields)
+}
+
+// New creates a new executable query with the given input
+func (cq *CompiledDeleteQuery[T, R]) New(input T) *ExecutableDeleteQuery[T, R] {
+	query, args := cq.PreviewQuery(input)
+	return &ExecutableDeleteQuery[T, R]{
+		compiled: cq,
+		query:    query,
+		args:     args,
+	}
+}
+
+func (cq *CompiledDeleteQuery[T, R]) PreviewQuery(input T) (string, []any) {
+	return cq.ph.bind(cq.query, extractFieldArgs(&input, cq.argFields))
+}
+
+// ExecContext executes the query and returns the number of deleted rows
+func (eq *ExecutableDeleteQuery[T, R]) ExecContext(ctx context.Context, db DB) (int64, error) {
+	res, err := db.ExecContext(ctx, eq.query, eq.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// QueryContext executes the query and scans the deleted rows, it requires a returning clause
+func (eq *ExecutableDeleteQuery[T, R]) QueryContext(ctx context.Context, db DB) ([]R, error) {
+	if len(eq.compiled.returningFields) == 0 {
+		return nil, fmt.Errorf("query has no returning clause: %s", eq.compiled.query)
+	}
+
+	return queryRows[R](ctx, db, eq.query, eq.args, eq.compiled.returningFields)
+}
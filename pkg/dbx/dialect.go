@@ -0,0 +1,58 @@
+package dbx
+
+import "strings"
+
+// Dialect selects placeholder syntax of the generated SQL
+type Dialect int
+
+const (
+	// Postgres uses $1, $2 placeholders, it's the default
+	Postgres Dialect = iota
+	// MySQL uses ? placeholders
+	MySQL
+	// SQLite uses ? placeholders
+	SQLite
+	// Oracle uses :1, :2 placeholders
+	Oracle
+)
+
+// Rebind rewrites $n placeholders of query into the placeholders of the dialect.
+// Quoted literals and identifiers are left untouched.
+func (d Dialect) Rebind(query string) string {
+	if d == Postgres {
+		return query
+	}
+
+	var sb strings.Builder
+	sb.Grow(len(query))
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			if d == Oracle {
+				sb.WriteString(":" + query[i+1:j])
+			} else {
+				sb.WriteByte('?')
+			}
+			i = j - 1
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,104 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"strings"
+)
+
+type explainConfig struct {
+	analyze bool
+}
+
+// ExplainOption configures Explain
+type ExplainOption func(*explainConfig)
+
+// Analyze runs EXPLAIN ANALYZE, it executes the statement, so roll back writes explained this way.
+// SQLite and Oracle don't support it.
+func Analyze() ExplainOption {
+	return func(c *explainConfig) {
+		c.analyze = true
+	}
+}
+
+// Explain runs EXPLAIN of the query with the given input and returns the plan text
+func (cq *CompiledInsertQuery[T, R]) Explain(ctx context.Context, db DB, input T, opts ...ExplainOption) (string, error) {
+	query, args := cq.PreviewQuery(input)
+	return explain(ctx, db, cq.dialect, query, args, opts)
+}
+
+// Explain runs EXPLAIN of the query with the given input and returns the plan text
+func (cq *CompiledUpdateQuery[T]) Explain(ctx context.Context, db DB, input T, opts ...ExplainOption) (string, error) {
+	query, args := cq.PreviewQuery(input)
+	return explain(ctx, db, cq.dialect, query, args, opts)
+}
+
+// Explain runs EXPLAIN of the query with the given input and returns the plan text
+func (cq *CompiledDeleteQuery[T, R]) Explain(ctx context.Context, db DB, input T, opts ...ExplainOption) (string, error) {
+	query, args := cq.PreviewQuery(input)
+	return explain(ctx, db, cq.dialect, query, args, opts)
+}
+
+// Explain runs EXPLAIN of the query with args bound like New and returns the plan text
+func (cq *CompiledSelectQuery[R]) Explain(ctx context.Context, db DB, args []interface{}, opts ...ExplainOption) (string, error) {
+	args, err := cq.bindArgs(args)
+	if err != nil {
+		return "", err
+	}
+	query, args := cq.ph.bind(cq.query, args)
+	return explain(ctx, db, cq.dialect, query, args, opts)
+}
+
+// explain runs EXPLAIN of query and joins columns of every plan row with spaces and rows with new lines
+func explain(ctx context.Context, db DB, d Dialect, query string, args []interface{}, opts []ExplainOption) (string, error) {
+	var cfg explainConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	var prefix string
+	switch {
+	case d == Postgres && cfg.analyze:
+		prefix = "EXPLAIN (ANALYZE) "
+	case d == MySQL && cfg.analyze:
+		prefix = "EXPLAIN ANALYZE "
+	case (d == Postgres || d == MySQL) && !cfg.analyze:
+		prefix = "EXPLAIN "
+	case d == SQLite && !cfg.analyze:
+		prefix = "EXPLAIN QUERY PLAN "
+	default:
+		return "", fmt.Errorf("explain (analyze: %t) is not supported by the dialect", cfg.analyze)
+	}
+
+	rows, err := db.QueryContext(ctx, prefix+query, args...)
+	if err != nil {
+		return "", err
+	}
+	defer rows.Close()
+
+	cols, err := rows.Columns()
+	if err != nil {
+		return "", err
+	}
+	values := make([]sql.NullString, len(cols))
+	dest := make([]interface{}, len(cols))
+	for i := range values {
+		dest[i] = &values[i]
+	}
+
+	var plan []string
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return "", err
+		}
+		line := make([]string, 0, len(values))
+		for _, v := range values {
+			if v.Valid {
+				line = append(line, v.String)
+			}
+		}
+		plan = append(plan, strings.Join(line, " "))
+	}
+	return strings.Join(plan, "\n"), rows.Err()
+}
@@ -0,0 +1,59 @@
+package dbx
+
+import (
+	"fmt"
+	"strings"
+	"unsafe"
+)
+
+// Expression is a raw SQL fragment created with Expr
+type Expression struct {
+	sql  string
+	args []interface{}
+}
+
+// Expr is a raw SQL fragment, every $? placeholder is bound to the next arg at Compile time, e.g.
+//
+//	dbx.Expr("lower(email) = lower($?)", email)
+//
+// It's a WHERE condition on its own, see UpdateBuilder.Set and the Expr methods of returning builders for other positions.
+// Args are fixed once compiled, use Raw for placeholders bound per call.
+func Expr(sql string, args ...interface{}) Expression {
+	return Expression{sql: sql, args: args}
+}
+
+func (e Expression) appendSQL(b *queryBuilder) {
+	parts := strings.Split(e.sql, "$?")
+	if len(parts)-1 != len(e.args) {
+		panic(fmt.Sprintf("dbx: expression %q has %d placeholders, got %d args", e.sql, len(parts)-1, len(e.args)))
+	}
+	b.writeString(parts[0])
+	for i, part := range parts[1:] {
+		b.writeString(b.constParam(e.args[i]))
+		b.writeString(part)
+	}
+}
+
+// returningList is the RETURNING column list of fields, columns with expressions are returned as `expr AS col`.
+// Expressions of RETURNING can't have args.
+func returningList(d Dialect, fields []fieldInfo, exprs map[string]Expression) string {
+	cols := make([]string, len(fields))
+	for i, field := range fields {
+		col := d.mustIdent(field.DbName)
+		e, ok := exprs[field.DbName]
+		if !ok {
+			cols[i] = col
+			continue
+		}
+		if len(e.args) > 0 {
+			panic(fmt.Sprintf("dbx: RETURNING expression %q can't have args", e.sql))
+		}
+		cols[i] = e.sql + " AS " + col
+	}
+	return strings.Join(cols, ", ")
+}
+
+// constField is a field returning v as the query arg whatever the input is
+func constField(v interface{}) fieldInfo {
+	return fieldInfo{access: access{get: func(unsafe.Pointer) interface{} { return v }}}
+}
@@ -0,0 +1,130 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Filter is a WHERE/ORDER BY fragment built from a request struct by ParseFilter, e.g.
+//
+//	f, err := dbx.ParseFilter(req)
+//	users, err := dbx.Select[User]("users").Where(f.Where...).OrderBy(f.OrderBy...).Compile().New(f.Args...).ExecContext(ctx, db)
+type Filter struct {
+	Where   []Condition
+	Args    []interface{}
+	OrderBy []string
+}
+
+type filterField struct {
+	index []int
+	col   string
+	op    string
+}
+
+type filterMapping struct {
+	fields []filterField
+	// sortIndex is the index of the sort field, nil if there is none
+	sortIndex []int
+	sortable  map[string]struct{}
+}
+
+var filterMappings sync.Map // reflect.Type -> *filterMapping
+
+var filterOps = map[string]func(col string) Condition{
+	"eq":   Eq,
+	"ne":   Ne,
+	"gt":   Gt,
+	"gte":  Gte,
+	"lt":   Lt,
+	"lte":  Lte,
+	"like": Like,
+	"in":   In,
+}
+
+// ParseFilter builds a filter from the fields of req tagged with filter and sort, e.g.
+//
+//	type ListUsersRequest struct {
+//		Name   *string  `query:"name" filter:"name"`
+//		MinAge *int     `query:"min_age" filter:"age,gte"`
+//		IDs    []int    `query:"id" filter:"id,in"`
+//		Sort   string   `query:"sort" sort:"name,age"`
+//	}
+//
+// Filter operators are eq (default), ne, gt, gte, lt, lte, like and in. Nil pointers, zero values
+// and empty slices are left out. Sort value is a comma separated list of allowed columns,
+// prefixed with - for descending order, e.g. "-age,name". Unknown sort columns are reported as errors,
+// so they can be returned to the client.
+// It panics if the tags of req are invalid.
+func ParseFilter[F any](req F) (Filter, error) {
+	m := filterMappingOf(reflect.TypeOf(req))
+	v := reflect.ValueOf(req)
+
+	var f Filter
+	for _, field := range m.fields {
+		fv := v.FieldByIndex(field.index)
+		if fv.IsZero() || (fv.Kind() == reflect.Slice && fv.Len() == 0) {
+			continue
+		}
+		if fv.Kind() == reflect.Pointer {
+			fv = fv.Elem()
+		}
+		f.Where = append(f.Where, filterOps[field.op](field.col))
+		f.Args = append(f.Args, fv.Interface())
+	}
+
+	if m.sortIndex == nil {
+		return f, nil
+	}
+	sort := v.FieldByIndex(m.sortIndex).String()
+	for _, term := range strings.Split(sort, ",") {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+		col, desc := strings.CutPrefix(term, "-")
+		if _, ok := m.sortable[col]; !ok {
+			return Filter{}, fmt.Errorf("can't sort by %q", col)
+		}
+		if desc {
+			col += " DESC"
+		}
+		f.OrderBy = append(f.OrderBy, col)
+	}
+	return f, nil
+}
+
+func filterMappingOf(t reflect.Type) *filterMapping {
+	if m, ok := filterMappings.Load(t); ok {
+		return m.(*filterMapping)
+	}
+
+	m := &filterMapping{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if tag, ok := field.Tag.Lookup("filter"); ok {
+			col, op, _ := strings.Cut(tag, ",")
+			if op == "" {
+				op = "eq"
+			}
+			if _, ok := filterOps[op]; !ok {
+				panic(fmt.Sprintf("dbx: unknown filter operator %q of %s.%s", op, t, field.Name))
+			}
+			m.fields = append(m.fields, filterField{index: field.Index, col: col, op: op})
+		}
+		if tag, ok := field.Tag.Lookup("sort"); ok {
+			if field.Type.Kind() != reflect.String {
+				panic(fmt.Sprintf("dbx: sort field %s.%s must be a string", t, field.Name))
+			}
+			m.sortIndex = field.Index
+			m.sortable = make(map[string]struct{})
+			for _, col := range strings.Split(tag, ",") {
+				m.sortable[strings.TrimSpace(col)] = struct{}{}
+			}
+		}
+	}
+
+	actual, _ := filterMappings.LoadOrStore(t, m)
+	return actual.(*filterMapping)
+}
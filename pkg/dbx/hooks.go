@@ -0,0 +1,116 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"time"
+)
+
+// QueryInfo describes a query passed to hooks
+type QueryInfo struct {
+	// Operation is Exec, Query or QueryRow
+	Operation string
+	Query     string
+	Args      []interface{}
+	// RowsAffected is the number of rows changed by Exec, it's known only in AfterQuery
+	// and is -1 for other operations, failed queries and drivers that don't report it
+	RowsAffected int64
+}
+
+// Hook intercepts queries executed through WithHooks
+type Hook interface {
+	// BeforeQuery is called before the query is sent, the returned context is used for the query and AfterQuery
+	BeforeQuery(ctx context.Context, q QueryInfo) context.Context
+	// AfterQuery is called once the query is done, for QueryRow err is the error known before Scan
+	AfterQuery(ctx context.Context, q QueryInfo, duration time.Duration, err error)
+}
+
+// WithHooks wraps db so hooks are called around every query, hooks are called in order
+func WithHooks(db DB, hooks ...Hook) DB {
+	return &hookedDB{db: db, hooks: hooks}
+}
+
+type hookedDB struct {
+	db    DB
+	hooks []Hook
+}
+
+func (h *hookedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := h.start(ctx, "QueryRow", query, args)
+	row := h.db.QueryRowContext(ctx, query, args...)
+	done(-1, row.Err())
+	return row
+}
+
+func (h *hookedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := h.start(ctx, "Query", query, args)
+	rows, err := h.db.QueryContext(ctx, query, args...)
+	done(-1, err)
+	return rows, err
+}
+
+func (h *hookedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := h.start(ctx, "Exec", query, args)
+	res, err := h.db.ExecContext(ctx, query, args...)
+	rowsAffected := int64(-1)
+	if err == nil {
+		if n, rowsErr := res.RowsAffected(); rowsErr == nil {
+			rowsAffected = n
+		}
+	}
+	done(rowsAffected, err)
+	return res, err
+}
+
+func (h *hookedDB) start(ctx context.Context, operation, query string, args []interface{}) (context.Context, func(int64, error)) {
+	q := QueryInfo{Operation: operation, Query: query, Args: args, RowsAffected: -1}
+	for _, hook := range h.hooks {
+		ctx = hook.BeforeQuery(ctx, q)
+	}
+	start := time.Now()
+
+	return ctx, func(rowsAffected int64, err error) {
+		duration := time.Since(start)
+		q.RowsAffected = rowsAffected
+		for _, hook := range h.hooks {
+			hook.AfterQuery(ctx, q, duration, err)
+		}
+	}
+}
+
+// LogHook logs failed and slow queries
+type LogHook struct {
+	Logger *slog.Logger
+	// SlowThreshold is the duration queries are logged after, zero logs only failed queries
+	SlowThreshold time.Duration
+	// RedactArgs replaces sensitive args before they are logged, args are not logged if it's nil
+	RedactArgs func(q QueryInfo) []interface{}
+}
+
+// BeforeQuery implements Hook
+func (l *LogHook) BeforeQuery(ctx context.Context, q QueryInfo) context.Context {
+	return ctx
+}
+
+// AfterQuery implements Hook
+func (l *LogHook) AfterQuery(ctx context.Context, q QueryInfo, duration time.Duration, err error) {
+	slow := l.SlowThreshold > 0 && duration >= l.SlowThreshold
+	if err == nil && !slow {
+		return
+	}
+
+	attrs := []any{
+		slog.String("operation", q.Operation),
+		slog.String("query", q.Query),
+		slog.Duration("duration", duration),
+	}
+	if l.RedactArgs != nil {
+		attrs = append(attrs, slog.Any("args", l.RedactArgs(q)))
+	}
+	if err != nil {
+		l.Logger.ErrorContext(ctx, "query failed", append(attrs, slog.Any("error", err))...)
+		return
+	}
+	l.Logger.WarnContext(ctx, "slow query", attrs...)
+}
@@ -0,0 +1,95 @@
+package dbx
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var identPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$]*$`)
+
+// reservedWords are quoted when used as identifiers, the list covers words reserved
+// by all supported dialects that are likely to be table or column names
+var reservedWords = map[string]struct{}{
+	"all": {}, "and": {}, "as": {}, "asc": {}, "between": {}, "by": {}, "case": {}, "check": {},
+	"column": {}, "constraint": {}, "create": {}, "cross": {}, "current_date": {}, "current_time": {},
+	"current_timestamp": {}, "current_user": {}, "default": {}, "delete": {}, "desc": {}, "distinct": {},
+	"drop": {}, "else": {}, "end": {}, "except": {}, "exists": {}, "false": {}, "fetch": {}, "for": {},
+	"foreign": {}, "from": {}, "full": {}, "grant": {}, "group": {}, "having": {}, "in": {}, "index": {},
+	"inner": {}, "insert": {}, "intersect": {}, "into": {}, "is": {}, "join": {}, "key": {}, "left": {},
+	"like": {}, "limit": {}, "natural": {}, "not": {}, "null": {}, "offset": {}, "on": {}, "or": {},
+	"order": {}, "outer": {}, "primary": {}, "references": {}, "right": {}, "rows": {}, "select": {},
+	"set": {}, "table": {}, "then": {}, "to": {}, "true": {}, "union": {}, "unique": {}, "update": {},
+	"user": {}, "using": {}, "values": {}, "when": {}, "where": {}, "with": {},
+}
+
+// QuoteIdent validates a possibly qualified identifier, e.g. users.name,
+// and quotes the parts that are reserved words, e.g. "order"
+func (d Dialect) QuoteIdent(ident string) (string, error) {
+	parts := strings.Split(ident, ".")
+	for i, part := range parts {
+		if part == "*" && i == len(parts)-1 && i > 0 {
+			continue
+		}
+		if !identPattern.MatchString(part) {
+			return "", fmt.Errorf("invalid identifier %q", ident)
+		}
+		if _, reserved := reservedWords[strings.ToLower(part)]; reserved {
+			parts[i] = d.quote(part)
+		}
+	}
+	return strings.Join(parts, "."), nil
+}
+
+// quoteTable validates a table reference with an optional alias, e.g. "users u" or "users AS u"
+func (d Dialect) quoteTable(table string) (string, error) {
+	parts := strings.Fields(table)
+	if len(parts) == 3 && strings.EqualFold(parts[1], "AS") {
+		parts = []string{parts[0], parts[2]}
+	}
+	if len(parts) == 0 || len(parts) > 2 {
+		return "", fmt.Errorf("invalid table %q", table)
+	}
+	for i, part := range parts {
+		quoted, err := d.QuoteIdent(part)
+		if err != nil {
+			return "", err
+		}
+		parts[i] = quoted
+	}
+	return strings.Join(parts, " "), nil
+}
+
+func (d Dialect) quote(ident string) string {
+	if d == MySQL {
+		return "`" + ident + "`"
+	}
+	return `"` + ident + `"`
+}
+
+// mustIdent is QuoteIdent panicking on invalid identifiers, builders validate identifiers at Compile time
+func (d Dialect) mustIdent(ident string) string {
+	quoted, err := d.QuoteIdent(ident)
+	if err != nil {
+		panic("dbx: " + err.Error())
+	}
+	return quoted
+}
+
+// mustTable is quoteTable panicking on invalid tables
+func (d Dialect) mustTable(table string) string {
+	quoted, err := d.quoteTable(table)
+	if err != nil {
+		panic("dbx: " + err.Error())
+	}
+	return quoted
+}
+
+// columnList quotes db names of fields joined with commas
+func (d Dialect) columnList(fields []fieldInfo) string {
+	cols := make([]string, len(fields))
+	for i, field := range fields {
+		cols[i] = d.mustIdent(field.DbName)
+	}
+	return strings.Join(cols, ", ")
+}
@@ -0,0 +1,105 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+type inCond struct {
+	col string
+	not bool
+}
+
+func (c inCond) appendSQL(b *queryBuilder) {
+	op := " IN ("
+	if c.not {
+		op = " NOT IN ("
+	}
+	b.writeString(b.ident(c.col) + op + b.spreadParam(c.col) + ")")
+}
+
+// In is col IN (?, ?, ...), the placeholder is bound to a slice and
+// expanded into one placeholder per element at execution time
+func In(col string) Condition { return inCond{col: col} }
+
+// NotIn is col NOT IN (?, ?, ...), see In
+func NotIn(col string) Condition { return inCond{col: col, not: true} }
+
+// placeholders expands slice placeholders of a compiled query.
+// The rest of the query stays pre-compiled, only queries with In conditions are rebuilt per call.
+type placeholders struct {
+	// template is the query with $n placeholders before dialect rebinding
+	template string
+	spread   map[int]struct{}
+	dialect  Dialect
+}
+
+func newPlaceholders(b *queryBuilder, d Dialect) placeholders {
+	return placeholders{
+		template: b.String(),
+		spread:   b.spread,
+		dialect:  d,
+	}
+}
+
+// bind returns query and args to execute, slice args of spread placeholders are flattened
+func (p placeholders) bind(query string, args []interface{}) (string, []interface{}) {
+	if len(p.spread) == 0 {
+		return query, args
+	}
+
+	var sb strings.Builder
+	var bound []interface{}
+	next := func(arg interface{}) string {
+		bound = append(bound, arg)
+		return fmt.Sprintf("$%d", len(bound))
+	}
+
+	tmpl := p.template
+	var quote byte
+	for i := 0; i < len(tmpl); i++ {
+		c := tmpl[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(tmpl) && isDigit(tmpl[i+1]):
+			j := i + 1
+			for j < len(tmpl) && isDigit(tmpl[j]) {
+				j++
+			}
+			idx, _ := strconv.Atoi(tmpl[i+1 : j])
+			idx--
+			i = j - 1
+			if _, ok := p.spread[idx]; ok {
+				sb.WriteString(expandSlice(args[idx], next))
+			} else {
+				sb.WriteString(next(args[idx]))
+			}
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return p.dialect.Rebind(sb.String()), bound
+}
+
+// expandSlice binds every element of a slice arg, an empty slice becomes NULL so IN matches nothing
+func expandSlice(arg interface{}, next func(interface{}) string) string {
+	v := reflect.ValueOf(arg)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array || v.Type().Elem().Kind() == reflect.Uint8 {
+		return next(arg)
+	}
+	if v.Len() == 0 {
+		return "NULL"
+	}
+	list := make([]string, v.Len())
+	for i := range list {
+		list[i] = next(v.Index(i).Interface())
+	}
+	return strings.Join(list, ", ")
+}
@@ -0,0 +1,74 @@
+package dbx
+
+import (
+	"context"
+	"strings"
+	"time"
+)
+
+// QueryLabels identify a query in metrics
+type QueryLabels struct {
+	// Name is set with WithQueryName, it's empty otherwise
+	Name string
+	// Table is the first table of the query, e.g. users for SELECT ... FROM users JOIN orders
+	Table string
+	// Operation is Exec, Query or QueryRow
+	Operation string
+}
+
+// Metrics records executed queries, see MetricsHook
+type Metrics interface {
+	ObserveQuery(ctx context.Context, labels QueryLabels, duration time.Duration, err error)
+}
+
+type queryNameKey struct{}
+
+// WithQueryName names queries executed with ctx in metrics, e.g. "users.get_by_id"
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// QueryName returns the query name set with WithQueryName
+func QueryName(ctx context.Context) string {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	return name
+}
+
+// MetricsHook returns a hook passing every query to m, use it with WithHooks
+func MetricsHook(m Metrics) Hook {
+	return metricsHook{m: m}
+}
+
+type metricsHook struct {
+	m Metrics
+}
+
+// BeforeQuery implements Hook
+func (h metricsHook) BeforeQuery(ctx context.Context, q QueryInfo) context.Context {
+	return ctx
+}
+
+// AfterQuery implements Hook
+func (h metricsHook) AfterQuery(ctx context.Context, q QueryInfo, duration time.Duration, err error) {
+	labels := QueryLabels{
+		Name:      QueryName(ctx),
+		Table:     queryTable(q.Query),
+		Operation: q.Operation,
+	}
+	h.m.ObserveQuery(ctx, labels, duration, err)
+}
+
+// queryTable returns the table following the first FROM, INTO or UPDATE keyword of query, unquoted
+func queryTable(query string) string {
+	words := strings.Fields(query)
+	for i, word := range words[:max(len(words)-1, 0)] {
+		switch strings.ToUpper(word) {
+		case "FROM", "INTO", "UPDATE":
+			table := strings.Trim(words[i+1], "\"`();")
+			if table != "" && !strings.HasPrefix(table, "(") {
+				return table
+			}
+		}
+	}
+	return ""
+}
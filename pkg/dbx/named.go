@@ -0,0 +1,136 @@
+package dbx
+
+import (
+	"context"
+	"reflect"
+	"strings"
+)
+
+// NamedBuilder represents a handwritten query with :name placeholders
+type NamedBuilder[T, R any] struct {
+	query   string
+	dialect Dialect
+	naming  NamingStrategy
+}
+
+// CompiledNamedQuery represents a compiled named query
+type CompiledNamedQuery[T, R any] struct {
+	query           string
+	argFields       []fieldInfo
+	returningFields []fieldInfo
+}
+
+// ExecutableNamedQuery represents a named query ready for execution
+type ExecutableNamedQuery[T, R any] struct {
+	compiled *CompiledNamedQuery[T, R]
+	args     []interface{}
+}
+
+// NamedQuery creates a query from handwritten SQL, similar to sqlx.NamedExec.
+// Every :name placeholder is bound from the field of T tagged db:"name",
+// rows are scanned into the db tagged fields of R, use struct{} if the query returns nothing.
+func NamedQuery[T, R any](query string) *NamedBuilder[T, R] {
+	return &NamedBuilder[T, R]{query: query}
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (nb *NamedBuilder[T, R]) Dialect(d Dialect) *NamedBuilder[T, R] {
+	nb.dialect = d
+	return nb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (nb *NamedBuilder[T, R]) Naming(naming NamingStrategy) *NamedBuilder[T, R] {
+	nb.naming = naming
+	return nb
+}
+
+// Compile compiles the named query into a reusable form.
+// It panics if a placeholder is not a field of T.
+func (nb *NamedBuilder[T, R]) Compile() *CompiledNamedQuery[T, R] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+
+	b := &queryBuilder{dialect: nb.dialect}
+	b.writeString(bindNamed(nb.query, b.param))
+
+	return &CompiledNamedQuery[T, R]{
+		query:           nb.dialect.Rebind(b.String()),
+		argFields:       b.resolve(inputType, extractNamedFields(inputType, nb.naming)),
+		returningFields: extractNamedFields(reflect.TypeOf((*R)(nil)).Elem(), nb.naming),
+	}
+}
+
+// New creates a new executable query with the given input
+func (cq *CompiledNamedQuery[T, R]) New(input T) *ExecutableNamedQuery[T, R] {
+	return &ExecutableNamedQuery[T, R]{
+		compiled: cq,
+		args:     extractFieldArgs(&input, cq.argFields),
+	}
+}
+
+func (cq *CompiledNamedQuery[T, R]) PreviewQuery(input T) (string, []any) {
+	return cq.query, extractFieldArgs(&input, cq.argFields)
+}
+
+// ExecContext executes the query and returns the number of affected rows
+func (eq *ExecutableNamedQuery[T, R]) ExecContext(ctx context.Context, db DB) (int64, error) {
+	res, err := db.ExecContext(ctx, eq.compiled.query, eq.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// QueryContext executes the query and scans all rows
+func (eq *ExecutableNamedQuery[T, R]) QueryContext(ctx context.Context, db DB) ([]R, error) {
+	return queryRows[R](ctx, db, eq.compiled.query, eq.args, eq.compiled.returningFields)
+}
+
+type rawCond struct {
+	sql string
+}
+
+func (c rawCond) appendSQL(b *queryBuilder) {
+	b.writeString(bindNamed(c.sql, b.param))
+}
+
+// Raw is a handwritten condition, every :name placeholder references the column name,
+// e.g. Raw("lower(email) = lower(:email)")
+func Raw(sql string) Condition { return rawCond{sql: sql} }
+
+// bindNamed replaces :name placeholders of query with the result of param.
+// Quoted literals and identifiers and postgres :: casts are left untouched.
+func bindNamed(query string, param func(name string) string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == ':' && i+1 < len(query) && query[i+1] == ':':
+			sb.WriteString("::")
+			i++
+			continue
+		case c == ':' && i+1 < len(query) && isNameStart(query[i+1]):
+			j := i + 1
+			for j < len(query) && (isNameStart(query[j]) || isDigit(query[j])) {
+				j++
+			}
+			sb.WriteString(param(query[i+1 : j]))
+			i = j - 1
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+func isNameStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
@@ -0,0 +1,30 @@
+package dbx
+
+import (
+	"strings"
+	"unicode"
+)
+
+// NamingStrategy derives a column name from the name of a struct field without db tag.
+// Builders ignore untagged fields unless a strategy is set with Naming.
+type NamingStrategy func(fieldName string) string
+
+// SnakeCase converts field names to snake_case, e.g. UserID to user_id and HTTPStatus to http_status
+func SnakeCase(fieldName string) string {
+	runes := []rune(fieldName)
+	var sb strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			prevLower := i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]))
+			// last upper letter of an acronym starts a new word, e.g. the S of HTTPStatus
+			acronymEnd := i > 0 && unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1])
+			if prevLower || acronymEnd {
+				sb.WriteByte('_')
+			}
+			sb.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		sb.WriteRune(r)
+	}
+	return sb.String()
+}
@@ -0,0 +1,79 @@
+package dbx
+
+import (
+	"database/sql"
+	"database/sql/driver"
+	"reflect"
+	"time"
+)
+
+var (
+	sqlScannerType   = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	driverValuerType = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+	timeType         = reflect.TypeOf(time.Time{})
+)
+
+// isColumnType reports whether a struct type is a single column value
+// rather than a group of columns, e.g. time.Time or uuid.UUID
+func isColumnType(t reflect.Type) bool {
+	return t == timeType ||
+		t.Implements(driverValuerType) ||
+		reflect.PointerTo(t).Implements(driverValuerType) ||
+		reflect.PointerTo(t).Implements(sqlScannerType)
+}
+
+// isNullable reports whether values of t can hold NULL: pointers and sql.Null* like scanners
+func isNullable(t reflect.Type) bool {
+	return t.Kind() == reflect.Pointer || reflect.PointerTo(t).Implements(sqlScannerType)
+}
+
+// fieldArg returns the value of field bound as a query arg, nil pointers and
+// fields of nil optional structs are bound as NULL
+func fieldArg(v reflect.Value, field fieldInfo) interface{} {
+	fv, err := v.FieldByIndexErr(field.Index)
+	if err != nil {
+		return nil
+	}
+	if fv.Kind() == reflect.Pointer && fv.IsNil() {
+		return nil
+	}
+	if field.Array {
+		return Array(fv.Interface())
+	}
+	if !fv.Type().Implements(driverValuerType) && reflect.PointerTo(fv.Type()).Implements(driverValuerType) {
+		// Value is declared on the pointer receiver, database/sql won't find it on a copy
+		ptr := reflect.New(fv.Type())
+		ptr.Elem().Set(fv)
+		return ptr.Interface()
+	}
+	return fv.Interface()
+}
+
+// assignOptional assigns fields of optional nested structs scanned into pointers.
+// Optional struct is allocated only if at least one of its columns is not NULL.
+func assignOptional(v reflect.Value, fields []fieldInfo, scanned []reflect.Value) {
+	for i, field := range fields {
+		if field.Optional == nil {
+			continue
+		}
+		ptr := scanned[i].Elem()
+		if ptr.IsNil() {
+			continue
+		}
+		fieldByIndexAlloc(v, field.Index).Set(ptr.Elem())
+	}
+}
+
+// fieldByIndexAlloc is reflect.Value.FieldByIndex allocating nil struct pointers on the way
+func fieldByIndexAlloc(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
@@ -0,0 +1,39 @@
+package dbx
+
+import (
+	"fmt"
+	"strings"
+)
+
+// quoteOrderBy quotes column names of validated ORDER BY terms
+func quoteOrderBy(d Dialect, terms []string) []string {
+	quoted := make([]string, len(terms))
+	for i, term := range terms {
+		col, rest, _ := strings.Cut(strings.TrimSpace(term), " ")
+		quoted[i] = strings.TrimSpace(d.mustIdent(col) + " " + rest)
+	}
+	return quoted
+}
+
+// checkOrderBy validates ORDER BY terms against the selectable columns,
+// a term is a column optionally followed by ASC/DESC and NULLS FIRST/LAST
+func checkOrderBy(terms []string, columns map[string]struct{}) error {
+	for _, term := range terms {
+		parts := strings.Fields(term)
+		if len(parts) == 0 {
+			return fmt.Errorf("empty ORDER BY term")
+		}
+		if _, ok := columns[parts[0]]; !ok {
+			return fmt.Errorf("ORDER BY column %q is not a selected column", parts[0])
+		}
+
+		rest := strings.ToUpper(strings.Join(parts[1:], " "))
+		rest = strings.TrimSpace(strings.TrimPrefix(strings.TrimPrefix(rest, "ASC"), "DESC"))
+		switch rest {
+		case "", "NULLS FIRST", "NULLS LAST":
+		default:
+			return fmt.Errorf("invalid ORDER BY term %q", term)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,217 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/pechorka/cruder/pkg/pagination"
+)
+
+// PaginateBuilder represents a keyset pagination query builder
+type PaginateBuilder[R any] struct {
+	sel   *SelectBuilder[R]
+	keys  []string
+	codec *pagination.Codec
+}
+
+// CompiledPaginateQuery represents a compiled keyset pagination query
+type CompiledPaginateQuery[R any] struct {
+	first     *CompiledSelectQuery[R]
+	next      *CompiledSelectQuery[R]
+	prev      *CompiledSelectQuery[R]
+	keyFields []fieldInfo
+	codec     *pagination.Codec
+}
+
+// ExecutablePaginateQuery represents a pagination query ready for execution
+type ExecutablePaginateQuery[R any] struct {
+	compiled *CompiledPaginateQuery[R]
+	query    *ExecutableSelectQuery[R]
+	size     int
+	// dir is the direction of the cursor the page was requested with, paged is false for the first page
+	dir   pagination.Direction
+	paged bool
+	err   error
+}
+
+// Paginate creates a keyset pagination query builder. Rows are ordered by keys ascending,
+// keys must be selected columns and uniquely identify a row, e.g. Paginate[User]("users", codec, "created_at", "id").
+// Cursors returned to the client are opaque and signed by codec, pages have the cursors of both the next
+// and the previous page, so they can be returned as is in next_cursor and prev_cursor fields.
+func Paginate[R any](table string, codec *pagination.Codec, keys ...string) *PaginateBuilder[R] {
+	return &PaginateBuilder[R]{
+		sel:   Select[R](table),
+		keys:  keys,
+		codec: codec,
+	}
+}
+
+// Where adds conditions joined with AND, placeholders are bound from args passed to New in order
+func (pb *PaginateBuilder[R]) Where(conds ...Condition) *PaginateBuilder[R] {
+	pb.sel.Where(conds...)
+	return pb
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (pb *PaginateBuilder[R]) Dialect(d Dialect) *PaginateBuilder[R] {
+	pb.sel.Dialect(d)
+	return pb
+}
+
+// Unscoped includes soft-deleted rows, see SelectBuilder.SoftDelete
+func (pb *PaginateBuilder[R]) Unscoped() *PaginateBuilder[R] {
+	pb.sel.Unscoped()
+	return pb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (pb *PaginateBuilder[R]) Naming(naming NamingStrategy) *PaginateBuilder[R] {
+	pb.sel.Naming(naming)
+	return pb
+}
+
+// Compile compiles the first page and next pages queries into a reusable form.
+// It panics if a key is not a selected column.
+func (pb *PaginateBuilder[R]) Compile() *CompiledPaginateQuery[R] {
+	if len(pb.keys) == 0 {
+		panic("dbx: pagination requires at least one key column")
+	}
+	keyFields := make([]fieldInfo, 0, len(pb.keys))
+	for _, key := range pb.keys {
+		field, ok := fieldByColumn(pb.sel.fields, key)
+		if !ok {
+			panic(fmt.Sprintf("dbx: pagination key %q is not a selected column", key))
+		}
+		keyFields = append(keyFields, field)
+	}
+
+	first := *pb.sel
+	first.orderBy = pb.keys
+	first.limitParam = true
+
+	next := first
+	next.where = append(slices.Clip(pb.sel.where), keysetCond{cols: pb.keys, op: ">"})
+
+	// previous pages are read in reverse order starting before the first row
+	prev := first
+	prev.where = append(slices.Clip(pb.sel.where), keysetCond{cols: pb.keys, op: "<"})
+	prev.orderBy = make([]string, len(pb.keys))
+	for i, key := range pb.keys {
+		prev.orderBy[i] = key + " DESC"
+	}
+
+	return &CompiledPaginateQuery[R]{
+		first:     first.Compile(),
+		next:      next.Compile(),
+		prev:      prev.Compile(),
+		keyFields: keyFields,
+		codec:     pb.codec,
+	}
+}
+
+// New creates a new executable query for the page requested by params,
+// args are bound to WHERE placeholders in order
+func (cq *CompiledPaginateQuery[R]) New(params pagination.Params, args ...interface{}) *ExecutablePaginateQuery[R] {
+	eq := &ExecutablePaginateQuery[R]{
+		compiled: cq,
+		size:     params.PageSize(),
+	}
+	// one extra row tells whether there is a next page
+	limit := eq.size + 1
+
+	if params.Cursor == "" {
+		eq.query = cq.first.New(append(slices.Clip(args), limit)...)
+		return eq
+	}
+
+	dest := make([]any, len(cq.keyFields))
+	for i, field := range cq.keyFields {
+		dest[i] = reflect.New(field.Type).Interface()
+	}
+	dir, err := cq.codec.DecodeCursor(params.Cursor, dest...)
+	if err != nil {
+		eq.err = err
+		return eq
+	}
+	eq.dir, eq.paged = dir, true
+
+	queryArgs := slices.Clip(args)
+	for _, d := range dest {
+		queryArgs = append(queryArgs, reflect.ValueOf(d).Elem().Interface())
+	}
+	if dir == pagination.Backward {
+		eq.query = cq.prev.New(append(queryArgs, limit)...)
+	} else {
+		eq.query = cq.next.New(append(queryArgs, limit)...)
+	}
+	return eq
+}
+
+// ExecContext executes the query and returns the page with the cursor of the next one
+func (eq *ExecutablePaginateQuery[R]) ExecContext(ctx context.Context, db DB) (pagination.Page[R], error) {
+	if eq.err != nil {
+		return pagination.Page[R]{}, eq.err
+	}
+
+	rows, err := eq.query.ExecContext(ctx, db)
+	if err != nil {
+		return pagination.Page[R]{}, err
+	}
+
+	page := pagination.Page[R]{Items: rows}
+	if page.Items == nil {
+		page.Items = []R{}
+	}
+	more := len(rows) > eq.size
+	if more {
+		page.Items = rows[:eq.size]
+	}
+	if eq.dir == pagination.Backward {
+		slices.Reverse(page.Items)
+	}
+	if len(page.Items) == 0 {
+		return page, nil
+	}
+
+	// rows past the page exist if there are more in the paging direction
+	// or if the page was reached from the other side
+	if (eq.dir == pagination.Forward && more) || eq.dir == pagination.Backward {
+		page.NextCursor, err = eq.cursor(pagination.Forward, page.Items[len(page.Items)-1])
+		if err != nil {
+			return page, err
+		}
+	}
+	if (eq.dir == pagination.Backward && more) || (eq.dir == pagination.Forward && eq.paged) {
+		page.PrevCursor, err = eq.cursor(pagination.Backward, page.Items[0])
+	}
+	return page, err
+}
+
+// cursor encodes key values of row into a cursor paging in dir
+func (eq *ExecutablePaginateQuery[R]) cursor(dir pagination.Direction, row R) (string, error) {
+	v := reflect.ValueOf(row)
+	values := make([]any, len(eq.compiled.keyFields))
+	for i, field := range eq.compiled.keyFields {
+		values[i] = v.FieldByIndex(field.Index).Interface()
+	}
+	return eq.compiled.codec.EncodeCursor(dir, values...)
+}
+
+type keysetCond struct {
+	cols []string
+	// op is > for next pages and < for previous ones
+	op string
+}
+
+func (c keysetCond) appendSQL(b *queryBuilder) {
+	cols := make([]string, len(c.cols))
+	params := make([]string, len(c.cols))
+	for i, col := range c.cols {
+		cols[i] = b.ident(col)
+		params[i] = b.param(col)
+	}
+	b.writeString("(" + strings.Join(cols, ", ") + ") " + c.op + " (" + strings.Join(params, ", ") + ")")
+}
@@ -3,9 +3,11 @@ package dbx
 import (
 	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"reflect"
 	"strings"
+	"unsafe"
 )
 
 // DB interface for executing queries
@@ -20,6 +22,11 @@ type InsertBuilder[T any] struct {
 	table       string
 	inputType   reflect.Type
 	inputFields []fieldInfo
+	dialect     Dialect
+	naming      NamingStrategy
+	// doNothing skips rows conflicting on conflictCols, any unique constraint if they're empty
+	doNothing    bool
+	conflictCols []string
 }
 
 // InsertReturningBuilder represents an insert query builder with returning clause
@@ -27,16 +34,28 @@ type InsertReturningBuilder[T, R any] struct {
 	insert          *InsertBuilder[T]
 	returningType   reflect.Type
 	returningFields []fieldInfo
+	exprs           map[string]Expression
 }
 
 // CompiledInsertQuery represents a compiled insert query
 type CompiledInsertQuery[T, R any] struct {
+	table           string
+	dialect         Dialect
 	query           string
 	inputFields     []fieldInfo
 	returningFields []fieldInfo
+	clauses         insertClauses
 	hasReturning    bool
 }
 
+// insertClauses are the parts of INSERT other than columns and values
+type insertClauses struct {
+	// returning is the RETURNING column list, empty if there is none
+	returning    string
+	doNothing    bool
+	conflictCols []string
+}
+
 // ExecutableQuery represents a query ready for execution
 type ExecutableQuery[T, R any] struct {
 	compiled *CompiledInsertQuery[T, R]
@@ -50,6 +69,21 @@ type fieldInfo struct {
 	Type     reflect.Type
 	IsAuto   bool
 	Position int
+	// Index is the field index path, it differs from Position for nested fields
+	Index []int
+	// Nullable fields bind nil as NULL and scan NULL without errors
+	Nullable bool
+	// SoftDelete marks the deleted_at like column of soft-deleted rows
+	SoftDelete bool
+	// PrimaryKey marks the columns of the primary key, there may be several of them
+	PrimaryKey bool
+	// Array binds and scans the slice field as a Postgres array with the Array adapter
+	Array bool
+	// Optional is the index path of the nested struct pointer the field belongs to,
+	// the pointer stays nil when all of its columns are NULL
+	Optional []int
+
+	access
 }
 
 // Insert creates a new insert query builder
@@ -64,10 +98,34 @@ func Insert[T any](table string) *InsertBuilder[T] {
 	}
 }
 
+// Dialect sets placeholder syntax of the generated SQL
+func (ib *InsertBuilder[T]) Dialect(d Dialect) *InsertBuilder[T] {
+	ib.dialect = d
+	return ib
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase).
+// It applies to the returning type as well, so call it before Returning.
+func (ib *InsertBuilder[T]) Naming(naming NamingStrategy) *InsertBuilder[T] {
+	ib.naming = naming
+	ib.inputFields = extractNamedFields(ib.inputType, naming)
+	return ib
+}
+
+// OnConflictDoNothing skips rows violating a unique constraint instead of failing,
+// with ON CONFLICT DO NOTHING on Postgres and SQLite and INSERT IGNORE on MySQL.
+// Columns narrow it to the conflicts on them, e.g. OnConflictDoNothing("event_id"), MySQL ignores them.
+// Use TryExecContext to know whether the row was inserted. Oracle doesn't support it, Compile panics.
+func (ib *InsertBuilder[T]) OnConflictDoNothing(cols ...string) *InsertBuilder[T] {
+	ib.doNothing = true
+	ib.conflictCols = cols
+	return ib
+}
+
 // Returning adds a returning clause to the insert query
 func Returning[T, R any](ib *InsertBuilder[T]) *InsertReturningBuilder[T, R] {
 	returningType := reflect.TypeOf((*R)(nil)).Elem()
-	returningFields := extractFields(returningType)
+	returningFields := extractNamedFields(returningType, ib.naming)
 
 	return &InsertReturningBuilder[T, R]{
 		insert:          ib,
@@ -76,32 +134,57 @@ func Returning[T, R any](ib *InsertBuilder[T]) *InsertReturningBuilder[T, R] {
 	}
 }
 
+// Expr returns expr as the column col of R, e.g. Expr("created_at", dbx.Expr("CURRENT_TIMESTAMP")).
+// The expression can't have args.
+func (irb *InsertReturningBuilder[T, R]) Expr(col string, expr Expression) *InsertReturningBuilder[T, R] {
+	if irb.exprs == nil {
+		irb.exprs = make(map[string]Expression)
+	}
+	irb.exprs[col] = expr
+	return irb
+}
+
 // Compile compiles the insert query into a reusable form
 func (ib *InsertBuilder[T]) Compile() *CompiledInsertQuery[T, struct{}] {
-	query := buildInsertQuery(ib.table, ib.inputFields, nil)
+	clauses := ib.clauses("")
+	query := ib.dialect.Rebind(buildInsertQuery(ib.dialect, ib.table, ib.inputFields, clauses))
 
 	return &CompiledInsertQuery[T, struct{}]{
+		table:        ib.table,
+		dialect:      ib.dialect,
 		query:        query,
 		inputFields:  ib.inputFields,
+		clauses:      clauses,
 		hasReturning: false,
 	}
 }
 
 // Compile compiles the insert with returning query into a reusable form
 func (irb *InsertReturningBuilder[T, R]) Compile() *CompiledInsertQuery[T, R] {
-	query := buildInsertQuery(irb.insert.table, irb.insert.inputFields, irb.returningFields)
+	clauses := irb.insert.clauses(returningList(irb.insert.dialect, irb.returningFields, irb.exprs))
+	query := irb.insert.dialect.Rebind(buildInsertQuery(irb.insert.dialect, irb.insert.table, irb.insert.inputFields, clauses))
 
 	return &CompiledInsertQuery[T, R]{
+		table:           irb.insert.table,
+		dialect:         irb.insert.dialect,
 		query:           query,
 		inputFields:     irb.insert.inputFields,
 		returningFields: irb.returningFields,
+		clauses:         clauses,
 		hasReturning:    true,
 	}
 }
 
+func (ib *InsertBuilder[T]) clauses(returning string) insertClauses {
+	if ib.doNothing && ib.dialect == Oracle {
+		panic("dbx: Oracle doesn't support OnConflictDoNothing")
+	}
+	return insertClauses{returning: returning, doNothing: ib.doNothing, conflictCols: ib.conflictCols}
+}
+
 // New creates a new executable query with the given input
 func (cq *CompiledInsertQuery[T, R]) New(input T) *ExecutableQuery[T, R] {
-	args := extractArgs(input, cq.inputFields)
+	args := extractArgs(&input, cq.inputFields)
 
 	return &ExecutableQuery[T, R]{
 		compiled: cq,
@@ -111,7 +194,7 @@ func (cq *CompiledInsertQuery[T, R]) New(input T) *ExecutableQuery[T, R] {
 }
 
 func (cq *CompiledInsertQuery[T, R]) PreviewQuery(input T) (string, []any) {
-	args := extractArgs(input, cq.inputFields)
+	args := extractArgs(&input, cq.inputFields)
 	return cq.query, args
 }
 
@@ -130,92 +213,264 @@ func (eq *ExecutableQuery[T, R]) ExecContext(ctx context.Context, db DB) (R, err
 	return result, err
 }
 
+// TryExecContext executes the query and reports whether the row was inserted,
+// it's false if the row was skipped by OnConflictDoNothing
+func (eq *ExecutableQuery[T, R]) TryExecContext(ctx context.Context, db DB) (R, bool, error) {
+	var result R
+	if eq.compiled.hasReturning {
+		row := db.QueryRowContext(ctx, eq.compiled.query, eq.args...)
+		err := scanRow(row, &result, eq.compiled.returningFields)
+		if errors.Is(err, sql.ErrNoRows) {
+			return result, false, nil
+		}
+		return result, err == nil, err
+	}
+
+	res, err := db.ExecContext(ctx, eq.compiled.query, eq.args...)
+	if err != nil {
+		return result, false, err
+	}
+	n, err := res.RowsAffected()
+	return result, n > 0, err
+}
+
+// ExecManyContext executes the query and scans every returned row, it requires a returning clause
+func (eq *ExecutableQuery[T, R]) ExecManyContext(ctx context.Context, db DB) ([]R, error) {
+	if !eq.compiled.hasReturning {
+		return nil, fmt.Errorf("query has no returning clause: %s", eq.compiled.query)
+	}
+	return queryRows[R](ctx, db, eq.compiled.query, eq.args, eq.compiled.returningFields)
+}
+
 // Helper functions
 
 func extractFields(t reflect.Type) []fieldInfo {
+	return extractNamedFields(t, nil)
+}
+
+// extractNamedFields is extractFields also mapping untagged fields with naming if it's set
+func extractNamedFields(t reflect.Type, naming NamingStrategy) []fieldInfo {
+	fields := collectFields(t, "", nil, nil, naming)
+	resolveAccess(t, fields)
+	return fields
+}
+
+// collectFields collects db tagged fields of t. Struct fields tagged with the nested option,
+// e.g. `db:"users,nested"`, are expanded into their own columns prefixed with the tag name.
+// Nested struct pointers are optional, e.g. the right side of LEFT JOIN.
+func collectFields(t reflect.Type, prefix string, index, optional []int, naming NamingStrategy) []fieldInfo {
 	var fields []fieldInfo
 
 	for i := 0; i < t.NumField(); i++ {
 		field := t.Field(i)
 		tag := field.Tag.Get("db")
 
-		if tag == "" || tag == "-" {
+		if tag == "-" {
 			continue
 		}
+		if tag == "" {
+			// untagged embedded structs, e.g. shared Timestamps, are flattened into the parent
+			if embedded, ok := embeddedStruct(field); ok {
+				fieldIndex := append(append([]int(nil), index...), i)
+				embeddedOptional := optional
+				if embeddedOptional == nil && field.Type.Kind() == reflect.Pointer {
+					embeddedOptional = fieldIndex
+				}
+				fields = append(fields, collectFields(embedded, prefix, fieldIndex, embeddedOptional, naming)...)
+				continue
+			}
+			if naming == nil || !field.IsExported() || field.Anonymous {
+				continue
+			}
+			tag = naming(field.Name)
+		}
 
 		parts := strings.Split(tag, ",")
 		dbName := parts[0]
+		if dbName == "" && naming != nil {
+			// options without a name, e.g. `db:",auto"`
+			dbName = naming(field.Name)
+		}
 		isAuto := false
+		isNested := false
+		isSoftDelete := false
+		isPrimaryKey := false
+		isArray := false
 
 		for _, part := range parts[1:] {
-			if part == "auto" {
+			switch part {
+			case "auto":
 				isAuto = true
+			case "nested":
+				isNested = true
+			case "softdelete":
+				isSoftDelete = true
+			case "pk":
+				isPrimaryKey = true
+			case "array":
+				isArray = true
+			}
+		}
+
+		fieldIndex := append(append([]int(nil), index...), i)
+		if isNested && field.Type.Kind() == reflect.Struct && !isColumnType(field.Type) {
+			fields = append(fields, collectFields(field.Type, prefix+dbName+".", fieldIndex, optional, naming)...)
+			continue
+		}
+		if isNested && field.Type.Kind() == reflect.Pointer && field.Type.Elem().Kind() == reflect.Struct && !isColumnType(field.Type.Elem()) {
+			nestedOptional := optional
+			if nestedOptional == nil {
+				nestedOptional = fieldIndex
 			}
+			fields = append(fields, collectFields(field.Type.Elem(), prefix+dbName+".", fieldIndex, nestedOptional, naming)...)
+			continue
 		}
 
 		fields = append(fields, fieldInfo{
-			Name:     field.Name,
-			DbName:   dbName,
-			Type:     field.Type,
-			IsAuto:   isAuto,
-			Position: i,
+			Name:       field.Name,
+			DbName:     prefix + dbName,
+			Type:       field.Type,
+			IsAuto:     isAuto,
+			Position:   i,
+			Index:      fieldIndex,
+			Nullable:   isNullable(field.Type) || isArray,
+			SoftDelete: isSoftDelete,
+			PrimaryKey: isPrimaryKey,
+			Array:      isArray,
+			Optional:   optional,
 		})
 	}
 
 	return fields
 }
 
-func buildInsertQuery(table string, inputFields, returningFields []fieldInfo) string {
-	var insertFields []string
-	var placeholders []string
-	placeholderCount := 0
+// primaryKeyConds returns Eq conditions on the columns tagged with the pk option, e.g. `db:"user_id,pk"`.
+// It panics if there are none.
+func primaryKeyConds(t reflect.Type, fields []fieldInfo) []Condition {
+	cols := primaryKeyColumns(fields)
+	if len(cols) == 0 {
+		panic(fmt.Sprintf("dbx: %s has no fields tagged with the pk option", t))
+	}
+	conds := make([]Condition, len(cols))
+	for i, col := range cols {
+		conds[i] = Eq(col)
+	}
+	return conds
+}
 
-	for _, field := range inputFields {
-		if !field.IsAuto {
-			insertFields = append(insertFields, field.DbName)
-			placeholderCount++
-			placeholders = append(placeholders, fmt.Sprintf("$%d", placeholderCount))
+// primaryKeyColumns returns the columns tagged with the pk option, columns of nested structs are not considered
+func primaryKeyColumns(fields []fieldInfo) []string {
+	var cols []string
+	for _, field := range fields {
+		if field.PrimaryKey && !strings.Contains(field.DbName, ".") {
+			cols = append(cols, field.DbName)
 		}
 	}
+	return cols
+}
 
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES (%s)",
-		table,
-		strings.Join(insertFields, ", "),
-		strings.Join(placeholders, ", "))
-
-	if returningFields != nil && len(returningFields) > 0 {
-		var returningCols []string
-		for _, field := range returningFields {
-			returningCols = append(returningCols, field.DbName)
-		}
-		query += " RETURNING " + strings.Join(returningCols, ", ")
+// embeddedStruct returns the struct type of an anonymous struct or struct pointer field
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous {
+		return nil, false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
 	}
+	if t.Kind() != reflect.Struct || isColumnType(t) {
+		return nil, false
+	}
+	return t, true
+}
 
-	return query
+func buildInsertQuery(d Dialect, table string, inputFields []fieldInfo, clauses insertClauses) string {
+	return buildBatchInsertQuery(d, table, inputFields, clauses, 1)
 }
 
-func extractArgs(input interface{}, fields []fieldInfo) []interface{} {
-	v := reflect.ValueOf(input)
-	var args []interface{}
+// extractArgs extracts values of the given fields skipping auto fields
+func extractArgs[T any](input *T, fields []fieldInfo) []interface{} {
+	p := unsafe.Pointer(input)
+	var v reflect.Value
+	args := make([]interface{}, 0, len(fields))
 
 	for _, field := range fields {
-		if !field.IsAuto {
-			fieldValue := v.FieldByName(field.Name)
-			args = append(args, fieldValue.Interface())
+		if field.IsAuto {
+			continue
+		}
+		if field.get != nil {
+			args = append(args, field.get(p))
+			continue
+		}
+		if !v.IsValid() {
+			v = reflect.ValueOf(input).Elem()
 		}
+		args = append(args, fieldArg(v, field))
 	}
 
 	return args
 }
 
-func scanRow(row *sql.Row, dest interface{}, fields []fieldInfo) error {
-	v := reflect.ValueOf(dest).Elem()
-	var scanArgs []interface{}
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
 
-	for _, field := range fields {
-		fieldValue := v.FieldByName(field.Name)
-		scanArgs = append(scanArgs, fieldValue.Addr().Interface())
+func scanRow[R any](row scanner, dest *R, fields []fieldInfo) error {
+	p := unsafe.Pointer(dest)
+	var v reflect.Value
+	scanArgs := make([]interface{}, len(fields))
+	var optional []reflect.Value
+
+	for i, field := range fields {
+		if field.Optional != nil {
+			// fields of optional structs are scanned into pointers and assigned after the scan
+			if optional == nil {
+				optional = make([]reflect.Value, len(fields))
+			}
+			optional[i] = reflect.New(reflect.PointerTo(field.Type))
+			scanArgs[i] = optional[i].Interface()
+			if field.Array {
+				scanArgs[i] = Array(scanArgs[i])
+			}
+			continue
+		}
+		if field.addr != nil {
+			scanArgs[i] = field.addr(p)
+			continue
+		}
+		if !v.IsValid() {
+			v = reflect.ValueOf(dest).Elem()
+		}
+		scanArgs[i] = v.FieldByIndex(field.Index).Addr().Interface()
+		if field.Array {
+			scanArgs[i] = Array(scanArgs[i])
+		}
+	}
+
+	if err := row.Scan(scanArgs...); err != nil {
+		return err
 	}
+	if optional != nil {
+		assignOptional(reflect.ValueOf(dest).Elem(), fields, optional)
+	}
+	return nil
+}
 
-	return row.Scan(scanArgs...)
+// queryRows executes query and scans every row into a new R
+func queryRows[R any](ctx context.Context, db DB, query string, args []interface{}, fields []fieldInfo) ([]R, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var result []R
+	for rows.Next() {
+		var r R
+		if err := scanRow(rows, &r, fields); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
 }
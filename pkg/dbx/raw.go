@@ -0,0 +1,97 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// rawFields caches fields of result types of raw queries
+var rawFields sync.Map // reflect.Type -> []fieldInfo
+
+// Get runs handwritten SQL and scans the first row into R, sql.ErrNoRows is returned if there are none.
+// Result columns are matched to the db tagged fields of R by name, in any order.
+func Get[R any](ctx context.Context, db DB, query string, args ...interface{}) (R, error) {
+	var result R
+	rows, err := queryColumns[R](ctx, db, query, args, 1)
+	if err != nil {
+		return result, err
+	}
+	if len(rows) == 0 {
+		return result, sql.ErrNoRows
+	}
+	return rows[0], nil
+}
+
+// Query runs handwritten SQL and scans all rows into R, it's the escape hatch for queries builders can't express.
+// Result columns are matched to the db tagged fields of R by name, in any order.
+// The name Select is taken by the builder.
+func Query[R any](ctx context.Context, db DB, query string, args ...interface{}) ([]R, error) {
+	return queryColumns[R](ctx, db, query, args, 0)
+}
+
+// queryColumns scans up to max rows, all of them if max is zero
+func queryColumns[R any](ctx context.Context, db DB, query string, args []interface{}, max int) ([]R, error) {
+	rows, err := db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	return scanColumns[R](rows, max)
+}
+
+// Rows is a result set of any driver, e.g. *sql.Rows or an adapter of pgx.Rows
+type Rows interface {
+	Columns() ([]string, error)
+	Next() bool
+	Scan(dest ...interface{}) error
+	Err() error
+}
+
+// ScanAll scans all rows into R matching columns to the db tagged fields of R by name, like Query.
+// It doesn't close rows.
+func ScanAll[R any](rows Rows) ([]R, error) {
+	return scanColumns[R](rows, 0)
+}
+
+func scanColumns[R any](rows Rows, max int) ([]R, error) {
+	cols, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fields, err := columnFields(reflect.TypeOf((*R)(nil)).Elem(), cols)
+	if err != nil {
+		return nil, err
+	}
+
+	var result []R
+	for (max == 0 || len(result) < max) && rows.Next() {
+		var r R
+		if err := scanRow(rows, &r, fields); err != nil {
+			return nil, err
+		}
+		result = append(result, r)
+	}
+	return result, rows.Err()
+}
+
+// columnFields orders fields of t to match result columns
+func columnFields(t reflect.Type, cols []string) ([]fieldInfo, error) {
+	cached, ok := rawFields.Load(t)
+	if !ok {
+		cached, _ = rawFields.LoadOrStore(t, extractFields(t))
+	}
+	all := cached.([]fieldInfo)
+
+	fields := make([]fieldInfo, len(cols))
+	for i, col := range cols {
+		field, ok := fieldByColumn(all, col)
+		if !ok {
+			return nil, fmt.Errorf("column %q is not a field of %s", col, t)
+		}
+		fields[i] = field
+	}
+	return fields, nil
+}
@@ -0,0 +1,175 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// Repository runs the standard CRUD queries of a model T with primary key of type ID.
+// Queries are compiled once by NewRepository, e.g.
+//
+//	users := dbx.NewRepository[User, int]("users")
+//	u, err := users.Insert(ctx, db, User{Name: "John"})
+//	u, err = users.GetByID(ctx, db, u.ID)
+//
+// Composite keys are passed as structs with a field for every key column, e.g.
+//
+//	type MemberKey struct {
+//		OrgID  int `db:"org_id"`
+//		UserID int `db:"user_id"`
+//	}
+//	members := dbx.NewRepository[Member, MemberKey]("members")
+type Repository[T any, ID any] struct {
+	keys []fieldInfo
+	// idFields are the fields of a composite ID in the order of keys
+	idFields []fieldInfo
+	insert   *CompiledInsertQuery[T, T]
+	get      *CompiledSelectQuery[T]
+	list     *CompiledSelectQuery[T]
+	update   *CompiledUpdateQuery[T]
+	deleteQ  *CompiledDeleteQuery[T, struct{}]
+}
+
+type repositoryConfig struct {
+	primaryKey []string
+	dialect    Dialect
+	naming     NamingStrategy
+}
+
+// RepositoryOption configures Repository
+type RepositoryOption func(*repositoryConfig)
+
+// WithPrimaryKey sets the primary key columns. By default they are the fields tagged
+// with the pk option, e.g. `db:"user_id,pk"`, or "id" if there are none.
+func WithPrimaryKey(cols ...string) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.primaryKey = cols
+	}
+}
+
+// WithRepositoryDialect sets placeholder syntax of the generated SQL
+func WithRepositoryDialect(d Dialect) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.dialect = d
+	}
+}
+
+// WithRepositoryNaming maps fields without db tag to columns named by naming, e.g. WithRepositoryNaming(SnakeCase)
+func WithRepositoryNaming(naming NamingStrategy) RepositoryOption {
+	return func(c *repositoryConfig) {
+		c.naming = naming
+	}
+}
+
+// NewRepository compiles CRUD queries of T stored in table. Soft delete is enabled
+// if T has a field tagged with the softdelete option.
+// It panics if a primary key column is not a field of T or ID doesn't match the primary key.
+func NewRepository[T any, ID any](table string, opts ...RepositoryOption) *Repository[T, ID] {
+	var cfg repositoryConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	fields := extractNamedFields(t, cfg.naming)
+	if cfg.primaryKey == nil {
+		cfg.primaryKey = primaryKeyColumns(fields)
+	}
+	if cfg.primaryKey == nil {
+		cfg.primaryKey = []string{"id"}
+	}
+
+	keys := resolveParams(t, fields, cfg.primaryKey)
+	idFields := repositoryIDFields[ID](t, keys, cfg.naming)
+	where := make([]Condition, len(keys))
+	for i, key := range keys {
+		where[i] = Eq(key.DbName)
+	}
+
+	insert := Insert[T](table).Dialect(cfg.dialect)
+	get := Select[T](table).Dialect(cfg.dialect)
+	list := Select[T](table).Dialect(cfg.dialect)
+	update := Update[T](table).Dialect(cfg.dialect)
+	del := Delete[T](table).Dialect(cfg.dialect)
+	if cfg.naming != nil {
+		insert.Naming(cfg.naming)
+		get.Naming(cfg.naming)
+		list.Naming(cfg.naming)
+		update.Naming(cfg.naming)
+		del.Naming(cfg.naming)
+	}
+
+	return &Repository[T, ID]{
+		keys:     keys,
+		idFields: idFields,
+		insert:   Returning[T, T](insert).Compile(),
+		get:      get.Where(where...).Compile(),
+		list:     list.OrderBy(cfg.primaryKey...).Compile(),
+		update:   update.Where(where...).Compile(),
+		deleteQ:  del.Where(where...).Compile(),
+	}
+}
+
+// repositoryIDFields checks that ID matches keys. A single key is the ID itself,
+// composite keys are the fields of ID with the same column names.
+func repositoryIDFields[ID any](t reflect.Type, keys []fieldInfo, naming NamingStrategy) []fieldInfo {
+	idType := reflect.TypeOf((*ID)(nil)).Elem()
+	if len(keys) == 1 {
+		if !idType.AssignableTo(keys[0].Type) {
+			panic(fmt.Sprintf("dbx: primary key %q of %s is %s, not %s", keys[0].DbName, t, keys[0].Type, idType))
+		}
+		return nil
+	}
+
+	if idType.Kind() != reflect.Struct {
+		panic(fmt.Sprintf("dbx: composite primary key of %s needs a struct ID, not %s", t, idType))
+	}
+	idFields := make([]fieldInfo, len(keys))
+	for i, key := range keys {
+		field, ok := fieldByColumn(extractNamedFields(idType, naming), key.DbName)
+		if !ok || !field.Type.AssignableTo(key.Type) {
+			panic(fmt.Sprintf("dbx: %s has no field for primary key %q of type %s", idType, key.DbName, key.Type))
+		}
+		idFields[i] = field
+	}
+	return idFields
+}
+
+// Insert inserts model and returns the stored row, including auto fields
+func (r *Repository[T, ID]) Insert(ctx context.Context, db DB, model T) (T, error) {
+	return r.insert.New(model).ExecContext(ctx, db)
+}
+
+// GetByID returns the row with the given primary key, sql.ErrNoRows is returned if there is none
+func (r *Repository[T, ID]) GetByID(ctx context.Context, db DB, id ID) (T, error) {
+	if r.idFields == nil {
+		return r.get.New(id).GetContext(ctx, db)
+	}
+	return r.get.New(extractFieldArgs(&id, r.idFields)...).GetContext(ctx, db)
+}
+
+// List returns all rows ordered by primary key
+func (r *Repository[T, ID]) List(ctx context.Context, db DB) ([]T, error) {
+	return r.list.New().ExecContext(ctx, db)
+}
+
+// Update updates the row with the primary key of model and returns the number of updated rows
+func (r *Repository[T, ID]) Update(ctx context.Context, db DB, model T) (int64, error) {
+	return r.update.New(model).ExecContext(ctx, db)
+}
+
+// Delete deletes the row with the given primary key and returns the number of deleted rows
+func (r *Repository[T, ID]) Delete(ctx context.Context, db DB, id ID) (int64, error) {
+	var model T
+	v := reflect.ValueOf(&model).Elem()
+	idv := reflect.ValueOf(&id).Elem()
+	for i, key := range r.keys {
+		if r.idFields == nil {
+			v.FieldByIndex(key.Index).Set(idv)
+			continue
+		}
+		v.FieldByIndex(key.Index).Set(idv.FieldByIndex(r.idFields[i].Index))
+	}
+	return r.deleteQ.New(model).ExecContext(ctx, db)
+}
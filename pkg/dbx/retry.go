@@ -0,0 +1,101 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"net"
+	"time"
+)
+
+// RetryPolicy configures Retry and WithTxRetry, zero fields fall back to defaults
+type RetryPolicy struct {
+	// MaxAttempts is the number of attempts including the first one, 3 by default
+	MaxAttempts int
+	// Backoff returns the delay before the given retry attempt, starting from 1.
+	// By default it doubles from 50ms up to 1s.
+	Backoff func(attempt int) time.Duration
+	// Retryable classifies errors, IsRetryable by default
+	Retryable func(err error) bool
+}
+
+// Retry runs fn until it succeeds, fails with an error that is not retryable or runs out of attempts, e.g.
+//
+//	n, err := dbx.Retry(ctx, db, dbx.RetryPolicy{}, q.New(input).ExecContext)
+//
+// Retry only idempotent statements outside transactions, use WithTxRetry for transactions.
+func Retry[T any](ctx context.Context, db DB, policy RetryPolicy, fn func(ctx context.Context, db DB) (T, error)) (T, error) {
+	policy = policy.withDefaults()
+	for attempt := 1; ; attempt++ {
+		res, err := fn(ctx, db)
+		if err == nil || attempt >= policy.MaxAttempts || !policy.Retryable(err) {
+			return res, err
+		}
+		if waitErr := sleep(ctx, policy.Backoff(attempt)); waitErr != nil {
+			return res, errors.Join(err, waitErr)
+		}
+	}
+}
+
+// WithTxRetry is WithTx running the whole transaction again when it fails with a retryable error,
+// e.g. a Postgres serialization failure of a SERIALIZABLE transaction. fn must not have side effects
+// outside the transaction, as it may run several times.
+func WithTxRetry(ctx context.Context, db *sql.DB, policy RetryPolicy, fn func(tx DB) error, opts ...TxOption) error {
+	_, err := Retry(ctx, nil, policy, func(ctx context.Context, _ DB) (struct{}, error) {
+		return struct{}{}, WithTx(ctx, db, fn, opts...)
+	})
+	return err
+}
+
+// IsRetryable reports whether err is a serialization failure (SQLSTATE 40001), a deadlock (40P01)
+// or a dropped connection. SQLSTATE is read from errors with a SQLState method, like pgx and lib/pq errors.
+func IsRetryable(err error) bool {
+	if err == nil || errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+
+	var stateErr interface{ SQLState() string }
+	if errors.As(err, &stateErr) {
+		switch stateErr.SQLState() {
+		case "40001", "40P01":
+			return true
+		}
+		// SQLSTATE class 08 is connection exceptions
+		return len(stateErr.SQLState()) == 5 && stateErr.SQLState()[:2] == "08"
+	}
+
+	var netErr net.Error
+	return errors.Is(err, driver.ErrBadConn) ||
+		errors.Is(err, io.ErrUnexpectedEOF) ||
+		errors.Is(err, net.ErrClosed) ||
+		errors.As(err, &netErr)
+}
+
+func (p RetryPolicy) withDefaults() RetryPolicy {
+	if p.MaxAttempts <= 0 {
+		p.MaxAttempts = 3
+	}
+	if p.Backoff == nil {
+		p.Backoff = func(attempt int) time.Duration {
+			return min(50*time.Millisecond<<(attempt-1), time.Second)
+		}
+	}
+	if p.Retryable == nil {
+		p.Retryable = IsRetryable
+	}
+	return p
+}
+
+// sleep waits for d or until ctx is done
+func sleep(ctx context.Context, d time.Duration) error {
+	t := time.NewTimer(d)
+	defer t.Stop()
+	select {
+	case <-t.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,167 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// Router is a DB sending read-only queries to replicas and everything else to the primary, e.g.
+//
+//	db := dbx.NewRouter(primary, []dbx.DB{replica1, replica2}, dbx.WithHealthCheck(5*time.Second))
+//	defer db.Close()
+//
+// Queries starting with SELECT, except SELECT ... FOR UPDATE/SHARE, are read-only, so compiled
+// Select, Count and Exists queries go to replicas while INSERT ... RETURNING goes to the primary.
+// Replicas lag behind the primary, use Sticky to read your own writes.
+type Router struct {
+	primary  DB
+	replicas []DB
+	healthy  []atomic.Bool
+	next     atomic.Uint64
+
+	interval time.Duration
+	stop     chan struct{}
+	stopOnce sync.Once
+	wg       sync.WaitGroup
+}
+
+// RouterOption configures Router
+type RouterOption func(*Router)
+
+// WithHealthCheck pings replicas implementing PingContext, e.g. *sql.DB, every interval.
+// Replicas failing the ping get no queries until they pass it again.
+func WithHealthCheck(interval time.Duration) RouterOption {
+	return func(r *Router) {
+		r.interval = interval
+	}
+}
+
+// NewRouter creates a router over primary and replicas, all replicas are considered healthy initially.
+// Without replicas all queries go to the primary.
+func NewRouter(primary DB, replicas []DB, opts ...RouterOption) *Router {
+	r := &Router{
+		primary:  primary,
+		replicas: replicas,
+		healthy:  make([]atomic.Bool, len(replicas)),
+		stop:     make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+	for i := range r.healthy {
+		r.healthy[i].Store(true)
+	}
+
+	if r.interval > 0 && len(replicas) > 0 {
+		r.wg.Add(1)
+		go r.healthLoop()
+	}
+	return r
+}
+
+// Primary returns the primary, e.g. to start transactions on it
+func (r *Router) Primary() DB {
+	return r.primary
+}
+
+// Close stops health checks, it doesn't close the primary and replicas
+func (r *Router) Close() error {
+	r.stopOnce.Do(func() { close(r.stop) })
+	r.wg.Wait()
+	return nil
+}
+
+type stickyKey struct{}
+
+// Sticky marks ctx, e.g. of an HTTP request, so once a write goes through the router with it,
+// later reads with it go to the primary as well
+func Sticky(ctx context.Context) context.Context {
+	return context.WithValue(ctx, stickyKey{}, new(atomic.Bool))
+}
+
+// ReadPrimary sends reads with ctx to the primary, e.g. for reads that must not be stale
+func ReadPrimary(ctx context.Context) context.Context {
+	wrote := new(atomic.Bool)
+	wrote.Store(true)
+	return context.WithValue(ctx, stickyKey{}, wrote)
+}
+
+func (r *Router) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	return r.route(ctx, query).QueryRowContext(ctx, query, args...)
+}
+
+func (r *Router) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	return r.route(ctx, query).QueryContext(ctx, query, args...)
+}
+
+func (r *Router) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	r.markWrite(ctx)
+	return r.primary.ExecContext(ctx, query, args...)
+}
+
+// CheckHealth pings replicas implementing PingContext once, WithHealthCheck runs it periodically
+func (r *Router) CheckHealth(ctx context.Context) {
+	for i, replica := range r.replicas {
+		pinger, ok := replica.(interface{ PingContext(context.Context) error })
+		if !ok {
+			continue
+		}
+		r.healthy[i].Store(pinger.PingContext(ctx) == nil)
+	}
+}
+
+func (r *Router) healthLoop() {
+	defer r.wg.Done()
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			ctx, cancel := context.WithTimeout(context.Background(), r.interval)
+			r.CheckHealth(ctx)
+			cancel()
+		}
+	}
+}
+
+// route picks a healthy replica for read-only queries round-robin, the primary otherwise
+func (r *Router) route(ctx context.Context, query string) DB {
+	if !isReadOnlyQuery(query) {
+		r.markWrite(ctx)
+		return r.primary
+	}
+	if wrote, ok := ctx.Value(stickyKey{}).(*atomic.Bool); ok && wrote.Load() {
+		return r.primary
+	}
+
+	n := uint64(len(r.replicas))
+	start := r.next.Add(1)
+	for i := uint64(0); i < n; i++ {
+		idx := (start + i) % n
+		if r.healthy[idx].Load() {
+			return r.replicas[idx]
+		}
+	}
+	return r.primary
+}
+
+func (r *Router) markWrite(ctx context.Context) {
+	if wrote, ok := ctx.Value(stickyKey{}).(*atomic.Bool); ok {
+		wrote.Store(true)
+	}
+}
+
+// isReadOnlyQuery reports whether query is a SELECT without locking clauses
+func isReadOnlyQuery(query string) bool {
+	query = strings.ToUpper(strings.TrimSpace(query))
+	if !strings.HasPrefix(query, "SELECT") {
+		return false
+	}
+	return !strings.Contains(query, " FOR UPDATE") && !strings.Contains(query, " FOR SHARE")
+}
@@ -0,0 +1,209 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Model describes the table a struct is stored in, for ValidateSchema
+type Model struct {
+	table   string
+	t       reflect.Type
+	fields  []fieldInfo
+	dialect Dialect
+}
+
+// ModelOf creates a model of T stored in table
+func ModelOf[T any](table string) *Model {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	return &Model{table: table, t: t, fields: extractFields(t)}
+}
+
+// Dialect sets the database the schema is read from
+func (m *Model) Dialect(d Dialect) *Model {
+	m.dialect = d
+	return m
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (m *Model) Naming(naming NamingStrategy) *Model {
+	m.fields = extractNamedFields(m.t, naming)
+	return m
+}
+
+// SchemaError lists differences between models and the database
+type SchemaError struct {
+	Problems []string
+}
+
+func (e *SchemaError) Error() string {
+	return "schema drift:\n" + strings.Join(e.Problems, "\n")
+}
+
+// ValidateSchema compares models with the live database and returns *SchemaError listing missing tables
+// and columns, columns of incompatible types, e.g. TEXT for an int field, and nullable columns of fields
+// that can't hold NULL. Types are compared by family, e.g. INTEGER and BIGINT are both integers.
+// Columns without matching fields are allowed.
+func ValidateSchema(ctx context.Context, db DB, models ...*Model) error {
+	var problems []string
+	for _, m := range models {
+		columns, err := m.columns(ctx, db)
+		if err != nil {
+			return fmt.Errorf("read columns of %s: %w", m.table, err)
+		}
+		if len(columns) == 0 {
+			problems = append(problems, fmt.Sprintf("table %s is missing", m.table))
+			continue
+		}
+
+		for _, field := range m.fields {
+			if strings.Contains(field.DbName, ".") {
+				// nested columns belong to joined tables
+				continue
+			}
+			col, ok := columns[strings.ToLower(field.DbName)]
+			if !ok {
+				problems = append(problems, fmt.Sprintf("column %s.%s is missing", m.table, field.DbName))
+				continue
+			}
+
+			want := fieldTypeFamily(field.Type)
+			if ddl, ok := m.t.FieldByIndex(field.Index).Tag.Lookup("ddl"); ok {
+				want = columnTypeFamily(ddl)
+			}
+			if got := columnTypeFamily(col.DataType); want != "" && got != "" && !compatibleFamilies(want, got) {
+				problems = append(problems, fmt.Sprintf("column %s.%s is %s, field %s is %s", m.table, field.DbName, col.DataType, field.Name, field.Type))
+			}
+			if col.Nullable() && !field.Nullable {
+				problems = append(problems, fmt.Sprintf("column %s.%s is nullable, field %s of type %s can't hold NULL", m.table, field.DbName, field.Name, field.Type))
+			}
+		}
+	}
+
+	if len(problems) > 0 {
+		return &SchemaError{Problems: problems}
+	}
+	return nil
+}
+
+// Column is a column of a database table
+type Column struct {
+	Name     string `db:"column_name"`
+	DataType string `db:"data_type"`
+	// IsNullable is "YES" or "NO" as in information_schema, see Nullable
+	IsNullable string `db:"is_nullable"`
+}
+
+// Nullable reports whether the column accepts NULL
+func (c Column) Nullable() bool {
+	return c.IsNullable == "YES"
+}
+
+// columns reads columns of the model table keyed by lower case name, it's empty if the table is missing
+func (m *Model) columns(ctx context.Context, db DB) (map[string]Column, error) {
+	rows, err := TableColumns(ctx, db, m.dialect, m.table)
+	if err != nil {
+		return nil, err
+	}
+	columns := make(map[string]Column, len(rows))
+	for _, col := range rows {
+		columns[strings.ToLower(col.Name)] = col
+	}
+	return columns, nil
+}
+
+// TableColumns reads columns of table from the schema of the live database, it's empty if the table is missing
+func TableColumns(ctx context.Context, db DB, d Dialect, table string) ([]Column, error) {
+	var query string
+	switch d {
+	case Postgres:
+		query = "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_name = $1 AND table_schema = current_schema()"
+	case MySQL:
+		query = "SELECT column_name, data_type, is_nullable FROM information_schema.columns WHERE table_name = ? AND table_schema = DATABASE()"
+	case SQLite:
+		// primary keys are reported as nullable, but SQLite doesn't allow NULL in INTEGER PRIMARY KEY
+		query = `SELECT name AS column_name, type AS data_type, CASE WHEN "notnull" = 1 OR pk > 0 THEN 'NO' ELSE 'YES' END AS is_nullable FROM pragma_table_info(?)`
+	case Oracle:
+		query = "SELECT column_name, data_type, CASE nullable WHEN 'Y' THEN 'YES' ELSE 'NO' END AS is_nullable FROM user_tab_columns WHERE table_name = :1"
+		// unquoted Oracle names are stored in upper case
+		table = strings.ToUpper(table)
+	}
+	return Query[Column](ctx, db, query, table)
+}
+
+// ColumnTypeCompatible reports whether a field of type t can be stored in a column of dataType,
+// e.g. int64 in INTEGER or BIGINT. Types unknown to dbx, e.g. uuid.UUID or JSONB, are compatible with anything.
+func ColumnTypeCompatible(t reflect.Type, dataType string) bool {
+	want, got := fieldTypeFamily(t), columnTypeFamily(dataType)
+	return want == "" || got == "" || compatibleFamilies(want, got)
+}
+
+// fieldTypeFamily returns the family of column types a field of type t can be stored in,
+// it's empty for types dbx doesn't know, e.g. uuid.UUID
+func fieldTypeFamily(t reflect.Type) string {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if isSQLNull(t) {
+		t = t.Field(0).Type
+	}
+	switch {
+	case t == timeType:
+		return "time"
+	case t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return "bytes"
+	case t.Kind() == reflect.Bool:
+		return "bool"
+	case isIntegerKind(t.Kind()):
+		return "integer"
+	case t.Kind() == reflect.Float32 || t.Kind() == reflect.Float64:
+		return "float"
+	case t.Kind() == reflect.String:
+		return "string"
+	}
+	return ""
+}
+
+// columnTypeFamily returns the family of a database column type, empty if it's unknown
+func columnTypeFamily(dataType string) string {
+	dataType = strings.ToLower(dataType)
+	for _, family := range [...]struct {
+		name    string
+		matches []string
+	}{
+		// order matters: "timestamp" contains "time", "interval" contains "int"
+		{"time", []string{"timestamp", "date", "time"}},
+		{"bool", []string{"bool", "bit"}},
+		{"integer", []string{"serial", "int"}},
+		{"numeric", []string{"numeric", "decimal", "number"}},
+		{"float", []string{"real", "double", "float"}},
+		{"string", []string{"char", "text", "clob", "uuid", "json", "enum"}},
+		{"bytes", []string{"bytea", "blob", "binary"}},
+	} {
+		for _, match := range family.matches {
+			if strings.Contains(dataType, match) {
+				return family.name
+			}
+		}
+	}
+	return ""
+}
+
+// compatibleFamilies reports whether a field of family want can be stored in a column of family got
+func compatibleFamilies(want, got string) bool {
+	switch {
+	case want == got:
+		return true
+	case got == "numeric":
+		// NUMERIC and Oracle NUMBER store integers, floats and booleans alike
+		return want == "integer" || want == "float" || want == "bool"
+	case want == "numeric":
+		return got == "integer" || got == "float"
+	case want == "bool":
+		// MySQL BOOLEAN is TINYINT(1)
+		return got == "integer"
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"iter"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// SelectBuilder represents a select query builder
+type SelectBuilder[R any] struct {
+	table string
+	// from is the subquery selected from, table is its alias then
+	from    Subquery
+	fields  []fieldInfo
+	joins   []join
+	where   []Condition
+	orderBy []string
+	limit   int
+	offset  int
+	dialect Dialect
+	// softDelete is the soft delete column set with SoftDelete
+	softDelete string
+	unscoped   bool
+	// limitParam binds LIMIT to the last arg instead of the limit value
+	limitParam bool
+}
+
+// join is a JOIN clause of a select query
+type join struct {
+	kind  string
+	table string
+	on    []Condition
+}
+
+// CompiledSelectQuery represents a compiled select query
+type CompiledSelectQuery[R any] struct {
+	dialect Dialect
+	query   string
+	params  []string
+	// consts are args of Expr placeholders, keyed by param index
+	consts map[int]interface{}
+	fields []fieldInfo
+	ph     placeholders
+}
+
+// ExecutableSelectQuery represents a select query ready for execution
+type ExecutableSelectQuery[R any] struct {
+	compiled *CompiledSelectQuery[R]
+	query    string
+	args     []interface{}
+	err      error
+}
+
+// Select creates a new select query builder, selected columns are the db tagged fields of R
+func Select[R any](table string) *SelectBuilder[R] {
+	return &SelectBuilder[R]{
+		table:  table,
+		fields: extractFields(reflect.TypeOf((*R)(nil)).Elem()),
+	}
+}
+
+// SelectFrom creates a new select query builder selecting from a subquery named alias, e.g.
+//
+//	recent := dbx.Select[Order]("orders").OrderBy("id DESC").Limit(100).Compile()
+//	dbx.SelectFrom[Order](recent, "recent").Where(dbx.Eq("status"))
+func SelectFrom[R any](sub Subquery, alias string) *SelectBuilder[R] {
+	sb := Select[R](alias)
+	sb.from = sub
+	return sb
+}
+
+// SoftDelete sets the column of soft-deleted rows, rows with non-NULL values are left out.
+// Fields tagged with the softdelete option, e.g. `db:"deleted_at,softdelete"`, set it automatically.
+func (sb *SelectBuilder[R]) SoftDelete(col string) *SelectBuilder[R] {
+	sb.softDelete = col
+	return sb
+}
+
+// Unscoped includes soft-deleted rows, e.g. for admin queries
+func (sb *SelectBuilder[R]) Unscoped() *SelectBuilder[R] {
+	sb.unscoped = true
+	return sb
+}
+
+// Join adds INNER JOIN clause with conditions joined with AND, e.g. Join("users u", dbx.On("u.id", "orders.user_id")).
+// Table is validated and quoted like the selected one, args of Expr conditions are bound before WHERE args.
+// Joined columns are scanned into struct fields tagged with the nested option, e.g. `db:"u,nested"`.
+func (sb *SelectBuilder[R]) Join(table string, on ...Condition) *SelectBuilder[R] {
+	sb.joins = append(sb.joins, join{kind: "JOIN", table: table, on: on})
+	return sb
+}
+
+// LeftJoin adds LEFT JOIN clause, see Join. Nested fields must be able to scan NULL
+// when the joined row is missing.
+func (sb *SelectBuilder[R]) LeftJoin(table string, on ...Condition) *SelectBuilder[R] {
+	sb.joins = append(sb.joins, join{kind: "LEFT JOIN", table: table, on: on})
+	return sb
+}
+
+// Where adds conditions joined with AND, placeholders are bound from args passed to New in order
+func (sb *SelectBuilder[R]) Where(conds ...Condition) *SelectBuilder[R] {
+	sb.where = append(sb.where, conds...)
+	return sb
+}
+
+// WherePK adds conditions on the primary key columns of R, args are bound in field order.
+// Columns are the fields tagged with the pk option, e.g. `db:"user_id,pk"`, it panics if there are none.
+func (sb *SelectBuilder[R]) WherePK() *SelectBuilder[R] {
+	return sb.Where(primaryKeyConds(reflect.TypeOf((*R)(nil)).Elem(), sb.fields)...)
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (sb *SelectBuilder[R]) Dialect(d Dialect) *SelectBuilder[R] {
+	sb.dialect = d
+	return sb
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (sb *SelectBuilder[R]) Naming(naming NamingStrategy) *SelectBuilder[R] {
+	sb.fields = extractNamedFields(reflect.TypeOf((*R)(nil)).Elem(), naming)
+	return sb
+}
+
+// OrderBy adds ORDER BY columns, e.g. OrderBy("created_at DESC", "id")
+func (sb *SelectBuilder[R]) OrderBy(cols ...string) *SelectBuilder[R] {
+	sb.orderBy = append(sb.orderBy, cols...)
+	return sb
+}
+
+// Limit sets LIMIT clause
+func (sb *SelectBuilder[R]) Limit(n int) *SelectBuilder[R] {
+	sb.limit = n
+	return sb
+}
+
+// Offset sets OFFSET clause
+func (sb *SelectBuilder[R]) Offset(n int) *SelectBuilder[R] {
+	sb.offset = n
+	return sb
+}
+
+// Compile compiles the select query into a reusable form.
+// It panics if an ORDER BY column is not a selected column, LIMIT/OFFSET is negative,
+// a table is invalid or a join has no conditions.
+func (sb *SelectBuilder[R]) Compile() *CompiledSelectQuery[R] {
+	if sb.limit < 0 || sb.offset < 0 {
+		panic(fmt.Sprintf("dbx: negative LIMIT %d or OFFSET %d", sb.limit, sb.offset))
+	}
+
+	// columns of the main table are qualified once other tables are joined
+	qualifier := ""
+	if len(sb.joins) > 0 {
+		tableParts := strings.Fields(sb.table)
+		qualifier = tableParts[len(tableParts)-1] + "."
+	}
+
+	var cols []string
+	selectable := make(map[string]struct{}, len(sb.fields))
+	for _, field := range sb.fields {
+		selectable[field.DbName] = struct{}{}
+		if strings.Contains(field.DbName, ".") {
+			cols = append(cols, sb.dialect.mustIdent(field.DbName))
+			continue
+		}
+		cols = append(cols, sb.dialect.mustIdent(qualifier+field.DbName))
+		selectable[qualifier+field.DbName] = struct{}{}
+	}
+	if err := checkOrderBy(sb.orderBy, selectable); err != nil {
+		panic("dbx: " + err.Error())
+	}
+
+	b := &queryBuilder{dialect: sb.dialect}
+	b.writeString(fmt.Sprintf("SELECT %s FROM ", strings.Join(cols, ", ")))
+	if sb.from != nil {
+		b.writeString("(")
+		writeSubquery(b, sb.from)
+		b.writeString(") " + sb.dialect.mustIdent(sb.table))
+	} else {
+		b.writeString(sb.dialect.mustTable(sb.table))
+	}
+	for _, j := range sb.joins {
+		if len(j.on) == 0 {
+			panic(fmt.Sprintf("dbx: %s %s has no ON conditions", j.kind, j.table))
+		}
+		b.writeString(fmt.Sprintf(" %s %s ON ", j.kind, sb.dialect.mustTable(j.table)))
+		appendAnd(b, j.on)
+	}
+	where := sb.where
+	if col := softDeleteColumn(sb.softDelete, sb.fields); col != "" && !sb.unscoped {
+		if !strings.Contains(col, ".") {
+			col = qualifier + col
+		}
+		where = append(slices.Clip(where), IsNull(col))
+	}
+	appendWhere(b, where)
+	if len(sb.orderBy) > 0 {
+		b.writeString(" ORDER BY " + strings.Join(quoteOrderBy(sb.dialect, sb.orderBy), ", "))
+	}
+	if sb.limitParam {
+		b.writeString(" LIMIT " + b.param("limit"))
+	} else if sb.limit > 0 {
+		b.writeString(fmt.Sprintf(" LIMIT %d", sb.limit))
+	}
+	if sb.offset > 0 {
+		b.writeString(fmt.Sprintf(" OFFSET %d", sb.offset))
+	}
+
+	return &CompiledSelectQuery[R]{
+		dialect: sb.dialect,
+		query:   sb.dialect.Rebind(b.String()),
+		params:  b.params,
+		consts:  b.consts,
+		fields:  sb.fields,
+		ph:      newPlaceholders(b, sb.dialect),
+	}
+}
+
+// New creates a new executable query with args bound to WHERE placeholders in order
+func (cq *CompiledSelectQuery[R]) New(args ...interface{}) *ExecutableSelectQuery[R] {
+	args, err := cq.bindArgs(args)
+	if err != nil {
+		return &ExecutableSelectQuery[R]{compiled: cq, err: err}
+	}
+	query, args := cq.ph.bind(cq.query, args)
+	return &ExecutableSelectQuery[R]{
+		compiled: cq,
+		query:    query,
+		args:     args,
+	}
+}
+
+func (cq *CompiledSelectQuery[R]) PreviewQuery(args ...interface{}) (string, []any) {
+	full, err := cq.bindArgs(args)
+	if err != nil {
+		return cq.query, args
+	}
+	return cq.ph.bind(cq.query, full)
+}
+
+// ExecContext executes the query and scans all rows
+func (eq *ExecutableSelectQuery[R]) ExecContext(ctx context.Context, db DB) ([]R, error) {
+	if eq.err != nil {
+		return nil, eq.err
+	}
+
+	return queryRows[R](ctx, db, eq.query, eq.args, eq.compiled.fields)
+}
+
+// GetContext executes the query and scans a single row, sql.ErrNoRows is returned if there are none
+func (eq *ExecutableSelectQuery[R]) GetContext(ctx context.Context, db DB) (R, error) {
+	var result R
+	if eq.err != nil {
+		return result, eq.err
+	}
+
+	row := db.QueryRowContext(ctx, eq.query, eq.args...)
+	err := scanRow(row, &result, eq.compiled.fields)
+	return result, err
+}
+
+// Iter executes the query and scans rows lazily, one at a time, so large results don't have to fit in memory, e.g.
+//
+//	for u, err := range q.New(age).Iter(ctx, db) {
+//		if err != nil {
+//			return err
+//		}
+//		...
+//	}
+//
+// Errors are yielded with the zero R and end the iteration. Rows are closed once the loop ends or breaks.
+func (eq *ExecutableSelectQuery[R]) Iter(ctx context.Context, db DB) iter.Seq2[R, error] {
+	return func(yield func(R, error) bool) {
+		var zero R
+		if eq.err != nil {
+			yield(zero, eq.err)
+			return
+		}
+
+		rows, err := db.QueryContext(ctx, eq.query, eq.args...)
+		if err != nil {
+			yield(zero, err)
+			return
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var r R
+			if err := scanRow(rows, &r, eq.compiled.fields); err != nil {
+				yield(zero, err)
+				return
+			}
+			if !yield(r, nil) {
+				return
+			}
+		}
+		if err := rows.Err(); err != nil {
+			yield(zero, err)
+		}
+	}
+}
+
+// bindArgs checks the number of args and puts Expr args between them
+func (cq *CompiledSelectQuery[R]) bindArgs(args []interface{}) ([]interface{}, error) {
+	if len(cq.consts) == 0 {
+		if len(args) != len(cq.params) {
+			return nil, fmt.Errorf("expected %d args for %v, got %d", len(cq.params), cq.params, len(args))
+		}
+		return args, nil
+	}
+
+	var cols []string
+	for i, col := range cq.params {
+		if _, ok := cq.consts[i]; !ok {
+			cols = append(cols, col)
+		}
+	}
+	if len(args) != len(cols) {
+		return nil, fmt.Errorf("expected %d args for %v, got %d", len(cols), cols, len(args))
+	}
+	full := make([]interface{}, 0, len(cq.params))
+	for i := range cq.params {
+		if v, ok := cq.consts[i]; ok {
+			full = append(full, v)
+			continue
+		}
+		full = append(full, args[0])
+		args = args[1:]
+	}
+	return full, nil
+}
@@ -0,0 +1,18 @@
+package dbx
+
+import "strings"
+
+// softDeleteColumn returns the column configured on the builder, or the one tagged
+// with the softdelete option, e.g. `db:"deleted_at,softdelete"`.
+// Columns of nested structs, e.g. joined tables, are not considered.
+func softDeleteColumn(configured string, fields []fieldInfo) string {
+	if configured != "" {
+		return configured
+	}
+	for _, field := range fields {
+		if field.SoftDelete && !strings.Contains(field.DbName, ".") {
+			return field.DbName
+		}
+	}
+	return ""
+}
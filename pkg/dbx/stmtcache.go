@@ -0,0 +1,142 @@
+package dbx
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// Preparer is a DB preparing statements, it's implemented by *sql.DB, *sql.Conn and *sql.Tx
+type Preparer interface {
+	DB
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+}
+
+// DefaultStmtCacheSize is the number of statements StmtCache keeps by default
+const DefaultStmtCacheSize = 256
+
+// StmtCache is a DB preparing every query once and reusing the statement on later calls.
+// Compiled queries have stable text, so they hit the cache on every call after the first one.
+// Least recently used statements are closed once the cache is full.
+type StmtCache struct {
+	db   Preparer
+	size int
+
+	mu     sync.Mutex
+	lru    *list.List
+	stmts  map[string]*list.Element
+	closed bool
+}
+
+type cachedStmt struct {
+	query string
+	stmt  *sql.Stmt
+}
+
+// StmtCacheOption configures StmtCache
+type StmtCacheOption func(*StmtCache)
+
+// WithStmtCacheSize sets the maximum number of cached statements
+func WithStmtCacheSize(size int) StmtCacheOption {
+	return func(c *StmtCache) {
+		c.size = size
+	}
+}
+
+// NewStmtCache creates a new statement cache of db
+func NewStmtCache(db Preparer, opts ...StmtCacheOption) *StmtCache {
+	c := &StmtCache{
+		db:    db,
+		size:  DefaultStmtCacheSize,
+		lru:   list.New(),
+		stmts: make(map[string]*list.Element),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// QueryRowContext implements DB
+func (c *StmtCache) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	stmt, err := c.stmt(ctx, query)
+	if err != nil {
+		// *sql.Row can't carry an error built outside database/sql, run the query unprepared instead
+		return c.db.QueryRowContext(ctx, query, args...)
+	}
+	return stmt.QueryRowContext(ctx, args...)
+}
+
+// QueryContext implements DB
+func (c *StmtCache) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	stmt, err := c.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.QueryContext(ctx, args...)
+}
+
+// ExecContext implements DB
+func (c *StmtCache) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	stmt, err := c.stmt(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	return stmt.ExecContext(ctx, args...)
+}
+
+// Len returns the number of cached statements
+func (c *StmtCache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.lru.Len()
+}
+
+// Close closes all cached statements, the cache can't be used afterwards
+func (c *StmtCache) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.closed = true
+	var errs []error
+	for e := c.lru.Front(); e != nil; e = e.Next() {
+		errs = append(errs, e.Value.(*cachedStmt).stmt.Close())
+	}
+	c.lru.Init()
+	clear(c.stmts)
+	return errors.Join(errs...)
+}
+
+// ErrStmtCacheClosed is returned when a closed StmtCache is used
+var ErrStmtCacheClosed = errors.New("statement cache is closed")
+
+func (c *StmtCache) stmt(ctx context.Context, query string) (*sql.Stmt, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.closed {
+		return nil, ErrStmtCacheClosed
+	}
+	if e, ok := c.stmts[query]; ok {
+		c.lru.MoveToFront(e)
+		return e.Value.(*cachedStmt).stmt, nil
+	}
+
+	stmt, err := c.db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	c.stmts[query] = c.lru.PushFront(&cachedStmt{query: query, stmt: stmt})
+
+	for c.size > 0 && c.lru.Len() > c.size {
+		oldest := c.lru.Back()
+		c.lru.Remove(oldest)
+		evicted := oldest.Value.(*cachedStmt)
+		delete(c.stmts, evicted.query)
+		// statements in use are closed by database/sql once their rows are closed
+		_ = evicted.stmt.Close()
+	}
+	return stmt, nil
+}
@@ -0,0 +1,95 @@
+package dbx
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Subquery is a compiled query embedded into another one, e.g. *CompiledSelectQuery.
+// Its placeholders are renumbered when the outer query is compiled, args of both
+// are bound in placeholder order, e.g. outer WHERE args before the subquery args after them.
+type Subquery interface {
+	subquerySQL() (template string, params []string, spread map[int]struct{}, consts map[int]interface{})
+}
+
+func (cq *CompiledSelectQuery[R]) subquerySQL() (string, []string, map[int]struct{}, map[int]interface{}) {
+	return cq.ph.template, cq.params, cq.ph.spread, cq.consts
+}
+
+// writeSubquery writes sub into b registering its placeholders as params of b
+func writeSubquery(b *queryBuilder, sub Subquery) {
+	tmpl, params, spread, consts := sub.subquerySQL()
+	b.writeString(mapPlaceholders(tmpl, func(idx int) string {
+		if v, ok := consts[idx]; ok {
+			return b.constParam(v)
+		}
+		if _, ok := spread[idx]; ok {
+			return b.spreadParam(params[idx])
+		}
+		return b.param(params[idx])
+	}))
+}
+
+// mapPlaceholders replaces $n placeholders outside quoted text with fn(n-1)
+func mapPlaceholders(query string, fn func(idx int) string) string {
+	var sb strings.Builder
+	sb.Grow(len(query))
+	var quote byte
+	for i := 0; i < len(query); i++ {
+		c := query[i]
+		switch {
+		case quote != 0:
+			if c == quote {
+				quote = 0
+			}
+		case c == '\'' || c == '"' || c == '`':
+			quote = c
+		case c == '$' && i+1 < len(query) && isDigit(query[i+1]):
+			j := i + 1
+			for j < len(query) && isDigit(query[j]) {
+				j++
+			}
+			idx, _ := strconv.Atoi(query[i+1 : j])
+			sb.WriteString(fn(idx - 1))
+			i = j - 1
+			continue
+		}
+		sb.WriteByte(c)
+	}
+	return sb.String()
+}
+
+type subqueryCond struct {
+	col string
+	op  string
+	sub Subquery
+}
+
+func (c subqueryCond) appendSQL(b *queryBuilder) {
+	if c.col != "" {
+		b.writeString(b.ident(c.col) + " ")
+	}
+	b.writeString(c.op + " (")
+	writeSubquery(b, c.sub)
+	b.writeString(")")
+}
+
+// InSubquery is col IN (SELECT ...), e.g.
+//
+//	active := dbx.Select[userID]("orders").Where(dbx.Gte("created_at")).Compile()
+//	dbx.Select[User]("users").Where(dbx.InSubquery("id", active))
+func InSubquery(col string, sub Subquery) Condition {
+	return subqueryCond{col: col, op: "IN", sub: sub}
+}
+
+// NotInSubquery is col NOT IN (SELECT ...), see InSubquery
+func NotInSubquery(col string, sub Subquery) Condition {
+	return subqueryCond{col: col, op: "NOT IN", sub: sub}
+}
+
+// ExistsSubquery is EXISTS (SELECT ...), correlate it with the outer query using Raw,
+// e.g. Raw("orders.user_id = users.id")
+func ExistsSubquery(sub Subquery) Condition { return subqueryCond{op: "EXISTS", sub: sub} }
+
+// NotExistsSubquery is NOT EXISTS (SELECT ...), see ExistsSubquery
+func NotExistsSubquery(sub Subquery) Condition { return subqueryCond{op: "NOT EXISTS", sub: sub} }
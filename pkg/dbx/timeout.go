@@ -0,0 +1,59 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// WithTimeout runs fn with a context deadline of timeout, e.g.
+//
+//	users, err := dbx.WithTimeout(ctx, db, 2*time.Second, q.New(age).ExecContext)
+//
+// Executable queries read all rows before returning, so the deadline covers scanning as well.
+func WithTimeout[T any](ctx context.Context, db DB, timeout time.Duration, fn func(ctx context.Context, db DB) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	return fn(ctx, db)
+}
+
+// WithPostgresTimeout is WithTimeout also setting Postgres statement_timeout, so the server
+// stops the query even if the client is gone. On *sql.DB fn runs on a dedicated connection
+// and statement_timeout is reset afterwards. On *sql.Tx it's set with SET LOCAL and lasts
+// until the end of the transaction. Other DBs get only the deadline.
+func WithPostgresTimeout[T any](ctx context.Context, db DB, timeout time.Duration, fn func(ctx context.Context, db DB) (T, error)) (T, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	var zero T
+	ms := timeout.Milliseconds()
+	switch db := db.(type) {
+	case *sql.Tx:
+		if _, err := db.ExecContext(ctx, fmt.Sprintf("SET LOCAL statement_timeout = %d", ms)); err != nil {
+			return zero, fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+		return fn(ctx, db)
+	case *sql.DB:
+		conn, err := db.Conn(ctx)
+		if err != nil {
+			return zero, err
+		}
+		defer conn.Close()
+
+		if _, err := conn.ExecContext(ctx, fmt.Sprintf("SET statement_timeout = %d", ms)); err != nil {
+			return zero, fmt.Errorf("failed to set statement_timeout: %w", err)
+		}
+		res, err := fn(ctx, conn)
+		// ctx may be done already, the reset must run anyway
+		if _, resetErr := conn.ExecContext(context.WithoutCancel(ctx), "RESET statement_timeout"); resetErr != nil {
+			// the connection keeps the timeout, so it's discarded instead of returning to the pool
+			_ = conn.Raw(func(any) error { return driver.ErrBadConn })
+			return res, errors.Join(err, fmt.Errorf("failed to reset statement_timeout: %w", resetErr))
+		}
+		return res, err
+	}
+	return fn(ctx, db)
+}
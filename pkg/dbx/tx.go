@@ -0,0 +1,118 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync/atomic"
+)
+
+// TxOption configures transactions started by WithTx
+type TxOption func(*sql.TxOptions)
+
+// WithIsolation sets transaction isolation level
+func WithIsolation(level sql.IsolationLevel) TxOption {
+	return func(o *sql.TxOptions) {
+		o.Isolation = level
+	}
+}
+
+// ReadOnly starts a read-only transaction
+func ReadOnly() TxOption {
+	return func(o *sql.TxOptions) {
+		o.ReadOnly = true
+	}
+}
+
+// TxBeginner starts transactions, it's implemented by *sql.DB and *sql.Conn
+type TxBeginner interface {
+	BeginTx(ctx context.Context, opts *sql.TxOptions) (*sql.Tx, error)
+}
+
+// savepoints numbers savepoints of nested WithTx calls
+var savepoints atomic.Uint64
+
+// WithTx runs fn in a transaction. The transaction is committed if fn returns nil
+// and rolled back if fn returns an error or panics, panics are re-raised after rollback.
+// db is a TxBeginner, e.g. *sql.DB, or the *sql.Tx of an outer WithTx. Nested calls run fn in a SAVEPOINT
+// and roll back only to it on failure, so the outer fn decides whether to go on, e.g.
+//
+//	dbx.WithTx(ctx, db, func(tx dbx.DB) error {
+//		if err := orders.Create(ctx, tx, order); err != nil { // calls WithTx(ctx, tx, ...) inside
+//			return err
+//		}
+//		return stock.Reserve(ctx, tx, order.Items)
+//	})
+//
+// opts of nested calls are ignored, the outer transaction options apply.
+func WithTx(ctx context.Context, db DB, fn func(tx DB) error, opts ...TxOption) error {
+	switch db := db.(type) {
+	case *sql.Tx:
+		return withSavepoint(ctx, db, fn)
+	case TxBeginner:
+		return withTx(ctx, db, fn, opts)
+	default:
+		return fmt.Errorf("dbx: %T can't begin transactions", db)
+	}
+}
+
+func withTx(ctx context.Context, db TxBeginner, fn func(tx DB) error, opts []TxOption) error {
+	var txOpts sql.TxOptions
+	for _, opt := range opts {
+		opt(&txOpts)
+	}
+
+	tx, err := db.BeginTx(ctx, &txOpts)
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.Rollback()
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if rbErr := tx.Rollback(); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback transaction: %w", rbErr))
+		}
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+	return nil
+}
+
+// withSavepoint runs fn in a savepoint of tx. Savepoint names are unique,
+// so savepoints of sibling and nested calls never shadow each other.
+func withSavepoint(ctx context.Context, tx *sql.Tx, fn func(tx DB) error) error {
+	name := "dbx_sp_" + strconv.FormatUint(savepoints.Add(1), 10)
+	if _, err := tx.ExecContext(ctx, "SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to create savepoint: %w", err)
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			_, _ = tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		if _, rbErr := tx.ExecContext(ctx, "ROLLBACK TO SAVEPOINT "+name); rbErr != nil {
+			return errors.Join(err, fmt.Errorf("failed to rollback to savepoint: %w", rbErr))
+		}
+		return err
+	}
+
+	if _, err := tx.ExecContext(ctx, "RELEASE SAVEPOINT "+name); err != nil {
+		return fmt.Errorf("failed to release savepoint: %w", err)
+	}
+	return nil
+}
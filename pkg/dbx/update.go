@@ -0,0 +1,279 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+	"unsafe"
+)
+
+// UpdateBuilder represents an update query builder
+type UpdateBuilder[T any] struct {
+	table       string
+	inputType   reflect.Type
+	inputFields []fieldInfo
+	where       []Condition
+	dialect     Dialect
+	naming      NamingStrategy
+	setExprs    []setExpr
+}
+
+// setExpr is a SET column assigned an expression rather than a field of T
+type setExpr struct {
+	col  string
+	expr Expression
+}
+
+// CompiledUpdateQuery represents a compiled update query
+type CompiledUpdateQuery[T any] struct {
+	updateShape
+
+	table       string
+	where       []Condition
+	dialect     Dialect
+	inputType   reflect.Type
+	inputFields []fieldInfo
+	setFields   []fieldInfo
+	setExprs    []setExpr
+	// partial caches shapes of partial updates by their SET columns
+	partial sync.Map // string -> *updateShape
+}
+
+// updateShape is an update query with a particular set of SET columns
+type updateShape struct {
+	query     string
+	argFields []fieldInfo
+	ph        placeholders
+}
+
+// ExecutableUpdateQuery represents an update query ready for execution
+type ExecutableUpdateQuery[T any] struct {
+	query string
+	args  []interface{}
+	err   error
+}
+
+// ErrNothingToUpdate is returned by partial updates without SET columns
+var ErrNothingToUpdate = errors.New("nothing to update")
+
+// Update creates a new update query builder, SET columns are the db tagged fields of T
+func Update[T any](table string) *UpdateBuilder[T] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+	return &UpdateBuilder[T]{
+		table:       table,
+		inputType:   inputType,
+		inputFields: extractFields(inputType),
+	}
+}
+
+// Where adds conditions joined with AND. Placeholders are bound from the fields of T
+// with the same column name, such columns are left out of SET.
+func (ub *UpdateBuilder[T]) Where(conds ...Condition) *UpdateBuilder[T] {
+	ub.where = append(ub.where, conds...)
+	return ub
+}
+
+// WherePK adds conditions on the primary key columns of T, see Where.
+// Columns are the fields tagged with the pk option, e.g. `db:"user_id,pk"`, it panics if there are none.
+func (ub *UpdateBuilder[T]) WherePK() *UpdateBuilder[T] {
+	return ub.Where(primaryKeyConds(ub.inputType, ub.inputFields)...)
+}
+
+// Set assigns expr to col instead of the field of T with the same column name, e.g.
+// Set("version", Expr("version + 1")) or Set("updated_at", Expr("CURRENT_TIMESTAMP")).
+// Expression columns are updated by partial updates as well.
+func (ub *UpdateBuilder[T]) Set(col string, expr Expression) *UpdateBuilder[T] {
+	ub.setExprs = append(ub.setExprs, setExpr{col: col, expr: expr})
+	return ub
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (ub *UpdateBuilder[T]) Dialect(d Dialect) *UpdateBuilder[T] {
+	ub.dialect = d
+	return ub
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (ub *UpdateBuilder[T]) Naming(naming NamingStrategy) *UpdateBuilder[T] {
+	ub.naming = naming
+	ub.inputFields = extractNamedFields(ub.inputType, naming)
+	return ub
+}
+
+// Compile compiles the update query into a reusable form.
+// It panics if a WHERE column is not a field of T.
+func (ub *UpdateBuilder[T]) Compile() *CompiledUpdateQuery[T] {
+	where := &queryBuilder{dialect: ub.dialect}
+	appendWhere(where, ub.where)
+
+	keyCols := make(map[string]struct{}, len(where.params)+len(ub.setExprs))
+	for _, col := range where.params {
+		keyCols[col] = struct{}{}
+	}
+	// expression columns are not SET from fields
+	for _, set := range ub.setExprs {
+		keyCols[set.col] = struct{}{}
+	}
+
+	var setFields []fieldInfo
+	for _, field := range ub.inputFields {
+		if _, isKey := keyCols[field.DbName]; field.IsAuto || isKey {
+			continue
+		}
+		setFields = append(setFields, field)
+	}
+
+	cq := &CompiledUpdateQuery[T]{
+		table:       ub.table,
+		where:       slices.Clone(ub.where),
+		dialect:     ub.dialect,
+		inputType:   ub.inputType,
+		inputFields: ub.inputFields,
+		setFields:   setFields,
+		setExprs:    slices.Clone(ub.setExprs),
+	}
+	cq.updateShape = cq.shape(setFields)
+	return cq
+}
+
+func (cq *CompiledUpdateQuery[T]) shape(setFields []fieldInfo) updateShape {
+	b := &queryBuilder{dialect: cq.dialect}
+	b.writeString(fmt.Sprintf("UPDATE %s SET ", cq.dialect.mustTable(cq.table)))
+	for i, field := range setFields {
+		if i > 0 {
+			b.writeString(", ")
+		}
+		b.writeString(cq.dialect.mustIdent(field.DbName) + " = " + b.param(field.DbName))
+	}
+	for i, set := range cq.setExprs {
+		if i > 0 || len(setFields) > 0 {
+			b.writeString(", ")
+		}
+		b.writeString(cq.dialect.mustIdent(set.col) + " = ")
+		set.expr.appendSQL(b)
+	}
+	appendWhere(b, cq.where)
+
+	return updateShape{
+		query:     cq.dialect.Rebind(b.String()),
+		argFields: b.resolve(cq.inputType, cq.inputFields),
+		ph:        newPlaceholders(b, cq.dialect),
+	}
+}
+
+// New creates a new executable query with the given input
+func (cq *CompiledUpdateQuery[T]) New(input T) *ExecutableUpdateQuery[T] {
+	query, args := cq.PreviewQuery(input)
+	return &ExecutableUpdateQuery[T]{query: query, args: args}
+}
+
+func (cq *CompiledUpdateQuery[T]) PreviewQuery(input T) (string, []any) {
+	return bindShape(&cq.updateShape, &input)
+}
+
+// NewPartial creates a new executable query updating only the given columns, e.g. from a PATCH field mask.
+// Unknown columns and columns of WHERE conditions are reported by ExecContext.
+func (cq *CompiledUpdateQuery[T]) NewPartial(input T, cols ...string) *ExecutableUpdateQuery[T] {
+	setFields := make([]fieldInfo, 0, len(cols))
+	for _, col := range cols {
+		field, ok := fieldByColumn(cq.setFields, col)
+		if !ok {
+			return &ExecutableUpdateQuery[T]{err: fmt.Errorf("column %q can't be updated", col)}
+		}
+		setFields = append(setFields, field)
+	}
+	return cq.newShaped(&input, setFields)
+}
+
+// NewNonZero creates a new executable query updating only the fields of input that are not zero values
+func (cq *CompiledUpdateQuery[T]) NewNonZero(input T) *ExecutableUpdateQuery[T] {
+	v := reflect.ValueOf(input)
+	setFields := make([]fieldInfo, 0, len(cq.setFields))
+	for _, field := range cq.setFields {
+		fv, err := v.FieldByIndexErr(field.Index)
+		if err != nil || fv.IsZero() {
+			continue
+		}
+		setFields = append(setFields, field)
+	}
+	return cq.newShaped(&input, setFields)
+}
+
+func (cq *CompiledUpdateQuery[T]) newShaped(input *T, setFields []fieldInfo) *ExecutableUpdateQuery[T] {
+	if len(setFields) == 0 && len(cq.setExprs) == 0 {
+		return &ExecutableUpdateQuery[T]{err: ErrNothingToUpdate}
+	}
+
+	key := make([]string, len(setFields))
+	for i, field := range setFields {
+		key[i] = field.DbName
+	}
+	cached, ok := cq.partial.Load(strings.Join(key, ","))
+	if !ok {
+		shape := cq.shape(setFields)
+		cached, _ = cq.partial.LoadOrStore(strings.Join(key, ","), &shape)
+	}
+
+	query, args := bindShape(cached.(*updateShape), input)
+	return &ExecutableUpdateQuery[T]{query: query, args: args}
+}
+
+func bindShape[T any](s *updateShape, input *T) (string, []any) {
+	return s.ph.bind(s.query, extractFieldArgs(input, s.argFields))
+}
+
+// ExecContext executes the query and returns the number of updated rows
+func (eq *ExecutableUpdateQuery[T]) ExecContext(ctx context.Context, db DB) (int64, error) {
+	if eq.err != nil {
+		return 0, eq.err
+	}
+	res, err := db.ExecContext(ctx, eq.query, eq.args...)
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// resolveParams maps placeholder columns to the fields of t
+func resolveParams(t reflect.Type, fields []fieldInfo, params []string) []fieldInfo {
+	res := make([]fieldInfo, 0, len(params))
+	for _, col := range params {
+		field, ok := fieldByColumn(fields, col)
+		if !ok {
+			panic(fmt.Sprintf("dbx: column %q is not a field of %s", col, t))
+		}
+		res = append(res, field)
+	}
+	return res
+}
+
+func fieldByColumn(fields []fieldInfo, col string) (fieldInfo, bool) {
+	for _, field := range fields {
+		if field.DbName == col {
+			return field, true
+		}
+	}
+	return fieldInfo{}, false
+}
+
+// extractFieldArgs extracts values of the given fields, unlike extractArgs it keeps auto fields
+func extractFieldArgs[T any](input *T, fields []fieldInfo) []interface{} {
+	p := unsafe.Pointer(input)
+	var v reflect.Value
+	args := make([]interface{}, 0, len(fields))
+	for _, field := range fields {
+		if field.get != nil {
+			args = append(args, field.get(p))
+			continue
+		}
+		if !v.IsValid() {
+			v = reflect.ValueOf(input).Elem()
+		}
+		args = append(args, fieldArg(v, field))
+	}
+	return args
+}
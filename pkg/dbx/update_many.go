@@ -0,0 +1,329 @@
+package dbx
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// UpdateManyBuilder represents a bulk update query builder
+type UpdateManyBuilder[T any] struct {
+	table       string
+	inputType   reflect.Type
+	inputFields []fieldInfo
+	key         []string
+	dialect     Dialect
+	naming      NamingStrategy
+}
+
+// UpdateManyReturningBuilder represents a bulk update query builder with returning clause
+type UpdateManyReturningBuilder[T, R any] struct {
+	update          *UpdateManyBuilder[T]
+	returningFields []fieldInfo
+}
+
+// CompiledUpdateManyQuery represents a compiled bulk update query
+type CompiledUpdateManyQuery[T, R any] struct {
+	table     string
+	dialect   Dialect
+	key       []fieldInfo
+	setFields []fieldInfo
+	// single updates one row at a time on dialects without UPDATE ... FROM
+	single          *CompiledUpdateQuery[T]
+	returningFields []fieldInfo
+}
+
+// ExecutableUpdateManyQuery represents a bulk update query ready for execution
+type ExecutableUpdateManyQuery[T, R any] struct {
+	compiled *CompiledUpdateManyQuery[T, R]
+	query    string
+	args     []interface{}
+	inputs   []T
+}
+
+// UpdateMany creates a new bulk update query builder, rows are matched by key columns
+// and the rest of the db tagged fields of T are SET.
+func UpdateMany[T any](table string) *UpdateManyBuilder[T] {
+	inputType := reflect.TypeOf((*T)(nil)).Elem()
+	return &UpdateManyBuilder[T]{
+		table:       table,
+		inputType:   inputType,
+		inputFields: extractFields(inputType),
+	}
+}
+
+// Key sets the columns rows are matched by. By default they are the fields tagged
+// with the pk option, e.g. `db:"user_id,pk"`, or "id" if there are none.
+func (ub *UpdateManyBuilder[T]) Key(cols ...string) *UpdateManyBuilder[T] {
+	ub.key = cols
+	return ub
+}
+
+// Dialect sets placeholder syntax of the generated SQL
+func (ub *UpdateManyBuilder[T]) Dialect(d Dialect) *UpdateManyBuilder[T] {
+	ub.dialect = d
+	return ub
+}
+
+// Naming maps fields without db tag to columns named by naming, e.g. Naming(SnakeCase)
+func (ub *UpdateManyBuilder[T]) Naming(naming NamingStrategy) *UpdateManyBuilder[T] {
+	ub.naming = naming
+	ub.inputFields = extractNamedFields(ub.inputType, naming)
+	return ub
+}
+
+// UpdateManyReturning adds a returning clause to the bulk update query, updated rows are
+// returned in input order. Only Postgres and SQLite support it, Compile panics on other dialects.
+func UpdateManyReturning[T, R any](ub *UpdateManyBuilder[T]) *UpdateManyReturningBuilder[T, R] {
+	return &UpdateManyReturningBuilder[T, R]{
+		update:          ub,
+		returningFields: extractNamedFields(reflect.TypeOf((*R)(nil)).Elem(), ub.naming),
+	}
+}
+
+// Compile compiles the bulk update query into a reusable form.
+// It panics if a key column is not a field of T.
+func (ub *UpdateManyBuilder[T]) Compile() *CompiledUpdateManyQuery[T, struct{}] {
+	return compileUpdateMany[T, struct{}](ub, nil)
+}
+
+// Compile compiles the bulk update with returning query into a reusable form.
+// It panics if a key column is not a field of T or the dialect has no UPDATE ... FROM.
+func (urb *UpdateManyReturningBuilder[T, R]) Compile() *CompiledUpdateManyQuery[T, R] {
+	cq := compileUpdateMany[T, R](urb.update, urb.returningFields)
+	if !cq.updatesFrom() {
+		panic("dbx: bulk update with returning clause requires Postgres or SQLite")
+	}
+	return cq
+}
+
+func compileUpdateMany[T, R any](ub *UpdateManyBuilder[T], returningFields []fieldInfo) *CompiledUpdateManyQuery[T, R] {
+	keyCols := ub.key
+	if keyCols == nil {
+		keyCols = primaryKeyColumns(ub.inputFields)
+	}
+	if keyCols == nil {
+		keyCols = []string{"id"}
+	}
+	key := resolveParams(ub.inputType, ub.inputFields, keyCols)
+
+	where := make([]Condition, len(keyCols))
+	for i, col := range keyCols {
+		where[i] = Eq(col)
+	}
+	single := Update[T](ub.table).Dialect(ub.dialect)
+	if ub.naming != nil {
+		single.Naming(ub.naming)
+	}
+
+	cq := &CompiledUpdateManyQuery[T, R]{
+		table:           ub.table,
+		dialect:         ub.dialect,
+		key:             key,
+		single:          single.Where(where...).Compile(),
+		returningFields: returningFields,
+	}
+	cq.setFields = cq.single.setFields
+	return cq
+}
+
+// New creates a new executable query updating all inputs. Postgres and SQLite update them
+// with a single UPDATE ... FROM statement, other dialects run an UPDATE per input,
+// so wrap it into a transaction with WithTx to keep it atomic.
+func (cq *CompiledUpdateManyQuery[T, R]) New(inputs []T) *ExecutableUpdateManyQuery[T, R] {
+	if !cq.updatesFrom() {
+		return &ExecutableUpdateManyQuery[T, R]{compiled: cq, inputs: inputs}
+	}
+
+	argFields := append(append([]fieldInfo(nil), cq.setFields...), cq.key...)
+	var args []interface{}
+	for i := range inputs {
+		args = append(args, extractFieldArgs(&inputs[i], argFields)...)
+	}
+	return &ExecutableUpdateManyQuery[T, R]{
+		compiled: cq,
+		query:    cq.dialect.Rebind(cq.buildQuery(len(inputs))),
+		args:     args,
+		inputs:   inputs,
+	}
+}
+
+// PreviewQuery returns the bulk statement, or the statement run per input on dialects without UPDATE ... FROM
+func (cq *CompiledUpdateManyQuery[T, R]) PreviewQuery(inputs []T) (string, []any) {
+	if !cq.updatesFrom() {
+		if len(inputs) == 0 {
+			return cq.single.query, nil
+		}
+		return cq.single.PreviewQuery(inputs[0])
+	}
+	eq := cq.New(inputs)
+	return eq.query, eq.args
+}
+
+func (cq *CompiledUpdateManyQuery[T, R]) updatesFrom() bool {
+	return cq.dialect == Postgres || cq.dialect == SQLite
+}
+
+// buildQuery builds UPDATE ... FROM a VALUES list of n rows. VALUES is appended to an empty
+// SELECT of the table, so placeholders get the types of the columns they are assigned to.
+// With a returning clause the rows are a CTE with input indexes, returned after the columns of R.
+func (cq *CompiledUpdateManyQuery[T, R]) buildQuery(n int) string {
+	table := cq.dialect.mustTable(cq.table)
+	tableParts := strings.Fields(table)
+	qualifier := tableParts[len(tableParts)-1]
+
+	cols := append(append([]fieldInfo(nil), cq.setFields...), cq.key...)
+	sets := make([]string, len(cq.setFields))
+	for i, field := range cq.setFields {
+		col := cq.dialect.mustIdent(field.DbName)
+		sets[i] = fmt.Sprintf("%s = v.%s", col, col)
+	}
+	matches := make([]string, len(cq.key))
+	for i, field := range cq.key {
+		col := cq.dialect.mustIdent(field.DbName)
+		matches[i] = fmt.Sprintf("%s.%s = v.%s", qualifier, col, col)
+	}
+
+	returning := cq.returningFields != nil
+	values := make([]string, n)
+	placeholderCount := 0
+	for i := range values {
+		row := make([]string, len(cols))
+		for j := range cols {
+			placeholderCount++
+			row[j] = fmt.Sprintf("$%d", placeholderCount)
+		}
+		if returning {
+			row = append(row, strconv.Itoa(i))
+		}
+		values[i] = "(" + strings.Join(row, ", ") + ")"
+	}
+
+	colList := cq.dialect.columnList(cols)
+	if !returning {
+		return fmt.Sprintf("UPDATE %s SET %s FROM (SELECT %s FROM %s WHERE 1 = 0 UNION ALL VALUES %s) AS v WHERE %s",
+			table,
+			strings.Join(sets, ", "),
+			colList,
+			tableParts[0],
+			strings.Join(values, ", "),
+			strings.Join(matches, " AND "))
+	}
+
+	// columns of v have the same names, so returned ones are qualified
+	ret := make([]string, 0, len(cq.returningFields)+1)
+	for _, field := range cq.returningFields {
+		ret = append(ret, qualifier+"."+cq.dialect.mustIdent(field.DbName))
+	}
+	if cq.dialect == SQLite {
+		// SQLite RETURNING can't reference FROM tables, but can query the CTE
+		ret = append(ret, fmt.Sprintf("(SELECT v.%s FROM v WHERE %s)", updateManyIndex, strings.Join(matches, " AND ")))
+	} else {
+		ret = append(ret, "v."+updateManyIndex)
+	}
+	return fmt.Sprintf("WITH v AS (SELECT %s, 0 AS %s FROM %s WHERE 1 = 0 UNION ALL VALUES %s) UPDATE %s SET %s FROM v WHERE %s RETURNING %s",
+		colList,
+		updateManyIndex,
+		tableParts[0],
+		strings.Join(values, ", "),
+		table,
+		strings.Join(sets, ", "),
+		strings.Join(matches, " AND "),
+		strings.Join(ret, ", "))
+}
+
+// updateManyIndex is the column of input indexes in the VALUES list
+const updateManyIndex = "dbx_index"
+
+// ExecContext executes the query and returns the number of updated rows
+func (eq *ExecutableUpdateManyQuery[T, R]) ExecContext(ctx context.Context, db DB) (int64, error) {
+	if err := eq.check(); err != nil {
+		return 0, err
+	}
+	if eq.compiled.returningFields != nil {
+		rows, err := eq.QueryContext(ctx, db)
+		return int64(len(rows)), err
+	}
+
+	if eq.query != "" {
+		res, err := db.ExecContext(ctx, eq.query, eq.args...)
+		if err != nil {
+			return 0, err
+		}
+		return res.RowsAffected()
+	}
+
+	var total int64
+	for _, input := range eq.inputs {
+		n, err := eq.compiled.single.New(input).ExecContext(ctx, db)
+		if err != nil {
+			return total, err
+		}
+		total += n
+	}
+	return total, nil
+}
+
+// QueryContext executes the query and scans the updated rows in input order, it requires a returning clause.
+// Inputs matching no row are left out.
+func (eq *ExecutableUpdateManyQuery[T, R]) QueryContext(ctx context.Context, db DB) ([]R, error) {
+	if eq.compiled.returningFields == nil {
+		return nil, fmt.Errorf("query has no returning clause: %s", eq.query)
+	}
+	if err := eq.check(); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.QueryContext(ctx, eq.query, eq.args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	type indexed struct {
+		index int
+		row   R
+	}
+	var updated []indexed
+	for rows.Next() {
+		var r indexed
+		if err := scanRow(indexScanner{rows, &r.index}, &r.row, eq.compiled.returningFields); err != nil {
+			return nil, err
+		}
+		updated = append(updated, r)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	slices.SortStableFunc(updated, func(a, b indexed) int { return a.index - b.index })
+	result := make([]R, len(updated))
+	for i, r := range updated {
+		result[i] = r.row
+	}
+	return result, nil
+}
+
+func (eq *ExecutableUpdateManyQuery[T, R]) check() error {
+	if len(eq.inputs) == 0 {
+		return errors.New("bulk update requires at least one input")
+	}
+	if len(eq.compiled.setFields) == 0 {
+		return ErrNothingToUpdate
+	}
+	return nil
+}
+
+// indexScanner scans the last column into index
+type indexScanner struct {
+	row   scanner
+	index *int
+}
+
+func (s indexScanner) Scan(dest ...interface{}) error {
+	return s.row.Scan(append(dest, s.index)...)
+}
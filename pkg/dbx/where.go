@@ -0,0 +1,178 @@
+package dbx
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Condition is a WHERE clause condition.
+// Every placeholder of a condition references a column, builders bind it either
+// from the positional args of New or from the same-named field of the input struct.
+type Condition interface {
+	appendSQL(b *queryBuilder)
+}
+
+type queryBuilder struct {
+	sb      strings.Builder
+	dialect Dialect
+	params  []string
+	// spread holds indexes of params bound to slices, see In
+	spread map[int]struct{}
+	// consts holds values of params bound at Compile time, see Expr
+	consts map[int]interface{}
+}
+
+// param registers a placeholder bound to col and returns its text
+func (b *queryBuilder) param(col string) string {
+	b.params = append(b.params, col)
+	return fmt.Sprintf("$%d", len(b.params))
+}
+
+// spreadParam registers a placeholder bound to a slice of col values
+func (b *queryBuilder) spreadParam(col string) string {
+	p := b.param(col)
+	if b.spread == nil {
+		b.spread = make(map[int]struct{})
+	}
+	b.spread[len(b.params)-1] = struct{}{}
+	return p
+}
+
+// constParam registers a placeholder bound to v rather than to a column
+func (b *queryBuilder) constParam(v interface{}) string {
+	p := b.param("")
+	if b.consts == nil {
+		b.consts = make(map[int]interface{})
+	}
+	b.consts[len(b.params)-1] = v
+	return p
+}
+
+// resolve maps params to the fields of t, params of Expr args get fields returning their values
+func (b *queryBuilder) resolve(t reflect.Type, fields []fieldInfo) []fieldInfo {
+	res := make([]fieldInfo, 0, len(b.params))
+	for i, col := range b.params {
+		if v, ok := b.consts[i]; ok {
+			res = append(res, constField(v))
+			continue
+		}
+		res = append(res, resolveParams(t, fields, []string{col})...)
+	}
+	return res
+}
+
+// ident validates and quotes an identifier, it panics on invalid ones
+func (b *queryBuilder) ident(name string) string {
+	return b.dialect.mustIdent(name)
+}
+
+func (b *queryBuilder) writeString(s string) {
+	b.sb.WriteString(s)
+}
+
+func (b *queryBuilder) String() string {
+	return b.sb.String()
+}
+
+type compareCond struct {
+	col string
+	op  string
+}
+
+func (c compareCond) appendSQL(b *queryBuilder) {
+	b.writeString(b.ident(c.col) + " " + c.op + " " + b.param(c.col))
+}
+
+// Eq is col = ?
+func Eq(col string) Condition { return compareCond{col: col, op: "="} }
+
+type columnsCond struct {
+	left  string
+	right string
+}
+
+func (c columnsCond) appendSQL(b *queryBuilder) {
+	b.writeString(b.ident(c.left) + " = " + b.ident(c.right))
+}
+
+// On is left = right comparing two columns, e.g. a join condition On("u.id", "orders.user_id")
+func On(left, right string) Condition { return columnsCond{left: left, right: right} }
+
+// Ne is col <> ?
+func Ne(col string) Condition { return compareCond{col: col, op: "<>"} }
+
+// Gt is col > ?
+func Gt(col string) Condition { return compareCond{col: col, op: ">"} }
+
+// Gte is col >= ?
+func Gte(col string) Condition { return compareCond{col: col, op: ">="} }
+
+// Lt is col < ?
+func Lt(col string) Condition { return compareCond{col: col, op: "<"} }
+
+// Lte is col <= ?
+func Lte(col string) Condition { return compareCond{col: col, op: "<="} }
+
+// Like is col LIKE ?
+func Like(col string) Condition { return compareCond{col: col, op: "LIKE"} }
+
+type nullCond struct {
+	col string
+	not bool
+}
+
+func (c nullCond) appendSQL(b *queryBuilder) {
+	if c.not {
+		b.writeString(b.ident(c.col) + " IS NOT NULL")
+		return
+	}
+	b.writeString(b.ident(c.col) + " IS NULL")
+}
+
+// IsNull is col IS NULL
+func IsNull(col string) Condition { return nullCond{col: col} }
+
+// IsNotNull is col IS NOT NULL
+func IsNotNull(col string) Condition { return nullCond{col: col, not: true} }
+
+type groupCond struct {
+	op    string
+	conds []Condition
+}
+
+func (c groupCond) appendSQL(b *queryBuilder) {
+	b.writeString("(")
+	for i, cond := range c.conds {
+		if i > 0 {
+			b.writeString(" " + c.op + " ")
+		}
+		cond.appendSQL(b)
+	}
+	b.writeString(")")
+}
+
+// And joins conditions with AND
+func And(conds ...Condition) Condition { return groupCond{op: "AND", conds: conds} }
+
+// Or joins conditions with OR
+func Or(conds ...Condition) Condition { return groupCond{op: "OR", conds: conds} }
+
+// appendWhere writes WHERE clause joining top-level conditions with AND
+func appendWhere(b *queryBuilder, conds []Condition) {
+	if len(conds) == 0 {
+		return
+	}
+	b.writeString(" WHERE ")
+	appendAnd(b, conds)
+}
+
+// appendAnd writes conditions joined with AND
+func appendAnd(b *queryBuilder, conds []Condition) {
+	for i, cond := range conds {
+		if i > 0 {
+			b.writeString(" AND ")
+		}
+		cond.appendSQL(b)
+	}
+}
@@ -0,0 +1,73 @@
+package httpio
+
+import (
+	"encoding/json"
+	"io"
+	"sync"
+)
+
+// Codec decodes request bodies and encodes response bodies of a media type
+type Codec interface {
+	Decode(r io.Reader, v interface{}) error
+	Encode(w io.Writer, v interface{}) error
+}
+
+// JSONDecoder decodes a stream of JSON values, it's implemented by json.Decoder and decoders of jsoniter and sonic
+type JSONDecoder interface {
+	Decode(v interface{}) error
+	UseNumber()
+	DisallowUnknownFields()
+}
+
+// JSONCodec is the codec of application/json, registered by default.
+// Register it again to tune decoding, e.g. RegisterCodec("application/json", JSONCodec{UseNumber: true}).
+type JSONCodec struct {
+	// DisallowUnknownFields fails decoding of objects with keys not matching fields, set in strict mode
+	DisallowUnknownFields bool
+	// UseNumber decodes numbers into interface{} values as json.Number instead of float64
+	UseNumber bool
+	// NewDecoder constructs decoders of an alternative library, encoding/json is used if nil
+	NewDecoder func(r io.Reader) JSONDecoder
+}
+
+func (c JSONCodec) Decode(r io.Reader, v interface{}) error {
+	var dec JSONDecoder
+	if c.NewDecoder != nil {
+		dec = c.NewDecoder(r)
+	} else {
+		dec = json.NewDecoder(r)
+	}
+	if c.DisallowUnknownFields {
+		dec.DisallowUnknownFields()
+	}
+	if c.UseNumber {
+		dec.UseNumber()
+	}
+	return dec.Decode(v)
+}
+
+func (JSONCodec) Encode(w io.Writer, v interface{}) error {
+	return json.NewEncoder(w).Encode(v)
+}
+
+// codecs maps media types to their Codec
+var codecs sync.Map // string -> Codec
+
+func init() {
+	RegisterCodec("application/json", JSONCodec{})
+}
+
+// RegisterCodec makes Unmarshal decode bodies of mediaType, e.g. application/msgpack, with codec.
+// It replaces the codec registered for the same media type, JSON included.
+func RegisterCodec(mediaType string, codec Codec) {
+	codecs.Store(mediaType, codec)
+}
+
+// CodecFor returns the codec registered for mediaType
+func CodecFor(mediaType string) (Codec, bool) {
+	codec, ok := codecs.Load(mediaType)
+	if !ok {
+		return nil, false
+	}
+	return codec.(Codec), true
+}
@@ -1,53 +1,271 @@
 package httpio
 
 import (
-	"encoding/json"
+	"bytes"
+	"encoding"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
 	"net/http"
 	"net/url"
 	"reflect"
+	"slices"
 	"strconv"
+	"strings"
 	"sync"
-	"unsafe"
+	"time"
 )
 
-const delimiter = '_'
+// delimiter joins names of nested groups, see SetDelimiter
+var delimiter byte = '_'
 
-var bytesPool = &sync.Pool{
-	New: func() interface{} {
-		buf := make([]byte, 0, 64)
-		return &buf
-	},
+// SetDelimiter joins names of nested groups with d, _ by default, e.g. name.first with '.'.
+// WithDelimiter overrides it per call. It is not thread-safe and should be called at the beginning of the program.
+func SetDelimiter(d byte) {
+	delimiter = d
 }
 
-func Unmarshal(r *http.Request, dest interface{}) error {
-	if r.Header.Get("Content-Type") == "application/json" {
-		// TODO: make json decoder configurable
-		if err := json.NewDecoder(r.Body).Decode(dest); err != nil {
-			return err
-		}
-	}
-
+// Unmarshal decodes the body and params of r into the struct dest points to.
+// Options override package-level settings for this call only.
+// It returns an error without reading the request for invalid struct definitions, e.g. a recursive group.
+func Unmarshal(r *http.Request, dest interface{}, opts ...Option) error {
 	v := reflect.ValueOf(dest)
 	if v.Kind() != reflect.Ptr || v.IsNil() {
 		return fmt.Errorf("destination must be a non-nil pointer")
 	}
 	v = v.Elem()
 
-	buf := bytesPool.Get().(*[]byte)
-	defer func() {
-		s := *buf
-		s = s[:0]
-		*buf = s // Copy the stack header with new capacity to the heap
-		bytesPool.Put(buf)
-	}()
-	return decode(&decodeIn{r: r}, v, *buf)
+	in := &decodeIn{r: r, opts: newOptions(opts)}
+	if in.opts.maxBodySize > 0 {
+		r.Body = http.MaxBytesReader(nil, r.Body, in.opts.maxBodySize)
+	}
+	keepRaw, err := hasRawBody(v.Type(), in.opts.delimiter)
+	if err != nil {
+		return err
+	}
+	if err := in.decodeBody(dest, keepRaw); err != nil {
+		var maxErr *http.MaxBytesError
+		if errors.As(err, &maxErr) {
+			return fmt.Errorf("%w: limit is %d bytes", ErrBodyTooLarge, maxErr.Limit)
+		}
+		return err
+	}
+
+	if err := decode(in, v); err != nil {
+		return err
+	}
+	if len(in.errs) == 1 {
+		return in.errs[0]
+	}
+	if len(in.errs) > 0 {
+		return in.errs
+	}
+	if len(in.missing) > 0 {
+		return &MissingParamsError{Params: in.missing}
+	}
+	if in.opts.strict {
+		if unknown := unknownParams(in, v.Type()); len(unknown) > 0 {
+			return &UnknownParamsError{Params: unknown}
+		}
+	}
+	return Validate(dest, opts...)
+}
+
+// DecodeError is a request value failing to parse into its field.
+// Unmarshal returns it for a single failure and DecodeErrors for several.
+type DecodeError struct {
+	// Field is the full parameter name, e.g. name_first
+	Field string `json:"field"`
+	// Source is one of query, path, header, cookie or form
+	Source string `json:"source"`
+	Value  string `json:"value"`
+	Err    error  `json:"-"`
+}
+
+func (e *DecodeError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrors lists every value failing to parse, errors.As finds the first DecodeError in it
+type DecodeErrors []*DecodeError
+
+func (e DecodeErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return strings.Join(msgs, "; ")
+}
+
+func (e DecodeErrors) Unwrap() []error {
+	errs := make([]error, len(e))
+	for i, err := range e {
+		errs[i] = err
+	}
+	return errs
+}
+
+// MissingParam is a required parameter absent from the request
+type MissingParam struct {
+	// Name is the full parameter name, e.g. name_first
+	Name string `json:"name"`
+	// Source is one of query, path, header, cookie or form
+	Source string `json:"source"`
+}
+
+// MissingParamsError lists every required parameter absent from the request,
+// fields are marked required with the required tag option, e.g. `query:"id,required"`
+type MissingParamsError struct {
+	Params []MissingParam
+}
+
+func (e *MissingParamsError) Error() string {
+	var sb strings.Builder
+	sb.WriteString("missing required parameters: ")
+	for i, p := range e.Params {
+		if i > 0 {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(p.Name + " (" + p.Source + ")")
+	}
+	return sb.String()
 }
 
 type decodeIn struct {
-	r             *http.Request
-	queryVals     url.Values
+	r    *http.Request
+	opts options
+	// plan of the root struct, params it doesn't claim go to catch-all fields
+	plan      []fieldPlan
+	queryVals url.Values
+	// formVals are values of the urlencoded or multipart body, nil for other requests
+	formVals url.Values
+	// files are file parts of the multipart body
+	files         map[string][]*multipart.FileHeader
 	parsedCookies []*http.Cookie
+	// missing collects absent required params, so all of them are reported at once
+	missing []MissingParam
+	// rawBody is the untouched body for fields tagged `body:"raw"`
+	rawBody []byte
+	// errs collects values failing to parse, so all of them are reported at once
+	errs DecodeErrors
+}
+
+// addError records err of the field, errors of slice and map elements are DecodeError naming the element
+func (in *decodeIn) addError(name string, tagType tagType, value string, err error) {
+	var decodeErr *DecodeError
+	if !errors.As(err, &decodeErr) {
+		decodeErr = &DecodeError{Field: name, Value: value, Err: err}
+	}
+	decodeErr.Source = tagTypeNames[tagType]
+	in.errs = append(in.errs, decodeErr)
+}
+
+func (in *decodeIn) addMissing(name string, tagType tagType) {
+	in.missing = append(in.missing, MissingParam{Name: name, Source: tagTypeNames[tagType]})
+}
+
+// params returns values query and form tags are looked up in
+func (in *decodeIn) params(tagType tagType) url.Values {
+	if tagType == tagTypeForm {
+		return in.formVals
+	}
+	if in.queryVals == nil {
+		in.queryVals = in.r.URL.Query()
+	}
+	return in.queryVals
+}
+
+// ErrBodyTooLarge is returned by Unmarshal for bodies over the limit set with SetMaxBodySize
+// or by an http.MaxBytesReader wrapping the body before
+var ErrBodyTooLarge = errors.New("request body too large")
+
+var maxBodySize int64
+
+// SetMaxBodySize limits bodies Unmarshal reads to n bytes, 0 disables the limit.
+// It is not thread-safe and should be called at the beginning of the program.
+func SetMaxBodySize(n int64) {
+	maxBodySize = n
+}
+
+// MaxBodySize returns the limit set with SetMaxBodySize, 0 if bodies aren't limited
+func MaxBodySize() int64 {
+	return maxBodySize
+}
+
+// MaxMultipartMemory is the number of bytes of multipart bodies kept in memory, file parts above it are stored on disk
+var MaxMultipartMemory int64 = 32 << 20
+
+// codecFor returns the codec of mediaType, set per call or registered,
+// types with a structured syntax suffix fall back to its codec, e.g. application/problem+json is decoded as application/json
+func (in *decodeIn) codecFor(mediaType string) (Codec, bool) {
+	for {
+		if codec, ok := in.opts.codecs[mediaType]; ok {
+			return codec, true
+		}
+		if codec, ok := CodecFor(mediaType); ok {
+			return codec, true
+		}
+		i := strings.LastIndexByte(mediaType, '+')
+		if i < 0 {
+			return nil, false
+		}
+		mediaType = "application/" + mediaType[i+1:]
+	}
+}
+
+// decodeBody decodes the body into dest with the codec registered for its media type,
+// urlencoded and multipart bodies, e.g. posted by an HTML form, are read into form values
+func (in *decodeIn) decodeBody(dest interface{}, keepRaw bool) error {
+	if keepRaw && in.r.Body != nil {
+		raw, err := io.ReadAll(in.r.Body)
+		if err != nil {
+			return err
+		}
+		in.rawBody = raw
+		in.r.Body = io.NopCloser(bytes.NewReader(raw))
+	}
+
+	mediaType, _, err := mime.ParseMediaType(in.r.Header.Get("Content-Type"))
+	if err != nil {
+		return nil
+	}
+	if codec, ok := in.codecFor(mediaType); ok {
+		if json, ok := codec.(JSONCodec); ok && in.opts.strict {
+			json.DisallowUnknownFields = true
+			codec = json
+		}
+		return codec.Decode(in.r.Body, dest)
+	}
+
+	switch mediaType {
+	case "application/x-www-form-urlencoded":
+		if err := in.r.ParseForm(); err != nil {
+			return err
+		}
+	case "multipart/form-data":
+		if err := in.r.ParseMultipartForm(MaxMultipartMemory); err != nil {
+			return err
+		}
+		in.files = in.r.MultipartForm.File
+	default:
+		return nil
+	}
+	in.formVals = in.r.PostForm
+	return nil
+}
+
+var fileHeaderType = reflect.TypeOf((*multipart.FileHeader)(nil))
+
+// isFileType reports whether t binds file parts of multipart bodies: *multipart.FileHeader or a slice of them
+func isFileType(t reflect.Type) bool {
+	return t == fileHeaderType || (t.Kind() == reflect.Slice && t.Elem() == fileHeaderType)
 }
 
 func (in *decodeIn) findCookieVal(name string) (string, bool) {
@@ -59,57 +277,201 @@ func (in *decodeIn) findCookieVal(name string) (string, bool) {
 	return "", false
 }
 
-func decode(in *decodeIn, v reflect.Value, fullName []byte) error {
-	t := v.Type()
-
-	switch t.Kind() {
-	case reflect.Pointer:
+func decode(in *decodeIn, v reflect.Value) error {
+	for v.Kind() == reflect.Pointer {
 		if v.IsNil() {
-			v.Set(reflect.New(t.Elem()))
+			v.Set(reflect.New(v.Type().Elem()))
 		}
-		return decode(in, v.Elem(), fullName)
-	case reflect.Struct:
-		for i := range t.NumField() {
-			field := t.Field(i)
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return fmt.Errorf("unsupported type: %v", v.Kind())
+	}
 
-			name, tagType, ok := findInTag(field)
-			if !ok {
+	plan, err := planFor(v.Type(), in.opts.delimiter)
+	if err != nil {
+		return err
+	}
+	in.plan = plan
+	return decodeStruct(in, v, in.plan, "")
+}
+
+// decodeStruct binds fields of plan to v, prefix is prepended to names of elements of slices of structs, e.g. items_0_
+func decodeStruct(in *decodeIn, v reflect.Value, plan []fieldPlan, prefix string) error {
+	// fields are looked up only to set them, so pointer groups stay nil without nested values
+	for _, f := range plan {
+		name := prefix + f.fullName
+		opts := f.opts
+		opts.strictBool = in.opts.strictBool
+		switch f.kind {
+		case bindFile:
+			files := in.files[name]
+			for i := 0; len(files) == 0 && i < len(f.aliases); i++ {
+				files = in.files[f.aliases[i]]
+			}
+			if len(files) == 0 {
+				if f.opts.required {
+					in.addMissing(name, f.tagType)
+				}
 				continue
 			}
-
-			fieldKind := field.Type.Kind()
-			if fieldKind == reflect.Struct {
-				fullName = appendWithDelimiter(fullName, name)
-				if err := decode(in, v.Field(i), fullName); err != nil {
-					return err
+			if f.typ == fileHeaderType {
+				fieldByIndex(v, f.index).Set(reflect.ValueOf(files[0]))
+			} else {
+				fieldByIndex(v, f.index).Set(reflect.ValueOf(files))
+			}
+		case bindRawBody:
+			if in.rawBody != nil {
+				fieldByIndex(v, f.index).SetBytes(in.rawBody)
+			}
+		case bindMap:
+			m, err := makeMap(in.params(f.tagType), f.typ, prefix+f.prefix, opts)
+			if err != nil {
+				in.addError(name, f.tagType, "", err)
+				continue
+			}
+			if m.IsValid() {
+				fieldByIndex(v, f.index).Set(m)
+			}
+		case bindRest:
+			rest := make(map[string][]string)
+			for param, values := range in.params(tagTypeQuery) {
+				if !claimed(in.plan, param) {
+					rest[param] = values
+				}
+			}
+			if len(rest) > 0 {
+				fieldByIndex(v, f.index).Set(reflect.ValueOf(rest).Convert(f.typ))
+			}
+		case bindStructSlice:
+			indexes := in.indexes(f.tagType, prefix+f.prefix)
+			if len(indexes) == 0 {
+				if f.opts.required {
+					in.addMissing(name, f.tagType)
 				}
-				fullName = popWithDelimiter(fullName, name)
 				continue
 			}
-
-			fullName = append(fullName, name...)
-			value, ok := getValue(in, fullName, tagType)
-			fullName = fullName[:len(fullName)-len(name)]
+			if err := decodeStructSlice(in, fieldByIndex(v, f.index), indexes, prefix+f.prefix); err != nil {
+				return err
+			}
+		case bindSlice:
+			src := paramSource{tagType: f.tagType, name: name}
+			values := getValues(in, name, f.tagType, f.opts)
+			for i := 0; len(values) == 0 && i < len(f.aliases); i++ {
+				values = getValues(in, f.aliases[i], f.tagType, f.opts)
+			}
+			for i := 0; len(values) == 0 && i < len(f.sources); i++ {
+				src = f.sources[i]
+				values = getValues(in, src.name, src.tagType, f.opts)
+			}
+			if len(values) == 0 {
+				if f.opts.required {
+					in.addMissing(name, f.tagType)
+				}
+				continue
+			}
+			if err := setSlice(fieldByIndex(v, f.index), src.name, values, opts); err != nil {
+				in.addError(src.name, src.tagType, "", err)
+			}
+		default:
+			src := paramSource{tagType: f.tagType, name: name}
+			value, ok := getValue(in, name, f.tagType)
+			for i := 0; !ok && i < len(f.aliases); i++ {
+				value, ok = getValue(in, f.aliases[i], f.tagType)
+			}
+			for i := 0; !ok && i < len(f.sources); i++ {
+				src = f.sources[i]
+				value, ok = getValue(in, src.name, src.tagType)
+			}
+			if (!ok || value == "") && f.opts.required {
+				in.addMissing(name, f.tagType)
+				continue
+			}
 			if !ok {
 				continue
 			}
-			if fieldKind == reflect.Pointer && value == "" {
+			if f.typ.Kind() == reflect.Pointer && value == "" {
 				// optional empty value is ignored
 				continue
 			}
 
-			// TODO: pass full name to setField
-			if err := setField(v.Field(i), bytesString(name), value); err != nil {
-				return err
+			// errors name the source of the value, e.g. an invalid api_key cookie
+			if err := setField(fieldByIndex(v, f.index), src.name, value, opts); err != nil {
+				in.addError(src.name, src.tagType, value, err)
 			}
 		}
-	default:
-		return fmt.Errorf("unsupported type: %v", t.Kind())
+	}
+	return nil
+}
+
+// decodeStructSlice decodes an element of v per index, elements are compacted in index order,
+// e.g. items_0_sku and items_5_sku decode into two elements
+func decodeStructSlice(in *decodeIn, v reflect.Value, indexes []int, prefix string) error {
+	elemType := v.Type().Elem()
+	group := elemType
+	if group.Kind() == reflect.Pointer {
+		group = group.Elem()
+	}
+	plan, err := planFor(group, in.opts.delimiter)
+	if err != nil {
+		return err
 	}
 
+	slice := reflect.MakeSlice(v.Type(), len(indexes), len(indexes))
+	for i, index := range indexes {
+		elem := slice.Index(i)
+		if elemType.Kind() == reflect.Pointer {
+			elem.Set(reflect.New(group))
+			elem = elem.Elem()
+		}
+		if err := decodeStruct(in, elem, plan, prefix+strconv.Itoa(index)+string(in.opts.delimiter)); err != nil {
+			return err
+		}
+	}
+	v.Set(slice)
 	return nil
 }
 
+// indexes returns sorted distinct indexes of params named prefix<index><delimiter><name>, e.g. items_0_sku
+func (in *decodeIn) indexes(tagType tagType, prefix string) []int {
+	var indexes []int
+	collect := func(params url.Values) {
+		for param := range params {
+			rest, ok := strings.CutPrefix(param, prefix)
+			if !ok {
+				continue
+			}
+			index, _, ok := strings.Cut(rest, string(in.opts.delimiter))
+			if !ok {
+				continue
+			}
+			if n, err := strconv.Atoi(index); err == nil && n >= 0 {
+				indexes = append(indexes, n)
+			}
+		}
+	}
+	collect(in.params(tagType))
+	if tagType == tagTypeQuery {
+		collect(in.formVals)
+	}
+	slices.Sort(indexes)
+	return slices.Compact(indexes)
+}
+
+// fieldByIndex is reflect.Value.FieldByIndex allocating nil pointers of groups and embedded structs on the way
+func fieldByIndex(v reflect.Value, index []int) reflect.Value {
+	for i, x := range index {
+		if i > 0 && v.Kind() == reflect.Pointer {
+			if v.IsNil() {
+				v.Set(reflect.New(v.Type().Elem()))
+			}
+			v = v.Elem()
+		}
+		v = v.Field(x)
+	}
+	return v
+}
+
 type tagType int
 
 const (
@@ -118,61 +480,157 @@ const (
 	tagTypePath
 	tagTypeHeader
 	tagTypeCookie
+	tagTypeForm
+	// tagTypeBody binds the raw body, e.g. `body:"raw"`
+	tagTypeBody
 )
 
-func findInTag(t reflect.StructField) ([]byte, tagType, bool) {
-	// Check for direct tag names: query, path, header, cookie
-	if tag, ok := t.Tag.Lookup("query"); ok && tag != "" {
-		return stringBytes(tag), tagTypeQuery, true
+// tagOptions are the options following the name in a tag, e.g. `query:"tags,comma"`
+type tagOptions struct {
+	// comma splits values of slice fields on commas, e.g. ?tags=a,b
+	comma bool
+	// required fields are reported in MissingParamsError if their value is absent or empty
+	required bool
+	// layout parses time.Time values, set with the layout or format tag, e.g. `layout:"2006-01-02"`
+	layout string
+	// base64 decodes values of []byte fields, standard and URL alphabets with or without padding are accepted
+	base64 bool
+	// aliases are full names looked up in order if the param is absent, e.g. `query:"first,alias=name.first"`.
+	// They aren't prefixed with names of parent groups and don't apply to map fields.
+	aliases []string
+	// sources are params of other sources looked up in order if the param and its aliases are absent,
+	// set with the in tag, see findInTag. Like aliases they're full names and apply to value and slice fields only.
+	sources []paramSource
+	// strictBool accepts only true and false as bool values, it's set per call rather than by the tag, see WithStrictBool
+	strictBool bool
+}
+
+// paramSource is a param of a source, e.g. the api_key cookie
+type paramSource struct {
+	tagType tagType
+	name    string
+}
+
+// sourceTag is the tag key of a source, e.g. query
+type sourceTag struct {
+	key     string
+	tagType tagType
+}
+
+var sourceTags = [...]sourceTag{
+	{"query", tagTypeQuery},
+	{"path", tagTypePath},
+	{"header", tagTypeHeader},
+	{"cookie", tagTypeCookie},
+	{"form", tagTypeForm},
+	{"body", tagTypeBody},
+}
+
+// findInTag returns the name, source and options of the param bound to t.
+// The in tag lists sources in priority order followed by options, e.g. `in:"header=X-Api-Key,query=api_key,required"`,
+// the first source is the param of the field and the rest are looked up if it's absent.
+func findInTag(t reflect.StructField) (string, tagType, tagOptions, bool) {
+	name, tagType, opts, ok := findSource(t)
+	if !ok {
+		return "", 0, tagOptions{}, false
+	}
+	opts.layout = t.Tag.Get("layout")
+	if opts.layout == "" {
+		opts.layout = t.Tag.Get("format")
+	}
+	return name, tagType, opts, true
+}
+
+func findSource(t reflect.StructField) (string, tagType, tagOptions, bool) {
+	for _, src := range sourceTags {
+		if tag, ok := t.Tag.Lookup(src.key); ok && tag != "" {
+			name, opts := parseTag(tag)
+			return name, src.tagType, opts, true
+		}
 	}
-	if tag, ok := t.Tag.Lookup("path"); ok && tag != "" {
-		return stringBytes(tag), tagTypePath, true
+
+	tag, ok := t.Tag.Lookup("in")
+	if !ok {
+		return "", 0, tagOptions{}, false
 	}
-	if tag, ok := t.Tag.Lookup("header"); ok && tag != "" {
-		return stringBytes(tag), tagTypeHeader, true
+	var sources []paramSource
+	var opts []string
+	for part := range strings.SplitSeq(tag, ",") {
+		key, name, _ := strings.Cut(part, "=")
+		i := slices.IndexFunc(sourceTags[:], func(src sourceTag) bool { return src.key == key })
+		if i < 0 || name == "" {
+			opts = append(opts, part)
+			continue
+		}
+		sources = append(sources, paramSource{tagType: sourceTags[i].tagType, name: name})
 	}
-	if tag, ok := t.Tag.Lookup("cookie"); ok && tag != "" {
-		return stringBytes(tag), tagTypeCookie, true
+	if len(sources) == 0 {
+		return "", 0, tagOptions{}, false
 	}
+	_, parsed := parseTag("," + strings.Join(opts, ","))
+	parsed.sources = sources[1:]
+	return sources[0].name, sources[0].tagType, parsed, true
+}
 
-	return nil, 0, false
+func parseTag(tag string) (string, tagOptions) {
+	name, rest, _ := strings.Cut(tag, ",")
+	var opts tagOptions
+	for rest != "" {
+		var opt string
+		opt, rest, _ = strings.Cut(rest, ",")
+		switch opt {
+		case "comma":
+			opts.comma = true
+		case "required":
+			opts.required = true
+		case "base64":
+			opts.base64 = true
+		default:
+			if alias, ok := strings.CutPrefix(opt, "alias="); ok {
+				opts.aliases = append(opts.aliases, alias)
+			}
+		}
+	}
+	return name, opts
 }
 
 type pathLookuper func(r *http.Request, name string) (string, bool)
 
-func defaultPathLookuper(r *http.Request, name string) (string, bool) {
-	v := r.PathValue(name)
+// lookupPath returns the path param of the http.ServeMux pattern, e.g. {id}, unless WithPathLookuper is set
+func (in *decodeIn) lookupPath(name string) (string, bool) {
+	if in.opts.pathLookuper != nil {
+		return in.opts.pathLookuper(in.r, name)
+	}
+	v := in.r.PathValue(name)
 	return v, len(v) > 0
 }
 
-var currentPathLookuper pathLookuper = defaultPathLookuper
-
-// SetPathLookuper sets the path lookuper function.
-// It is not thread-safe and should be called at the beginning of the program.
-func SetPathLookuper(lookuper pathLookuper) {
-	currentPathLookuper = lookuper
-}
-
-func getValue(in *decodeIn, name []byte, tagType tagType) (string, bool) {
+func getValue(in *decodeIn, name string, tagType tagType) (string, bool) {
 	switch tagType {
-	case tagTypeQuery:
-		if in.queryVals == nil {
-			in.queryVals = in.r.URL.Query()
+	case tagTypeQuery, tagTypeForm:
+		vals := in.params(tagType)[name]
+		if len(vals) == 0 && tagType == tagTypeQuery {
+			// query params fall back to the form body
+			vals = in.formVals[name]
 		}
-		vals, ok := in.queryVals[bytesString(name)]
-		if !ok || len(vals) == 0 {
+		if len(vals) == 0 {
 			return "", false
 		}
 		return vals[0], true
 	case tagTypePath:
-		return currentPathLookuper(in.r, bytesString(name))
+		return in.lookupPath(name)
 	case tagTypeHeader:
-		return in.r.Header.Get(bytesString(name)), true
+		// names of header fields are canonical, see buildPlan
+		vals := in.r.Header[name]
+		if len(vals) == 0 {
+			return "", false
+		}
+		return vals[0], true
 	case tagTypeCookie:
-		if cookieVal, ok := in.findCookieVal(bytesString(name)); ok {
+		if cookieVal, ok := in.findCookieVal(name); ok {
 			return cookieVal, true
 		}
-		cookie, err := in.r.Cookie(bytesString(name))
+		cookie, err := in.r.Cookie(name)
 		if err != nil {
 			return "", false
 		}
@@ -183,12 +641,222 @@ func getValue(in *decodeIn, name []byte, tagType tagType) (string, bool) {
 	}
 }
 
-func setField(v reflect.Value, name, value string) error {
+// getValues returns all values of a slice field: repeated query params and headers,
+// split on commas if the field has the comma option
+func getValues(in *decodeIn, name string, tagType tagType, opts tagOptions) []string {
+	var values []string
+	switch tagType {
+	case tagTypeQuery, tagTypeForm:
+		values = in.params(tagType)[name]
+		if len(values) == 0 && tagType == tagTypeQuery {
+			values = in.formVals[name]
+		}
+	case tagTypeHeader:
+		values = in.r.Header[name]
+	default:
+		value, ok := getValue(in, name, tagType)
+		if !ok {
+			return nil
+		}
+		values = []string{value}
+	}
+	if !opts.comma {
+		return values
+	}
+
+	var split []string
+	for _, value := range values {
+		for _, part := range strings.Split(value, ",") {
+			// list headers separate elements with ", "
+			if part = strings.TrimSpace(part); part != "" {
+				split = append(split, part)
+			}
+		}
+	}
+	return split
+}
+
+var bytesType = reflect.TypeOf([]byte(nil))
+
+// restType is the type of catch-all fields, e.g. url.Values
+var restType = reflect.TypeOf(map[string][]string(nil))
+
+// decodeBase64 decodes s in the standard or URL alphabet, padding is optional
+func decodeBase64(s string) ([]byte, error) {
+	s = strings.TrimRight(s, "=")
+	if strings.ContainsAny(s, "-_") {
+		return base64.RawURLEncoding.DecodeString(s)
+	}
+	return base64.RawStdEncoding.DecodeString(s)
+}
+
+// makeMap collects params starting with prefix into a map of type t keyed by the rest of their names,
+// e.g. meta_color=red into {"color": "red"} of a field tagged `query:"meta"`.
+// Values of slice elements are all values of repeated params. The map is invalid if no param matches.
+func makeMap(params url.Values, t reflect.Type, prefix string, opts tagOptions) (reflect.Value, error) {
+	if t.Key().Kind() != reflect.String {
+		return reflect.Value{}, fmt.Errorf("unsupported map key type: %v", t.Key().Kind())
+	}
+
+	var m reflect.Value
+	for param, values := range params {
+		key, ok := strings.CutPrefix(param, prefix)
+		if !ok || key == "" || len(values) == 0 {
+			continue
+		}
+		if !m.IsValid() {
+			m = reflect.MakeMap(t)
+		}
+		elem := reflect.New(t.Elem()).Elem()
+		var err error
+		if elem.Kind() == reflect.Slice && !isValueType(elem.Type()) {
+			err = setSlice(elem, param, values, opts)
+		} else {
+			err = setField(elem, param, values[0], opts)
+			if err != nil {
+				err = &DecodeError{Field: param, Value: values[0], Err: err}
+			}
+		}
+		if err != nil {
+			return reflect.Value{}, err
+		}
+		m.SetMapIndex(reflect.ValueOf(key).Convert(t.Key()), elem)
+	}
+	return m, nil
+}
+
+func setSlice(v reflect.Value, name string, values []string, opts tagOptions) error {
+	slice := reflect.MakeSlice(v.Type(), len(values), len(values))
+	for i, value := range values {
+		if err := setField(slice.Index(i), name, value, opts); err != nil {
+			return &DecodeError{Field: name, Value: value, Err: err}
+		}
+	}
+	v.Set(slice)
+	return nil
+}
+
+var strictBool bool
+
+// SetStrictBool makes Unmarshal accept only true and false as bool values.
+// By default 1, 0, yes, no, on, off, t and f are accepted too, case-insensitively, and an empty value is false.
+// WithStrictBool overrides it per call. It is not thread-safe and should be called at the beginning of the program.
+func SetStrictBool(strict bool) {
+	strictBool = strict
+}
+
+func parseBool(value string, strict bool) (bool, error) {
+	if strict {
+		switch value {
+		case "true":
+			return true, nil
+		case "false":
+			return false, nil
+		}
+		return false, fmt.Errorf("invalid bool %q, expected true or false", value)
+	}
+
+	switch strings.ToLower(value) {
+	case "true", "t", "1", "yes", "y", "on":
+		return true, nil
+	case "false", "f", "0", "no", "n", "off", "":
+		return false, nil
+	}
+	return false, fmt.Errorf("invalid bool %q", value)
+}
+
+var (
+	timeType     = reflect.TypeOf(time.Time{})
+	durationType = reflect.TypeOf(time.Duration(0))
+)
+
+// decoders are the decoders of custom types added with RegisterDecoder
+var decoders sync.Map // reflect.Type -> func(string) (reflect.Value, error)
+
+// RegisterDecoder makes Unmarshal parse values of fields of type T with decode,
+// e.g. project-specific IDs or money types that don't implement encoding.TextUnmarshaler.
+// Fields of *T are parsed with it as well. Registering a type again replaces its decoder.
+func RegisterDecoder[T any](decode func(string) (T, error)) {
+	t := reflect.TypeOf((*T)(nil)).Elem()
+	decoders.Store(t, func(value string) (reflect.Value, error) {
+		v, err := decode(value)
+		return reflect.ValueOf(&v).Elem(), err
+	})
+}
+
+func init() {
+	RegisterDecoder(func(value string) (url.URL, error) {
+		u, err := url.Parse(value)
+		if err != nil {
+			return url.URL{}, err
+		}
+		return *u, nil
+	})
+}
+
+var textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+
+// isValueType reports whether t is parsed from a single value rather than being a group of fields, a slice or a map:
+// time.Time, types of registered decoders and types implementing encoding.TextUnmarshaler, e.g. uuid.UUID and net.IP
+func isValueType(t reflect.Type) bool {
+	if _, ok := decoders.Load(t); ok {
+		return true
+	}
+	return t == timeType || reflect.PointerTo(t).Implements(textUnmarshalerType)
+}
+
+func setField(v reflect.Value, name, value string, opts tagOptions) error {
+	if decode, ok := decoders.Load(v.Type()); ok {
+		decoded, err := decode.(func(string) (reflect.Value, error))(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as %s: %w", name, v.Type(), err)
+		}
+		v.Set(decoded)
+		return nil
+	}
+
 	if v.Kind() == reflect.Ptr {
 		if v.IsNil() {
 			v.Set(reflect.New(v.Type().Elem()))
 		}
-		return setField(v.Elem(), name, value)
+		return setField(v.Elem(), name, value, opts)
+	}
+
+	if opts.base64 && v.Type() == bytesType {
+		b, err := decodeBase64(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as base64: %w", name, err)
+		}
+		v.SetBytes(b)
+		return nil
+	}
+
+	switch v.Type() {
+	case timeType:
+		layout := opts.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		t, err := time.Parse(layout, value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as time: %w", name, err)
+		}
+		v.Set(reflect.ValueOf(t))
+		return nil
+	case durationType:
+		d, err := time.ParseDuration(value)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as duration: %w", name, err)
+		}
+		v.SetInt(int64(d))
+		return nil
+	}
+
+	if v.CanAddr() && reflect.PointerTo(v.Type()).Implements(textUnmarshalerType) {
+		if err := v.Addr().Interface().(encoding.TextUnmarshaler).UnmarshalText([]byte(value)); err != nil {
+			return fmt.Errorf("failed to parse %s as %s: %w", name, v.Type(), err)
+		}
+		return nil
 	}
 
 	switch v.Kind() {
@@ -213,34 +881,14 @@ func setField(v reflect.Value, name, value string) error {
 		}
 		v.SetFloat(floatVal)
 	case reflect.Bool:
-		if value == "true" {
-			v.SetBool(true)
-		} else {
-			v.SetBool(false)
+		b, err := parseBool(value, opts.strictBool)
+		if err != nil {
+			return fmt.Errorf("failed to parse %s as bool: %w", name, err)
 		}
+		v.SetBool(b)
 	default:
 		return fmt.Errorf("unsupported type: %v", v.Kind())
 	}
 
 	return nil
 }
-
-func appendWithDelimiter(prefix []byte, name []byte) []byte {
-	prefix = append(prefix, name...)
-	prefix = append(prefix, delimiter)
-	return prefix
-}
-
-func popWithDelimiter(prefix []byte, name []byte) []byte {
-	return prefix[:len(prefix)-len(name)-1] // -1 for the delimiter
-}
-
-//nolint:gosec // TODO: cover with tests
-func stringBytes(s string) []byte {
-	return *(*[]byte)(unsafe.Pointer(&s))
-}
-
-//nolint:gosec // TODO: cover with tests
-func bytesString(b []byte) string {
-	return *(*string)(unsafe.Pointer(&b))
-}
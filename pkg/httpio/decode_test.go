@@ -1,9 +1,24 @@
 package httpio_test
 
 import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"mime/multipart"
+	"net"
+	"net/http"
 	"net/http/httptest"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"strconv"
 	"strings"
 	"testing"
+	"time"
 
 	"github.com/pechorka/cruder/pkg/httpio"
 	"github.com/stretchr/testify/require"
@@ -26,7 +41,7 @@ func TestUnmarshal(t *testing.T) {
 		r := httptest.NewRequest("GET", "/?name.first=John&name.last=Doe&age=30&banned=true&income=100000&name.middle=Middle", nil)
 
 		var v input
-		err := httpio.Unmarshal(r, &v)
+		err := httpio.Unmarshal(r, &v, httpio.WithDelimiter('.'))
 		require.NoError(t, err)
 
 		require.Equal(t, "John", v.Name.First)
@@ -55,7 +70,7 @@ func TestUnmarshal(t *testing.T) {
 
 		body := `{"app_config":{"host":"localhost","port":8080}}`
 
-		r := httptest.NewRequest("POST", "/?name.first=John&name.last=Doe&age=30&banned=true&income=100000", strings.NewReader(body))
+		r := httptest.NewRequest("POST", "/?name_first=John&name_last=Doe&age=30&banned=true&income=100000", strings.NewReader(body))
 		r.Header.Set("Content-Type", "application/json")
 
 		var v input
@@ -70,6 +85,854 @@ func TestUnmarshal(t *testing.T) {
 		require.Equal(t, "localhost", v.AppConfig.Host)
 		require.Equal(t, 8080, v.AppConfig.Port)
 	})
+
+	t.Run("slices", func(t *testing.T) {
+		type input struct {
+			Tags   []string `query:"tag"`
+			IDs    []int    `query:"ids,comma"`
+			Langs  []string `header:"Accept-Language"`
+			Absent []string `query:"absent"`
+		}
+
+		r := httptest.NewRequest("GET", "/?tag=a&tag=b,c&ids=1,2&ids=3", nil)
+		r.Header.Add("Accept-Language", "en")
+		r.Header.Add("Accept-Language", "de")
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		require.NoError(t, err)
+
+		require.Equal(t, []string{"a", "b,c"}, v.Tags)
+		require.Equal(t, []int{1, 2, 3}, v.IDs)
+		require.Equal(t, []string{"en", "de"}, v.Langs)
+		require.Nil(t, v.Absent)
+
+		r = httptest.NewRequest("GET", "/?ids=1,x", nil)
+		require.Error(t, httpio.Unmarshal(r, &v))
+	})
+
+	t.Run("maps", func(t *testing.T) {
+		type input struct {
+			Meta   map[string]string   `query:"meta"`
+			Limits map[string]int      `query:"limit"`
+			Multi  map[string][]string `query:"multi"`
+			Absent map[string]string   `query:"absent"`
+		}
+
+		r := httptest.NewRequest("GET", "/?meta_color=red&meta_size=xl&metadata=x&limit_users=10&multi_tag=a&multi_tag=b", nil)
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		require.NoError(t, err)
+
+		require.Equal(t, map[string]string{"color": "red", "size": "xl"}, v.Meta)
+		require.Equal(t, map[string]int{"users": 10}, v.Limits)
+		require.Equal(t, map[string][]string{"tag": {"a", "b"}}, v.Multi)
+		require.Nil(t, v.Absent)
+
+		r = httptest.NewRequest("GET", "/?limit_users=many", nil)
+		require.Error(t, httpio.Unmarshal(r, &v))
+	})
+
+	t.Run("time", func(t *testing.T) {
+		type input struct {
+			Since   time.Time      `query:"since"`
+			Day     *time.Time     `query:"day" layout:"2006-01-02"`
+			Timeout time.Duration  `header:"X-Timeout"`
+			Days    []time.Time    `query:"days,comma" format:"2006-01-02"`
+			Absent  *time.Duration `query:"absent"`
+		}
+
+		r := httptest.NewRequest("GET", "/?since=2024-01-02T03:04:05Z&day=2024-05-06&days=2024-01-01,2024-01-02", nil)
+		r.Header.Set("X-Timeout", "1m30s")
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		require.NoError(t, err)
+
+		require.Equal(t, time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC), v.Since)
+		require.Equal(t, time.Date(2024, 5, 6, 0, 0, 0, 0, time.UTC), *v.Day)
+		require.Equal(t, 90*time.Second, v.Timeout)
+		require.Equal(t, []time.Time{time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC), time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)}, v.Days)
+		require.Nil(t, v.Absent)
+
+		r = httptest.NewRequest("GET", "/?day=02.01.2024", nil)
+		require.Error(t, httpio.Unmarshal(r, &v))
+	})
+
+	t.Run("registered decoders", func(t *testing.T) {
+		type money struct {
+			Cents    int64
+			Currency string
+		}
+		type userID string
+		httpio.RegisterDecoder(func(s string) (money, error) {
+			amount, currency, ok := strings.Cut(s, " ")
+			if !ok {
+				return money{}, errors.New("missing currency")
+			}
+			cents, err := strconv.ParseInt(amount, 10, 64)
+			return money{Cents: cents, Currency: currency}, err
+		})
+		httpio.RegisterDecoder(func(s string) (userID, error) {
+			id, ok := strings.CutPrefix(s, "usr_")
+			if !ok {
+				return "", errors.New("missing usr_ prefix")
+			}
+			return userID(id), nil
+		})
+
+		type input struct {
+			Price money    `query:"price"`
+			Max   *money   `query:"max"`
+			Owner userID   `path:"owner"`
+			Users []userID `query:"users,comma"`
+		}
+
+		r := httptest.NewRequest("GET", "/?price=1250%20EUR&max=5000%20EUR&users=usr_a,usr_b", nil)
+		r.SetPathValue("owner", "usr_42")
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		require.NoError(t, err)
+
+		require.Equal(t, money{Cents: 1250, Currency: "EUR"}, v.Price)
+		require.Equal(t, money{Cents: 5000, Currency: "EUR"}, *v.Max)
+		require.Equal(t, userID("42"), v.Owner)
+		require.Equal(t, []userID{"a", "b"}, v.Users)
+
+		r = httptest.NewRequest("GET", "/?users=42", nil)
+		require.ErrorContains(t, httpio.Unmarshal(r, &v), "missing usr_ prefix")
+	})
+
+	t.Run("required", func(t *testing.T) {
+		type input struct {
+			ID      int      `path:"id,required"`
+			Token   string   `header:"X-Token,required"`
+			Tags    []string `query:"tag,required"`
+			Session string   `cookie:"session,required"`
+			Note    string   `query:"note"`
+		}
+
+		r := httptest.NewRequest("GET", "/?tag=", nil)
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		var missing *httpio.MissingParamsError
+		require.ErrorAs(t, err, &missing)
+		require.Equal(t, []httpio.MissingParam{
+			{Name: "id", Source: "path"},
+			{Name: "X-Token", Source: "header"},
+			{Name: "session", Source: "cookie"},
+		}, missing.Params)
+		require.EqualError(t, err, "missing required parameters: id (path), X-Token (header), session (cookie)")
+
+		r = httptest.NewRequest("GET", "/?tag=a", nil)
+		r.SetPathValue("id", "7")
+		r.Header.Set("X-Token", "secret")
+		r.AddCookie(&http.Cookie{Name: "session", Value: "s1"})
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{ID: 7, Token: "secret", Tags: []string{"a"}, Session: "s1"}, v)
+
+		require.True(t, describe(t, reflect.TypeOf(v))[0].Required)
+	})
+
+	t.Run("validate", func(t *testing.T) {
+		type page struct {
+			Limit int `query:"limit" validate:"min=1,max=100"`
+		}
+		type input struct {
+			Page  page     `query:"page"`
+			Sort  string   `query:"sort" validate:"oneof=asc desc"`
+			Code  string   `query:"code" validate:"len=2,pattern=^[A-Z]+$"`
+			Tags  []string `query:"tag" validate:"max=2"`
+			Score *float64 `query:"score" validate:"min=0.5"`
+			Name  string   `json:"name" validate:"min=3"`
+		}
+
+		r := httptest.NewRequest("GET", "/?page_limit=0&sort=up&code=a1&tag=a&tag=b&tag=c", nil)
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		var invalid *httpio.ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, []httpio.FieldError{
+			{Field: "page_limit", Rule: "min", Message: "must be at least 1"},
+			{Field: "sort", Rule: "oneof", Message: "must be one of asc, desc"},
+			{Field: "code", Rule: "pattern", Message: "must match ^[A-Z]+$"},
+			{Field: "tag", Rule: "max", Message: "length must be at most 2"},
+			{Field: "name", Rule: "min", Message: "length must be at least 3"},
+		}, invalid.Fields)
+
+		r = httptest.NewRequest("GET", "/?page_limit=10&sort=asc&code=AB&tag=a&score=0.7", nil)
+		v = input{Name: "Ann"}
+		require.NoError(t, httpio.Unmarshal(r, &v))
+
+		type badRule struct {
+			ID int `query:"id" validate:"positive"`
+		}
+		require.EqualError(t, httpio.Validate(&badRule{}), `field ID: unknown validation rule "positive"`)
+	})
+
+	t.Run("enum", func(t *testing.T) {
+		type input struct {
+			Order  string   `query:"order" enum:"asc,desc"`
+			Fields []string `query:"field" enum:"id,name"`
+		}
+
+		r := httptest.NewRequest("GET", "/?order=up&field=id&field=age", nil)
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		var invalid *httpio.ValidationError
+		require.ErrorAs(t, err, &invalid)
+		require.Equal(t, []httpio.FieldError{
+			{Field: "order", Rule: "enum", Message: `"up" is not one of asc, desc`},
+			{Field: "field", Rule: "enum", Message: `"age" is not one of id, name`},
+		}, invalid.Fields)
+
+		r = httptest.NewRequest("GET", "/?field=name", nil)
+		v = input{}
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Fields: []string{"name"}}, v)
+	})
+
+	t.Run("form", func(t *testing.T) {
+		type input struct {
+			Text    string            `form:"text"`
+			Channel string            `query:"channel_id"`
+			Tags    []string          `form:"tag"`
+			Meta    map[string]string `form:"meta"`
+			Page    int               `query:"page"`
+		}
+
+		body := strings.NewReader("text=hello+world&channel_id=C1&tag=a&tag=b&meta_team=T1&page=3")
+		r := httptest.NewRequest("POST", "/?page=2", body)
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded; charset=utf-8")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{
+			Text:    "hello world",
+			Channel: "C1",
+			Tags:    []string{"a", "b"},
+			Meta:    map[string]string{"team": "T1"},
+			Page:    2,
+		}, v)
+		require.Equal(t, "form", describe(t, reflect.TypeOf(v))[0].Source)
+	})
+
+	t.Run("multipart", func(t *testing.T) {
+		type input struct {
+			Title   string                  `form:"title"`
+			Avatar  *multipart.FileHeader   `form:"avatar,required"`
+			Photos  []*multipart.FileHeader `form:"photo"`
+			Missing *multipart.FileHeader   `form:"missing"`
+		}
+
+		var body bytes.Buffer
+		mw := multipart.NewWriter(&body)
+		require.NoError(t, mw.WriteField("title", "holidays"))
+		for _, f := range []struct{ field, name, content string }{
+			{"avatar", "me.png", "png"},
+			{"photo", "1.jpg", "first"},
+			{"photo", "2.jpg", "second"},
+		} {
+			w, err := mw.CreateFormFile(f.field, f.name)
+			require.NoError(t, err)
+			_, err = io.WriteString(w, f.content)
+			require.NoError(t, err)
+		}
+		require.NoError(t, mw.Close())
+
+		r := httptest.NewRequest("POST", "/", &body)
+		r.Header.Set("Content-Type", mw.FormDataContentType())
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, "holidays", v.Title)
+		require.Equal(t, "me.png", v.Avatar.Filename)
+		require.Len(t, v.Photos, 2)
+		require.Equal(t, int64(len("second")), v.Photos[1].Size)
+		require.Nil(t, v.Missing)
+
+		f, err := v.Photos[0].Open()
+		require.NoError(t, err)
+		defer f.Close()
+		content, err := io.ReadAll(f)
+		require.NoError(t, err)
+		require.Equal(t, "first", string(content))
+
+		r = httptest.NewRequest("POST", "/", strings.NewReader("title=x"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		v = input{}
+		require.EqualError(t, httpio.Unmarshal(r, &v), "missing required parameters: avatar (form)")
+	})
+
+	t.Run("codec", func(t *testing.T) {
+		httpio.RegisterCodec("application/xml", xmlCodec{})
+
+		type input struct {
+			Name string `xml:"name"`
+			Page int    `query:"page"`
+		}
+
+		r := httptest.NewRequest("POST", "/?page=2", strings.NewReader("<input><name>John</name></input>"))
+		r.Header.Set("Content-Type", "application/xml; charset=utf-8")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Name: "John", Page: 2}, v)
+
+		codec, ok := httpio.CodecFor("application/json")
+		require.True(t, ok)
+		require.Equal(t, httpio.JSONCodec{}, codec)
+	})
+
+	t.Run("media type suffix", func(t *testing.T) {
+		type input struct {
+			Name string `json:"name"`
+		}
+
+		for _, contentType := range []string{
+			"application/json; charset=utf-8",
+			"Application/JSON",
+			"application/merge-patch+json",
+			"application/vnd.api+json; charset=utf-8",
+		} {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"John"}`))
+			r.Header.Set("Content-Type", contentType)
+
+			var v input
+			require.NoError(t, httpio.Unmarshal(r, &v), contentType)
+			require.Equal(t, input{Name: "John"}, v, contentType)
+		}
+	})
+
+	t.Run("json options", func(t *testing.T) {
+		var decoders int
+		httpio.RegisterCodec("application/json", httpio.JSONCodec{
+			UseNumber: true,
+			NewDecoder: func(r io.Reader) httpio.JSONDecoder {
+				decoders++
+				return json.NewDecoder(r)
+			},
+		})
+		t.Cleanup(func() { httpio.RegisterCodec("application/json", httpio.JSONCodec{}) })
+
+		type input struct {
+			Amount interface{} `json:"amount"`
+		}
+
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"amount":12345678901234567890}`))
+		r.Header.Set("Content-Type", "application/json")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, json.Number("12345678901234567890"), v.Amount)
+		require.Equal(t, 1, decoders)
+	})
+
+	t.Run("strict", func(t *testing.T) {
+		httpio.SetStrict(true)
+		t.Cleanup(func() { httpio.SetStrict(false) })
+
+		type page struct {
+			Limit int `query:"limit"`
+		}
+		type input struct {
+			Page   page              `query:"page"`
+			Filter map[string]string `query:"filter"`
+			Name   string            `json:"name"`
+		}
+
+		r := httptest.NewRequest("GET", "/?page_limit=10&filter_status=new&pgae=2&sort=asc", nil)
+		var v input
+		require.EqualError(t, httpio.Unmarshal(r, &v), "unknown parameters: pgae, sort")
+
+		r = httptest.NewRequest("POST", "/?page_limit=10", strings.NewReader(`{"name":"John","nmae":"typo"}`))
+		r.Header.Set("Content-Type", "application/json")
+		require.EqualError(t, httpio.Unmarshal(r, &v), `json: unknown field "nmae"`)
+
+		r = httptest.NewRequest("POST", "/?page_limit=10&filter_status=new", strings.NewReader(`{"name":"John"}`))
+		r.Header.Set("Content-Type", "application/json")
+		v = input{}
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Page: page{Limit: 10}, Filter: map[string]string{"status": "new"}, Name: "John"}, v)
+	})
+
+	t.Run("catch-all query", func(t *testing.T) {
+		type item struct {
+			SKU string `query:"sku"`
+		}
+		type input struct {
+			Page  int        `query:"page,alias=p"`
+			Items []item     `query:"items"`
+			Rest  url.Values `query:"*"`
+		}
+
+		r := httptest.NewRequest("GET", "/?p=2&items_0_sku=a&utm_source=ad&tag=a&tag=b", nil)
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v, httpio.WithStrict(true)))
+		require.Equal(t, input{
+			Page:  2,
+			Items: []item{{SKU: "a"}},
+			Rest:  url.Values{"utm_source": {"ad"}, "tag": {"a", "b"}},
+		}, v)
+
+		r = httptest.NewRequest("GET", "/?page=1", nil)
+		v = input{}
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Nil(t, v.Rest)
+
+		type badRest struct {
+			Rest map[string]string `query:"*"`
+		}
+		err := httpio.Unmarshal(r, &badRest{})
+		require.EqualError(t, err, "httpio: catch-all field Rest must be map[string][]string, got map[string]string")
+		_, describeErr := httpio.Describe(reflect.TypeOf(badRest{}))
+		require.Equal(t, err, describeErr)
+	})
+
+	t.Run("max body size", func(t *testing.T) {
+		httpio.SetMaxBodySize(16)
+		t.Cleanup(func() { httpio.SetMaxBodySize(0) })
+
+		type input struct {
+			Name string `json:"name"`
+		}
+
+		for _, contentType := range []string{"application/json", "application/x-www-form-urlencoded"} {
+			r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"`+strings.Repeat("a", 32)+`"}`))
+			r.Header.Set("Content-Type", contentType)
+			var v input
+			err := httpio.Unmarshal(r, &v)
+			require.ErrorIs(t, err, httpio.ErrBodyTooLarge, contentType)
+			require.EqualError(t, err, "request body too large: limit is 16 bytes")
+		}
+
+		r := httptest.NewRequest("POST", "/", strings.NewReader(`{"name":"John"}`))
+		r.Header.Set("Content-Type", "application/json")
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, "John", v.Name)
+	})
+
+	t.Run("embedded", func(t *testing.T) {
+		type Pagination struct {
+			Limit  int `query:"limit" validate:"max=100"`
+			Offset int `query:"offset"`
+		}
+		type Auth struct {
+			Token string `header:"X-Token"`
+		}
+		type input struct {
+			Pagination
+			*Auth
+			Name string `query:"name"`
+		}
+
+		r := httptest.NewRequest("GET", "/?limit=10&offset=20&name=John", nil)
+		r.Header.Set("X-Token", "secret")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Pagination: Pagination{Limit: 10, Offset: 20}, Auth: &Auth{Token: "secret"}, Name: "John"}, v)
+		require.Equal(t, "Pagination.Limit", describe(t, reflect.TypeOf(v))[0].Field)
+
+		r = httptest.NewRequest("GET", "/?limit=1000", nil)
+		var invalid *httpio.ValidationError
+		require.ErrorAs(t, httpio.Unmarshal(r, &v), &invalid)
+		require.Equal(t, "limit", invalid.Fields[0].Field)
+	})
+
+	t.Run("headers", func(t *testing.T) {
+		type input struct {
+			Languages []string `header:"accept-language"`
+			Forwarded []string `header:"X-Forwarded-For,comma"`
+			Retries   int      `header:"x-retry-count"`
+			RequestID string   `header:"x-request-id,required"`
+		}
+
+		r := httptest.NewRequest("GET", "/", nil)
+		r.Header.Add("Accept-Language", "en")
+		r.Header.Add("Accept-Language", "fr")
+		r.Header.Add("X-Forwarded-For", "10.0.0.1, 10.0.0.2")
+		r.Header.Set("X-Request-ID", "r1")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{
+			Languages: []string{"en", "fr"},
+			Forwarded: []string{"10.0.0.1", "10.0.0.2"},
+			RequestID: "r1",
+		}, v)
+		require.Equal(t, "Accept-Language", describe(t, reflect.TypeOf(v))[0].Name)
+
+		r = httptest.NewRequest("GET", "/", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &v), "missing required parameters: X-Request-Id (header)")
+	})
+
+	t.Run("raw body", func(t *testing.T) {
+		type input struct {
+			Signature string          `header:"X-Signature"`
+			Payload   json.RawMessage `body:"raw"`
+			Event     string          `json:"event"`
+		}
+
+		body := `{"event": "paid",  "id": 1}`
+		r := httptest.NewRequest("POST", "/", strings.NewReader(body))
+		r.Header.Set("Content-Type", "application/json")
+		r.Header.Set("X-Signature", "sha256=abc")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Signature: "sha256=abc", Payload: json.RawMessage(body), Event: "paid"}, v)
+
+		type form struct {
+			Raw  []byte `body:"raw"`
+			Text string `form:"text"`
+		}
+		r = httptest.NewRequest("POST", "/", strings.NewReader("text=hi"))
+		r.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		var f form
+		require.NoError(t, httpio.Unmarshal(r, &f))
+		require.Equal(t, form{Raw: []byte("text=hi"), Text: "hi"}, f)
+
+		// the body isn't read for invalid definitions
+		type badRaw struct {
+			Raw string `body:"raw"`
+		}
+		r = httptest.NewRequest("POST", "/", strings.NewReader("text=hi"))
+		require.EqualError(t, httpio.Unmarshal(r, &badRaw{}), "httpio: raw body field Raw must be []byte, got string")
+		rest, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		require.Equal(t, "text=hi", string(rest))
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		type input struct {
+			Token  []byte   `query:"token,base64"`
+			Sig    []byte   `header:"X-Signature,base64"`
+			Chunks [][]byte `query:"chunk,base64"`
+		}
+
+		r := httptest.NewRequest("GET", "/?token=aGk_Pz4&chunk=YQ==&chunk=Yg", nil)
+		r.Header.Set("X-Signature", "aGk/Pz4=")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{
+			Token:  []byte("hi??>"),
+			Sig:    []byte("hi??>"),
+			Chunks: [][]byte{[]byte("a"), []byte("b")},
+		}, v)
+
+		r = httptest.NewRequest("GET", "/?token=!!", nil)
+		require.ErrorContains(t, httpio.Unmarshal(r, &v), "failed to parse token as base64")
+	})
+
+	t.Run("decode errors", func(t *testing.T) {
+		type page struct {
+			Limit int `query:"limit"`
+		}
+		type input struct {
+			Page  page           `query:"page"`
+			IDs   []int          `query:"id"`
+			Sizes map[string]int `query:"size"`
+			Retry int            `header:"X-Retry"`
+			Name  string         `query:"name"`
+		}
+
+		r := httptest.NewRequest("GET", "/?page_limit=ten&id=1&id=x&size_s=big&name=ok", nil)
+		r.Header.Set("X-Retry", "3")
+
+		var v input
+		err := httpio.Unmarshal(r, &v)
+		var errs httpio.DecodeErrors
+		require.ErrorAs(t, err, &errs)
+		require.Len(t, errs, 3)
+		for i, want := range []httpio.DecodeError{
+			{Field: "page_limit", Source: "query", Value: "ten"},
+			{Field: "id", Source: "query", Value: "x"},
+			{Field: "size_s", Source: "query", Value: "big"},
+		} {
+			require.Equal(t, want.Field, errs[i].Field)
+			require.Equal(t, want.Source, errs[i].Source)
+			require.Equal(t, want.Value, errs[i].Value)
+		}
+		var numErr *strconv.NumError
+		require.ErrorAs(t, errs[0], &numErr)
+		require.ErrorContains(t, err, "failed to parse page_limit as int")
+
+		r = httptest.NewRequest("GET", "/", nil)
+		r.Header.Set("X-Retry", "soon")
+		err = httpio.Unmarshal(r, &v)
+		var decodeErr *httpio.DecodeError
+		require.ErrorAs(t, err, &decodeErr)
+		require.Equal(t, "header", decodeErr.Source)
+		require.Equal(t, "X-Retry", decodeErr.Field)
+	})
+
+	t.Run("bool", func(t *testing.T) {
+		type input struct {
+			A bool `query:"a"`
+			B bool `query:"b"`
+			C bool `query:"c"`
+			D bool `query:"d"`
+			E bool `query:"e"`
+		}
+
+		r := httptest.NewRequest("GET", "/?a=YES&b=off&c=1&d=True&e=", nil)
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{A: true, C: true, D: true}, v)
+
+		r = httptest.NewRequest("GET", "/?a=maybe", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &v), `failed to parse a as bool: invalid bool "maybe"`)
+
+		r = httptest.NewRequest("GET", "/?a=yes", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &v, httpio.WithStrictBool(true)), `failed to parse a as bool: invalid bool "yes", expected true or false`)
+		type flags struct {
+			F []bool `query:"f"`
+		}
+		r = httptest.NewRequest("GET", "/?f=true&f=on", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &flags{}, httpio.WithStrictBool(true)), `failed to parse f as bool: invalid bool "on", expected true or false`)
+
+		httpio.SetStrictBool(true)
+		t.Cleanup(func() { httpio.SetStrictBool(false) })
+
+		r = httptest.NewRequest("GET", "/?a=true&b=false", nil)
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		r = httptest.NewRequest("GET", "/?a=yes", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &v), `failed to parse a as bool: invalid bool "yes", expected true or false`)
+		require.NoError(t, httpio.Unmarshal(r, &v, httpio.WithStrictBool(false)))
+	})
+
+	t.Run("uuid, ip and url", func(t *testing.T) {
+		type input struct {
+			ID       uuid       `path:"id"`
+			IP       net.IP     `header:"X-Real-Ip"`
+			Allowed  []net.IP   `query:"allow"`
+			Addr     netip.Addr `query:"addr"`
+			Callback url.URL    `query:"callback"`
+			Redirect *url.URL   `query:"redirect"`
+		}
+
+		r := httptest.NewRequest("GET", "/?allow=10.0.0.1&allow=::1&addr=192.168.0.1&callback=https://example.com/hook?a=1", nil)
+		r.SetPathValue("id", "6ba7b810-9dad-11d1-80b4-00c04fd430c8")
+		r.Header.Set("X-Real-Ip", "127.0.0.1")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, "6ba7b810-9dad-11d1-80b4-00c04fd430c8", v.ID.String())
+		require.Equal(t, "127.0.0.1", v.IP.String())
+		require.Equal(t, []net.IP{net.ParseIP("10.0.0.1"), net.ParseIP("::1")}, v.Allowed)
+		require.Equal(t, netip.MustParseAddr("192.168.0.1"), v.Addr)
+		require.Equal(t, "example.com", v.Callback.Host)
+		require.Nil(t, v.Redirect)
+
+		r = httptest.NewRequest("GET", "/", nil)
+		r.SetPathValue("id", "not-a-uuid")
+		require.ErrorContains(t, httpio.Unmarshal(r, &v), "failed to parse id as httpio_test.uuid")
+	})
+
+	t.Run("pointer groups", func(t *testing.T) {
+		type rangeFilter struct {
+			From int `query:"from"`
+			To   int `query:"to" validate:"max=100"`
+		}
+		type input struct {
+			Price  *rangeFilter `query:"price"`
+			Rating *rangeFilter `query:"rating"`
+		}
+
+		r := httptest.NewRequest("GET", "/?price_from=10&price_to=20", nil)
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, input{Price: &rangeFilter{From: 10, To: 20}}, v)
+
+		r = httptest.NewRequest("GET", "/?rating_to=500", nil)
+		v = input{}
+		var invalid *httpio.ValidationError
+		require.ErrorAs(t, httpio.Unmarshal(r, &v), &invalid)
+		require.Equal(t, "rating_to", invalid.Fields[0].Field)
+		require.Nil(t, v.Price)
+	})
+
+	t.Run("options", func(t *testing.T) {
+		type page struct {
+			Limit int `query:"limit"`
+		}
+		type input struct {
+			Page page   `query:"page"`
+			ID   int    `path:"id"`
+			Name string `xml:"name"`
+		}
+
+		r := httptest.NewRequest("POST", "/?page.limit=10", strings.NewReader("<input><name>John</name></input>"))
+		r.Header.Set("Content-Type", "text/xml")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v,
+			httpio.WithDelimiter('.'),
+			httpio.WithCodec("text/xml", xmlCodec{}),
+			httpio.WithPathLookuper(func(r *http.Request, name string) (string, bool) {
+				return map[string]string{"id": "7"}[name], true
+			}),
+		))
+		require.Equal(t, input{Page: page{Limit: 10}, ID: 7, Name: "John"}, v)
+
+		r = httptest.NewRequest("GET", "/?page.limit=10", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &v, httpio.WithStrict(true)), "unknown parameters: page.limit")
+
+		r = httptest.NewRequest("POST", "/", strings.NewReader("<input><name>John</name></input>"))
+		r.Header.Set("Content-Type", "text/xml")
+		err := httpio.Unmarshal(r, &v, httpio.WithCodec("text/xml", xmlCodec{}), httpio.WithMaxBodySize(8))
+		require.ErrorIs(t, err, httpio.ErrBodyTooLarge)
+	})
+
+	t.Run("aliases", func(t *testing.T) {
+		type fullName struct {
+			First string `query:"first,alias=name.first,alias=first_name"`
+			Last  string `query:"last,alias=name.last"`
+		}
+		type input struct {
+			Name  fullName `query:"name"`
+			Token string   `header:"authorization,alias=x-api-key"`
+		}
+
+		r := httptest.NewRequest("GET", "/?first_name=John&name.last=Doe&name_last=Smith", nil)
+		r.Header.Set("X-Api-Key", "secret")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v, httpio.WithStrict(true)))
+		require.Equal(t, input{Name: fullName{First: "John", Last: "Smith"}, Token: "secret"}, v)
+	})
+
+	t.Run("multiple sources", func(t *testing.T) {
+		type input struct {
+			APIKey string   `in:"header=x-api-key,query=api_key,cookie=api_key,required"`
+			Langs  []string `in:"query=lang,header=Accept-Language,comma"`
+			Limit  int      `in:"header=X-Limit,query=limit"`
+		}
+
+		r := httptest.NewRequest("GET", "/?api_key=from-query", nil)
+		r.Header.Set("X-Api-Key", "from-header")
+		r.Header.Set("Accept-Language", "en, de")
+
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v, httpio.WithStrict(true)))
+		require.Equal(t, input{APIKey: "from-header", Langs: []string{"en", "de"}}, v)
+
+		r = httptest.NewRequest("GET", "/?limit=x", nil)
+		r.AddCookie(&http.Cookie{Name: "api_key", Value: "from-cookie"})
+		v = input{}
+		var decodeErr *httpio.DecodeError
+		require.ErrorAs(t, httpio.Unmarshal(r, &v), &decodeErr)
+		require.Equal(t, "limit", decodeErr.Field)
+		require.Equal(t, "query", decodeErr.Source)
+		require.Equal(t, "from-cookie", v.APIKey)
+
+		r = httptest.NewRequest("GET", "/", nil)
+		require.EqualError(t, httpio.Unmarshal(r, &input{}), "missing required parameters: X-Api-Key (header)")
+
+		binding := describe(t, reflect.TypeOf(input{}))[0]
+		require.Equal(t, []httpio.Fallback{{Name: "api_key", Source: "query"}, {Name: "api_key", Source: "cookie"}}, binding.Fallbacks)
+	})
+
+	t.Run("indexed slices of structs", func(t *testing.T) {
+		type item struct {
+			SKU string `query:"sku,required"`
+			Qty int    `query:"qty" validate:"min=1"`
+		}
+		type input struct {
+			Items []item  `query:"items"`
+			Extra []*item `query:"extra"`
+		}
+
+		r := httptest.NewRequest("GET", "/?items.0.sku=A&items.0.qty=2&items.1.sku=B&items.1.qty=1&extra.7.sku=C&extra.7.qty=3", nil)
+		var v input
+		require.NoError(t, httpio.Unmarshal(r, &v, httpio.WithDelimiter('.'), httpio.WithStrict(true)))
+		require.Equal(t, input{
+			Items: []item{{SKU: "A", Qty: 2}, {SKU: "B", Qty: 1}},
+			Extra: []*item{{SKU: "C", Qty: 3}},
+		}, v)
+
+		r = httptest.NewRequest("GET", "/?items_0_qty=2", nil)
+		v = input{}
+		require.EqualError(t, httpio.Unmarshal(r, &v), "missing required parameters: items_0_sku (query)")
+
+		r = httptest.NewRequest("GET", "/?items_0_sku=A&items_0_qty=0", nil)
+		var invalid *httpio.ValidationError
+		require.ErrorAs(t, httpio.Unmarshal(r, &v), &invalid)
+		require.Equal(t, "items_0_qty", invalid.Fields[0].Field)
+
+		// fields are named with the delimiter of the call
+		r = httptest.NewRequest("GET", "/?items.0.sku=A&items.0.qty=0", nil)
+		require.ErrorAs(t, httpio.Unmarshal(r, &v, httpio.WithDelimiter('.')), &invalid)
+		require.Equal(t, "items.0.qty", invalid.Fields[0].Field)
+	})
+
+	t.Run("recursive group", func(t *testing.T) {
+		type node struct {
+			V    int   `query:"v"`
+			Next *node `query:"next"`
+		}
+		type input struct {
+			Node node `query:"node"`
+		}
+
+		r := httptest.NewRequest("GET", "/?node_v=1&node_next_v=2", nil)
+		err := httpio.Unmarshal(r, &input{})
+		require.EqualError(t, err, "httpio: field Node.Next has recursive type httpio_test.node, use a slice of structs instead")
+		_, describeErr := httpio.Describe(reflect.TypeOf(input{}))
+		require.Equal(t, err, describeErr)
+
+		// the same group in sibling fields isn't recursive
+		type point struct {
+			X int `query:"x"`
+		}
+		type line struct {
+			From point `query:"from"`
+			To   point `query:"to"`
+		}
+		r = httptest.NewRequest("GET", "/?from_x=1&to_x=2", nil)
+		var v line
+		require.NoError(t, httpio.Unmarshal(r, &v))
+		require.Equal(t, line{From: point{X: 1}, To: point{X: 2}}, v)
+	})
+}
+
+// describe returns bindings of t, failing the test on invalid struct definitions
+func describe(t *testing.T, typ reflect.Type) []httpio.Binding {
+	t.Helper()
+	bindings, err := httpio.Describe(typ)
+	require.NoError(t, err)
+	return bindings
+}
+
+type xmlCodec struct{}
+
+func (xmlCodec) Decode(r io.Reader, v interface{}) error { return xml.NewDecoder(r).Decode(v) }
+func (xmlCodec) Encode(w io.Writer, v interface{}) error { return xml.NewEncoder(w).Encode(v) }
+
+// uuid stands for uuid.UUID, which is bound through encoding.TextUnmarshaler
+type uuid [16]byte
+
+func (u *uuid) UnmarshalText(text []byte) error {
+	b, err := hex.DecodeString(strings.ReplaceAll(string(text), "-", ""))
+	if err != nil || len(b) != len(u) {
+		return fmt.Errorf("invalid uuid %q", text)
+	}
+	copy(u[:], b)
+	return nil
+}
+
+func (u uuid) String() string {
+	s := hex.EncodeToString(u[:])
+	return s[:8] + "-" + s[8:12] + "-" + s[12:16] + "-" + s[16:20] + "-" + s[20:]
 }
 
 func BenchmarkUnmarshal(b *testing.B) {
@@ -157,7 +1020,7 @@ func BenchmarkUnmarshal(b *testing.B) {
 		} `query:"field11"`
 	}
 
-	r := httptest.NewRequest("GET", "/?name.first=John&name.last=Doe&age=30&banned=true&income=100000", nil)
+	r := httptest.NewRequest("GET", "/?name_first=John&name_last=Doe&age=30&banned=true&income=100000", nil)
 
 	b.ResetTimer()
 	b.ReportAllocs()
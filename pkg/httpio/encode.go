@@ -0,0 +1,256 @@
+package httpio
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Marshal writes v to w mirroring Unmarshal: fields tagged header and cookie are written as
+// response headers and cookies, an int field tagged `status:""` sets the status code
+// and v is written as the JSON body. Zero header and cookie values are omitted.
+// Cookie fields may be http.Cookie to set attributes, the tag names them unless Name is set.
+// Header, cookie and status fields must be exported and tagged `json:"-"`, so the body is encoded
+// like any other value, e.g. with its MarshalJSON method. Without other fields no body is written.
+//
+//	type listResp struct {
+//		NextCursor string `header:"X-Next-Cursor" json:"-"`
+//		Status     int    `status:"" json:"-"`
+//		Items      []Item `json:"items"`
+//	}
+func Marshal(w http.ResponseWriter, v interface{}) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return writeBody(w, http.StatusOK, v)
+	}
+
+	enc := encoderFor(rv.Type())
+	if enc.err != nil {
+		return enc.err
+	}
+	status := http.StatusOK
+	for _, f := range enc.meta {
+		fv := rv.Field(f.index)
+		switch f.tagType {
+		case tagTypeStatus:
+			if code := int(fv.Int()); code != 0 {
+				status = code
+			}
+		case tagTypeHeader:
+			for _, value := range formatValues(fv, f.opts) {
+				w.Header().Add(f.name, value)
+			}
+		case tagTypeCookie:
+			if cookie := formatCookie(fv, f.name, f.opts); cookie != nil {
+				http.SetCookie(w, cookie)
+			}
+		}
+	}
+
+	if !enc.body {
+		w.WriteHeader(status)
+		return nil
+	}
+	return writeBody(w, status, v)
+}
+
+// CheckResponse returns the error Marshal returns for values of t with invalid header, cookie or status fields
+func CheckResponse(t reflect.Type) error {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil
+	}
+	return encoderFor(t).err
+}
+
+func writeBody(w http.ResponseWriter, status int, v interface{}) error {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	return JSONCodec{}.Encode(w, v)
+}
+
+// tagTypeStatus marks the field holding the response status code, it's only used by Marshal
+const tagTypeStatus tagType = -1
+
+type metaField struct {
+	index   int
+	name    string
+	tagType tagType
+	opts    tagOptions
+}
+
+// encoder lists metadata fields of a response type
+type encoder struct {
+	meta []metaField
+	// body is false for types without fields written as the body, e.g. only a Location header and a status
+	body bool
+	// err is the invalid definition of a metadata field, Marshal returns it for every value
+	err error
+}
+
+var encoders sync.Map // reflect.Type -> *encoder
+
+var jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+
+func encoderFor(t reflect.Type) *encoder {
+	if enc, ok := encoders.Load(t); ok {
+		return enc.(*encoder)
+	}
+
+	enc := &encoder{body: reflect.PointerTo(t).Implements(jsonMarshalerType)}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		f := metaField{index: i, tagType: tagTypeStatus}
+		_, isStatus := field.Tag.Lookup("status")
+		if !isStatus {
+			var name string
+			var ok bool
+			name, f.tagType, f.opts, ok = findInTag(field)
+			if !ok || (f.tagType != tagTypeHeader && f.tagType != tagTypeCookie) {
+				// fields of embedded structs are promoted to the body even if the struct type is unexported
+				if (field.IsExported() || field.Anonymous) && field.Tag.Get("json") != "-" {
+					enc.body = true
+				}
+				continue
+			}
+			f.name = name
+		}
+		if enc.err = checkMetaField(field, f.tagType); enc.err != nil {
+			break
+		}
+		enc.meta = append(enc.meta, f)
+	}
+
+	actual, _ := encoders.LoadOrStore(t, enc)
+	return actual.(*encoder)
+}
+
+// checkMetaField returns an error if Marshal can't write field as metadata of tagType or would write it to the body too
+func checkMetaField(field reflect.StructField, tagType tagType) error {
+	source := "status"
+	if tagType != tagTypeStatus {
+		source = tagTypeNames[tagType]
+	}
+	switch {
+	case !field.IsExported():
+		return fmt.Errorf("httpio: %s field %s must be exported", source, field.Name)
+	case field.Tag.Get("json") != "-":
+		return fmt.Errorf(`httpio: %s field %s must be tagged json:"-"`, source, field.Name)
+	case tagType == tagTypeStatus && !isInt(field.Type):
+		return fmt.Errorf("httpio: status field %s must be an int, got %v", field.Name, field.Type)
+	case tagType != tagTypeStatus && !isFormatted(field.Type, tagType):
+		return fmt.Errorf("httpio: %s field %s has unsupported type %v", source, field.Name, field.Type)
+	}
+	return nil
+}
+
+func isInt(t reflect.Type) bool {
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return true
+	}
+	return false
+}
+
+// isFormatted reports whether formatValues formats values of t: types setField parses, slices and pointers of them,
+// cookie fields may be http.Cookie as well
+func isFormatted(t reflect.Type, tagType tagType) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if tagType == tagTypeCookie && t == cookieType {
+		return true
+	}
+	if t.Kind() == reflect.Slice && !isValueType(t) && t != bytesType {
+		t = t.Elem()
+	}
+	if isValueType(t) || t == bytesType {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool, reflect.Float32, reflect.Float64:
+		return true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return true
+	}
+	return isInt(t)
+}
+
+var cookieType = reflect.TypeOf(http.Cookie{})
+
+func formatCookie(v reflect.Value, name string, opts tagOptions) *http.Cookie {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Type() == cookieType {
+		cookie := v.Interface().(http.Cookie)
+		if cookie.Name == "" {
+			cookie.Name = name
+		}
+		return &cookie
+	}
+
+	values := formatValues(v, opts)
+	if len(values) == 0 {
+		return nil
+	}
+	return &http.Cookie{Name: name, Value: strings.Join(values, ",")}
+}
+
+// formatValues formats a field the way setField parses it, slices format into a value per element
+func formatValues(v reflect.Value, opts tagOptions) []string {
+	if v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.IsZero() {
+		return nil
+	}
+	if v.Kind() == reflect.Slice && !isValueType(v.Type()) && !(opts.base64 && v.Type() == bytesType) {
+		values := make([]string, 0, v.Len())
+		for i := range v.Len() {
+			values = append(values, formatValue(v.Index(i), opts))
+		}
+		if opts.comma {
+			return []string{strings.Join(values, ",")}
+		}
+		return values
+	}
+	return []string{formatValue(v, opts)}
+}
+
+func formatValue(v reflect.Value, opts tagOptions) string {
+	switch value := v.Interface().(type) {
+	case []byte:
+		if opts.base64 {
+			return base64.StdEncoding.EncodeToString(value)
+		}
+	case url.URL:
+		return value.String()
+	case time.Time:
+		layout := opts.layout
+		if layout == "" {
+			layout = time.RFC3339
+		}
+		return value.Format(layout)
+	case fmt.Stringer:
+		return value.String()
+	}
+	return fmt.Sprint(v.Interface())
+}
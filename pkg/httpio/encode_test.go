@@ -0,0 +1,130 @@
+package httpio_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/httpio"
+	"github.com/stretchr/testify/require"
+)
+
+func TestMarshal(t *testing.T) {
+	t.Run("metadata and body", func(t *testing.T) {
+		type item struct {
+			ID int `json:"id"`
+		}
+		type resp struct {
+			NextCursor string      `header:"X-Next-Cursor" json:"-"`
+			Prev       *string     `header:"X-Prev-Cursor" json:"-"`
+			Links      []string    `header:"Link" json:"-"`
+			Expires    time.Time   `header:"Expires" layout:"2006-01-02" json:"-"`
+			Session    http.Cookie `cookie:"session" json:"-"`
+			Theme      string      `cookie:"theme" json:"-"`
+			Status     int         `status:"" json:"-"`
+			Items      []item      `json:"items"`
+			Total      int         `json:"total,omitempty"`
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httpio.Marshal(w, resp{
+			NextCursor: "abc",
+			Links:      []string{"</a>", "</b>"},
+			Expires:    time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC),
+			Session:    http.Cookie{Value: "s1", HttpOnly: true},
+			Status:     http.StatusCreated,
+			Items:      []item{{ID: 1}},
+		}))
+
+		require.Equal(t, http.StatusCreated, w.Code)
+		require.Equal(t, "abc", w.Header().Get("X-Next-Cursor"))
+		require.Empty(t, w.Header().Values("X-Prev-Cursor"))
+		require.Equal(t, []string{"</a>", "</b>"}, w.Header().Values("Link"))
+		require.Equal(t, "2024-05-01", w.Header().Get("Expires"))
+		require.Equal(t, []string{"session=s1; HttpOnly"}, w.Header().Values("Set-Cookie"))
+		require.Equal(t, "application/json", w.Header().Get("Content-Type"))
+		require.JSONEq(t, `{"items":[{"id":1}]}`, w.Body.String())
+	})
+
+	t.Run("no body", func(t *testing.T) {
+		type resp struct {
+			Location string `header:"Location" json:"-"`
+			Status   int    `status:"" json:"-"`
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httpio.Marshal(w, &resp{Location: "/users/1", Status: http.StatusSeeOther}))
+		require.Equal(t, http.StatusSeeOther, w.Code)
+		require.Equal(t, "/users/1", w.Header().Get("Location"))
+		require.Empty(t, w.Body.String())
+	})
+
+	t.Run("base64", func(t *testing.T) {
+		type resp struct {
+			Sig []byte `header:"X-Signature,base64" json:"-"`
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httpio.Marshal(w, resp{Sig: []byte("hi??>")}))
+		require.Equal(t, "aGk/Pz4=", w.Header().Get("X-Signature"))
+	})
+
+	t.Run("custom body", func(t *testing.T) {
+		type resp struct {
+			tagged
+			Status int `status:"" json:"-"`
+		}
+
+		w := httptest.NewRecorder()
+		require.NoError(t, httpio.Marshal(w, resp{tagged: tagged{Name: "a"}, Status: http.StatusAccepted}))
+		require.Equal(t, http.StatusAccepted, w.Code)
+		require.JSONEq(t, `{"tagged":"a"}`, w.Body.String())
+	})
+
+	t.Run("invalid metadata", func(t *testing.T) {
+		for _, tt := range []struct {
+			name string
+			v    interface{}
+			err  string
+		}{
+			{"not excluded from the body", struct {
+				Token string `header:"X-Token"`
+			}{}, `httpio: header field Token must be tagged json:"-"`},
+			{"unexported", struct {
+				token string `cookie:"token" json:"-"`
+			}{}, "httpio: cookie field token must be exported"},
+			{"status not an int", struct {
+				Status string `status:"" json:"-"`
+			}{}, "httpio: status field Status must be an int, got string"},
+			{"unsupported type", struct {
+				Meta map[string]string `header:"X-Meta" json:"-"`
+			}{}, "httpio: header field Meta has unsupported type map[string]string"},
+		} {
+			t.Run(tt.name, func(t *testing.T) {
+				w := httptest.NewRecorder()
+				require.EqualError(t, httpio.Marshal(w, tt.v), tt.err)
+				require.Empty(t, w.Header())
+				require.EqualError(t, httpio.CheckResponse(reflect.TypeOf(tt.v)), tt.err)
+			})
+		}
+	})
+
+	t.Run("plain value", func(t *testing.T) {
+		w := httptest.NewRecorder()
+		require.NoError(t, httpio.Marshal(w, []string{"a"}))
+		require.Equal(t, http.StatusOK, w.Code)
+		require.JSONEq(t, `["a"]`, w.Body.String())
+	})
+}
+
+// tagged is encoded as an object naming it, its method is promoted to the types it's embedded in
+type tagged struct {
+	Name string
+}
+
+func (t tagged) MarshalJSON() ([]byte, error) {
+	return json.Marshal(map[string]string{"tagged": t.Name})
+}
@@ -0,0 +1,76 @@
+package httpio
+
+import (
+	"net/http"
+)
+
+// Option configures a single Unmarshal call, overriding package-level settings
+type Option func(*options)
+
+type options struct {
+	delimiter    byte
+	strict       bool
+	strictBool   bool
+	maxBodySize  int64
+	codecs       map[string]Codec
+	pathLookuper pathLookuper
+}
+
+func newOptions(opts []Option) options {
+	o := options{
+		delimiter:   delimiter,
+		strict:      strictMode,
+		strictBool:  strictBool,
+		maxBodySize: maxBodySize,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+	return o
+}
+
+// WithDelimiter joins names of nested groups with d, see SetDelimiter
+func WithDelimiter(d byte) Option {
+	return func(o *options) {
+		o.delimiter = d
+	}
+}
+
+// WithStrict rejects unknown params and JSON fields, see SetStrict
+func WithStrict(strict bool) Option {
+	return func(o *options) {
+		o.strict = strict
+	}
+}
+
+// WithStrictBool accepts only true and false as bool values, see SetStrictBool
+func WithStrictBool(strict bool) Option {
+	return func(o *options) {
+		o.strictBool = strict
+	}
+}
+
+// WithMaxBodySize limits the body to n bytes, 0 disables the limit, see SetMaxBodySize
+func WithMaxBodySize(n int64) Option {
+	return func(o *options) {
+		o.maxBodySize = n
+	}
+}
+
+// WithCodec decodes bodies of mediaType with codec, taking precedence over codecs added with RegisterCodec
+func WithCodec(mediaType string, codec Codec) Option {
+	return func(o *options) {
+		if o.codecs == nil {
+			o.codecs = make(map[string]Codec)
+		}
+		o.codecs[mediaType] = codec
+	}
+}
+
+// WithPathLookuper looks up path params with lookuper, e.g. of a router other than http.ServeMux.
+// By default they are looked up with http.Request.PathValue.
+func WithPathLookuper(lookuper func(r *http.Request, name string) (string, bool)) Option {
+	return func(o *options) {
+		o.pathLookuper = lookuper
+	}
+}
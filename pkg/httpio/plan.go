@@ -0,0 +1,266 @@
+package httpio
+
+import (
+	"fmt"
+	"net/textproto"
+	"reflect"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// Binding describes how a single field is bound from the request
+type Binding struct {
+	// Field is the Go path of the field, e.g. Name.First
+	Field string `json:"field"`
+	// Name is the full parameter name, e.g. name_first, header names are canonical, e.g. Accept-Language
+	Name string `json:"name"`
+	// Source is one of query, path, header, cookie, form or body
+	Source string `json:"source"`
+	// Type is the Go type of the field
+	Type string `json:"type"`
+	// Optional is true for pointer fields, which are left nil when the value is absent
+	Optional bool `json:"optional"`
+	// Required is true for fields tagged with the required option
+	Required bool `json:"required"`
+	// Aliases are alternative names of the param in the same source, see the alias tag option
+	Aliases []string `json:"aliases,omitempty"`
+	// Fallbacks are params of other sources looked up in order if the param is absent, see the in tag
+	Fallbacks []Fallback `json:"fallbacks,omitempty"`
+	// Index is the index sequence of the field, see reflect.Type.FieldByIndex
+	Index []int `json:"-"`
+}
+
+// Fallback is a param of another source a field is bound from, e.g. the api_key query param
+type Fallback struct {
+	Name   string `json:"name"`
+	Source string `json:"source"`
+}
+
+var tagTypeNames = map[tagType]string{
+	tagTypeQuery:  "query",
+	tagTypePath:   "path",
+	tagTypeHeader: "header",
+	tagTypeCookie: "cookie",
+	tagTypeForm:   "form",
+	tagTypeBody:   "body",
+}
+
+// Describe returns bindings Unmarshal applies to values of type t, in field order.
+// It returns the same error as Unmarshal for invalid struct definitions,
+// e.g. a recursive group or a catch-all field that is not map[string][]string.
+func Describe(t reflect.Type) ([]Binding, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return nil, nil
+	}
+
+	plan, err := planFor(t, delimiter)
+	if err != nil {
+		return nil, err
+	}
+	bindings := make([]Binding, len(plan))
+	for i, f := range plan {
+		bindings[i] = Binding{
+			Field:    f.field,
+			Name:     f.fullName,
+			Source:   tagTypeNames[f.tagType],
+			Type:     f.typ.String(),
+			Optional: f.typ.Kind() == reflect.Pointer,
+			Required: f.opts.required,
+			Aliases:  f.aliases,
+			Index:    f.index,
+		}
+		for _, src := range f.sources {
+			bindings[i].Fallbacks = append(bindings[i].Fallbacks, Fallback{Name: src.name, Source: tagTypeNames[src.tagType]})
+		}
+	}
+	return bindings, nil
+}
+
+type bindKind int
+
+const (
+	bindValue bindKind = iota
+	bindSlice
+	bindMap
+	bindFile
+	bindRawBody
+	bindStructSlice
+	// bindRest collects query params no other field claims, e.g. `query:"*"`
+	bindRest
+)
+
+// fieldPlan is a field bound from the request, resolved once per type
+type fieldPlan struct {
+	// index is the index sequence of the field in the root struct, see reflect.Value.FieldByIndex
+	index []int
+	// field is the Go path of the field, e.g. Name.First
+	field string
+	// fullName is the full parameter name, e.g. name_first
+	fullName string
+	// aliases are alternative full names, see tagOptions
+	aliases []string
+	// sources are params of other sources, see tagOptions
+	sources []paramSource
+	// prefix of params collected into map fields and slices of structs, e.g. meta_
+	prefix  string
+	typ     reflect.Type
+	tagType tagType
+	opts    tagOptions
+	kind    bindKind
+}
+
+type planKey struct {
+	t         reflect.Type
+	delimiter byte
+}
+
+// typePlan is the plan of a type or the error of its invalid definition
+type typePlan struct {
+	fields []fieldPlan
+	err    error
+}
+
+// plans caches fields bound by decode, so decoding does no tag parsing
+var plans sync.Map // planKey -> typePlan
+
+func planFor(t reflect.Type, delimiter byte) ([]fieldPlan, error) {
+	key := planKey{t: t, delimiter: delimiter}
+	if plan, ok := plans.Load(key); ok {
+		return plan.(typePlan).fields, plan.(typePlan).err
+	}
+	fields, err := buildPlan(t, delimiter, nil, "", "", nil, nil)
+	plan, _ := plans.LoadOrStore(key, typePlan{fields: fields, err: err})
+	return plan.(typePlan).fields, plan.(typePlan).err
+}
+
+// buildPlan appends fields of t to plan, groups are the types of groups t is nested in,
+// so a group containing itself is an error rather than an endless plan
+func buildPlan(t reflect.Type, delimiter byte, index []int, fieldPrefix, namePrefix string, groups []reflect.Type, plan []fieldPlan) ([]fieldPlan, error) {
+	if slices.Contains(groups, t) {
+		return nil, fmt.Errorf("httpio: field %s has recursive type %v, use a slice of structs instead", strings.TrimSuffix(fieldPrefix, "."), t)
+	}
+	groups = append(slices.Clip(groups), t)
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+
+		name, tagType, opts, ok := findInTag(field)
+		if !ok {
+			// fields of embedded structs are bound like fields of t, e.g. a shared Pagination
+			if embedded, ok := embeddedStruct(field); ok {
+				var err error
+				plan, err = buildPlan(embedded, delimiter, append(slices.Clip(index), i), fieldPrefix+field.Name+".", namePrefix, groups, plan)
+				if err != nil {
+					return nil, err
+				}
+			}
+			continue
+		}
+
+		f := fieldPlan{
+			index:    append(slices.Clip(index), i),
+			field:    fieldPrefix + field.Name,
+			fullName: namePrefix + name,
+			aliases:  opts.aliases,
+			sources:  opts.sources,
+			typ:      field.Type,
+			tagType:  tagType,
+			opts:     opts,
+		}
+		if tagType == tagTypeHeader {
+			// match headers without canonicalizing names per request, e.g. accept-language is Accept-Language
+			f.fullName = textproto.CanonicalMIMEHeaderKey(f.fullName)
+			f.aliases = make([]string, len(opts.aliases))
+			for i, alias := range opts.aliases {
+				f.aliases[i] = textproto.CanonicalMIMEHeaderKey(alias)
+			}
+		}
+		if slices.ContainsFunc(opts.sources, func(src paramSource) bool { return src.tagType == tagTypeHeader }) {
+			f.sources = slices.Clone(opts.sources)
+			for i, src := range f.sources {
+				if src.tagType == tagTypeHeader {
+					f.sources[i].name = textproto.CanonicalMIMEHeaderKey(src.name)
+				}
+			}
+		}
+		switch kind := field.Type.Kind(); {
+		case tagType == tagTypeQuery && name == "*":
+			if !restType.ConvertibleTo(field.Type) {
+				return nil, fmt.Errorf("httpio: catch-all field %s must be map[string][]string, got %v", f.field, field.Type)
+			}
+			f.kind = bindRest
+		case tagType == tagTypeBody:
+			if kind != reflect.Slice || field.Type.Elem().Kind() != reflect.Uint8 {
+				return nil, fmt.Errorf("httpio: raw body field %s must be []byte, got %v", f.field, field.Type)
+			}
+			f.kind = bindRawBody
+		case tagType == tagTypeForm && isFileType(field.Type):
+			f.kind = bindFile
+		case isGroup(field.Type):
+			// pointer groups are allocated by decode once a nested value is present
+			group := field.Type
+			if kind == reflect.Pointer {
+				group = group.Elem()
+			}
+			var err error
+			plan, err = buildPlan(group, delimiter, f.index, f.field+".", f.fullName+string(delimiter), groups, plan)
+			if err != nil {
+				return nil, err
+			}
+			continue
+		case kind == reflect.Map && (tagType == tagTypeQuery || tagType == tagTypeForm):
+			f.kind = bindMap
+			f.prefix = f.fullName + string(delimiter)
+		case kind == reflect.Slice && isGroup(field.Type.Elem()) && (tagType == tagTypeQuery || tagType == tagTypeForm):
+			f.kind = bindStructSlice
+			f.prefix = f.fullName + string(delimiter)
+		case kind == reflect.Slice && !isValueType(field.Type) && !(opts.base64 && field.Type == bytesType):
+			f.kind = bindSlice
+		}
+		plan = append(plan, f)
+	}
+	return plan, nil
+}
+
+// hasRawBody reports whether t has a field tagged `body:"raw"`, so the body is kept for it
+func hasRawBody(t reflect.Type, delimiter byte) (bool, error) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		return false, nil
+	}
+	plan, err := planFor(t, delimiter)
+	if err != nil {
+		return false, err
+	}
+	return slices.ContainsFunc(plan, func(f fieldPlan) bool { return f.kind == bindRawBody }), nil
+}
+
+// embeddedStruct returns the struct type of an anonymous struct or struct pointer field,
+// pointers to unexported types are skipped as they can't be allocated
+func embeddedStruct(field reflect.StructField) (reflect.Type, bool) {
+	if !field.Anonymous {
+		return nil, false
+	}
+	t := field.Type
+	if t.Kind() == reflect.Pointer {
+		if !field.IsExported() {
+			return nil, false
+		}
+		t = t.Elem()
+	}
+	return t, isGroup(t)
+}
+
+// isGroup reports whether t is a struct or struct pointer grouping nested fields
+func isGroup(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct && !isValueType(t)
+}
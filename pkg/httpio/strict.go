@@ -0,0 +1,68 @@
+package httpio
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+)
+
+var strictMode bool
+
+// SetStrict makes Unmarshal reject unknown JSON fields and report query and form params
+// no field is bound to in UnknownParamsError, so typos like ?pgae=2 fail instead of being ignored.
+// It is not thread-safe and should be called at the beginning of the program.
+func SetStrict(strict bool) {
+	strictMode = strict
+}
+
+// UnknownParamsError lists query and form params no field is bound to, reported in strict mode
+type UnknownParamsError struct {
+	Params []string
+}
+
+func (e *UnknownParamsError) Error() string {
+	return "unknown parameters: " + strings.Join(e.Params, ", ")
+}
+
+// unknownParams returns sorted names of query and form params not bound to fields of t,
+// query params are all known to types with a catch-all field
+func unknownParams(in *decodeIn, t reflect.Type) []string {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// the plan of t is built by decode before, so it's valid
+	plan, _ := planFor(t, in.opts.delimiter)
+	catchAll := slices.ContainsFunc(plan, func(f fieldPlan) bool { return f.kind == bindRest })
+
+	var unknown []string
+	for i, params := range [...]map[string][]string{in.params(tagTypeQuery), in.formVals} {
+		if i == 0 && catchAll {
+			continue
+		}
+		for param := range params {
+			if !claimed(plan, param) && !slices.Contains(unknown, param) {
+				unknown = append(unknown, param)
+			}
+		}
+	}
+	slices.Sort(unknown)
+	return unknown
+}
+
+// claimed reports whether a query or form field of plan is bound to param
+func claimed(plan []fieldPlan, param string) bool {
+	for _, f := range plan {
+		if slices.ContainsFunc(f.sources, func(src paramSource) bool {
+			return (src.tagType == tagTypeQuery || src.tagType == tagTypeForm) && src.name == param
+		}) {
+			return true
+		}
+		if f.tagType != tagTypeQuery && f.tagType != tagTypeForm || f.kind == bindRest {
+			continue
+		}
+		if param == f.fullName || slices.Contains(f.aliases, param) || (f.prefix != "" && strings.HasPrefix(param, f.prefix)) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,333 @@
+package httpio
+
+import (
+	"fmt"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// FieldError is a validation failure of a single field
+type FieldError struct {
+	// Field is the path of the field named like in the request, e.g. name_first or X-Token
+	Field string `json:"field"`
+	// Rule is the failed rule, e.g. min
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// ValidationError lists every field failing its validate tag
+type ValidationError struct {
+	Fields []FieldError
+}
+
+func (e *ValidationError) Error() string {
+	msgs := make([]string, len(e.Fields))
+	for i, f := range e.Fields {
+		msgs[i] = f.Field + ": " + f.Message
+	}
+	return "validation failed: " + strings.Join(msgs, "; ")
+}
+
+// Rule is a constraint parsed from a validate tag, e.g. min=1
+type Rule struct {
+	Name  string
+	Value string
+}
+
+// ParseRules parses a validate tag, e.g. `validate:"min=1,max=100"`. Rules are:
+//
+//   - min and max bound numbers, the length of strings in runes and the length of slices and maps
+//   - len is the exact length of strings, slices and maps
+//   - oneof lists allowed values separated by spaces, e.g. oneof=asc desc
+//   - pattern is a regular expression strings must match, it's the last rule and may contain commas
+func ParseRules(tag string) ([]Rule, error) {
+	var rules []Rule
+	for tag != "" {
+		var part string
+		if strings.HasPrefix(tag, "pattern=") {
+			part, tag = tag, ""
+		} else {
+			part, tag, _ = strings.Cut(tag, ",")
+		}
+		name, value, _ := strings.Cut(part, "=")
+		switch name {
+		case "min", "max", "len":
+			if _, err := strconv.ParseFloat(value, 64); err != nil {
+				return nil, fmt.Errorf("invalid %s rule %q: %w", name, part, err)
+			}
+		case "pattern":
+			if _, err := compilePattern(value); err != nil {
+				return nil, err
+			}
+		case "oneof":
+		default:
+			return nil, fmt.Errorf("unknown validation rule %q", part)
+		}
+		rules = append(rules, Rule{Name: name, Value: value})
+	}
+	return rules, nil
+}
+
+// Enum returns values allowed by the enum tag of field, e.g. `enum:"asc,desc"`
+func Enum(field reflect.StructField) ([]string, bool) {
+	tag, ok := field.Tag.Lookup("enum")
+	if !ok {
+		return nil, false
+	}
+	return strings.Split(tag, ","), true
+}
+
+// patterns caches compiled pattern rules
+var patterns sync.Map // string -> *regexp.Regexp
+
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := patterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("invalid pattern rule %q: %w", pattern, err)
+	}
+	patterns.Store(pattern, re)
+	return re, nil
+}
+
+// Validate checks fields of the struct v points to against their validate tags, see ParseRules,
+// and enum tags listing allowed values of strings and elements of string slices, e.g. `enum:"asc,desc"`.
+// Empty values pass enum tags, mark fields required to reject them.
+// Nil pointers are skipped, nested structs are validated too. Unmarshal calls it after decoding with its options.
+// It returns *ValidationError listing every failed field, named with the delimiter of nested groups, see WithDelimiter.
+func Validate(v interface{}, opts ...Option) error {
+	rv := reflect.ValueOf(v)
+	for rv.Kind() == reflect.Pointer {
+		if rv.IsNil() {
+			return nil
+		}
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return nil
+	}
+
+	var fields []FieldError
+	if err := validateStruct(rv, newOptions(opts).delimiter, "", &fields); err != nil {
+		return err
+	}
+	if len(fields) > 0 {
+		return &ValidationError{Fields: fields}
+	}
+	return nil
+}
+
+// validatedField is a field with a validate tag or holding nested fields to validate
+type validatedField struct {
+	index int
+	// name is the field named like in the request, see fieldName
+	name   string
+	rules  []Rule
+	nested bool
+	// elems are structs of a slice validated with the index in their prefix, e.g. items_0_sku
+	elems bool
+	// embedded structs without a name tag validate their fields without a prefix
+	embedded bool
+}
+
+type validation struct {
+	fields []validatedField
+	err    error
+}
+
+// validations caches parsed validate tags per struct type
+var validations sync.Map // reflect.Type -> *validation
+
+func validationFor(t reflect.Type) *validation {
+	if val, ok := validations.Load(t); ok {
+		return val.(*validation)
+	}
+
+	val := &validation{}
+	for i := range t.NumField() {
+		field := t.Field(i)
+		if !field.IsExported() && !field.Anonymous {
+			continue
+		}
+		f := validatedField{index: i, name: fieldName(field)}
+		if _, ok := embeddedStruct(field); ok && f.name == field.Name {
+			f.embedded = true
+		}
+		if tag, ok := field.Tag.Lookup("validate"); ok {
+			rules, err := ParseRules(tag)
+			if err != nil {
+				val.err = fmt.Errorf("field %s: %w", field.Name, err)
+				break
+			}
+			f.rules = rules
+		}
+		if enum, ok := Enum(field); ok {
+			f.rules = append(f.rules, Rule{Name: "enum", Value: strings.Join(enum, ",")})
+		}
+		ft := field.Type
+		for ft.Kind() == reflect.Pointer {
+			ft = ft.Elem()
+		}
+		f.nested = isGroup(ft)
+		f.elems = ft.Kind() == reflect.Slice && isGroup(ft.Elem())
+		if len(f.rules) > 0 || f.nested || f.elems {
+			val.fields = append(val.fields, f)
+		}
+	}
+
+	actual, _ := validations.LoadOrStore(t, val)
+	return actual.(*validation)
+}
+
+// validateStruct appends failures of fields of v to errs, prefix names the group of v joined with delimiter, e.g. name_
+func validateStruct(v reflect.Value, delimiter byte, prefix string, errs *[]FieldError) error {
+	val := validationFor(v.Type())
+	if val.err != nil {
+		return val.err
+	}
+	for _, f := range val.fields {
+		fv := v.Field(f.index)
+		if len(f.rules) > 0 {
+			if fe, ok := validateValue(fv, f.rules); !ok {
+				fe.Field = prefix + f.name
+				*errs = append(*errs, fe)
+			}
+		}
+		if f.elems {
+			for i := range fv.Len() {
+				elem := fv.Index(i)
+				if elem.Kind() == reflect.Pointer {
+					if elem.IsNil() {
+						continue
+					}
+					elem = elem.Elem()
+				}
+				if err := validateStruct(elem, delimiter, prefix+f.name+string(delimiter)+strconv.Itoa(i)+string(delimiter), errs); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+		if !f.nested {
+			continue
+		}
+		for fv.Kind() == reflect.Pointer && !fv.IsNil() {
+			fv = fv.Elem()
+		}
+		if fv.Kind() != reflect.Struct {
+			continue
+		}
+		nestedPrefix := prefix + f.name + string(delimiter)
+		if f.embedded {
+			nestedPrefix = prefix
+		}
+		if err := validateStruct(fv, delimiter, nestedPrefix, errs); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// fieldName returns the name of field in the request: the name of its param or json tag, the Go name otherwise
+func fieldName(field reflect.StructField) string {
+	if name, _, _, ok := findInTag(field); ok {
+		return name
+	}
+	if name, _, _ := strings.Cut(field.Tag.Get("json"), ","); name != "" && name != "-" {
+		return name
+	}
+	return field.Name
+}
+
+// validateValue checks v against rules and returns the first failed one
+func validateValue(v reflect.Value, rules []Rule) (FieldError, bool) {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return FieldError{}, true
+		}
+		v = v.Elem()
+	}
+
+	for _, rule := range rules {
+		if msg := checkRule(v, rule); msg != "" {
+			return FieldError{Rule: rule.Name, Message: msg}, false
+		}
+	}
+	return FieldError{}, true
+}
+
+// checkRule returns the message of a failed rule, empty if v passes it
+func checkRule(v reflect.Value, rule Rule) string {
+	switch rule.Name {
+	case "min", "max", "len":
+		bound, _ := strconv.ParseFloat(rule.Value, 64)
+		n, isLength, ok := measure(v)
+		if !ok {
+			return ""
+		}
+		switch {
+		case rule.Name == "len" && n != bound:
+			return "length must be " + rule.Value
+		case rule.Name == "min" && n < bound && isLength:
+			return "length must be at least " + rule.Value
+		case rule.Name == "min" && n < bound:
+			return "must be at least " + rule.Value
+		case rule.Name == "max" && n > bound && isLength:
+			return "length must be at most " + rule.Value
+		case rule.Name == "max" && n > bound:
+			return "must be at most " + rule.Value
+		}
+	case "pattern":
+		re, _ := compilePattern(rule.Value)
+		if v.Kind() == reflect.String && !re.MatchString(v.String()) {
+			return "must match " + rule.Value
+		}
+	case "oneof":
+		value := fmt.Sprint(v.Interface())
+		for _, allowed := range strings.Fields(rule.Value) {
+			if value == allowed {
+				return ""
+			}
+		}
+		return "must be one of " + strings.Join(strings.Fields(rule.Value), ", ")
+	case "enum":
+		allowed := strings.Split(rule.Value, ",")
+		values := []reflect.Value{v}
+		if v.Kind() == reflect.Slice {
+			values = make([]reflect.Value, v.Len())
+			for i := range values {
+				values[i] = v.Index(i)
+			}
+		}
+		for _, value := range values {
+			if s := fmt.Sprint(value.Interface()); s != "" && !slices.Contains(allowed, s) {
+				return fmt.Sprintf("%q is not one of %s", s, strings.Join(allowed, ", "))
+			}
+		}
+	}
+	return ""
+}
+
+// measure returns the number min and max compare, the value of numbers and the length of the rest
+func measure(v reflect.Value) (n float64, isLength bool, ok bool) {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(v.Int()), false, true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(v.Uint()), false, true
+	case reflect.Float32, reflect.Float64:
+		return v.Float(), false, true
+	case reflect.String:
+		return float64(utf8.RuneCountInString(v.String())), true, true
+	case reflect.Slice, reflect.Map, reflect.Array:
+		return float64(v.Len()), true, true
+	}
+	return 0, false, false
+}
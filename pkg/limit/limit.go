@@ -0,0 +1,72 @@
+package limit
+
+import (
+	"context"
+	"math"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Limiter caps the number of concurrently handled requests.
+// Requests above the limit wait in queue for at most queueTimeout
+// and are shed with 503 afterwards.
+type Limiter struct {
+	sem          chan struct{}
+	queueTimeout time.Duration
+}
+
+// NewLimiter creates a limiter allowing max concurrent requests
+func NewLimiter(max int, queueTimeout time.Duration) *Limiter {
+	return &Limiter{
+		sem:          make(chan struct{}, max),
+		queueTimeout: queueTimeout,
+	}
+}
+
+// Acquire takes a slot, waiting for at most queue timeout.
+// It returns false if the request should be shed.
+func (l *Limiter) Acquire(ctx context.Context) bool {
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	default:
+	}
+	if l.queueTimeout <= 0 {
+		return false
+	}
+
+	timer := time.NewTimer(l.queueTimeout)
+	defer timer.Stop()
+	select {
+	case l.sem <- struct{}{}:
+		return true
+	case <-timer.C:
+		return false
+	case <-ctx.Done():
+		return false
+	}
+}
+
+// Release frees a slot taken by Acquire
+func (l *Limiter) Release() {
+	<-l.sem
+}
+
+// Reject responds with 503 and Retry-After header to a request that failed to Acquire
+func (l *Limiter) Reject(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(l.queueTimeout.Seconds())))))
+	http.Error(w, http.StatusText(http.StatusServiceUnavailable), http.StatusServiceUnavailable)
+}
+
+// Middleware sheds requests above the limit with 503 and Retry-After header
+func (l *Limiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Acquire(r.Context()) {
+			l.Reject(w)
+			return
+		}
+		defer l.Release()
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,61 @@
+package limit_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/limit"
+	"github.com/stretchr/testify/require"
+)
+
+func TestLimiterSheds(t *testing.T) {
+	release := make(chan struct{})
+	started := make(chan struct{})
+	h := limit.NewLimiter(1, 10*time.Millisecond).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	}))
+
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/", nil))
+	}()
+	<-started
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusServiceUnavailable, w.Code)
+	require.Equal(t, "1", w.Header().Get("Retry-After"))
+
+	close(release)
+	<-done
+}
+
+func TestRateLimiterRejects(t *testing.T) {
+	h := limit.NewRateLimiter(2, time.Minute).Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	for range 2 {
+		w := httptest.NewRecorder()
+		h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+		require.Equal(t, http.StatusOK, w.Code)
+	}
+
+	w := httptest.NewRecorder()
+	h.ServeHTTP(w, httptest.NewRequest("GET", "/", nil))
+	require.Equal(t, http.StatusTooManyRequests, w.Code)
+	// a request is refilled every 30 seconds
+	retry, err := strconv.Atoi(w.Header().Get("Retry-After"))
+	require.NoError(t, err)
+	require.InDelta(t, 30, retry, 1)
+}
+
+func TestRateLimiterRefills(t *testing.T) {
+	l := limit.NewRateLimiter(1, 20*time.Millisecond)
+	require.True(t, l.Allow())
+	require.False(t, l.Allow())
+	require.Eventually(t, l.Allow, time.Second, 5*time.Millisecond)
+}
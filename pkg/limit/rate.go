@@ -0,0 +1,72 @@
+package limit
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// RateLimiter allows a number of requests per interval. Requests are refilled gradually,
+// e.g. 60 per minute allow a request a second after a burst of 60.
+// Requests above the rate are rejected with 429.
+type RateLimiter struct {
+	mu       sync.Mutex
+	requests float64
+	// refill is the time it takes to allow another request
+	refill time.Duration
+	tokens float64
+	last   time.Time
+}
+
+// NewRateLimiter creates a limiter allowing requests per interval
+func NewRateLimiter(requests int, interval time.Duration) *RateLimiter {
+	return &RateLimiter{
+		requests: float64(requests),
+		refill:   interval / time.Duration(requests),
+		tokens:   float64(requests),
+		last:     time.Now(),
+	}
+}
+
+// Allow takes a request from the limit, it returns false if the request should be rejected
+func (l *RateLimiter) Allow() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.fill()
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// fill adds requests refilled since the last call
+func (l *RateLimiter) fill() {
+	now := time.Now()
+	l.tokens = math.Min(l.requests, l.tokens+float64(now.Sub(l.last))/float64(l.refill))
+	l.last = now
+}
+
+// Reject responds with 429 and Retry-After header to a request Allow returned false for
+func (l *RateLimiter) Reject(w http.ResponseWriter) {
+	l.mu.Lock()
+	l.fill()
+	wait := time.Duration((1 - l.tokens) * float64(l.refill))
+	l.mu.Unlock()
+
+	w.Header().Set("Retry-After", strconv.Itoa(int(math.Max(1, math.Ceil(wait.Seconds())))))
+	http.Error(w, http.StatusText(http.StatusTooManyRequests), http.StatusTooManyRequests)
+}
+
+// Middleware rejects requests above the rate with 429 and Retry-After header
+func (l *RateLimiter) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !l.Allow() {
+			l.Reject(w)
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
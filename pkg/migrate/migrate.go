@@ -0,0 +1,266 @@
+// Package migrate applies versioned schema migrations. SQL migrations are read from an fs.FS,
+// usually embedded, as files named <version>_<name>.up.sql and <version>_<name>.down.sql, e.g.
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	err := migrate.Up(ctx, db, migrations)
+//
+// Every migration runs in its own transaction together with the update of the versions table.
+// A migration file may hold several statements if the driver supports it, e.g. MySQL needs multiStatements=true.
+package migrate
+
+import (
+	"cmp"
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strconv"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+// DefaultTable is the table tracking applied versions
+const DefaultTable = "schema_migrations"
+
+// ErrNoDown is returned by Down when the last applied migration can't be rolled back
+var ErrNoDown = errors.New("migration has no down step")
+
+// Migration is a versioned schema change
+type Migration struct {
+	Version int64
+	Name    string
+	Up      func(ctx context.Context, tx dbx.DB) error
+	// Down is nil if the migration can't be rolled back
+	Down func(ctx context.Context, tx dbx.DB) error
+}
+
+// Migrator applies migrations to db
+type Migrator struct {
+	db         *sql.DB
+	fsys       fs.FS
+	table      string
+	dialect    dbx.Dialect
+	migrations []Migration
+}
+
+// Option configures Migrator
+type Option func(*Migrator)
+
+// WithTable sets the table tracking applied versions, DefaultTable by default
+func WithTable(table string) Option {
+	return func(m *Migrator) {
+		m.table = table
+	}
+}
+
+// WithDialect sets placeholder syntax of queries on the versions table
+func WithDialect(d dbx.Dialect) Option {
+	return func(m *Migrator) {
+		m.dialect = d
+	}
+}
+
+// WithMigrations adds Go migrations, e.g. data backfills that are awkward in SQL.
+// Their versions share the sequence with SQL migrations.
+func WithMigrations(migrations ...Migration) Option {
+	return func(m *Migrator) {
+		m.migrations = append(m.migrations, migrations...)
+	}
+}
+
+type appliedVersion struct {
+	Version int64  `db:"version"`
+	Name    string `db:"name"`
+}
+
+var fileRe = regexp.MustCompile(`^(\d+)_(.+)\.(up|down)\.sql$`)
+
+// New creates a migrator of SQL migrations in the root of fsys, fsys may be nil if there are only Go migrations.
+// It returns an error if a file name can't be parsed or versions are duplicated.
+func New(db *sql.DB, fsys fs.FS, opts ...Option) (*Migrator, error) {
+	m := &Migrator{db: db, fsys: fsys, table: DefaultTable}
+	for _, opt := range opts {
+		opt(m)
+	}
+
+	migrations := make(map[int64]*Migration)
+	for i := range m.migrations {
+		mg := m.migrations[i]
+		if _, ok := migrations[mg.Version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", mg.Version)
+		}
+		migrations[mg.Version] = &mg
+	}
+
+	var files []string
+	if fsys != nil {
+		var err error
+		files, err = fs.Glob(fsys, "*.sql")
+		if err != nil {
+			return nil, err
+		}
+	}
+	// SQL files are in their own map, so up and down files of a version don't clash with each other
+	sqlMigrations := make(map[int64]*Migration)
+	for _, file := range files {
+		match := fileRe.FindStringSubmatch(file)
+		if match == nil {
+			return nil, fmt.Errorf("migration file %q is not named <version>_<name>.(up|down).sql", file)
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("migration file %q: %w", file, err)
+		}
+		if _, ok := migrations[version]; ok {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		content, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+
+		mg, ok := sqlMigrations[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: match[2]}
+			sqlMigrations[version] = mg
+		}
+		if mg.Name != match[2] {
+			return nil, fmt.Errorf("duplicate migration version %d", version)
+		}
+		step := execSQL(string(content))
+		if match[3] == "up" {
+			mg.Up = step
+		} else {
+			mg.Down = step
+		}
+	}
+	for version, mg := range sqlMigrations {
+		if mg.Up == nil {
+			return nil, fmt.Errorf("migration %d_%s has no up file", version, mg.Name)
+		}
+		migrations[version] = mg
+	}
+
+	m.migrations = m.migrations[:0]
+	for _, mg := range migrations {
+		m.migrations = append(m.migrations, *mg)
+	}
+	slices.SortFunc(m.migrations, func(a, b Migration) int {
+		return cmp.Compare(a.Version, b.Version)
+	})
+	return m, nil
+}
+
+// Up applies migrations of fsys not applied to db yet, see Migrator.Up
+func Up(ctx context.Context, db *sql.DB, fsys fs.FS, opts ...Option) error {
+	m, err := New(db, fsys, opts...)
+	if err != nil {
+		return err
+	}
+	return m.Up(ctx)
+}
+
+// Up applies migrations not applied yet in version order. It stops at the first failing
+// migration, migrations applied before it stay applied.
+func (m *Migrator) Up(ctx context.Context) error {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	done := make(map[int64]struct{}, len(applied))
+	for _, version := range applied {
+		done[version] = struct{}{}
+	}
+
+	record := dbx.Insert[appliedVersion](m.table).Dialect(m.dialect).Compile()
+	for _, mg := range m.migrations {
+		if _, ok := done[mg.Version]; ok {
+			continue
+		}
+		err := dbx.WithTx(ctx, m.db, func(tx dbx.DB) error {
+			if err := mg.Up(ctx, tx); err != nil {
+				return err
+			}
+			_, err := record.New(appliedVersion{Version: mg.Version, Name: mg.Name}).ExecContext(ctx, tx)
+			return err
+		})
+		if err != nil {
+			return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+		}
+	}
+	return nil
+}
+
+// Down rolls back the last applied migration, it does nothing if there are none
+func (m *Migrator) Down(ctx context.Context) error {
+	applied, err := m.Applied(ctx)
+	if err != nil {
+		return err
+	}
+	if len(applied) == 0 {
+		return nil
+	}
+	last := applied[len(applied)-1]
+
+	idx := slices.IndexFunc(m.migrations, func(mg Migration) bool { return mg.Version == last })
+	if idx < 0 {
+		return fmt.Errorf("applied migration %d is unknown", last)
+	}
+	mg := m.migrations[idx]
+	if mg.Down == nil {
+		return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, ErrNoDown)
+	}
+
+	forget := dbx.Delete[appliedVersion](m.table).Dialect(m.dialect).Where(dbx.Eq("version")).Compile()
+	err = dbx.WithTx(ctx, m.db, func(tx dbx.DB) error {
+		if err := mg.Down(ctx, tx); err != nil {
+			return err
+		}
+		_, err := forget.New(appliedVersion{Version: mg.Version}).ExecContext(ctx, tx)
+		return err
+	})
+	if err != nil {
+		return fmt.Errorf("migration %d_%s: %w", mg.Version, mg.Name, err)
+	}
+	return nil
+}
+
+// Applied returns applied versions in ascending order, the versions table is created if it doesn't exist
+func (m *Migrator) Applied(ctx context.Context) ([]int64, error) {
+	table, err := m.dialect.QuoteIdent(m.table)
+	if err != nil {
+		return nil, err
+	}
+	_, err = m.db.ExecContext(ctx, "CREATE TABLE IF NOT EXISTS "+table+
+		" (version BIGINT PRIMARY KEY, name VARCHAR(255) NOT NULL, applied_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP)")
+	if err != nil {
+		return nil, fmt.Errorf("create versions table: %w", err)
+	}
+
+	rows, err := dbx.Select[appliedVersion](m.table).Dialect(m.dialect).OrderBy("version").Compile().New().ExecContext(ctx, m.db)
+	if err != nil {
+		return nil, err
+	}
+	versions := make([]int64, len(rows))
+	for i, row := range rows {
+		versions[i] = row.Version
+	}
+	return versions, nil
+}
+
+// Migrations returns all known migrations in version order
+func (m *Migrator) Migrations() []Migration {
+	return slices.Clone(m.migrations)
+}
+
+func execSQL(query string) func(ctx context.Context, tx dbx.DB) error {
+	return func(ctx context.Context, tx dbx.DB) error {
+		_, err := tx.ExecContext(ctx, query)
+		return err
+	}
+}
@@ -0,0 +1,81 @@
+package migrate_test
+
+import (
+	"context"
+	"database/sql"
+	"testing"
+	"testing/fstest"
+
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/stretchr/testify/require"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/migrate"
+)
+
+func TestMigrate(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY, name TEXT NOT NULL)")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+		"3_add_age.up.sql":        {Data: []byte("ALTER TABLE users ADD COLUMN age INTEGER NOT NULL DEFAULT 0")},
+	}
+	seed := migrate.Migration{
+		Version: 2,
+		Name:    "seed_users",
+		Up: func(ctx context.Context, tx dbx.DB) error {
+			_, err := tx.ExecContext(ctx, "INSERT INTO users (name) VALUES ('admin')")
+			return err
+		},
+	}
+
+	m, err := migrate.New(db, fsys, migrate.WithDialect(dbx.SQLite), migrate.WithMigrations(seed))
+	require.NoError(t, err)
+	require.NoError(t, m.Up(ctx))
+	// applied migrations are skipped
+	require.NoError(t, m.Up(ctx))
+
+	applied, err := m.Applied(ctx)
+	require.NoError(t, err)
+	require.Equal(t, []int64{1, 2, 3}, applied)
+
+	var age int
+	require.NoError(t, db.QueryRow("SELECT age FROM users WHERE name = 'admin'").Scan(&age))
+
+	// 3 has no down file
+	require.ErrorIs(t, m.Down(ctx), migrate.ErrNoDown)
+
+	_, err = migrate.New(db, fstest.MapFS{"create_users.sql": {}})
+	require.Error(t, err)
+	_, err = migrate.New(db, fsys, migrate.WithMigrations(migrate.Migration{Version: 1, Up: seed.Up}))
+	require.Error(t, err)
+}
+
+func TestDown(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+
+	fsys := fstest.MapFS{
+		"1_create_users.up.sql":   {Data: []byte("CREATE TABLE users (id INTEGER PRIMARY KEY)")},
+		"1_create_users.down.sql": {Data: []byte("DROP TABLE users")},
+	}
+	require.NoError(t, migrate.Up(ctx, db, fsys))
+
+	m, err := migrate.New(db, fsys)
+	require.NoError(t, err)
+	require.NoError(t, m.Down(ctx))
+
+	applied, err := m.Applied(ctx)
+	require.NoError(t, err)
+	require.Empty(t, applied)
+	_, err = db.Exec("SELECT * FROM users")
+	require.Error(t, err)
+}
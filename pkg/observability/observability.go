@@ -0,0 +1,246 @@
+package observability
+
+import (
+	"context"
+	"database/sql"
+	"log/slog"
+	"net/http"
+	"time"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/metric"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pechorka/cruder/internal/respwriter"
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+const instrumentationName = "github.com/pechorka/cruder"
+
+// Attribute keys shared by http, decode and db spans and metrics
+const (
+	AttrHTTPMethod     = attribute.Key("http.request.method")
+	AttrHTTPRoute      = attribute.Key("http.route")
+	AttrHTTPStatusCode = attribute.Key("http.response.status_code")
+	AttrDBStatement    = attribute.Key("db.statement")
+	AttrDBOperation    = attribute.Key("db.operation")
+	AttrDBRowsAffected = attribute.Key("db.rows_affected")
+)
+
+// Log attribute names used for trace correlation
+const (
+	LogTraceID = "trace_id"
+	LogSpanID  = "span_id"
+)
+
+// Config configures tracing and metrics, nil providers fall back to otel globals
+type Config struct {
+	TracerProvider trace.TracerProvider
+	MeterProvider  metric.MeterProvider
+}
+
+// Instruments holds tracer and meters created from Config
+type Instruments struct {
+	tracer          trace.Tracer
+	requestDuration metric.Float64Histogram
+	queryDuration   metric.Float64Histogram
+}
+
+// New creates instruments for the given config
+func New(cfg Config) *Instruments {
+	tp := cfg.TracerProvider
+	if tp == nil {
+		tp = otel.GetTracerProvider()
+	}
+	mp := cfg.MeterProvider
+	if mp == nil {
+		mp = otel.GetMeterProvider()
+	}
+	meter := mp.Meter(instrumentationName)
+
+	requestDuration, err := meter.Float64Histogram("http.server.request.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of HTTP server requests"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+	queryDuration, err := meter.Float64Histogram("db.client.operation.duration",
+		metric.WithUnit("s"),
+		metric.WithDescription("Duration of database queries"),
+	)
+	if err != nil {
+		otel.Handle(err)
+	}
+
+	return &Instruments{
+		tracer:          tp.Tracer(instrumentationName),
+		requestDuration: requestDuration,
+		queryDuration:   queryDuration,
+	}
+}
+
+// Middleware starts a server span for every request and records request duration.
+// Span is renamed to the matched route pattern once the request is routed.
+func (in *Instruments) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		ctx, span := in.tracer.Start(r.Context(), r.Method, trace.WithSpanKind(trace.SpanKindServer))
+		defer span.End()
+
+		sw := respwriter.New(w)
+		r = r.WithContext(ctx)
+		next.ServeHTTP(sw, r)
+
+		attrs := []attribute.KeyValue{
+			AttrHTTPMethod.String(r.Method),
+			AttrHTTPStatusCode.Int(sw.Status()),
+		}
+		if r.Pattern != "" {
+			span.SetName(r.Pattern)
+			attrs = append(attrs, AttrHTTPRoute.String(r.Pattern))
+		}
+		span.SetAttributes(attrs...)
+		if sw.Status() >= http.StatusInternalServerError {
+			span.SetStatus(codes.Error, http.StatusText(sw.Status()))
+		}
+		if in.requestDuration != nil {
+			in.requestDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs...))
+		}
+	})
+}
+
+// Decode wraps request decoding into a span
+func (in *Instruments) Decode(ctx context.Context, decode func() error) error {
+	_, span := in.tracer.Start(ctx, "httpio.Unmarshal")
+	defer span.End()
+
+	err := decode()
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	return err
+}
+
+// DB wraps db so every query gets a client span and a duration measurement.
+// Spans of failed queries get error status, Exec spans get the number of affected rows.
+//
+// Query and QueryRow spans end once the driver returns the result, not when rows are read:
+// *sql.Rows and *sql.Row are concrete types and can't be wrapped to observe Close,
+// so time spent iterating rows and errors reported by rows.Err are not part of the span.
+func (in *Instruments) DB(db dbx.DB) dbx.DB {
+	return &tracedDB{db: db, in: in}
+}
+
+type tracedDB struct {
+	db dbx.DB
+	in *Instruments
+}
+
+func (t *tracedDB) QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row {
+	ctx, done := t.in.startQuery(ctx, "QueryRow", query)
+	row := t.db.QueryRowContext(ctx, query, args...)
+	done(row.Err())
+	return row
+}
+
+func (t *tracedDB) QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error) {
+	ctx, done := t.in.startQuery(ctx, "Query", query)
+	rows, err := t.db.QueryContext(ctx, query, args...)
+	done(err)
+	return rows, err
+}
+
+func (t *tracedDB) ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	ctx, done := t.in.startQuery(ctx, "Exec", query)
+	res, err := t.db.ExecContext(ctx, query, args...)
+	if err == nil {
+		if n, rowsErr := res.RowsAffected(); rowsErr == nil {
+			trace.SpanFromContext(ctx).SetAttributes(AttrDBRowsAffected.Int64(n))
+		}
+	}
+	done(err)
+	return res, err
+}
+
+func (in *Instruments) startQuery(ctx context.Context, operation, query string) (context.Context, func(error)) {
+	start := time.Now()
+	attrs := []attribute.KeyValue{
+		AttrDBOperation.String(operation),
+		AttrDBStatement.String(query),
+	}
+	ctx, span := in.tracer.Start(ctx, "dbx."+operation,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(attrs...),
+	)
+
+	return ctx, func(err error) {
+		if err != nil {
+			span.RecordError(err)
+			span.SetStatus(codes.Error, err.Error())
+		}
+		span.End()
+		if in.queryDuration != nil {
+			// statement is left out of metric attributes to keep cardinality low
+			in.queryDuration.Record(ctx, time.Since(start).Seconds(), metric.WithAttributes(attrs[0]))
+		}
+	}
+}
+
+// Hook returns a dbx.Hook creating the same spans and measurements as DB,
+// for use with dbx.WithHooks next to other hooks. The same limitation on Query spans applies.
+func (in *Instruments) Hook() dbx.Hook {
+	return queryHook{in: in}
+}
+
+type queryHook struct {
+	in *Instruments
+}
+
+type queryDoneKey struct{}
+
+// BeforeQuery implements dbx.Hook
+func (h queryHook) BeforeQuery(ctx context.Context, q dbx.QueryInfo) context.Context {
+	ctx, done := h.in.startQuery(ctx, q.Operation, q.Query)
+	return context.WithValue(ctx, queryDoneKey{}, done)
+}
+
+// AfterQuery implements dbx.Hook
+func (h queryHook) AfterQuery(ctx context.Context, q dbx.QueryInfo, _ time.Duration, err error) {
+	if q.RowsAffected >= 0 {
+		trace.SpanFromContext(ctx).SetAttributes(AttrDBRowsAffected.Int64(q.RowsAffected))
+	}
+	if done, ok := ctx.Value(queryDoneKey{}).(func(error)); ok {
+		done(err)
+	}
+}
+
+// LogHandler wraps h so every record logged with a traced context gets trace and span ids
+func LogHandler(h slog.Handler) slog.Handler {
+	return &logHandler{Handler: h}
+}
+
+type logHandler struct {
+	slog.Handler
+}
+
+func (h *logHandler) Handle(ctx context.Context, record slog.Record) error {
+	if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+		record.AddAttrs(
+			slog.String(LogTraceID, sc.TraceID().String()),
+			slog.String(LogSpanID, sc.SpanID().String()),
+		)
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *logHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *logHandler) WithGroup(name string) slog.Handler {
+	return &logHandler{Handler: h.Handler.WithGroup(name)}
+}
@@ -0,0 +1,244 @@
+package observability_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdkmetric "go.opentelemetry.io/otel/sdk/metric"
+	"go.opentelemetry.io/otel/sdk/metric/metricdata"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/dbx/dbxtest"
+	"github.com/pechorka/cruder/pkg/observability"
+	"github.com/stretchr/testify/require"
+)
+
+func newInstruments(t *testing.T) (*observability.Instruments, *tracetest.SpanRecorder) {
+	in, recorder, _ := newInstrumentsWithMetrics(t)
+	return in, recorder
+}
+
+func newInstrumentsWithMetrics(t *testing.T) (*observability.Instruments, *tracetest.SpanRecorder, *sdkmetric.ManualReader) {
+	t.Helper()
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	reader := sdkmetric.NewManualReader()
+	mp := sdkmetric.NewMeterProvider(sdkmetric.WithReader(reader))
+	t.Cleanup(func() {
+		tp.Shutdown(context.Background())
+		mp.Shutdown(context.Background())
+	})
+	return observability.New(observability.Config{TracerProvider: tp, MeterProvider: mp}), recorder, reader
+}
+
+// histogram returns data points of the named histogram collected by reader
+func histogram(t *testing.T, reader *sdkmetric.ManualReader, name string) []metricdata.HistogramDataPoint[float64] {
+	t.Helper()
+	var rm metricdata.ResourceMetrics
+	require.NoError(t, reader.Collect(context.Background(), &rm))
+	for _, sm := range rm.ScopeMetrics {
+		for _, m := range sm.Metrics {
+			if m.Name == name {
+				return m.Data.(metricdata.Histogram[float64]).DataPoints
+			}
+		}
+	}
+	t.Fatalf("histogram %s is not recorded", name)
+	return nil
+}
+
+func spanAttrs(span sdktrace.ReadOnlySpan) map[attribute.Key]attribute.Value {
+	attrs := make(map[attribute.Key]attribute.Value)
+	for _, kv := range span.Attributes() {
+		attrs[kv.Key] = kv.Value
+	}
+	return attrs
+}
+
+func TestDB(t *testing.T) {
+	for _, tt := range []struct {
+		name string
+		wrap func(*observability.Instruments, dbx.DB) dbx.DB
+	}{
+		{"wrapper", func(in *observability.Instruments, db dbx.DB) dbx.DB { return in.DB(db) }},
+		{"hook", func(in *observability.Instruments, db dbx.DB) dbx.DB { return dbx.WithHooks(db, in.Hook()) }},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			in, recorder := newInstruments(t)
+			fake := dbxtest.New(t)
+			db := tt.wrap(in, fake)
+
+			fake.AddResult(3)
+			_, err := db.ExecContext(ctx, "UPDATE users SET age = 1")
+			require.NoError(t, err)
+
+			fake.AddRows([]string{"id"}, []any{int64(1)})
+			rows, err := db.QueryContext(ctx, "SELECT id FROM users")
+			require.NoError(t, err)
+			require.NoError(t, rows.Close())
+
+			fake.AddError(errors.New("boom"))
+			_, err = db.ExecContext(ctx, "DELETE FROM users")
+			require.Error(t, err)
+
+			spans := recorder.Ended()
+			require.Len(t, spans, 3)
+
+			exec := spans[0]
+			require.Equal(t, "dbx.Exec", exec.Name())
+			require.Equal(t, trace.SpanKindClient, exec.SpanKind())
+			attrs := spanAttrs(exec)
+			require.Equal(t, "Exec", attrs[observability.AttrDBOperation].AsString())
+			require.Equal(t, "UPDATE users SET age = 1", attrs[observability.AttrDBStatement].AsString())
+			require.Equal(t, int64(3), attrs[observability.AttrDBRowsAffected].AsInt64())
+			require.Equal(t, codes.Unset, exec.Status().Code)
+
+			query := spans[1]
+			require.Equal(t, "dbx.Query", query.Name())
+			require.NotContains(t, spanAttrs(query), observability.AttrDBRowsAffected)
+
+			failed := spans[2]
+			require.Equal(t, codes.Error, failed.Status().Code)
+			require.Equal(t, "boom", failed.Status().Description)
+			require.NotContains(t, spanAttrs(failed), observability.AttrDBRowsAffected)
+		})
+	}
+}
+
+func TestMiddleware(t *testing.T) {
+	in, recorder, reader := newInstrumentsWithMetrics(t)
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /items/{id}", func(w http.ResponseWriter, r *http.Request) {
+		require.True(t, trace.SpanContextFromContext(r.Context()).IsValid())
+		w.WriteHeader(http.StatusCreated)
+	})
+	mux.HandleFunc("GET /fail", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "oops", http.StatusBadGateway)
+	})
+	h := in.Middleware(mux)
+
+	for _, path := range []string{"/items/1", "/fail", "/missing"} {
+		h.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, tt := range []struct {
+		name   string
+		status int
+		code   codes.Code
+	}{
+		{"GET /items/{id}", http.StatusCreated, codes.Unset},
+		{"GET /fail", http.StatusBadGateway, codes.Error},
+		// unmatched requests keep the method as span name and have no route
+		{"GET", http.StatusNotFound, codes.Unset},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			i := 0
+			for i < len(spans) && spans[i].Name() != tt.name {
+				i++
+			}
+			require.Less(t, i, len(spans))
+			span := spans[i]
+			require.Equal(t, trace.SpanKindServer, span.SpanKind())
+			require.Equal(t, tt.code, span.Status().Code)
+			attrs := spanAttrs(span)
+			require.Equal(t, "GET", attrs[observability.AttrHTTPMethod].AsString())
+			require.Equal(t, int64(tt.status), attrs[observability.AttrHTTPStatusCode].AsInt64())
+			if tt.name == "GET" {
+				require.NotContains(t, attrs, observability.AttrHTTPRoute)
+			} else {
+				require.Equal(t, tt.name, attrs[observability.AttrHTTPRoute].AsString())
+			}
+		})
+	}
+
+	points := histogram(t, reader, "http.server.request.duration")
+	require.Len(t, points, 3)
+	for _, p := range points {
+		require.Equal(t, uint64(1), p.Count)
+		status, ok := p.Attributes.Value(observability.AttrHTTPStatusCode)
+		require.True(t, ok)
+		route, ok := p.Attributes.Value(observability.AttrHTTPRoute)
+		if status.AsInt64() == http.StatusNotFound {
+			require.False(t, ok)
+		} else {
+			require.True(t, ok)
+			require.Contains(t, []string{"GET /items/{id}", "GET /fail"}, route.AsString())
+		}
+	}
+}
+
+func TestDecode(t *testing.T) {
+	in, recorder := newInstruments(t)
+	ctx, parent := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "parent")
+	defer parent.End()
+
+	require.NoError(t, in.Decode(ctx, func() error { return nil }))
+	err := errors.New("bad request")
+	require.ErrorIs(t, in.Decode(ctx, func() error { return err }), err)
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 2)
+	for _, span := range spans {
+		require.Equal(t, "httpio.Unmarshal", span.Name())
+		require.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	require.Equal(t, codes.Unset, spans[0].Status().Code)
+	require.Equal(t, codes.Error, spans[1].Status().Code)
+	require.Equal(t, "bad request", spans[1].Status().Description)
+	require.Len(t, spans[1].Events(), 1)
+	require.Equal(t, "exception", spans[1].Events()[0].Name)
+}
+
+func TestDBMetrics(t *testing.T) {
+	in, _, reader := newInstrumentsWithMetrics(t)
+	fake := dbxtest.New(t)
+	db := in.DB(fake)
+	for range 2 {
+		_, err := db.ExecContext(context.Background(), "UPDATE users SET age = 1")
+		require.NoError(t, err)
+	}
+
+	points := histogram(t, reader, "db.client.operation.duration")
+	require.Len(t, points, 1)
+	require.Equal(t, uint64(2), points[0].Count)
+	// statement is not part of the metric attributes
+	require.Equal(t, attribute.NewSet(observability.AttrDBOperation.String("Exec")), points[0].Attributes)
+}
+
+func TestLogHandler(t *testing.T) {
+	var buf bytes.Buffer
+	logger := slog.New(observability.LogHandler(slog.NewJSONHandler(&buf, nil))).With("service", "api")
+	ctx, span := sdktrace.NewTracerProvider().Tracer("test").Start(context.Background(), "request")
+	defer span.End()
+
+	logRecord := func(ctx context.Context) map[string]any {
+		buf.Reset()
+		logger.InfoContext(ctx, "hello")
+		var record map[string]any
+		require.NoError(t, json.Unmarshal(buf.Bytes(), &record))
+		return record
+	}
+
+	record := logRecord(ctx)
+	require.Equal(t, span.SpanContext().TraceID().String(), record[observability.LogTraceID])
+	require.Equal(t, span.SpanContext().SpanID().String(), record[observability.LogSpanID])
+	require.Equal(t, "api", record["service"])
+
+	record = logRecord(context.Background())
+	require.NotContains(t, record, observability.LogTraceID)
+	require.NotContains(t, record, observability.LogSpanID)
+}
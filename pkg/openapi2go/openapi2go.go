@@ -0,0 +1,318 @@
+package openapi2go
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/format"
+	"sort"
+	"strings"
+	"unicode"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+const refPrefix = "#/components/schemas/"
+
+// Parse parses OpenAPI document in JSON or YAML format
+func Parse(data []byte) (*swaggergen.OpenAPI, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) > 0 && data[0] != '{' {
+		// yaml has no knowledge of json tags, so convert it to json first
+		var v any
+		if err := yaml.Unmarshal(data, &v); err != nil {
+			return nil, fmt.Errorf("failed to parse yaml: %w", err)
+		}
+		var err error
+		data, err = json.Marshal(v)
+		if err != nil {
+			return nil, fmt.Errorf("failed to convert yaml to json: %w", err)
+		}
+	}
+
+	var spec swaggergen.OpenAPI
+	if err := json.Unmarshal(data, &spec); err != nil {
+		return nil, fmt.Errorf("failed to parse json: %w", err)
+	}
+	return &spec, nil
+}
+
+// Generate generates request/response structs and handler stubs for every operation in spec
+func Generate(spec *swaggergen.OpenAPI, pkgName string) ([]byte, error) {
+	g := &generator{imports: make(map[string]struct{})}
+	g.imports["context"] = struct{}{}
+	g.imports["errors"] = struct{}{}
+	g.imports["github.com/pechorka/cruder"] = struct{}{}
+
+	var body bytes.Buffer
+	g.out = &body
+
+	if spec.Components != nil {
+		for _, name := range sortedKeys(spec.Components.Schemas) {
+			schema := spec.Components.Schemas[name]
+			g.printf("type %s %s\n\n", exportedName(name), g.goType(schema))
+		}
+	}
+
+	var ops []operation
+	for _, path := range sortedKeys(spec.Paths) {
+		ops = append(ops, pathOperations(path, spec.Paths[path])...)
+	}
+
+	for _, op := range ops {
+		g.genOperation(op)
+	}
+
+	g.printf("// Register registers all handlers on mux\n")
+	g.printf("func Register(mux *cruder.Mux) error {\n")
+	for _, op := range ops {
+		g.printf("if err := cruder.RegisterHandler(mux, %q, %sHandler); err != nil {\nreturn err\n}\n", op.method+" "+op.path, op.name)
+	}
+	g.printf("return nil\n}\n")
+
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "// Generated by openapi2go, handler stubs are meant to be filled in by hand.\n\npackage %s\n\nimport (\n", pkgName)
+	for _, imp := range sortedKeys(g.imports) {
+		fmt.Fprintf(&out, "%q\n", imp)
+	}
+	out.WriteString(")\n\n")
+	out.Write(body.Bytes())
+
+	formatted, err := format.Source(out.Bytes())
+	if err != nil {
+		return out.Bytes(), fmt.Errorf("failed to format generated code: %w", err)
+	}
+	return formatted, nil
+}
+
+type operation struct {
+	name   string
+	method string
+	path   string
+	op     *swaggergen.Operation
+}
+
+func pathOperations(path string, item swaggergen.PathItem) []operation {
+	candidates := []struct {
+		method string
+		op     *swaggergen.Operation
+	}{
+		{"GET", item.GET},
+		{"POST", item.POST},
+		{"PUT", item.PUT},
+		{"PATCH", item.PATCH},
+		{"DELETE", item.DELETE},
+	}
+
+	var ops []operation
+	for _, c := range candidates {
+		if c.op == nil {
+			continue
+		}
+		name := c.op.OperationID
+		if name == "" {
+			name = strings.ToLower(c.method) + "_" + path
+		}
+		ops = append(ops, operation{
+			name:   exportedName(name),
+			method: c.method,
+			path:   path,
+			op:     c.op,
+		})
+	}
+	return ops
+}
+
+type generator struct {
+	out     *bytes.Buffer
+	imports map[string]struct{}
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(g.out, format, args...)
+}
+
+func (g *generator) genOperation(op operation) {
+	reqName := op.name + "Request"
+	respType := g.responseType(op)
+
+	if op.op.Summary != "" {
+		g.printf("// %s is the request of %s\n", reqName, strings.TrimSpace(op.op.Summary))
+	}
+	g.printf("type %s struct {\n", reqName)
+	for _, p := range op.op.Parameters {
+		typ := g.goType(p.Schema)
+		if !p.Required && p.In != "path" {
+			typ = "*" + typ
+		}
+		g.printf("%s %s `%s:%q`\n", exportedName(p.Name), typ, p.In, p.Name)
+	}
+	if op.op.RequestBody != nil {
+		if media, ok := op.op.RequestBody.Content["application/json"]; ok && media.Schema != nil {
+			g.bodyFields(media.Schema)
+		}
+	}
+	g.printf("}\n\n")
+
+	g.printf("func %sHandler(ctx context.Context, req %s) (%s, error) {\n", op.name, reqName, respType)
+	g.printf("var resp %s\nreturn resp, errors.New(\"not implemented\")\n}\n\n", respType)
+}
+
+// bodyFields inlines json body properties into the request struct, since cruder decodes
+// the body and the parameters into the same struct
+func (g *generator) bodyFields(schema *swaggergen.Schema) {
+	if schema.Ref != "" {
+		// embedded struct fields are flattened by encoding/json
+		g.printf("%s\n", g.goType(schema))
+		return
+	}
+	if schema.Type != "object" || len(schema.Properties) == 0 {
+		g.printf("Body %s `json:\"-\"` // TODO: non-object bodies can't be bound by cruder\n", g.goType(schema))
+		return
+	}
+	g.structFields(schema)
+}
+
+func (g *generator) responseType(op operation) string {
+	for _, code := range []string{"200", "201", "202"} {
+		resp, ok := op.op.Responses[code]
+		if !ok {
+			continue
+		}
+		if media, ok := resp.Content["application/json"]; ok && media.Schema != nil {
+			return g.goType(media.Schema)
+		}
+	}
+	return "struct{}"
+}
+
+func (g *generator) goType(schema *swaggergen.Schema) string {
+	if schema == nil {
+		return "any"
+	}
+	if schema.Ref != "" {
+		return exportedName(strings.TrimPrefix(schema.Ref, refPrefix))
+	}
+
+	switch schema.Type {
+	case "string":
+		switch schema.Format {
+		case "date-time":
+			g.imports["time"] = struct{}{}
+			return "time.Time"
+		case "byte", "binary":
+			return "[]byte"
+		}
+		return "string"
+	case "integer":
+		switch schema.Format {
+		case "int32":
+			return "int32"
+		case "int64":
+			return "int64"
+		}
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items)
+	case "object":
+		if len(schema.Properties) == 0 {
+			if items, ok := schema.AdditionalProperties.(map[string]any); ok {
+				return "map[string]" + g.goType(schemaFromMap(items))
+			}
+			return "map[string]any"
+		}
+		var buf bytes.Buffer
+		out := g.out
+		g.out = &buf
+		g.printf("struct {\n")
+		g.structFields(schema)
+		g.printf("}")
+		g.out = out
+		return buf.String()
+	}
+	return "any"
+}
+
+func (g *generator) structFields(schema *swaggergen.Schema) {
+	required := make(map[string]struct{}, len(schema.Required))
+	for _, name := range schema.Required {
+		required[name] = struct{}{}
+	}
+
+	for _, name := range sortedKeys(schema.Properties) {
+		prop := schema.Properties[name]
+		if prop.Description != "" {
+			g.printf("// %s\n", strings.TrimSpace(prop.Description))
+		}
+		tag := name
+		if _, ok := required[name]; !ok {
+			tag += ",omitempty"
+		}
+		g.printf("%s %s `json:%q`\n", exportedName(name), g.goType(prop), tag)
+	}
+}
+
+func schemaFromMap(m map[string]any) *swaggergen.Schema {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil
+	}
+	var s swaggergen.Schema
+	if err := json.Unmarshal(data, &s); err != nil {
+		return nil
+	}
+	return &s
+}
+
+var initialisms = map[string]string{
+	"id":   "ID",
+	"url":  "URL",
+	"uri":  "URI",
+	"api":  "API",
+	"http": "HTTP",
+	"json": "JSON",
+	"uuid": "UUID",
+	"ip":   "IP",
+}
+
+// exportedName converts snake_case, kebab-case, paths and camelCase names to exported Go identifiers
+func exportedName(name string) string {
+	words := strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+
+	var b strings.Builder
+	for _, word := range words {
+		if v, ok := initialisms[strings.ToLower(word)]; ok {
+			b.WriteString(v)
+			continue
+		}
+		runes := []rune(word)
+		runes[0] = unicode.ToUpper(runes[0])
+		b.WriteString(string(runes))
+	}
+
+	res := b.String()
+	if res == "" || unicode.IsDigit([]rune(res)[0]) {
+		res = "X" + res
+	}
+	return res
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
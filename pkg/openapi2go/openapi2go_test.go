@@ -0,0 +1,82 @@
+package openapi2go_test
+
+import (
+	"testing"
+
+	"github.com/pechorka/cruder/pkg/openapi2go"
+	"github.com/stretchr/testify/require"
+)
+
+const spec = `
+openapi: 3.0.0
+info:
+  title: Users
+  version: 1.0.0
+paths:
+  /users/{id}:
+    get:
+      operationId: get_user
+      parameters:
+        - name: id
+          in: path
+          required: true
+          schema:
+            type: integer
+            format: int64
+        - name: X-Request-Id
+          in: header
+          schema:
+            type: string
+      responses:
+        "200":
+          description: ok
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+  /users:
+    post:
+      operationId: create_user
+      requestBody:
+        content:
+          application/json:
+            schema:
+              $ref: '#/components/schemas/User'
+      responses:
+        "201":
+          description: created
+          content:
+            application/json:
+              schema:
+                $ref: '#/components/schemas/User'
+components:
+  schemas:
+    User:
+      type: object
+      required: [name]
+      properties:
+        name:
+          type: string
+        created_at:
+          type: string
+          format: date-time
+`
+
+func TestGenerate(t *testing.T) {
+	parsed, err := openapi2go.Parse([]byte(spec))
+	require.NoError(t, err)
+
+	code, err := openapi2go.Generate(parsed, "api")
+	require.NoError(t, err)
+
+	src := string(code)
+	require.Contains(t, src, "package api")
+	require.Contains(t, src, "type User struct {")
+	require.Contains(t, src, "CreatedAt time.Time `json:\"created_at,omitempty\"`")
+	require.Contains(t, src, "Name      string    `json:\"name\"`")
+	require.Contains(t, src, "ID         int64   `path:\"id\"`")
+	require.Contains(t, src, "XRequestID *string `header:\"X-Request-Id\"`")
+	require.Contains(t, src, "func GetUserHandler(ctx context.Context, req GetUserRequest) (User, error)")
+	require.Contains(t, src, "type CreateUserRequest struct {\n\tUser\n}")
+	require.Contains(t, src, `cruder.RegisterHandler(mux, "POST /users", CreateUserHandler)`)
+}
@@ -0,0 +1,170 @@
+package pagination
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// DefaultLimit is used when the client does not ask for a page size
+const DefaultLimit = 20
+
+// MaxLimit caps the page size a client can ask for
+const MaxLimit = 100
+
+// ErrInvalidCursor is returned when a cursor is malformed or its signature doesn't match
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Params are the pagination parameters of a list request.
+// Use it as a tagged field of a request struct, e.g. `query:"page"`,
+// to bind page_cursor and page_limit query parameters.
+type Params struct {
+	Cursor string `query:"cursor"`
+	Limit  *int   `query:"limit"`
+}
+
+// PageSize returns the requested limit clamped to [1, MaxLimit]
+func (p Params) PageSize() int {
+	if p.Limit == nil || *p.Limit <= 0 {
+		return DefaultLimit
+	}
+	if *p.Limit > MaxLimit {
+		return MaxLimit
+	}
+	return *p.Limit
+}
+
+// Page is a single page of a list response
+type Page[T any] struct {
+	Items      []T    `json:"items"`
+	NextCursor string `json:"next_cursor,omitempty"`
+	PrevCursor string `json:"prev_cursor,omitempty"`
+}
+
+// Direction is the direction a cursor pages in
+type Direction int
+
+const (
+	// Forward cursors point past the last row of a page
+	Forward Direction = iota
+	// Backward cursors point before the first row of a page
+	Backward
+)
+
+// backwardPayload is the payload of Backward cursors, Forward cursors are bare value arrays
+type backwardPayload struct {
+	Prev   bool              `json:"prev"`
+	Values []json.RawMessage `json:"values"`
+}
+
+// Codec encodes keyset values into opaque signed cursors and back
+type Codec struct {
+	key []byte
+}
+
+// NewCodec creates a new cursor codec signing cursors with the given key
+func NewCodec(key []byte) *Codec {
+	return &Codec{key: key}
+}
+
+// Encode encodes keyset values of the last row of a page into a Forward cursor
+func (c *Codec) Encode(values ...any) (string, error) {
+	return c.EncodeCursor(Forward, values...)
+}
+
+// EncodeCursor encodes keyset values of the boundary row of a page and the direction
+// to page in from it into a signed URL-safe cursor, e.g. the first row and Backward
+// for the previous page cursor
+func (c *Codec) EncodeCursor(dir Direction, values ...any) (string, error) {
+	var payload []byte
+	var err error
+	if dir == Backward {
+		raw := make([]json.RawMessage, len(values))
+		for i, v := range values {
+			if raw[i], err = json.Marshal(v); err != nil {
+				return "", fmt.Errorf("failed to encode cursor: %w", err)
+			}
+		}
+		payload, err = json.Marshal(backwardPayload{Prev: true, Values: raw})
+	} else {
+		payload, err = json.Marshal(values)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to encode cursor: %w", err)
+	}
+
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString(payload) + "." + enc.EncodeToString(c.sign(payload)), nil
+}
+
+// Decode verifies a Forward cursor and decodes its keyset values into dest.
+// Number of dest values must match the number of encoded values. Backward cursors are rejected,
+// use DecodeCursor to accept both.
+func (c *Codec) Decode(cursor string, dest ...any) error {
+	dir, err := c.DecodeCursor(cursor, dest...)
+	if err == nil && dir != Forward {
+		return fmt.Errorf("%w: unexpected backward cursor", ErrInvalidCursor)
+	}
+	return err
+}
+
+// DecodeCursor verifies the cursor, decodes its keyset values into dest and returns its direction.
+// Number of dest values must match the number of encoded values.
+func (c *Codec) DecodeCursor(cursor string, dest ...any) (Direction, error) {
+	payload, err := c.verify(cursor)
+	if err != nil {
+		return Forward, err
+	}
+
+	dir := Forward
+	var raw []json.RawMessage
+	if err := json.Unmarshal(payload, &raw); err != nil {
+		var backward backwardPayload
+		if err := json.Unmarshal(payload, &backward); err != nil || !backward.Prev {
+			return Forward, ErrInvalidCursor
+		}
+		dir, raw = Backward, backward.Values
+	}
+	if len(raw) != len(dest) {
+		return Forward, fmt.Errorf("%w: expected %d values, got %d", ErrInvalidCursor, len(dest), len(raw))
+	}
+
+	for i := range raw {
+		if err := json.Unmarshal(raw[i], dest[i]); err != nil {
+			return Forward, fmt.Errorf("%w: value %d: %v", ErrInvalidCursor, i, err)
+		}
+	}
+	return dir, nil
+}
+
+func (c *Codec) verify(cursor string) ([]byte, error) {
+	encPayload, encSig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return nil, ErrInvalidCursor
+	}
+
+	enc := base64.RawURLEncoding
+	payload, err := enc.DecodeString(encPayload)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	sig, err := enc.DecodeString(encSig)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	if !hmac.Equal(sig, c.sign(payload)) {
+		return nil, ErrInvalidCursor
+	}
+	return payload, nil
+}
+
+func (c *Codec) sign(payload []byte) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
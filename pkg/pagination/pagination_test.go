@@ -0,0 +1,80 @@
+package pagination_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/pagination"
+	"github.com/stretchr/testify/require"
+)
+
+func TestCodec(t *testing.T) {
+	t.Run("round trip", func(t *testing.T) {
+		codec := pagination.NewCodec([]byte("secret"))
+		createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+
+		cursor, err := codec.Encode(createdAt, 42)
+		require.NoError(t, err)
+
+		var gotCreatedAt time.Time
+		var gotID int
+		err = codec.Decode(cursor, &gotCreatedAt, &gotID)
+		require.NoError(t, err)
+		require.True(t, createdAt.Equal(gotCreatedAt))
+		require.Equal(t, 42, gotID)
+	})
+
+	t.Run("tampered cursor", func(t *testing.T) {
+		cursor, err := pagination.NewCodec([]byte("secret")).Encode(42)
+		require.NoError(t, err)
+
+		var id int
+		err = pagination.NewCodec([]byte("other secret")).Decode(cursor, &id)
+		require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+		err = pagination.NewCodec([]byte("secret")).Decode("garbage", &id)
+		require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	})
+
+	t.Run("direction", func(t *testing.T) {
+		codec := pagination.NewCodec([]byte("secret"))
+		cursor, err := codec.EncodeCursor(pagination.Backward, "b", 7)
+		require.NoError(t, err)
+
+		var name string
+		var id int
+		dir, err := codec.DecodeCursor(cursor, &name, &id)
+		require.NoError(t, err)
+		require.Equal(t, pagination.Backward, dir)
+		require.Equal(t, "b", name)
+		require.Equal(t, 7, id)
+
+		err = codec.Decode(cursor, &name, &id)
+		require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+
+		cursor, err = codec.Encode("a", 1)
+		require.NoError(t, err)
+		dir, err = codec.DecodeCursor(cursor, &name, &id)
+		require.NoError(t, err)
+		require.Equal(t, pagination.Forward, dir)
+	})
+
+	t.Run("values count mismatch", func(t *testing.T) {
+		codec := pagination.NewCodec([]byte("secret"))
+		cursor, err := codec.Encode(1, 2)
+		require.NoError(t, err)
+
+		var id int
+		err = codec.Decode(cursor, &id)
+		require.ErrorIs(t, err, pagination.ErrInvalidCursor)
+	})
+}
+
+func TestParamsPageSize(t *testing.T) {
+	limit := func(v int) *int { return &v }
+
+	require.Equal(t, pagination.DefaultLimit, pagination.Params{}.PageSize())
+	require.Equal(t, 5, pagination.Params{Limit: limit(5)}.PageSize())
+	require.Equal(t, pagination.MaxLimit, pagination.Params{Limit: limit(1000)}.PageSize())
+	require.Equal(t, pagination.DefaultLimit, pagination.Params{Limit: limit(-1)}.PageSize())
+}
@@ -0,0 +1,203 @@
+package redact
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// Placeholder replaces redacted values
+const Placeholder = "[REDACTED]"
+
+var (
+	jsonMarshalerType = reflect.TypeFor[json.Marshaler]()
+	textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+)
+
+// Value returns a json and slog friendly copy of v where every field tagged
+// `redact:"true"` is replaced by Placeholder. Structs become maps keyed by json names.
+func Value(v any) any {
+	if v == nil {
+		return nil
+	}
+	return value(reflect.ValueOf(v))
+}
+
+func value(v reflect.Value) any {
+	switch v.Kind() {
+	case reflect.Invalid:
+		return nil
+	case reflect.Pointer, reflect.Interface:
+		if v.IsNil() {
+			return nil
+		}
+		return value(v.Elem())
+	}
+
+	t := v.Type()
+	// types with custom encoding (time.Time, uuids) are kept as is
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return v.Interface()
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		res := make(map[string]any, t.NumField())
+		for i := range t.NumField() {
+			field := t.Field(i)
+			name, ok := fieldName(field)
+			if !ok {
+				continue
+			}
+			if isRedacted(field) {
+				res[name] = Placeholder
+				continue
+			}
+			res[name] = value(v.Field(i))
+		}
+		return res
+	case reflect.Slice, reflect.Array:
+		if v.Kind() == reflect.Slice && v.IsNil() {
+			return nil
+		}
+		if t.Elem().Kind() == reflect.Uint8 {
+			return v.Interface()
+		}
+		res := make([]any, v.Len())
+		for i := range v.Len() {
+			res[i] = value(v.Index(i))
+		}
+		return res
+	case reflect.Map:
+		if v.IsNil() {
+			return nil
+		}
+		res := make(map[string]any, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			res[mapKey(iter.Key())] = value(iter.Value())
+		}
+		return res
+	default:
+		return v.Interface()
+	}
+}
+
+var fieldsCache sync.Map // reflect.Type -> []string
+
+// Fields returns json names of every redacted field of t, including fields of nested types.
+// Names are used to redact raw payloads where Go types are not available.
+func Fields(t reflect.Type) []string {
+	if cached, ok := fieldsCache.Load(t); ok {
+		return cached.([]string)
+	}
+
+	set := make(map[string]struct{})
+	collectFields(t, set, make(map[reflect.Type]bool))
+
+	fields := make([]string, 0, len(set))
+	for name := range set {
+		fields = append(fields, name)
+	}
+	fieldsCache.Store(t, fields)
+	return fields
+}
+
+func collectFields(t reflect.Type, set map[string]struct{}, visited map[reflect.Type]bool) {
+	for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct || visited[t] {
+		return
+	}
+	visited[t] = true
+
+	for i := range t.NumField() {
+		field := t.Field(i)
+		name, ok := fieldName(field)
+		if !ok {
+			continue
+		}
+		if isRedacted(field) {
+			set[name] = struct{}{}
+			continue
+		}
+		collectFields(field.Type, set, visited)
+	}
+}
+
+// JSON replaces values of the given fields at any depth of a json document.
+// Invalid json is returned unchanged.
+func JSON(data []byte, fields ...string) []byte {
+	if len(fields) == 0 || len(data) == 0 {
+		return data
+	}
+	set := make(map[string]struct{}, len(fields))
+	for _, f := range fields {
+		set[f] = struct{}{}
+	}
+
+	var v any
+	if err := json.Unmarshal(data, &v); err != nil {
+		return data
+	}
+	redacted, err := json.Marshal(redactJSON(v, set))
+	if err != nil {
+		return data
+	}
+	return redacted
+}
+
+func redactJSON(v any, fields map[string]struct{}) any {
+	switch v := v.(type) {
+	case map[string]any:
+		for k, val := range v {
+			if _, ok := fields[k]; ok {
+				v[k] = Placeholder
+				continue
+			}
+			v[k] = redactJSON(val, fields)
+		}
+		return v
+	case []any:
+		for i := range v {
+			v[i] = redactJSON(v[i], fields)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+func isRedacted(field reflect.StructField) bool {
+	return field.Tag.Get("redact") == "true"
+}
+
+func fieldName(field reflect.StructField) (string, bool) {
+	if !field.IsExported() {
+		return "", false
+	}
+	tag := field.Tag.Get("json")
+	if tag == "-" {
+		return "", false
+	}
+	if name, _, _ := strings.Cut(tag, ","); name != "" {
+		return name, true
+	}
+	return field.Name, true
+}
+
+func mapKey(k reflect.Value) string {
+	if k.Kind() == reflect.String {
+		return k.String()
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if b, err := tm.MarshalText(); err == nil {
+			return string(b)
+		}
+	}
+	b, _ := json.Marshal(k.Interface())
+	return strings.Trim(string(b), `"`)
+}
@@ -0,0 +1,50 @@
+package redact_test
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/pechorka/cruder/pkg/redact"
+	"github.com/stretchr/testify/require"
+)
+
+type credentials struct {
+	Login    string `json:"login"`
+	Password string `json:"password" redact:"true"`
+}
+
+type loginRequest struct {
+	Credentials credentials `json:"credentials"`
+	Token       *string     `json:"token,omitempty" redact:"true"`
+	Tags        []string    `json:"tags"`
+	internal    string
+}
+
+func TestValue(t *testing.T) {
+	token := "secret-token"
+	v := redact.Value(loginRequest{
+		Credentials: credentials{Login: "john", Password: "qwerty"},
+		Token:       &token,
+		Tags:        []string{"a"},
+		internal:    "hidden",
+	})
+
+	require.Equal(t, map[string]any{
+		"credentials": map[string]any{
+			"login":    "john",
+			"password": redact.Placeholder,
+		},
+		"token": redact.Placeholder,
+		"tags":  []any{"a"},
+	}, v)
+}
+
+func TestFieldsAndJSON(t *testing.T) {
+	fields := redact.Fields(reflect.TypeOf(&loginRequest{}))
+	require.ElementsMatch(t, []string{"password", "token"}, fields)
+
+	body := redact.JSON([]byte(`{"credentials":{"login":"john","password":"qwerty"},"token":"t"}`), fields...)
+	require.JSONEq(t, `{"credentials":{"login":"john","password":"[REDACTED]"},"token":"[REDACTED]"}`, string(body))
+
+	require.Equal(t, "not json", string(redact.JSON([]byte("not json"), fields...)))
+}
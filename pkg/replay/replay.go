@@ -0,0 +1,231 @@
+package replay
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"testing"
+
+	"github.com/pechorka/cruder/internal/respwriter"
+	"github.com/pechorka/cruder/pkg/redact"
+)
+
+// Redacted replaces values matched by redaction rules
+const Redacted = redact.Placeholder
+
+// Exchange is a single recorded request/response pair stored in a golden file
+type Exchange struct {
+	Request  Request  `json:"request"`
+	Response Response `json:"response"`
+}
+
+// Request is a recorded http request
+type Request struct {
+	Method string      `json:"method"`
+	URL    string      `json:"url"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+// Response is a recorded http response
+type Response struct {
+	Status int         `json:"status"`
+	Header http.Header `json:"header,omitempty"`
+	Body   string      `json:"body,omitempty"`
+}
+
+type config struct {
+	headers map[string]struct{}
+	fields  map[string]struct{}
+}
+
+// Option configures recorder and replayer
+type Option func(*config)
+
+// RedactHeaders replaces values of the given headers in requests and responses
+func RedactHeaders(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.headers[http.CanonicalHeaderKey(name)] = struct{}{}
+		}
+	}
+}
+
+// RedactFields replaces values of the given JSON fields (at any depth) in request and response bodies
+func RedactFields(names ...string) Option {
+	return func(c *config) {
+		for _, name := range names {
+			c.fields[name] = struct{}{}
+		}
+	}
+}
+
+// RedactTagged redacts every field tagged `redact:"true"` in the types of the given values,
+// pass request and response types of the recorded handlers, e.g. RedactTagged(LoginRequest{})
+func RedactTagged(samples ...any) Option {
+	return func(c *config) {
+		for _, sample := range samples {
+			for _, name := range redact.Fields(reflect.TypeOf(sample)) {
+				c.fields[name] = struct{}{}
+			}
+		}
+	}
+}
+
+func newConfig(opts []Option) *config {
+	c := &config{
+		headers: make(map[string]struct{}),
+		fields:  make(map[string]struct{}),
+	}
+	for _, opt := range opts {
+		opt(c)
+	}
+	return c
+}
+
+// Recorder returns a middleware that writes every handled exchange to dir as a golden file
+func Recorder(dir string, opts ...Option) func(http.Handler) http.Handler {
+	cfg := newConfig(opts)
+	var seq atomic.Int64
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			reqBody, err := io.ReadAll(r.Body)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			r.Body = io.NopCloser(bytes.NewReader(reqBody))
+
+			rec := respwriter.Recording(w)
+			next.ServeHTTP(rec, r)
+
+			ex := Exchange{
+				Request: Request{
+					Method: r.Method,
+					URL:    r.URL.RequestURI(),
+					Header: cfg.redactHeader(r.Header),
+					Body:   cfg.redactBody(reqBody),
+				},
+				Response: Response{
+					Status: rec.Status(),
+					Header: cfg.redactHeader(w.Header()),
+					Body:   cfg.redactBody(rec.Body()),
+				},
+			}
+			name := fmt.Sprintf("%04d_%s%s.json", seq.Add(1), r.Method, fileSafe(r.URL.Path))
+			// recording must never break the handled request
+			_ = writeExchange(filepath.Join(dir, name), ex)
+		})
+	}
+}
+
+// Replay re-issues every exchange recorded in dir through h and asserts
+// that status codes and bodies match the golden files
+func Replay(t *testing.T, h http.Handler, dir string, opts ...Option) {
+	t.Helper()
+	cfg := newConfig(opts)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatalf("failed to list golden files: %v", err)
+	}
+	sort.Strings(files)
+
+	for _, file := range files {
+		t.Run(strings.TrimSuffix(filepath.Base(file), ".json"), func(t *testing.T) {
+			ex, err := readExchange(file)
+			if err != nil {
+				t.Fatalf("failed to read golden file: %v", err)
+			}
+
+			r := httptest.NewRequest(ex.Request.Method, ex.Request.URL, strings.NewReader(ex.Request.Body))
+			for name, vals := range ex.Request.Header {
+				r.Header[name] = vals
+			}
+			w := httptest.NewRecorder()
+			h.ServeHTTP(w, r)
+
+			if w.Code != ex.Response.Status {
+				t.Errorf("status: expected %d, got %d", ex.Response.Status, w.Code)
+			}
+			got := cfg.redactBody(w.Body.Bytes())
+			if !bodiesEqual(ex.Response.Body, got) {
+				t.Errorf("body:\nexpected: %s\ngot:      %s", ex.Response.Body, got)
+			}
+		})
+	}
+}
+
+func (c *config) redactHeader(h http.Header) http.Header {
+	if len(h) == 0 {
+		return nil
+	}
+	res := h.Clone()
+	for name := range res {
+		if _, ok := c.headers[name]; ok {
+			res[name] = []string{Redacted}
+		}
+	}
+	return res
+}
+
+func (c *config) redactBody(body []byte) string {
+	fields := make([]string, 0, len(c.fields))
+	for name := range c.fields {
+		fields = append(fields, name)
+	}
+	return string(redact.JSON(body, fields...))
+}
+
+// bodiesEqual compares JSON bodies semantically and everything else byte by byte
+func bodiesEqual(expected, actual string) bool {
+	var e, a any
+	if json.Unmarshal([]byte(expected), &e) != nil || json.Unmarshal([]byte(actual), &a) != nil {
+		return expected == actual
+	}
+	eb, _ := json.Marshal(e)
+	ab, _ := json.Marshal(a)
+	return bytes.Equal(eb, ab)
+}
+
+func fileSafe(path string) string {
+	return strings.Map(func(r rune) rune {
+		switch {
+		case r >= 'a' && r <= 'z', r >= 'A' && r <= 'Z', r >= '0' && r <= '9', r == '-':
+			return r
+		default:
+			return '_'
+		}
+	}, path)
+}
+
+func writeExchange(path string, ex Exchange) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	data, err := json.MarshalIndent(ex, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+func readExchange(path string) (Exchange, error) {
+	var ex Exchange
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return ex, err
+	}
+	err = json.Unmarshal(data, &ex)
+	return ex, err
+}
@@ -0,0 +1,49 @@
+package replay_test
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/pechorka/cruder/pkg/replay"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req map[string]string
+		_ = json.NewDecoder(r.Body).Decode(&req)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]string{
+			"login": req["login"],
+			"token": "random-token",
+		})
+	})
+
+	opts := []replay.Option{
+		replay.RedactHeaders("Authorization"),
+		replay.RedactFields("password", "token"),
+	}
+
+	r := httptest.NewRequest("POST", "/api/login", strings.NewReader(`{"login":"john","password":"qwerty"}`))
+	r.Header.Set("Authorization", "Bearer secret")
+	replay.Recorder(dir, opts...)(handler).ServeHTTP(httptest.NewRecorder(), r)
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	require.NoError(t, err)
+	require.Len(t, files, 1)
+
+	golden, err := os.ReadFile(files[0])
+	require.NoError(t, err)
+	require.NotContains(t, string(golden), "qwerty")
+	require.NotContains(t, string(golden), "Bearer secret")
+	require.NotContains(t, string(golden), "random-token")
+
+	replay.Replay(t, handler, dir, opts...)
+}
@@ -0,0 +1,109 @@
+package session
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+// ErrInvalidCookie is returned when a cookie value can't be verified or decrypted
+var ErrInvalidCookie = errors.New("invalid cookie")
+
+// CookieCodec encodes cookie values before they are sent and decodes them when they are received.
+// Cookie name is bound to the value, so a value can't be moved to another cookie.
+type CookieCodec interface {
+	Encode(name, value string) (string, error)
+	Decode(name, value string) (string, error)
+}
+
+type signedCookieCodec struct {
+	key []byte
+}
+
+// NewSignedCookieCodec creates a codec appending HMAC-SHA256 signature to values.
+// Values stay readable by the client but can't be tampered with.
+func NewSignedCookieCodec(key []byte) CookieCodec {
+	return &signedCookieCodec{key: key}
+}
+
+func (c *signedCookieCodec) Encode(name, value string) (string, error) {
+	enc := base64.RawURLEncoding
+	return enc.EncodeToString([]byte(value)) + "." + enc.EncodeToString(c.sign(name, value)), nil
+}
+
+func (c *signedCookieCodec) Decode(name, value string) (string, error) {
+	encValue, encSig, ok := strings.Cut(value, ".")
+	if !ok {
+		return "", ErrInvalidCookie
+	}
+	enc := base64.RawURLEncoding
+	raw, err := enc.DecodeString(encValue)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	sig, err := enc.DecodeString(encSig)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	if !hmac.Equal(sig, c.sign(name, string(raw))) {
+		return "", ErrInvalidCookie
+	}
+	return string(raw), nil
+}
+
+func (c *signedCookieCodec) sign(name, value string) []byte {
+	mac := hmac.New(sha256.New, c.key)
+	mac.Write([]byte(name))
+	mac.Write([]byte{0})
+	mac.Write([]byte(value))
+	return mac.Sum(nil)
+}
+
+type encryptedCookieCodec struct {
+	aead cipher.AEAD
+}
+
+// NewEncryptedCookieCodec creates a codec encrypting values with AES-GCM.
+// Key must be 16, 24 or 32 bytes long.
+func NewEncryptedCookieCodec(key []byte) (CookieCodec, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create cipher: %w", err)
+	}
+	aead, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create gcm: %w", err)
+	}
+	return &encryptedCookieCodec{aead: aead}, nil
+}
+
+func (c *encryptedCookieCodec) Encode(name, value string) (string, error) {
+	nonce := make([]byte, c.aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("failed to generate nonce: %w", err)
+	}
+	sealed := c.aead.Seal(nonce, nonce, []byte(value), []byte(name))
+	return base64.RawURLEncoding.EncodeToString(sealed), nil
+}
+
+func (c *encryptedCookieCodec) Decode(name, value string) (string, error) {
+	sealed, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	nonceSize := c.aead.NonceSize()
+	if len(sealed) < nonceSize {
+		return "", ErrInvalidCookie
+	}
+	raw, err := c.aead.Open(nil, sealed[:nonceSize], sealed[nonceSize:], []byte(name))
+	if err != nil {
+		return "", ErrInvalidCookie
+	}
+	return string(raw), nil
+}
@@ -0,0 +1,262 @@
+package session
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Session holds typed session data of the current request
+type Session[T any] struct {
+	mu        sync.Mutex
+	id        string
+	data      T
+	dirty     bool
+	renew     bool
+	destroyed bool
+}
+
+// Get returns session data
+func (s *Session[T]) Get() T {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.data
+}
+
+// Set replaces session data, it's persisted before the response is written
+func (s *Session[T]) Set(data T) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data = data
+	s.dirty = true
+	s.destroyed = false
+}
+
+// Renew moves session data to a new id, call it after login to prevent session fixation
+func (s *Session[T]) Renew() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.renew = true
+	s.dirty = true
+}
+
+// Destroy deletes the session from the store and expires the cookie
+func (s *Session[T]) Destroy() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var zero T
+	s.data = zero
+	s.destroyed = true
+}
+
+type sessionCtxKey struct{}
+
+// FromContext returns the session loaded by Manager middleware
+func FromContext[T any](ctx context.Context) (*Session[T], bool) {
+	s, ok := ctx.Value(sessionCtxKey{}).(*Session[T])
+	return s, ok
+}
+
+// Manager loads sessions from cookies and persists them in the store
+type Manager[T any] struct {
+	store  Store
+	codec  CookieCodec
+	cookie http.Cookie
+	ttl    time.Duration
+}
+
+// Option configures Manager
+type Option func(*options)
+
+type options struct {
+	cookie http.Cookie
+	ttl    time.Duration
+}
+
+// WithCookie sets cookie attributes, value and expiration are managed by Manager
+func WithCookie(cookie http.Cookie) Option {
+	return func(o *options) {
+		o.cookie = cookie
+	}
+}
+
+// WithTTL sets session lifetime
+func WithTTL(ttl time.Duration) Option {
+	return func(o *options) {
+		o.ttl = ttl
+	}
+}
+
+// NewManager creates a new session manager.
+// Session id in the cookie is protected by codec, use NewSignedCookieCodec or NewEncryptedCookieCodec.
+func NewManager[T any](store Store, codec CookieCodec, opts ...Option) *Manager[T] {
+	o := options{
+		cookie: http.Cookie{
+			Name:     "session",
+			Path:     "/",
+			HttpOnly: true,
+			SameSite: http.SameSiteLaxMode,
+		},
+		ttl: 24 * time.Hour,
+	}
+	for _, opt := range opts {
+		opt(&o)
+	}
+
+	return &Manager[T]{
+		store:  store,
+		codec:  codec,
+		cookie: o.cookie,
+		ttl:    o.ttl,
+	}
+}
+
+// Middleware loads the session of the request into the context and persists its changes
+// right before the response headers are written
+func (m *Manager[T]) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, err := m.load(r)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		ctx := context.WithValue(r.Context(), sessionCtxKey{}, sess)
+		sw := &sessionWriter{
+			ResponseWriter: w,
+			commit: func() error {
+				return m.commit(ctx, w, sess)
+			},
+		}
+		next.ServeHTTP(sw, r.WithContext(ctx))
+		sw.flush()
+	})
+}
+
+func (m *Manager[T]) load(r *http.Request) (*Session[T], error) {
+	sess := &Session[T]{}
+
+	cookie, err := r.Cookie(m.cookie.Name)
+	if err != nil {
+		return sess, nil
+	}
+	id, err := m.codec.Decode(m.cookie.Name, cookie.Value)
+	if err != nil {
+		// tampered or stale cookie is treated as no session
+		return sess, nil
+	}
+
+	data, err := m.store.Load(r.Context(), id)
+	if errors.Is(err, ErrNotFound) {
+		return sess, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to load session: %w", err)
+	}
+	if err := json.Unmarshal(data, &sess.data); err != nil {
+		return nil, fmt.Errorf("failed to decode session: %w", err)
+	}
+	sess.id = id
+	return sess, nil
+}
+
+func (m *Manager[T]) commit(ctx context.Context, w http.ResponseWriter, sess *Session[T]) error {
+	sess.mu.Lock()
+	defer sess.mu.Unlock()
+
+	if sess.destroyed {
+		if sess.id != "" {
+			if err := m.store.Delete(ctx, sess.id); err != nil {
+				return fmt.Errorf("failed to delete session: %w", err)
+			}
+		}
+		cookie := m.cookie
+		cookie.MaxAge = -1
+		http.SetCookie(w, &cookie)
+		return nil
+	}
+
+	if !sess.dirty {
+		return nil
+	}
+
+	if sess.renew && sess.id != "" {
+		if err := m.store.Delete(ctx, sess.id); err != nil {
+			return fmt.Errorf("failed to delete session: %w", err)
+		}
+		sess.id = ""
+	}
+	if sess.id == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		sess.id = id
+	}
+
+	data, err := json.Marshal(sess.data)
+	if err != nil {
+		return fmt.Errorf("failed to encode session: %w", err)
+	}
+	if err := m.store.Save(ctx, sess.id, data, m.ttl); err != nil {
+		return fmt.Errorf("failed to save session: %w", err)
+	}
+
+	value, err := m.codec.Encode(m.cookie.Name, sess.id)
+	if err != nil {
+		return fmt.Errorf("failed to encode session cookie: %w", err)
+	}
+	cookie := m.cookie
+	cookie.Value = value
+	cookie.MaxAge = int(m.ttl.Seconds())
+	http.SetCookie(w, &cookie)
+	return nil
+}
+
+// sessionWriter commits the session before anything is written to the client
+type sessionWriter struct {
+	http.ResponseWriter
+	commit    func() error
+	committed bool
+	failed    bool
+}
+
+func (w *sessionWriter) flush() bool {
+	if w.committed {
+		return !w.failed
+	}
+	w.committed = true
+	if err := w.commit(); err != nil {
+		w.failed = true
+		http.Error(w.ResponseWriter, err.Error(), http.StatusInternalServerError)
+		return false
+	}
+	return true
+}
+
+func (w *sessionWriter) WriteHeader(status int) {
+	if w.flush() {
+		w.ResponseWriter.WriteHeader(status)
+	}
+}
+
+func (w *sessionWriter) Write(b []byte) (int, error) {
+	if !w.flush() {
+		return len(b), nil
+	}
+	return w.ResponseWriter.Write(b)
+}
+
+func newID() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", fmt.Errorf("failed to generate session id: %w", err)
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
@@ -0,0 +1,128 @@
+package session_test
+
+import (
+	"context"
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/session"
+	"github.com/stretchr/testify/require"
+)
+
+type userSession struct {
+	UserID int `json:"user_id"`
+}
+
+func TestManager(t *testing.T) {
+	codec, err := session.NewEncryptedCookieCodec([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	m := session.NewManager[userSession](session.NewMemoryStore(), codec)
+
+	handler := m.Middleware(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sess, ok := session.FromContext[userSession](r.Context())
+		require.True(t, ok)
+
+		switch r.URL.Path {
+		case "/login":
+			sess.Set(userSession{UserID: 42})
+			sess.Renew()
+		case "/logout":
+			sess.Destroy()
+		}
+		_, _ = w.Write([]byte{byte(sess.Get().UserID)})
+	}))
+
+	do := func(path string, cookies ...*http.Cookie) *httptest.ResponseRecorder {
+		r := httptest.NewRequest("GET", path, nil)
+		for _, c := range cookies {
+			r.AddCookie(c)
+		}
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, r)
+		return w
+	}
+
+	w := do("/me")
+	require.Equal(t, []byte{0}, w.Body.Bytes())
+	require.Empty(t, w.Result().Cookies())
+
+	w = do("/login")
+	cookies := w.Result().Cookies()
+	require.Len(t, cookies, 1)
+
+	w = do("/me", cookies...)
+	require.Equal(t, []byte{42}, w.Body.Bytes())
+
+	w = do("/logout", cookies...)
+	require.Equal(t, -1, w.Result().Cookies()[0].MaxAge)
+
+	w = do("/me", cookies...)
+	require.Equal(t, []byte{0}, w.Body.Bytes())
+}
+
+func TestDBStore(t *testing.T) {
+	ctx := context.Background()
+	db, err := sql.Open("sqlite3", ":memory:")
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	db.SetMaxOpenConns(1)
+	_, err = db.Exec("CREATE TABLE sessions (id TEXT PRIMARY KEY, data BLOB NOT NULL, expires_at TIMESTAMP NOT NULL)")
+	require.NoError(t, err)
+	store := session.NewDBStore(db, session.WithDialect(dbx.SQLite))
+
+	_, err = store.Load(ctx, "a")
+	require.ErrorIs(t, err, session.ErrNotFound)
+
+	require.NoError(t, store.Save(ctx, "a", []byte("first"), time.Hour))
+	require.NoError(t, store.Save(ctx, "a", []byte("second"), time.Hour))
+	data, err := store.Load(ctx, "a")
+	require.NoError(t, err)
+	require.Equal(t, []byte("second"), data)
+
+	require.NoError(t, store.Save(ctx, "expired", []byte("old"), -time.Minute))
+	_, err = store.Load(ctx, "expired")
+	require.ErrorIs(t, err, session.ErrNotFound)
+
+	require.NoError(t, store.Delete(ctx, "a"))
+	_, err = store.Load(ctx, "a")
+	require.ErrorIs(t, err, session.ErrNotFound)
+}
+
+func TestCookieCodecs(t *testing.T) {
+	encrypted, err := session.NewEncryptedCookieCodec([]byte("0123456789abcdef"))
+	require.NoError(t, err)
+	_, err = session.NewEncryptedCookieCodec([]byte("short"))
+	require.Error(t, err)
+
+	for name, codec := range map[string]session.CookieCodec{
+		"signed":    session.NewSignedCookieCodec([]byte("secret")),
+		"encrypted": encrypted,
+	} {
+		t.Run(name, func(t *testing.T) {
+			value, err := codec.Encode("session", "id-1")
+			require.NoError(t, err)
+			require.NotEqual(t, "id-1", value)
+
+			decoded, err := codec.Decode("session", value)
+			require.NoError(t, err)
+			require.Equal(t, "id-1", decoded)
+
+			// values are bound to the cookie name
+			_, err = codec.Decode("other", value)
+			require.ErrorIs(t, err, session.ErrInvalidCookie)
+
+			tampered := []byte(value)
+			tampered[0] ^= 1
+			_, err = codec.Decode("session", string(tampered))
+			require.ErrorIs(t, err, session.ErrInvalidCookie)
+			_, err = codec.Decode("session", "garbage")
+			require.ErrorIs(t, err, session.ErrInvalidCookie)
+		})
+	}
+}
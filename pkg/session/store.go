@@ -0,0 +1,176 @@
+package session
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+)
+
+// ErrNotFound is returned by stores when a session doesn't exist or has expired
+var ErrNotFound = errors.New("session not found")
+
+// Store persists encoded session data
+type Store interface {
+	Load(ctx context.Context, id string) ([]byte, error)
+	Save(ctx context.Context, id string, data []byte, ttl time.Duration) error
+	Delete(ctx context.Context, id string) error
+}
+
+type memoryEntry struct {
+	data      []byte
+	expiresAt time.Time
+}
+
+// MemoryStore keeps sessions in process memory, it's meant for tests and single instance deployments
+type MemoryStore struct {
+	mu       sync.Mutex
+	sessions map[string]memoryEntry
+}
+
+// NewMemoryStore creates a new in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{sessions: make(map[string]memoryEntry)}
+}
+
+// Load implements Store
+func (s *MemoryStore) Load(_ context.Context, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	entry, ok := s.sessions[id]
+	if !ok {
+		return nil, ErrNotFound
+	}
+	if time.Now().After(entry.expiresAt) {
+		delete(s.sessions, id)
+		return nil, ErrNotFound
+	}
+	return entry.data, nil
+}
+
+// Save implements Store
+func (s *MemoryStore) Save(_ context.Context, id string, data []byte, ttl time.Duration) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.sessions[id] = memoryEntry{data: data, expiresAt: time.Now().Add(ttl)}
+	return nil
+}
+
+// Delete implements Store
+func (s *MemoryStore) Delete(_ context.Context, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.sessions, id)
+	return nil
+}
+
+// sessionRow is a row of the sessions table
+type sessionRow struct {
+	ID        string    `db:"id"`
+	Data      []byte    `db:"data"`
+	ExpiresAt time.Time `db:"expires_at"`
+}
+
+type sessionData struct {
+	Data []byte `db:"data"`
+}
+
+// DBStore keeps sessions in the sessions table with id, data and expires_at columns
+type DBStore struct {
+	db      dbx.DB
+	dialect dbx.Dialect
+	load    *dbx.CompiledSelectQuery[sessionData]
+	insert  *dbx.CompiledInsertQuery[sessionRow, struct{}]
+	update  *dbx.CompiledUpdateQuery[sessionRow]
+	delete  *dbx.CompiledDeleteQuery[sessionRow, struct{}]
+}
+
+// StoreOption configures DBStore
+type StoreOption func(*DBStore)
+
+// WithDialect sets placeholder syntax of the store queries, dbx.Postgres by default
+func WithDialect(d dbx.Dialect) StoreOption {
+	return func(s *DBStore) {
+		s.dialect = d
+	}
+}
+
+// NewDBStore creates a new dbx backed store
+func NewDBStore(db dbx.DB, opts ...StoreOption) *DBStore {
+	s := &DBStore{db: db}
+	for _, opt := range opts {
+		opt(s)
+	}
+	s.load = dbx.Select[sessionData]("sessions").Dialect(s.dialect).Where(dbx.Eq("id"), dbx.Gt("expires_at")).Compile()
+	s.insert = dbx.Insert[sessionRow]("sessions").Dialect(s.dialect).Compile()
+	s.update = dbx.Update[sessionRow]("sessions").Dialect(s.dialect).Where(dbx.Eq("id")).Compile()
+	s.delete = dbx.Delete[sessionRow]("sessions").Dialect(s.dialect).Where(dbx.Eq("id")).Compile()
+	return s
+}
+
+// Load implements Store
+func (s *DBStore) Load(ctx context.Context, id string) ([]byte, error) {
+	row, err := s.load.New(id, time.Now().UTC()).GetContext(ctx, s.db)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return row.Data, err
+}
+
+// Save implements Store. Existing rows are updated, the row is inserted if there is none,
+// so it works on every dialect without an upsert.
+func (s *DBStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	row := sessionRow{ID: id, Data: data, ExpiresAt: time.Now().UTC().Add(ttl)}
+	n, err := s.update.New(row).ExecContext(ctx, s.db)
+	if err != nil || n > 0 {
+		return err
+	}
+	_, err = s.insert.New(row).ExecContext(ctx, s.db)
+	return err
+}
+
+// Delete implements Store
+func (s *DBStore) Delete(ctx context.Context, id string) error {
+	_, err := s.delete.New(sessionRow{ID: id}).ExecContext(ctx, s.db)
+	return err
+}
+
+// RedisClient is the subset of redis commands used by RedisStore.
+// Get must return ErrNotFound for missing keys.
+type RedisClient interface {
+	Get(ctx context.Context, key string) ([]byte, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Del(ctx context.Context, key string) error
+}
+
+// RedisStore keeps sessions in redis under prefixed keys
+type RedisStore struct {
+	client RedisClient
+	prefix string
+}
+
+// NewRedisStore creates a new redis backed store
+func NewRedisStore(client RedisClient, prefix string) *RedisStore {
+	return &RedisStore{client: client, prefix: prefix}
+}
+
+// Load implements Store
+func (s *RedisStore) Load(ctx context.Context, id string) ([]byte, error) {
+	return s.client.Get(ctx, s.prefix+id)
+}
+
+// Save implements Store
+func (s *RedisStore) Save(ctx context.Context, id string, data []byte, ttl time.Duration) error {
+	return s.client.Set(ctx, s.prefix+id, data, ttl)
+}
+
+// Delete implements Store
+func (s *RedisStore) Delete(ctx context.Context, id string) error {
+	return s.client.Del(ctx, s.prefix+id)
+}
@@ -1,17 +1,28 @@
 package swaggergen
 
 import (
+	"encoding"
+	"encoding/json"
+	"net/http"
+	"net/url"
 	"reflect"
+	"slices"
+	"strconv"
 	"strings"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/httpio"
 )
 
 // OpenAPI represents the root OpenAPI 3.0 specification
 type OpenAPI struct {
-	OpenAPI    string              `json:"openapi"`
-	Info       Info                `json:"info"`
-	Servers    []Server            `json:"servers,omitempty"`
-	Paths      map[string]PathItem `json:"paths"`
-	Components *Components         `json:"components,omitempty"`
+	OpenAPI    string                `json:"openapi"`
+	Info       Info                  `json:"info"`
+	Servers    []Server              `json:"servers,omitempty"`
+	Paths      map[string]PathItem   `json:"paths"`
+	Webhooks   map[string]PathItem   `json:"webhooks,omitempty"`
+	Components *Components           `json:"components,omitempty"`
+	Security   []SecurityRequirement `json:"security,omitempty"`
 }
 
 // Info provides metadata about the API
@@ -45,6 +56,8 @@ type Operation struct {
 	Parameters  []Parameter         `json:"parameters,omitempty"`
 	RequestBody *RequestBody        `json:"requestBody,omitempty"`
 	Responses   map[string]Response `json:"responses"`
+	// Security lists alternative requirements of the operation, overriding the ones of the spec
+	Security []SecurityRequirement `json:"security,omitempty"`
 }
 
 // Parameter describes a single operation parameter
@@ -76,9 +89,55 @@ type MediaType struct {
 
 // Components holds a set of reusable objects for different aspects of the OAS
 type Components struct {
-	Schemas map[string]*Schema `json:"schemas,omitempty"`
+	Schemas         map[string]*Schema         `json:"schemas,omitempty"`
+	SecuritySchemes map[string]*SecurityScheme `json:"securitySchemes,omitempty"`
+}
+
+// SecurityScheme defines a security scheme that can be used by the operations
+type SecurityScheme struct {
+	Type         string      `json:"type"`
+	Description  string      `json:"description,omitempty"`
+	Name         string      `json:"name,omitempty"`
+	In           string      `json:"in,omitempty"`
+	Scheme       string      `json:"scheme,omitempty"`
+	BearerFormat string      `json:"bearerFormat,omitempty"`
+	Flows        *OAuthFlows `json:"flows,omitempty"`
+}
+
+// OAuthFlows configures flows of an oauth2 security scheme
+type OAuthFlows struct {
+	Implicit          *OAuthFlow `json:"implicit,omitempty"`
+	Password          *OAuthFlow `json:"password,omitempty"`
+	ClientCredentials *OAuthFlow `json:"clientCredentials,omitempty"`
+	AuthorizationCode *OAuthFlow `json:"authorizationCode,omitempty"`
+}
+
+// OAuthFlow configures a single oauth2 flow, Scopes maps scope names to their descriptions
+type OAuthFlow struct {
+	AuthorizationURL string            `json:"authorizationUrl,omitempty"`
+	TokenURL         string            `json:"tokenUrl,omitempty"`
+	RefreshURL       string            `json:"refreshUrl,omitempty"`
+	Scopes           map[string]string `json:"scopes"`
+}
+
+// BearerAuth returns an http bearer scheme, format hints at the token, e.g. JWT
+func BearerAuth(format string) *SecurityScheme {
+	return &SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: format}
 }
 
+// APIKeyAuth returns a scheme of a key passed in the named header, query param or cookie
+func APIKeyAuth(in, name string) *SecurityScheme {
+	return &SecurityScheme{Type: "apiKey", In: in, Name: name}
+}
+
+// OAuth2 returns an oauth2 scheme with the given flows
+func OAuth2(flows OAuthFlows) *SecurityScheme {
+	return &SecurityScheme{Type: "oauth2", Flows: &flows}
+}
+
+// SecurityRequirement maps security scheme names to required scopes
+type SecurityRequirement map[string][]string
+
 // Schema represents a JSON Schema
 type Schema struct {
 	Type                 string             `json:"type,omitempty"`
@@ -91,6 +150,13 @@ type Schema struct {
 	Example              interface{}        `json:"example,omitempty"`
 	Enum                 []interface{}      `json:"enum,omitempty"`
 	AdditionalProperties interface{}        `json:"additionalProperties,omitempty"`
+	Minimum              *float64           `json:"minimum,omitempty"`
+	Maximum              *float64           `json:"maximum,omitempty"`
+	MinLength            *int               `json:"minLength,omitempty"`
+	MaxLength            *int               `json:"maxLength,omitempty"`
+	MinItems             *int               `json:"minItems,omitempty"`
+	MaxItems             *int               `json:"maxItems,omitempty"`
+	Pattern              string             `json:"pattern,omitempty"`
 }
 
 // HandlerInfo contains information about a registered handler
@@ -103,13 +169,28 @@ type HandlerInfo struct {
 	Tags         []string
 	Summary      string
 	Description  string
+	// Errors documents error responses of the handler, replacing ones added with AddErrorResponse for the same status
+	Errors []ErrorResponse
+	// Security lists alternative requirements of the handler, see Operation
+	Security []SecurityRequirement
+}
+
+// ErrorResponse documents an error status of an operation, e.g. 404 with a not found envelope
+type ErrorResponse struct {
+	Status int
+	// Description defaults to the status text, e.g. Not Found
+	Description string
+	// BodyType is the Go type of the JSON body, nil for responses without one
+	BodyType reflect.Type
 }
 
 // Generator generates OpenAPI specifications
 type Generator struct {
 	openapi    *OpenAPI
 	components *Components
-	schemas    map[string]*Schema
+	errors     []ErrorResponse
+	// types are schemas set with MapType
+	types map[reflect.Type]Schema
 }
 
 // NewGenerator creates a new swagger generator
@@ -129,7 +210,6 @@ func NewGenerator() *Generator {
 			Components: components,
 		},
 		components: components,
-		schemas:    make(map[string]*Schema),
 	}
 }
 
@@ -148,6 +228,38 @@ func (g *Generator) AddServer(url, description string) {
 	})
 }
 
+// AddSecurityScheme adds a security scheme to components.
+// If required is true, the scheme is also required by every operation,
+// including the ones registered before, and they get the 401 response documented.
+func (g *Generator) AddSecurityScheme(name string, scheme *SecurityScheme, required bool) {
+	if g.components.SecuritySchemes == nil {
+		g.components.SecuritySchemes = make(map[string]*SecurityScheme)
+	}
+	g.components.SecuritySchemes[name] = scheme
+
+	if required {
+		g.openapi.Security = append(g.openapi.Security, SecurityRequirement{name: {}})
+		for _, item := range g.openapi.Paths {
+			for _, op := range []*Operation{item.GET, item.POST, item.PUT, item.DELETE, item.PATCH} {
+				if op == nil {
+					continue
+				}
+				if _, ok := op.Responses["401"]; !ok {
+					op.Responses["401"] = unauthorizedResponse
+				}
+			}
+		}
+	}
+}
+
+var unauthorizedResponse = Response{Description: "Unauthorized"}
+
+// AddErrorResponse documents an error response of every handler registered after the call,
+// e.g. a 400 validation error body. Later calls for the same status replace earlier ones.
+func (g *Generator) AddErrorResponse(resp ErrorResponse) {
+	g.errors = append(g.errors, resp)
+}
+
 // RegisterHandler registers a handler for swagger generation
 func (g *Generator) RegisterHandler(info HandlerInfo) {
 	pathItem := g.openapi.Paths[info.Path]
@@ -158,11 +270,12 @@ func (g *Generator) RegisterHandler(info HandlerInfo) {
 		Description: info.Description,
 		OperationID: info.Name,
 		Responses:   make(map[string]Response),
+		Security:    info.Security,
 	}
 
 	// Extract all types of parameters if request type exists
 	if info.RequestType != nil && info.RequestType.Kind() != reflect.Invalid {
-		allParams := g.extractAllParameters(info.RequestType, "")
+		allParams := g.extractAllParameters(info.RequestType)
 
 		// Separate query parameters from path/cookie parameters
 		var queryParams []Parameter
@@ -213,10 +326,21 @@ func (g *Generator) RegisterHandler(info HandlerInfo) {
 		}
 	}
 
-	// Add error response
+	// Add error responses
+	if info.RequestType != nil && info.RequestType.Kind() != reflect.Invalid {
+		operation.Responses["400"] = Response{
+			Description: "Invalid request",
+		}
+	}
 	operation.Responses["500"] = Response{
 		Description: "Internal server error",
 	}
+	if len(info.Security) > 0 || len(g.openapi.Security) > 0 {
+		operation.Responses["401"] = unauthorizedResponse
+	}
+	for _, resp := range append(slices.Clip(g.errors), info.Errors...) {
+		operation.Responses[strconv.Itoa(resp.Status)] = g.errorResponse(resp)
+	}
 
 	// Set operation based on method
 	switch strings.ToUpper(info.Method) {
@@ -235,8 +359,54 @@ func (g *Generator) RegisterHandler(info HandlerInfo) {
 	g.openapi.Paths[info.Path] = pathItem
 }
 
-// extractAllParameters extracts query, path, header, and cookie parameters from a struct type
-func (g *Generator) extractAllParameters(t reflect.Type, prefix string) []Parameter {
+func (g *Generator) errorResponse(resp ErrorResponse) Response {
+	response := Response{Description: resp.Description}
+	if response.Description == "" {
+		response.Description = http.StatusText(resp.Status)
+	}
+	if resp.BodyType != nil {
+		response.Content = map[string]MediaType{
+			"application/json": {
+				Schema: g.generateSchema(resp.BodyType),
+			},
+		}
+	}
+	return response
+}
+
+// RegisterWebhook documents an outgoing webhook event with the given payload type.
+// Webhooks section was introduced in OpenAPI 3.1, so the spec version is bumped on first use.
+func (g *Generator) RegisterWebhook(name, description string, payloadType reflect.Type) {
+	if g.openapi.Webhooks == nil {
+		g.openapi.Webhooks = make(map[string]PathItem)
+		g.openapi.OpenAPI = "3.1.0"
+	}
+
+	g.openapi.Webhooks[name] = PathItem{
+		POST: &Operation{
+			Summary:     name,
+			Description: description,
+			OperationID: name,
+			RequestBody: &RequestBody{
+				Description: "Event payload",
+				Content: map[string]MediaType{
+					"application/json": {
+						Schema: g.generateSchema(payloadType),
+					},
+				},
+				Required: true,
+			},
+			Responses: map[string]Response{
+				"200": {
+					Description: "Event accepted by the subscriber",
+				},
+			},
+		},
+	}
+}
+
+// extractAllParameters extracts query, path, header, and cookie parameters httpio binds to fields of a struct type
+func (g *Generator) extractAllParameters(t reflect.Type) []Parameter {
 	var params []Parameter
 
 	// Handle pointers
@@ -248,69 +418,50 @@ func (g *Generator) extractAllParameters(t reflect.Type, prefix string) []Parame
 		return params
 	}
 
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-
-		// Skip unexported fields
-		if !field.IsExported() {
-			continue
+	isParam := func(source string) bool {
+		switch source {
+		case "query", "path", "header", "cookie":
+			return true
 		}
-
-		// Check for query, path, header, and cookie tags
-		queryTag := field.Tag.Get("query")
-		pathTag := field.Tag.Get("path")
-		headerTag := field.Tag.Get("header")
-		cookieTag := field.Tag.Get("cookie")
-
-		var paramName, paramIn string
-		var hasParam bool
-
-		if queryTag != "" {
-			paramName = queryTag
-			paramIn = "query"
-			hasParam = true
-		} else if pathTag != "" {
-			paramName = pathTag
-			paramIn = "path"
-			hasParam = true
-		} else if headerTag != "" {
-			paramName = headerTag
-			paramIn = "header"
-			hasParam = true
-		} else if cookieTag != "" {
-			paramName = cookieTag
-			paramIn = "cookie"
-			hasParam = true
-		}
-
-		if !hasParam {
-			// Check if this is a nested struct that might contain parameters
-			if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
-				nestedParams := g.extractAllParameters(field.Type, prefix)
-				params = append(params, nestedParams...)
-			}
+		return false
+	}
+	// invalid types have no params, registering their handlers fails, see httpio.Describe
+	bindings, _ := httpio.Describe(t)
+	documented := make(map[[2]string]bool)
+	for _, binding := range bindings {
+		// the catch-all field takes params the spec doesn't list
+		if !isParam(binding.Source) || binding.Name == "*" {
 			continue
 		}
 
-		// Build parameter name with prefix for nested structures
-		if prefix != "" {
-			paramName = prefix + "_" + paramName
-		}
-
-		// Handle nested structs
-		if field.Type.Kind() == reflect.Struct || (field.Type.Kind() == reflect.Ptr && field.Type.Elem().Kind() == reflect.Struct) {
-			nestedParams := g.extractAllParameters(field.Type, paramName)
-			params = append(params, nestedParams...)
-		} else {
-			// Create parameter for primitive types
+		field := t.FieldByIndex(binding.Index)
+		add := func(name, in string, required bool) {
+			if !isParam(in) || documented[[2]string{name, in}] {
+				return
+			}
+			documented[[2]string{name, in}] = true
 			param := Parameter{
-				Name:     paramName,
-				In:       paramIn,
-				Required: g.isFieldRequiredForParam(field, paramIn),
-				Schema:   g.generateSchemaForPrimitive(field.Type),
+				Name:        name,
+				In:          in,
+				Description: field.Tag.Get("doc"),
+				Required:    required || in == "path",
+				Schema:      g.generateSchemaForPrimitive(field.Type),
+			}
+			applyValidation(param.Schema, field)
+			if example, ok := field.Tag.Lookup("example"); ok {
+				param.Schema.Example = exampleValue(param.Schema, example)
 			}
 			params = append(params, param)
 		}
+
+		// aliases and fallbacks are alternatives of the primary param, so only it may be required
+		add(binding.Name, binding.Source, binding.Required || g.isFieldRequiredForParam(field, binding.Source))
+		for _, alias := range binding.Aliases {
+			add(alias, binding.Source, false)
+		}
+		for _, fallback := range binding.Fallbacks {
+			add(fallback.Name, fallback.Source, false)
+		}
 	}
 
 	return params
@@ -357,6 +508,53 @@ func (g *Generator) isFieldRequiredForParam(field reflect.StructField, paramIn s
 	return true
 }
 
+// typeSchemas maps well-known types to schemas of their JSON encoding, see Generator.MapType
+var typeSchemas = map[reflect.Type]Schema{
+	reflect.TypeFor[time.Time]():       {Type: "string", Format: "date-time"},
+	reflect.TypeFor[time.Duration]():   {Type: "integer", Format: "int64", Description: "Duration in nanoseconds"},
+	reflect.TypeFor[[]byte]():          {Type: "string", Format: "byte"},
+	reflect.TypeFor[url.URL]():         {Type: "string", Format: "uri"},
+	reflect.TypeFor[json.RawMessage](): {},
+}
+
+// paramTypeSchemas maps types httpio parses from params differently than JSON does
+var paramTypeSchemas = map[reflect.Type]Schema{
+	reflect.TypeFor[time.Duration](): {Type: "string", Format: "duration", Example: "1m30s"},
+}
+
+var textMarshalerType = reflect.TypeFor[encoding.TextMarshaler]()
+
+// MapType makes t documented with schema instead of the inferred one, e.g. a decimal type as a string.
+// It overrides the built-in mapping of time.Time, time.Duration, []byte, url.URL, json.RawMessage and
+// types implementing encoding.TextMarshaler, which are strings, with the uuid format for types named UUID.
+func (g *Generator) MapType(t reflect.Type, schema Schema) {
+	if g.types == nil {
+		g.types = make(map[reflect.Type]Schema)
+	}
+	g.types[t] = schema
+}
+
+// mappedSchema returns a copy of the schema t is mapped to, so tags applied to fields don't change the mapping
+func (g *Generator) mappedSchema(t reflect.Type, param bool) (*Schema, bool) {
+	if schema, ok := g.types[t]; ok {
+		return &schema, true
+	}
+	if schema, ok := paramTypeSchemas[t]; ok && param {
+		return &schema, true
+	}
+	if schema, ok := typeSchemas[t]; ok {
+		return &schema, true
+	}
+	if t.Implements(textMarshalerType) || reflect.PointerTo(t).Implements(textMarshalerType) {
+		schema := &Schema{Type: "string"}
+		if t.Name() == "UUID" {
+			schema.Format = "uuid"
+		}
+		return schema, true
+	}
+	return nil, false
+}
+
 // generateSchemaForPrimitive generates a schema for primitive types
 func (g *Generator) generateSchemaForPrimitive(t reflect.Type) *Schema {
 	// Handle pointers
@@ -364,6 +562,15 @@ func (g *Generator) generateSchemaForPrimitive(t reflect.Type) *Schema {
 		t = t.Elem()
 	}
 
+	if schema, ok := g.mappedSchema(t, true); ok {
+		return schema
+	}
+
+	// repeated params and comma separated values, e.g. ?tag=a&tag=b
+	if t.Kind() == reflect.Slice {
+		return &Schema{Type: "array", Items: g.generateSchemaForPrimitive(t.Elem())}
+	}
+
 	schema := &Schema{}
 
 	switch t.Kind() {
@@ -382,18 +589,149 @@ func (g *Generator) generateSchemaForPrimitive(t reflect.Type) *Schema {
 	return schema
 }
 
+// applyValidation reflects the httpio validate and enum tags of field as schema constraints,
+// min and max bound the value of numbers, the length of strings and the number of items of arrays
+func applyValidation(schema *Schema, field reflect.StructField) {
+	if schema.Ref != "" {
+		return
+	}
+	if enum, ok := httpio.Enum(field); ok {
+		// enum tags constrain elements of arrays
+		target := schema
+		if schema.Type == "array" && schema.Items != nil && schema.Items.Ref == "" {
+			target = schema.Items
+		}
+		target.Enum = nil
+		for _, v := range enum {
+			target.Enum = append(target.Enum, enumValue(target.Type, v))
+		}
+	}
+	tag, ok := field.Tag.Lookup("validate")
+	if !ok {
+		return
+	}
+	rules, err := httpio.ParseRules(tag)
+	if err != nil {
+		return
+	}
+
+	for _, rule := range rules {
+		n, _ := strconv.ParseFloat(rule.Value, 64)
+		length := int(n)
+		switch {
+		case rule.Name == "pattern":
+			schema.Pattern = rule.Value
+		case rule.Name == "oneof":
+			schema.Enum = nil
+			for _, v := range strings.Fields(rule.Value) {
+				schema.Enum = append(schema.Enum, enumValue(schema.Type, v))
+			}
+		case schema.Type == "integer" || schema.Type == "number":
+			switch rule.Name {
+			case "min":
+				schema.Minimum = &n
+			case "max":
+				schema.Maximum = &n
+			}
+		case schema.Type == "string":
+			switch rule.Name {
+			case "min":
+				schema.MinLength = &length
+			case "max":
+				schema.MaxLength = &length
+			case "len":
+				schema.MinLength, schema.MaxLength = &length, &length
+			}
+		case schema.Type == "array":
+			switch rule.Name {
+			case "min":
+				schema.MinItems = &length
+			case "max":
+				schema.MaxItems = &length
+			case "len":
+				schema.MinItems, schema.MaxItems = &length, &length
+			}
+		}
+	}
+}
+
+// applyDoc documents schema with the doc and example tags of field, e.g. `doc:"Page size" example:"20"`.
+// Referenced schemas are shared by every field of their type, so they're left as is.
+func applyDoc(schema *Schema, field reflect.StructField) {
+	if schema.Ref != "" {
+		return
+	}
+	if doc, ok := field.Tag.Lookup("doc"); ok {
+		schema.Description = doc
+	}
+	if example, ok := field.Tag.Lookup("example"); ok {
+		schema.Example = exampleValue(schema, example)
+	}
+}
+
+// exampleValue converts an example tag to the type of schema, examples of arrays list items separated by commas
+func exampleValue(schema *Schema, example string) interface{} {
+	if schema.Type == "array" && schema.Items != nil {
+		items := []interface{}{}
+		for _, v := range strings.Split(example, ",") {
+			items = append(items, enumValue(schema.Items.Type, v))
+		}
+		return items
+	}
+	return enumValue(schema.Type, example)
+}
+
+// enumValue converts a oneof value to the type of the schema
+func enumValue(typ, v string) interface{} {
+	switch typ {
+	case "integer":
+		if n, err := strconv.ParseInt(v, 10, 64); err == nil {
+			return n
+		}
+	case "number":
+		if n, err := strconv.ParseFloat(v, 64); err == nil {
+			return n
+		}
+	case "boolean":
+		if b, err := strconv.ParseBool(v); err == nil {
+			return b
+		}
+	}
+	return v
+}
+
+// SchemaFor returns the schema of a Go type, named types are added to components and referenced
+func (g *Generator) SchemaFor(t reflect.Type) *Schema {
+	return g.generateSchema(t)
+}
+
+// SchemaInto returns the schema of a Go type like SchemaFor, but named types are added to defs,
+// leaving components of the spec as is. References keep pointing at #/components/schemas.
+func (g *Generator) SchemaInto(t reflect.Type, defs map[string]*Schema) *Schema {
+	return g.schemaIn(t, defs)
+}
+
 // generateSchema generates a JSON schema for a Go type
 func (g *Generator) generateSchema(t reflect.Type) *Schema {
+	return g.schemaIn(t, g.components.Schemas)
+}
+
+// schemaIn generates a JSON schema for a Go type, named types are stored in defs and referenced
+func (g *Generator) schemaIn(t reflect.Type, defs map[string]*Schema) *Schema {
 	// Handle pointers
 	if t.Kind() == reflect.Ptr {
 		t = t.Elem()
 	}
 
+	if schema, ok := g.mappedSchema(t, false); ok {
+		return schema
+	}
+
 	typeName := g.getTypeName(t)
 
 	// Check if schema already exists
 	if typeName != "" {
-		if _, exists := g.schemas[typeName]; exists {
+		if _, exists := defs[typeName]; exists {
 			return &Schema{Ref: "#/components/schemas/" + typeName}
 		}
 	}
@@ -411,7 +749,7 @@ func (g *Generator) generateSchema(t reflect.Type) *Schema {
 		schema.Type = "boolean"
 	case reflect.Slice, reflect.Array:
 		schema.Type = "array"
-		itemSchema := g.generateSchema(t.Elem())
+		itemSchema := g.schemaIn(t.Elem(), defs)
 		schema.Items = itemSchema
 	case reflect.Map:
 		schema.Type = "object"
@@ -463,7 +801,9 @@ func (g *Generator) generateSchema(t reflect.Type) *Schema {
 				}
 			}
 
-			fieldSchema := g.generateSchema(field.Type)
+			fieldSchema := g.schemaIn(field.Type, defs)
+			applyValidation(fieldSchema, field)
+			applyDoc(fieldSchema, field)
 			schema.Properties[fieldName] = fieldSchema
 		}
 
@@ -473,8 +813,7 @@ func (g *Generator) generateSchema(t reflect.Type) *Schema {
 
 		// Store schema in components if it's a named type
 		if typeName != "" {
-			g.schemas[typeName] = schema
-			g.components.Schemas[typeName] = schema
+			defs[typeName] = schema
 			return &Schema{Ref: "#/components/schemas/" + typeName}
 		}
 	}
@@ -485,7 +824,7 @@ func (g *Generator) generateSchema(t reflect.Type) *Schema {
 // getTypeName returns a clean type name for schema references
 func (g *Generator) getTypeName(t reflect.Type) string {
 	if t.Name() != "" {
-		return t.Name()
+		return cleanGenericName(t.Name())
 	}
 
 	// For anonymous types, create a name based on the structure
@@ -501,6 +840,32 @@ func (g *Generator) getTypeName(t reflect.Type) string {
 	return ""
 }
 
+// cleanGenericName turns instantiated generic names like Page[github.com/foo/bar.User]
+// into PageOfUser, since brackets, slashes and dots are not allowed in component names
+func cleanGenericName(name string) string {
+	base, args, ok := strings.Cut(name, "[")
+	if !ok {
+		return name
+	}
+	args = strings.TrimSuffix(args, "]")
+
+	var b strings.Builder
+	b.WriteString(base)
+	for i, arg := range strings.Split(args, ",") {
+		if i == 0 {
+			b.WriteString("Of")
+		} else {
+			b.WriteString("And")
+		}
+		arg = strings.Trim(arg, "[]*")
+		if idx := strings.LastIndex(arg, "."); idx >= 0 {
+			arg = arg[idx+1:]
+		}
+		b.WriteString(arg)
+	}
+	return b.String()
+}
+
 // GenerateJSON generates the OpenAPI specification as JSON
 func (g *Generator) Schema() *OpenAPI {
 	return g.openapi
@@ -0,0 +1,268 @@
+package swaggergen_test
+
+import (
+	"encoding/json"
+	"net/netip"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/swaggergen"
+	"github.com/stretchr/testify/require"
+)
+
+type paramsRequest struct {
+	ID      int      `path:"id"`
+	Token   string   `header:"x-api-key,required"`
+	Session string   `cookie:"session"`
+	Tags    []string `query:"tag"`
+	Page    *int     `query:"page,alias=p"`
+	Key     string   `in:"header=X-Key,query=key,cookie=key,required"`
+}
+
+func TestParameters(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.RegisterHandler(swaggergen.HandlerInfo{
+		Name:        "GET /items/{id}",
+		Path:        "/items/{id}",
+		Method:      "GET",
+		RequestType: reflect.TypeOf(paramsRequest{}),
+	})
+	params := g.Schema().Paths["/items/{id}"].GET.Parameters
+
+	for _, tt := range []struct {
+		name string
+		want swaggergen.Parameter
+	}{
+		{"path", swaggergen.Parameter{Name: "id", In: "path", Required: true, Schema: &swaggergen.Schema{Type: "integer"}}},
+		{"canonical header without options", swaggergen.Parameter{Name: "X-Api-Key", In: "header", Required: true, Schema: &swaggergen.Schema{Type: "string"}}},
+		{"cookie", swaggergen.Parameter{Name: "session", In: "cookie", Schema: &swaggergen.Schema{Type: "string"}}},
+		{"slice", swaggergen.Parameter{Name: "tag", In: "query", Required: true, Schema: &swaggergen.Schema{Type: "array", Items: &swaggergen.Schema{Type: "string"}}}},
+		{"optional pointer", swaggergen.Parameter{Name: "page", In: "query", Schema: &swaggergen.Schema{Type: "integer"}}},
+		{"alias", swaggergen.Parameter{Name: "p", In: "query", Schema: &swaggergen.Schema{Type: "integer"}}},
+		{"primary source", swaggergen.Parameter{Name: "X-Key", In: "header", Required: true, Schema: &swaggergen.Schema{Type: "string"}}},
+		{"query fallback", swaggergen.Parameter{Name: "key", In: "query", Schema: &swaggergen.Schema{Type: "string"}}},
+		{"cookie fallback", swaggergen.Parameter{Name: "key", In: "cookie", Schema: &swaggergen.Schema{Type: "string"}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Contains(t, params, tt.want)
+		})
+	}
+	require.Len(t, params, 9)
+}
+
+type validationError struct {
+	Fields []string `json:"fields"`
+}
+
+type notFound struct {
+	Message string `json:"message"`
+}
+
+func TestErrorResponses(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.AddErrorResponse(swaggergen.ErrorResponse{Status: 400, BodyType: reflect.TypeOf(validationError{})})
+	g.AddErrorResponse(swaggergen.ErrorResponse{Status: 404, Description: "global not found"})
+	g.RegisterHandler(swaggergen.HandlerInfo{
+		Path:        "/items",
+		Method:      "POST",
+		RequestType: reflect.TypeOf(notFound{}),
+		Errors: []swaggergen.ErrorResponse{
+			{Status: 404, Description: "item not found", BodyType: reflect.TypeOf(notFound{})},
+			{Status: 409},
+		},
+	})
+	// added after registration, so the handler doesn't get it
+	g.AddErrorResponse(swaggergen.ErrorResponse{Status: 503})
+
+	responses := g.Schema().Paths["/items"].POST.Responses
+	for _, tt := range []struct {
+		status string
+		want   swaggergen.Response
+	}{
+		{"400", swaggergen.Response{
+			Description: "Bad Request",
+			Content:     map[string]swaggergen.MediaType{"application/json": {Schema: &swaggergen.Schema{Ref: "#/components/schemas/validationError"}}},
+		}},
+		{"404", swaggergen.Response{
+			Description: "item not found",
+			Content:     map[string]swaggergen.MediaType{"application/json": {Schema: &swaggergen.Schema{Ref: "#/components/schemas/notFound"}}},
+		}},
+		{"409", swaggergen.Response{Description: "Conflict"}},
+		{"500", swaggergen.Response{Description: "Internal server error"}},
+	} {
+		t.Run(tt.status, func(t *testing.T) {
+			require.Equal(t, tt.want, responses[tt.status])
+		})
+	}
+	require.NotContains(t, responses, "503")
+}
+
+func TestSecurity(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.AddSecurityScheme("apiKey", swaggergen.APIKeyAuth("header", "X-Api-Key"), true)
+	g.AddSecurityScheme("bearer", swaggergen.BearerAuth("JWT"), false)
+	g.AddSecurityScheme("oauth", swaggergen.OAuth2(swaggergen.OAuthFlows{
+		ClientCredentials: &swaggergen.OAuthFlow{TokenURL: "https://auth.example.com/token", Scopes: map[string]string{"orders:write": "Place orders"}},
+	}), false)
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/public", Method: "GET"})
+	g.RegisterHandler(swaggergen.HandlerInfo{
+		Path:   "/orders",
+		Method: "POST",
+		Security: []swaggergen.SecurityRequirement{
+			{"bearer": {}},
+			{"oauth": {"orders:write"}},
+		},
+	})
+
+	spec := g.Schema()
+	require.Equal(t, []swaggergen.SecurityRequirement{{"apiKey": {}}}, spec.Security)
+	for _, tt := range []struct {
+		name string
+		want *swaggergen.SecurityScheme
+	}{
+		{"apiKey", &swaggergen.SecurityScheme{Type: "apiKey", In: "header", Name: "X-Api-Key"}},
+		{"bearer", &swaggergen.SecurityScheme{Type: "http", Scheme: "bearer", BearerFormat: "JWT"}},
+		{"oauth", &swaggergen.SecurityScheme{Type: "oauth2", Flows: &swaggergen.OAuthFlows{
+			ClientCredentials: &swaggergen.OAuthFlow{TokenURL: "https://auth.example.com/token", Scopes: map[string]string{"orders:write": "Place orders"}},
+		}}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, spec.Components.SecuritySchemes[tt.name])
+		})
+	}
+
+	// operations without own requirements inherit the global ones
+	require.Nil(t, spec.Paths["/public"].GET.Security)
+	require.Equal(t, []swaggergen.SecurityRequirement{{"bearer": {}}, {"oauth": {"orders:write"}}}, spec.Paths["/orders"].POST.Security)
+	require.Equal(t, swaggergen.Response{Description: "Unauthorized"}, spec.Paths["/public"].GET.Responses["401"])
+	require.Equal(t, swaggergen.Response{Description: "Unauthorized"}, spec.Paths["/orders"].POST.Responses["401"])
+}
+
+func TestSecurityDocumentsUnauthorized(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/before", Method: "GET"})
+	g.RegisterHandler(swaggergen.HandlerInfo{
+		Path:     "/custom",
+		Method:   "GET",
+		Security: []swaggergen.SecurityRequirement{{"bearer": {}}},
+		Errors:   []swaggergen.ErrorResponse{{Status: 401, Description: "token expired"}},
+	})
+	require.NotContains(t, g.Schema().Paths["/before"].GET.Responses, "401")
+
+	// requiring a scheme documents 401 on operations registered before as well
+	g.AddSecurityScheme("apiKey", swaggergen.APIKeyAuth("header", "X-Api-Key"), true)
+	paths := g.Schema().Paths
+	require.Equal(t, "Unauthorized", paths["/before"].GET.Responses["401"].Description)
+	require.Equal(t, "token expired", paths["/custom"].GET.Responses["401"].Description)
+}
+
+type UUID [16]byte
+
+func (UUID) MarshalText() ([]byte, error) { return nil, nil }
+
+type decimal struct {
+	units int64
+}
+
+type typesBody struct {
+	At    time.Time       `json:"at"`
+	When  *time.Time      `json:"when"`
+	ID    UUID            `json:"id"`
+	Data  []byte          `json:"data"`
+	TTL   time.Duration   `json:"ttl"`
+	Link  url.URL         `json:"link"`
+	Raw   json.RawMessage `json:"raw"`
+	Addr  netip.Addr      `json:"addr"`
+	Price decimal         `json:"price"`
+}
+
+type timeoutQuery struct {
+	Timeout time.Duration `query:"timeout"`
+}
+
+func TestMappedTypes(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.MapType(reflect.TypeOf(decimal{}), swaggergen.Schema{Type: "string", Format: "decimal"})
+	g.MapType(reflect.TypeOf(url.URL{}), swaggergen.Schema{Type: "string", Format: "url"})
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/types", Method: "POST", RequestType: reflect.TypeOf(typesBody{})})
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/types", Method: "GET", RequestType: reflect.TypeOf(timeoutQuery{})})
+
+	spec := g.Schema()
+	props := spec.Components.Schemas["typesBody"].Properties
+	for _, tt := range []struct {
+		name string
+		want *swaggergen.Schema
+	}{
+		{"at", &swaggergen.Schema{Type: "string", Format: "date-time"}},
+		{"when", &swaggergen.Schema{Type: "string", Format: "date-time"}},
+		{"id", &swaggergen.Schema{Type: "string", Format: "uuid"}},
+		{"data", &swaggergen.Schema{Type: "string", Format: "byte"}},
+		{"ttl", &swaggergen.Schema{Type: "integer", Format: "int64", Description: "Duration in nanoseconds"}},
+		{"link", &swaggergen.Schema{Type: "string", Format: "url"}},
+		{"raw", &swaggergen.Schema{}},
+		{"addr", &swaggergen.Schema{Type: "string"}},
+		{"price", &swaggergen.Schema{Type: "string", Format: "decimal"}},
+	} {
+		t.Run(tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, props[tt.name])
+		})
+	}
+
+	// httpio parses durations of params like time.ParseDuration
+	param := spec.Paths["/types"].GET.Parameters[0]
+	require.Equal(t, &swaggergen.Schema{Type: "string", Format: "duration", Example: "1m30s"}, param.Schema)
+	require.NotContains(t, spec.Components.Schemas, "decimal")
+}
+
+type docQuery struct {
+	Limit int    `query:"limit" doc:"Page size" example:"20"`
+	IDs   []int  `query:"id" example:"1,2"`
+	Sort  string `query:"sort" enum:"asc,desc" example:"asc"`
+}
+
+type docBody struct {
+	Name   string   `json:"name" doc:"Display name" example:"John"`
+	Active bool     `json:"active" example:"true"`
+	Tags   []string `json:"tags" example:"a,b"`
+	Owner  notFound `json:"owner" doc:"not applied to references"`
+}
+
+func TestDocTags(t *testing.T) {
+	g := swaggergen.NewGenerator()
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/docs", Method: "GET", RequestType: reflect.TypeOf(docQuery{})})
+	g.RegisterHandler(swaggergen.HandlerInfo{Path: "/docs", Method: "POST", RequestType: reflect.TypeOf(docBody{})})
+	spec := g.Schema()
+
+	params := spec.Paths["/docs"].GET.Parameters
+	for _, tt := range []struct {
+		name string
+		want swaggergen.Parameter
+	}{
+		{"limit", swaggergen.Parameter{Name: "limit", In: "query", Description: "Page size", Required: true, Schema: &swaggergen.Schema{Type: "integer", Example: int64(20)}}},
+		{"id", swaggergen.Parameter{Name: "id", In: "query", Required: true, Schema: &swaggergen.Schema{
+			Type: "array", Items: &swaggergen.Schema{Type: "integer"}, Example: []interface{}{int64(1), int64(2)},
+		}}},
+		{"sort", swaggergen.Parameter{Name: "sort", In: "query", Required: true, Schema: &swaggergen.Schema{Type: "string", Enum: []interface{}{"asc", "desc"}, Example: "asc"}}},
+	} {
+		t.Run("param "+tt.name, func(t *testing.T) {
+			require.Contains(t, params, tt.want)
+		})
+	}
+
+	props := spec.Components.Schemas["docBody"].Properties
+	for _, tt := range []struct {
+		name string
+		want *swaggergen.Schema
+	}{
+		{"name", &swaggergen.Schema{Type: "string", Description: "Display name", Example: "John"}},
+		{"active", &swaggergen.Schema{Type: "boolean", Example: true}},
+		{"tags", &swaggergen.Schema{Type: "array", Items: &swaggergen.Schema{Type: "string"}, Example: []interface{}{"a", "b"}}},
+		{"owner", &swaggergen.Schema{Ref: "#/components/schemas/notFound"}},
+	} {
+		t.Run("property "+tt.name, func(t *testing.T) {
+			require.Equal(t, tt.want, props[tt.name])
+		})
+	}
+}
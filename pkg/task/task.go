@@ -0,0 +1,218 @@
+package task
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// ErrClosed is returned when a job is enqueued after Shutdown was called
+var ErrClosed = errors.New("task runner is closed")
+
+// Job is a unit of background work
+type Job interface {
+	Run(ctx context.Context) error
+}
+
+// JobFunc adapts a function to Job
+type JobFunc func(ctx context.Context) error
+
+// Run implements Job
+func (f JobFunc) Run(ctx context.Context) error {
+	return f(ctx)
+}
+
+// Runner executes enqueued jobs on a fixed pool of workers
+type Runner struct {
+	workers     int
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+	onFailure   func(ctx context.Context, job Job, err error)
+
+	queue chan queuedJob
+	// stop is closed by Shutdown, it wakes up blocked Enqueue calls and skips retry backoffs
+	stop   chan struct{}
+	mu     sync.RWMutex
+	closed bool
+	// senders counts Enqueue calls sending to the queue, it's closed once they are done
+	senders sync.WaitGroup
+	wg      sync.WaitGroup
+}
+
+type queuedJob struct {
+	ctx context.Context
+	job Job
+}
+
+// Option configures Runner
+type Option func(*Runner)
+
+// WithWorkers sets number of concurrently running jobs
+func WithWorkers(n int) Option {
+	return func(r *Runner) {
+		r.workers = max(n, 1)
+	}
+}
+
+// WithQueueSize sets how many jobs can wait for a free worker before Enqueue blocks
+func WithQueueSize(n int) Option {
+	return func(r *Runner) {
+		r.queue = make(chan queuedJob, n)
+	}
+}
+
+// WithMaxAttempts sets how many times a failing job is run before giving up
+func WithMaxAttempts(n int) Option {
+	return func(r *Runner) {
+		r.maxAttempts = max(n, 1)
+	}
+}
+
+// WithBackoff sets the delay before the given retry attempt
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(r *Runner) {
+		r.backoff = backoff
+	}
+}
+
+// WithFailureHandler sets the function called when a job fails after all attempts
+func WithFailureHandler(fn func(ctx context.Context, job Job, err error)) Option {
+	return func(r *Runner) {
+		r.onFailure = fn
+	}
+}
+
+// NewRunner creates a runner and starts its workers
+func NewRunner(opts ...Option) *Runner {
+	r := &Runner{
+		workers:     4,
+		maxAttempts: 3,
+		backoff: func(attempt int) time.Duration {
+			return time.Second << (attempt - 1)
+		},
+		onFailure: func(ctx context.Context, job Job, err error) {
+			slog.ErrorContext(ctx, "background job failed", "job", fmt.Sprintf("%T", job), "error", err)
+		},
+		queue: make(chan queuedJob, 100),
+		stop:  make(chan struct{}),
+	}
+	for _, opt := range opts {
+		opt(r)
+	}
+
+	r.wg.Add(r.workers)
+	for range r.workers {
+		go r.work()
+	}
+	return r
+}
+
+// Enqueue schedules the job. Job context keeps values of ctx (trace ids, principals)
+// but is not canceled together with it, so jobs outlive the request that enqueued them.
+// If the queue is full it blocks until a worker is free, ctx is done or Shutdown is called.
+func (r *Runner) Enqueue(ctx context.Context, job Job) error {
+	r.mu.RLock()
+	if r.closed {
+		r.mu.RUnlock()
+		return ErrClosed
+	}
+	r.senders.Add(1)
+	r.mu.RUnlock()
+	defer r.senders.Done()
+
+	select {
+	case r.queue <- queuedJob{ctx: context.WithoutCancel(ctx), job: job}:
+		return nil
+	case <-r.stop:
+		return ErrClosed
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Shutdown stops accepting new jobs and waits until already enqueued jobs are done
+// or ctx is done, whichever comes first. Retries of failing jobs are run without backoff.
+func (r *Runner) Shutdown(ctx context.Context) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	r.mu.Lock()
+	if !r.closed {
+		r.closed = true
+		close(r.stop)
+		go func() {
+			// blocked senders return on stop, so the queue is closed only after their last send
+			r.senders.Wait()
+			close(r.queue)
+		}()
+	}
+	r.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		r.wg.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func (r *Runner) work() {
+	defer r.wg.Done()
+	for q := range r.queue {
+		r.run(q)
+	}
+}
+
+func (r *Runner) run(q queuedJob) {
+	var err error
+	for attempt := 1; attempt <= r.maxAttempts; attempt++ {
+		if attempt > 1 {
+			r.wait(r.backoff(attempt - 1))
+		}
+		if err = safeRun(q.ctx, q.job); err == nil {
+			return
+		}
+	}
+	r.onFailure(q.ctx, q.job, err)
+}
+
+// wait sleeps for d or until Shutdown is called, so draining doesn't wait out backoffs
+func (r *Runner) wait(d time.Duration) {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+	case <-r.stop:
+	}
+}
+
+func safeRun(ctx context.Context, job Job) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			err = fmt.Errorf("job panicked: %v", p)
+		}
+	}()
+	return job.Run(ctx)
+}
+
+type runnerCtxKey struct{}
+
+// NewContext returns a copy of ctx carrying the runner
+func NewContext(ctx context.Context, r *Runner) context.Context {
+	return context.WithValue(ctx, runnerCtxKey{}, r)
+}
+
+// FromContext returns the runner carried by ctx
+func FromContext(ctx context.Context) (*Runner, bool) {
+	r, ok := ctx.Value(runnerCtxKey{}).(*Runner)
+	return r, ok
+}
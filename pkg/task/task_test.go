@@ -0,0 +1,94 @@
+package task_test
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/task"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRunner(t *testing.T) {
+	var failures atomic.Int32
+	r := task.NewRunner(
+		task.WithWorkers(2),
+		task.WithMaxAttempts(3),
+		task.WithBackoff(func(int) time.Duration { return time.Millisecond }),
+		task.WithFailureHandler(func(context.Context, task.Job, error) { failures.Add(1) }),
+	)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var succeeded, attempts atomic.Int32
+	for range 10 {
+		err := r.Enqueue(ctx, task.JobFunc(func(ctx context.Context) error {
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			time.Sleep(time.Millisecond)
+			succeeded.Add(1)
+			return nil
+		}))
+		require.NoError(t, err)
+	}
+	err := r.Enqueue(ctx, task.JobFunc(func(context.Context) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	}))
+	require.NoError(t, err)
+	// jobs must survive cancellation of the enqueuing request
+	cancel()
+
+	require.NoError(t, r.Shutdown(context.Background()))
+	require.Equal(t, int32(10), succeeded.Load())
+	require.Equal(t, int32(3), attempts.Load())
+	require.Equal(t, int32(1), failures.Load())
+
+	err = r.Enqueue(context.Background(), task.JobFunc(func(context.Context) error { return nil }))
+	require.ErrorIs(t, err, task.ErrClosed)
+}
+
+func TestRunnerShutdownUnblocksEnqueue(t *testing.T) {
+	release := make(chan struct{})
+	r := task.NewRunner(task.WithWorkers(1), task.WithQueueSize(1))
+	block := task.JobFunc(func(context.Context) error {
+		<-release
+		return nil
+	})
+	require.NoError(t, r.Enqueue(context.Background(), block))
+	require.NoError(t, r.Enqueue(context.Background(), block))
+
+	// the worker and the queue are busy, so this call blocks until Shutdown
+	enqueued := make(chan error)
+	go func() {
+		enqueued <- r.Enqueue(context.Background(), block)
+	}()
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	require.ErrorIs(t, r.Shutdown(ctx), context.DeadlineExceeded)
+	require.ErrorIs(t, <-enqueued, task.ErrClosed)
+
+	close(release)
+	require.NoError(t, r.Shutdown(context.Background()))
+}
+
+func TestRunnerShutdownSkipsBackoff(t *testing.T) {
+	var attempts atomic.Int32
+	r := task.NewRunner(
+		task.WithMaxAttempts(3),
+		task.WithBackoff(func(int) time.Duration { return time.Hour }),
+		task.WithFailureHandler(func(context.Context, task.Job, error) {}),
+	)
+	require.NoError(t, r.Enqueue(context.Background(), task.JobFunc(func(context.Context) error {
+		attempts.Add(1)
+		return errors.New("boom")
+	})))
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	require.NoError(t, r.Shutdown(ctx))
+	require.Equal(t, int32(3), attempts.Load())
+}
@@ -0,0 +1,270 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"reflect"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/dbx"
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+// Headers set on every delivery
+const (
+	HeaderEvent     = "X-Webhook-Event"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signature"
+)
+
+// Event describes a typed webhook event
+type Event[T any] struct {
+	Name        string
+	Description string
+}
+
+// NewEvent creates a new event definition
+func NewEvent[T any](name, description string) Event[T] {
+	return Event[T]{Name: name, Description: description}
+}
+
+// Document adds the event payload schema to the webhooks section of the spec
+func (e Event[T]) Document(g *swaggergen.Generator) {
+	g.RegisterWebhook(e.Name, e.Description, reflect.TypeOf((*T)(nil)).Elem())
+}
+
+// Subscriber is an endpoint receiving webhook events
+type Subscriber struct {
+	ID     string
+	URL    string
+	Secret []byte
+	// Events the subscriber is interested in, empty means all events
+	Events []string
+}
+
+func (s Subscriber) wants(event string) bool {
+	return len(s.Events) == 0 || slices.Contains(s.Events, event)
+}
+
+// Delivery is a single delivery attempt
+type Delivery struct {
+	SubscriberID string    `db:"subscriber_id"`
+	Event        string    `db:"event"`
+	Attempt      int       `db:"attempt"`
+	Status       int       `db:"status"`
+	Error        string    `db:"error"`
+	AttemptedAt  time.Time `db:"attempted_at"`
+}
+
+// DeliveryLog stores delivery attempts
+type DeliveryLog interface {
+	LogDelivery(ctx context.Context, d Delivery) error
+}
+
+var insertDeliveryQuery = dbx.Insert[Delivery]("webhook_deliveries").Compile()
+
+// DBLog stores delivery attempts in the webhook_deliveries table
+type DBLog struct {
+	db dbx.DB
+}
+
+// NewDBLog creates a new dbx backed delivery log
+func NewDBLog(db dbx.DB) *DBLog {
+	return &DBLog{db: db}
+}
+
+// LogDelivery implements DeliveryLog
+func (l *DBLog) LogDelivery(ctx context.Context, d Delivery) error {
+	_, err := insertDeliveryQuery.New(d).ExecContext(ctx, l.db)
+	return err
+}
+
+// Dispatcher delivers events to registered subscribers
+type Dispatcher struct {
+	client      *http.Client
+	maxAttempts int
+	backoff     func(attempt int) time.Duration
+	log         DeliveryLog
+
+	mu          sync.RWMutex
+	subscribers map[string]Subscriber
+}
+
+// Option configures Dispatcher
+type Option func(*Dispatcher)
+
+// WithClient sets http client used for deliveries
+func WithClient(client *http.Client) Option {
+	return func(d *Dispatcher) {
+		d.client = client
+	}
+}
+
+// WithMaxAttempts sets how many times a delivery is attempted before giving up
+func WithMaxAttempts(n int) Option {
+	return func(d *Dispatcher) {
+		d.maxAttempts = max(n, 1)
+	}
+}
+
+// WithBackoff sets the delay before the given retry attempt
+func WithBackoff(backoff func(attempt int) time.Duration) Option {
+	return func(d *Dispatcher) {
+		d.backoff = backoff
+	}
+}
+
+// WithDeliveryLog sets the log every delivery attempt is written to
+func WithDeliveryLog(log DeliveryLog) Option {
+	return func(d *Dispatcher) {
+		d.log = log
+	}
+}
+
+// ExponentialBackoff doubles the delay after each attempt starting from base
+func ExponentialBackoff(base time.Duration) func(attempt int) time.Duration {
+	return func(attempt int) time.Duration {
+		return base << (attempt - 1)
+	}
+}
+
+// NewDispatcher creates a new webhook dispatcher
+func NewDispatcher(opts ...Option) *Dispatcher {
+	d := &Dispatcher{
+		client:      &http.Client{Timeout: 10 * time.Second},
+		maxAttempts: 3,
+		backoff:     ExponentialBackoff(500 * time.Millisecond),
+		subscribers: make(map[string]Subscriber),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Subscribe registers or replaces a subscriber
+func (d *Dispatcher) Subscribe(s Subscriber) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	d.subscribers[s.ID] = s
+}
+
+// Unsubscribe removes a subscriber
+func (d *Dispatcher) Unsubscribe(id string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.subscribers, id)
+}
+
+// Publish delivers payload to every subscriber of the event.
+// Each delivery is retried independently, returned error joins all failed deliveries.
+func Publish[T any](ctx context.Context, d *Dispatcher, event Event[T], payload T) error {
+	body, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("failed to encode %s payload: %w", event.Name, err)
+	}
+
+	d.mu.RLock()
+	var subscribers []Subscriber
+	for _, s := range d.subscribers {
+		if s.wants(event.Name) {
+			subscribers = append(subscribers, s)
+		}
+	}
+	d.mu.RUnlock()
+
+	var errs []error
+	for _, s := range subscribers {
+		if err := d.deliver(ctx, s, event.Name, body); err != nil {
+			errs = append(errs, fmt.Errorf("subscriber %s: %w", s.ID, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func (d *Dispatcher) deliver(ctx context.Context, s Subscriber, event string, body []byte) error {
+	var err error
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		if attempt > 1 {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(d.backoff(attempt - 1)):
+			}
+		}
+
+		var status int
+		status, err = d.send(ctx, s, event, body)
+		if d.log != nil {
+			d.logAttempt(ctx, s, event, attempt, status, err)
+		}
+		if err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+func (d *Dispatcher) send(ctx context.Context, s Subscriber, event string, body []byte) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, s.URL, bytes.NewReader(body))
+	if err != nil {
+		return 0, err
+	}
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(HeaderEvent, event)
+	req.Header.Set(HeaderTimestamp, timestamp)
+	req.Header.Set(HeaderSignature, Sign(s.Secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+	_, _ = io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return resp.StatusCode, fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return resp.StatusCode, nil
+}
+
+func (d *Dispatcher) logAttempt(ctx context.Context, s Subscriber, event string, attempt, status int, err error) {
+	delivery := Delivery{
+		SubscriberID: s.ID,
+		Event:        event,
+		Attempt:      attempt,
+		Status:       status,
+		AttemptedAt:  time.Now().UTC(),
+	}
+	if err != nil {
+		delivery.Error = err.Error()
+	}
+	// failing to log must not affect the delivery itself
+	_ = d.log.LogDelivery(ctx, delivery)
+}
+
+// Sign computes the signature header value for the given timestamp and body
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte{'.'})
+	mac.Write(body)
+	return "sha256=" + hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verify checks the signature of a received delivery, it's intended for subscribers written with cruder
+func Verify(secret []byte, timestamp string, body []byte, signature string) bool {
+	return hmac.Equal([]byte(signature), []byte(Sign(secret, timestamp, body)))
+}
@@ -0,0 +1,67 @@
+package webhook_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/pechorka/cruder/pkg/webhook"
+	"github.com/stretchr/testify/require"
+)
+
+type userCreated struct {
+	ID int `json:"id"`
+}
+
+type memoryLog struct {
+	mu         sync.Mutex
+	deliveries []webhook.Delivery
+}
+
+func (l *memoryLog) LogDelivery(_ context.Context, d webhook.Delivery) error {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.deliveries = append(l.deliveries, d)
+	return nil
+}
+
+func TestPublish(t *testing.T) {
+	secret := []byte("secret")
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		body, _ := io.ReadAll(r.Body)
+		ok := webhook.Verify(secret, r.Header.Get(webhook.HeaderTimestamp), body, r.Header.Get(webhook.HeaderSignature))
+		require.True(t, ok)
+		require.Equal(t, "user.created", r.Header.Get(webhook.HeaderEvent))
+		require.JSONEq(t, `{"id":1}`, string(body))
+
+		if calls == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	log := &memoryLog{}
+	d := webhook.NewDispatcher(
+		webhook.WithBackoff(func(int) time.Duration { return time.Millisecond }),
+		webhook.WithDeliveryLog(log),
+	)
+	d.Subscribe(webhook.Subscriber{ID: "sub", URL: srv.URL, Secret: secret, Events: []string{"user.created"}})
+	d.Subscribe(webhook.Subscriber{ID: "other", URL: srv.URL, Secret: secret, Events: []string{"user.deleted"}})
+
+	event := webhook.NewEvent[userCreated]("user.created", "")
+	err := webhook.Publish(context.Background(), d, event, userCreated{ID: 1})
+	require.NoError(t, err)
+
+	require.Equal(t, 2, calls)
+	require.Len(t, log.deliveries, 2)
+	require.Equal(t, http.StatusServiceUnavailable, log.deliveries[0].Status)
+	require.NotEmpty(t, log.deliveries[0].Error)
+	require.Equal(t, http.StatusOK, log.deliveries[1].Status)
+	require.Equal(t, 2, log.deliveries[1].Attempt)
+}
@@ -0,0 +1,124 @@
+package cruder
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"maps"
+	"net/http"
+	"strings"
+
+	"github.com/pechorka/cruder/pkg/swaggergen"
+)
+
+const jsonSchemaDialect = "https://json-schema.org/draft/2020-12/schema"
+
+// SchemaBundle is a JSON Schema document defining every registered request and response type
+type SchemaBundle struct {
+	Schema  string                        `json:"$schema"`
+	ID      string                        `json:"$id,omitempty"`
+	Version string                        `json:"version"`
+	Defs    map[string]*swaggergen.Schema `json:"$defs"`
+	// Routes maps route patterns to their request and response schemas
+	Routes map[string]BundleRoute `json:"routes"`
+}
+
+// BundleRoute references request and response schemas of a route
+type BundleRoute struct {
+	Request  *swaggergen.Schema `json:"request,omitempty"`
+	Response *swaggergen.Schema `json:"response,omitempty"`
+}
+
+// WithSchemaRegistry serves the schema bundle at path, so other services can validate
+// payloads against the same definitions the server uses. Version should be bumped
+// whenever contracts change. The ETag is a hash of the served bundle, so it changes
+// with the version and with feature flags hiding routes too.
+func WithSchemaRegistry(path, version string) Option {
+	return func(mux *Mux) {
+		mux.mux.HandleFunc("GET "+path, func(w http.ResponseWriter, r *http.Request) {
+			bundle := mux.SchemaBundle(r.Context(), version)
+			bundle.ID = path
+			body, err := json.Marshal(bundle)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			sum := sha256.Sum256(body)
+			etag := `"` + hex.EncodeToString(sum[:16]) + `"`
+
+			// a 304 carries the ETag it would be sent with, see RFC 9110 section 15.4.5
+			w.Header().Set("ETag", etag)
+			if r.Header.Get("If-None-Match") == etag {
+				w.WriteHeader(http.StatusNotModified)
+				return
+			}
+			w.Header().Set("Content-Type", "application/schema+json")
+			w.Write(body)
+		})
+	}
+}
+
+// SchemaBundle builds the schema bundle of routes registered so far,
+// routes behind feature flags disabled for ctx and types only they use are left out
+func (mux *Mux) SchemaBundle(ctx context.Context, version string) SchemaBundle {
+	routes := make(map[string]BundleRoute, len(mux.routes))
+	used := make(map[string]bool)
+	for _, rt := range mux.routes {
+		if rt.cfg.featureFlag != "" && !mux.flagEnabled(ctx, rt.cfg.featureFlag) {
+			continue
+		}
+		collectRefs(rt.requestSchema, mux.defs, used)
+		collectRefs(rt.responseSchema, mux.defs, used)
+		routes[rt.pattern] = BundleRoute{
+			Request:  toDefs(rt.requestSchema),
+			Response: toDefs(rt.responseSchema),
+		}
+	}
+
+	defs := make(map[string]*swaggergen.Schema, len(used))
+	for name := range used {
+		defs[name] = toDefs(mux.defs[name])
+	}
+
+	return SchemaBundle{
+		Schema:  jsonSchemaDialect,
+		Version: version,
+		Defs:    defs,
+		Routes:  routes,
+	}
+}
+
+// collectRefs marks names of component schemas s references, directly or through other components
+func collectRefs(s *swaggergen.Schema, components map[string]*swaggergen.Schema, used map[string]bool) {
+	if s == nil {
+		return
+	}
+	if name, ok := strings.CutPrefix(s.Ref, "#/components/schemas/"); ok && !used[name] {
+		used[name] = true
+		collectRefs(components[name], components, used)
+	}
+	collectRefs(s.Items, components, used)
+	for _, prop := range s.Properties {
+		collectRefs(prop, components, used)
+	}
+}
+
+// toDefs copies the schema rewriting OpenAPI component references to $defs references
+func toDefs(s *swaggergen.Schema) *swaggergen.Schema {
+	if s == nil {
+		return nil
+	}
+	res := *s
+	if res.Ref != "" {
+		res.Ref = "#/$defs/" + strings.TrimPrefix(res.Ref, "#/components/schemas/")
+	}
+	res.Items = toDefs(s.Items)
+	if s.Properties != nil {
+		res.Properties = maps.Clone(s.Properties)
+		for name, prop := range res.Properties {
+			res.Properties[name] = toDefs(prop)
+		}
+	}
+	return &res
+}
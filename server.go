@@ -3,80 +3,274 @@ package cruder
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"log/slog"
 	"net/http"
 	"reflect"
 	"strings"
+	"sync/atomic"
 
+	"github.com/pechorka/cruder/internal/respwriter"
+	"github.com/pechorka/cruder/pkg/audit"
 	"github.com/pechorka/cruder/pkg/httpio"
+	"github.com/pechorka/cruder/pkg/limit"
+	"github.com/pechorka/cruder/pkg/observability"
+	"github.com/pechorka/cruder/pkg/redact"
 	"github.com/pechorka/cruder/pkg/swaggergen"
 )
 
 type Mux struct {
-	sg  *swaggergen.Generator
-	mux *http.ServeMux
+	sg *swaggergen.Generator
+	// defs are schemas of named request and response types, generated apart from the spec
+	// at registration, so Manifest and SchemaBundle only read them
+	defs        map[string]*swaggergen.Schema
+	mux         *http.ServeMux
+	handler     http.Handler
+	middlewares []Middleware
+	obs         *observability.Instruments
+	logger      *slog.Logger
+	flags       FlagProvider
+	flagged     []flaggedRoute
+	routes      []route
+	auditLog    *audit.Log
+	runtime     *atomic.Pointer[runtimeState]
+	// limited is set by WithConcurrencyLimit, routes document 503 then
+	limited bool
 }
 
-func NewMux() *Mux {
+// Option configures Mux
+type Option func(*Mux)
+
+// Middleware wraps http.Handler with additional behavior
+type Middleware func(http.Handler) http.Handler
+
+func NewMux(opts ...Option) *Mux {
 	sg := swaggergen.NewGenerator()
 	mux := http.NewServeMux()
+	m := &Mux{
+		sg:      sg,
+		defs:    make(map[string]*swaggergen.Schema),
+		mux:     mux,
+		handler: mux,
+	}
+
 	// TODO: allow to customize swagger path
 	mux.HandleFunc("/swagger.json", func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(sg.Schema()); err != nil {
+		if err := json.NewEncoder(w).Encode(m.publicSchema(r.Context())); err != nil {
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
 	})
 
-	return &Mux{
-		sg:  sg,
-		mux: mux,
+	for _, opt := range opts {
+		opt(m)
+	}
+	return m
+}
+
+// Swagger returns the generator behind the served swagger.json,
+// so the spec can be extended beyond what handler registration produces
+func (mux *Mux) Swagger() *swaggergen.Generator {
+	return mux.sg
+}
+
+// Use adds middlewares to the mux. Middlewares are applied to every request
+// in the order they were added, so the first one is the outermost.
+func (mux *Mux) Use(mws ...Middleware) {
+	mux.middlewares = append(mux.middlewares, mws...)
+
+	var h http.Handler = mux.mux
+	for i := len(mux.middlewares) - 1; i >= 0; i-- {
+		h = mux.middlewares[i](h)
+	}
+	mux.handler = h
+}
+
+// RouteOption configures a single route
+type RouteOption func(*routeConfig)
+
+type routeConfig struct {
+	featureFlag string
+	limiter     *limit.Limiter
+	errors      []swaggergen.ErrorResponse
+	security    []swaggergen.SecurityRequirement
+}
+
+// WithSecurity documents that the route requires the security scheme added with Mux.Swagger().AddSecurityScheme,
+// scopes are required oauth2 scopes. Several options are alternatives, any one of them grants access.
+func WithSecurity(scheme string, scopes ...string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.security = append(cfg.security, swaggergen.SecurityRequirement{scheme: append([]string{}, scopes...)})
 	}
 }
 
+// WithErrorResponse documents a status the route fails with in swagger.json, with a JSON body of type T,
+// e.g. WithErrorResponse[NotFound](http.StatusNotFound, "user not found").
+// Use Mux.Swagger().AddErrorResponse for statuses every route shares.
+func WithErrorResponse[T any](status int, description string) RouteOption {
+	return func(cfg *routeConfig) {
+		cfg.errors = append(cfg.errors, swaggergen.ErrorResponse{
+			Status:      status,
+			Description: description,
+			BodyType:    reflect.TypeFor[T](),
+		})
+	}
+}
+
+type route struct {
+	pattern      string
+	method       string
+	path         string
+	requestType  reflect.Type
+	responseType reflect.Type
+	// requestSchema and responseSchema reference defs of Mux
+	requestSchema  *swaggergen.Schema
+	responseSchema *swaggergen.Schema
+	cfg            routeConfig
+}
+
 // pattern is GET /api/v1/users/{id}
-func RegisterHandler[Req, Resp any](mux *Mux, pattern string, hndl func(ctx context.Context, req Req) (Resp, error)) error {
+func RegisterHandler[Req, Resp any](mux *Mux, pattern string, hndl func(ctx context.Context, req Req) (Resp, error), opts ...RouteOption) error {
 	method, path, ok := strings.Cut(pattern, " ")
 	if !ok {
 		return fmt.Errorf("invalid template: %s", pattern)
 	}
 
-	mux.mux.HandleFunc(pattern, func(w http.ResponseWriter, r *http.Request) {
+	var cfg routeConfig
+	for _, opt := range opts {
+		opt(&cfg)
+	}
+
+	// invalid request and response types fail here rather than on every request
+	var req Req
+	requestType := reflect.TypeOf(req)
+	if requestType != nil {
+		if _, err := httpio.Describe(requestType); err != nil {
+			return fmt.Errorf("invalid request type of %s: %w", pattern, err)
+		}
+	}
+	var resp Resp
+	responseType := reflect.TypeOf(resp)
+	if responseType != nil {
+		if err := httpio.CheckResponse(responseType); err != nil {
+			return fmt.Errorf("invalid response type of %s: %w", pattern, err)
+		}
+	}
+
+	var handler http.Handler = http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req Req
-		if err := httpio.Unmarshal(r, &req); err != nil {
+		if err := mux.decode(r, &req); err != nil {
 			// TODO: allow to customize error response
-			http.Error(w, err.Error(), http.StatusBadRequest)
+			status := http.StatusBadRequest
+			if errors.Is(err, httpio.ErrBodyTooLarge) {
+				status = http.StatusRequestEntityTooLarge
+			}
+			mux.writeError(w, r, status, err, nil)
 			return
 		}
+		mux.logPayload(r, req)
 
 		resp, err := hndl(r.Context(), req)
 		if err != nil {
 			// TODO: allow user to specify http status code
-			http.Error(w, err.Error(), http.StatusInternalServerError)
+			mux.writeError(w, r, http.StatusInternalServerError, err, req)
 			return
 		}
 
-		w.Header().Set("Content-Type", "application/json")
-		if err := json.NewEncoder(w).Encode(resp); err != nil {
+		// the status tag of resp may set another status than 200
+		sw := respwriter.New(w)
+		if err := httpio.Marshal(sw, resp); err != nil {
 			// TODO: allow to customize error response
 			http.Error(w, err.Error(), http.StatusInternalServerError)
 			return
 		}
+		mux.recordAudit(r, req, sw.Status())
 	})
+	if cfg.limiter != nil {
+		handler = cfg.limiter.Middleware(handler)
+	}
+	if cfg.featureFlag != "" {
+		handler = mux.gate(cfg.featureFlag, handler)
+		mux.flagged = append(mux.flagged, flaggedRoute{path: path, method: method, flag: cfg.featureFlag})
+	}
+	mux.mux.Handle(pattern, handler)
 
-	var req Req
-	var resp Resp
+	rt := route{
+		pattern:      pattern,
+		method:       method,
+		path:         path,
+		requestType:  requestType,
+		responseType: responseType,
+		cfg:          cfg,
+	}
+	if rt.requestType != nil {
+		rt.requestSchema = mux.sg.SchemaInto(rt.requestType, mux.defs)
+	}
+	if rt.responseType != nil {
+		rt.responseSchema = mux.sg.SchemaInto(rt.responseType, mux.defs)
+	}
+	mux.routes = append(mux.routes, rt)
 	mux.sg.RegisterHandler(swaggergen.HandlerInfo{
 		Name:         pattern,
 		Path:         path,
 		Method:       method,
-		RequestType:  reflect.TypeOf(req),
-		ResponseType: reflect.TypeOf(resp),
+		RequestType:  requestType,
+		ResponseType: responseType,
+		Errors:       append(mux.frameworkErrors(rt), cfg.errors...),
+		Security:     cfg.security,
 	})
 	return nil
 }
 
+// frameworkErrors documents statuses the mux responds with before the handler runs,
+// error responses of route options come after them, so they replace them for the same status
+func (mux *Mux) frameworkErrors(rt route) []swaggergen.ErrorResponse {
+	var runtime RuntimeConfig
+	if mux.runtime != nil {
+		runtime = mux.runtime.Load().cfg
+	}
+
+	var errs []swaggergen.ErrorResponse
+	// limits of the runtime config are the ones set at registration
+	if rt.requestType != nil && (httpio.MaxBodySize() > 0 || runtime.MaxBodyBytes > 0) {
+		errs = append(errs, swaggergen.ErrorResponse{Status: http.StatusRequestEntityTooLarge, Description: "Request body is over the size limit"})
+	}
+	if rt.cfg.featureFlag != "" {
+		errs = append(errs, swaggergen.ErrorResponse{Status: http.StatusNotFound, Description: "Feature flag of the route is disabled"})
+	}
+	if runtime.rateLimited(rt.pattern) {
+		errs = append(errs, swaggergen.ErrorResponse{Status: http.StatusTooManyRequests, Description: "Rate limit is exceeded"})
+	}
+	if rt.cfg.limiter != nil || mux.limited || mux.runtime != nil {
+		errs = append(errs, swaggergen.ErrorResponse{Status: http.StatusServiceUnavailable, Description: "Concurrency limit is reached"})
+	}
+	return errs
+}
+
+// writeError logs the failed request together with its redacted payload and writes err to the client
+func (mux *Mux) writeError(w http.ResponseWriter, r *http.Request, status int, err error, req any) {
+	if mux.logger != nil {
+		attrs := []any{"method", r.Method, "path", r.URL.Path, "status", status, "error", err}
+		if req != nil {
+			attrs = append(attrs, "request", redact.Value(req))
+		}
+		mux.logger.ErrorContext(r.Context(), "request failed", attrs...)
+	}
+	mux.recordAudit(r, req, status)
+	http.Error(w, err.Error(), status)
+}
+
+func (mux *Mux) decode(r *http.Request, dest any) error {
+	if mux.obs == nil {
+		return httpio.Unmarshal(r, dest)
+	}
+	return mux.obs.Decode(r.Context(), func() error {
+		return httpio.Unmarshal(r, dest)
+	})
+}
+
 func (mux *Mux) ServeHTTP(w http.ResponseWriter, r *http.Request) {
-	mux.mux.ServeHTTP(w, r)
+	mux.handler.ServeHTTP(w, r)
 }
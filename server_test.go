@@ -0,0 +1,68 @@
+package cruder_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/pechorka/cruder"
+	"github.com/pechorka/cruder/pkg/httpio"
+	"github.com/pechorka/cruder/pkg/swaggergen"
+	"github.com/stretchr/testify/require"
+)
+
+type treeNode struct {
+	V    int       `query:"v"`
+	Next *treeNode `query:"next"`
+}
+
+type treeRequest struct {
+	Node treeNode `query:"node"`
+}
+
+type badCatchAllRequest struct {
+	Rest map[string]string `query:"*"`
+}
+
+type untaggedStatusResponse struct {
+	Status int `status:""`
+	ID     int `json:"id"`
+}
+
+func TestRegisterHandlerRejectsInvalidRequestType(t *testing.T) {
+	mux := cruder.NewMux()
+	err := cruder.RegisterHandler(mux, "GET /tree", func(context.Context, treeRequest) (struct{}, error) {
+		return struct{}{}, nil
+	})
+	require.ErrorContains(t, err, "invalid request type of GET /tree: httpio: field Node.Next has recursive type")
+
+	err = cruder.RegisterHandler(mux, "GET /search", func(context.Context, badCatchAllRequest) (struct{}, error) {
+		return struct{}{}, nil
+	})
+	require.EqualError(t, err, "invalid request type of GET /search: httpio: catch-all field Rest must be map[string][]string, got map[string]string")
+
+	err = cruder.RegisterHandler(mux, "POST /items", func(context.Context, struct{}) (untaggedStatusResponse, error) {
+		return untaggedStatusResponse{}, nil
+	})
+	require.EqualError(t, err, `invalid response type of POST /items: httpio: status field Status must be tagged json:"-"`)
+
+	paths := mux.Swagger().Schema().Paths
+	require.NotContains(t, paths, "/tree")
+	require.NotContains(t, paths, "/search")
+	require.NotContains(t, paths, "/items")
+}
+
+func TestBodyLimitDocumented(t *testing.T) {
+	responses := func(opts ...cruder.Option) map[string]swaggergen.Response {
+		mux := cruder.NewMux(opts...)
+		require.NoError(t, cruder.RegisterHandler(mux, "POST /checkout", checkout))
+		return mux.Swagger().Schema().Paths["/checkout"].POST.Responses
+	}
+
+	require.NotContains(t, responses(), "413")
+	require.NotContains(t, responses(cruder.WithRuntimeConfig(cruder.RuntimeConfig{})), "413")
+	require.Contains(t, responses(cruder.WithRuntimeConfig(cruder.RuntimeConfig{MaxBodyBytes: 1 << 20})), "413")
+
+	httpio.SetMaxBodySize(1 << 20)
+	t.Cleanup(func() { httpio.SetMaxBodySize(0) })
+	require.Contains(t, responses(), "413")
+}
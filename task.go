@@ -0,0 +1,34 @@
+package cruder
+
+import (
+	"context"
+	"errors"
+	"net/http"
+
+	"github.com/pechorka/cruder/pkg/task"
+)
+
+// ErrNoTaskRunner is returned by Enqueue when the mux was created without WithTaskRunner
+var ErrNoTaskRunner = errors.New("task runner is not configured")
+
+// WithTaskRunner makes runner available to handlers through Enqueue.
+// Runner is owned by the caller, call its Shutdown after the http server is stopped
+// to drain enqueued jobs.
+func WithTaskRunner(runner *task.Runner) Option {
+	return func(mux *Mux) {
+		mux.Use(func(next http.Handler) http.Handler {
+			return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				next.ServeHTTP(w, r.WithContext(task.NewContext(r.Context(), runner)))
+			})
+		})
+	}
+}
+
+// Enqueue schedules job on the task runner of the mux handling the request
+func Enqueue(ctx context.Context, job task.Job) error {
+	runner, ok := task.FromContext(ctx)
+	if !ok {
+		return ErrNoTaskRunner
+	}
+	return runner.Enqueue(ctx, job)
+}